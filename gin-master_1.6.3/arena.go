@@ -0,0 +1,93 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"sync/atomic"
+)
+
+// maxArenaScratch is the capacity of the largest render scratch kept by an arena, the
+// larger ones are released to the GC so that a single large response doesn't pin
+// its buffer.
+const maxArenaScratch = 1 << 20
+
+// requestArena holds the request-scoped allocations of a Context when
+// Engine.RequestArena is enabled. It's released once the response is complete and
+// reused by the next request served with the context.
+type requestArena struct {
+	keys    map[string]interface{}
+	scratch bytes.Buffer // encoded JSON of Context.JSON, see render.ScratchJSON
+}
+
+// ArenaStats are the sizes of the arenas of the requests, see Engine.RequestArena.
+type ArenaStats struct {
+	// Requests is the number of requests served with an arena.
+	Requests uint64
+	// Bytes is the sum of the render scratch capacities of the arenas when released.
+	Bytes uint64
+	// MaxBytes is the largest render scratch capacity of an arena when released.
+	MaxBytes uint64
+	// Keys is the number of keys set in the arenas.
+	Keys uint64
+}
+
+// arenaStats are the ArenaStats of an Engine, updated atomically.
+type arenaStats struct {
+	requests uint64
+	bytes    uint64
+	maxBytes uint64
+	keys     uint64
+}
+
+// ArenaStats returns the sizes of the arenas of the requests served so far.
+func (engine *Engine) ArenaStats() ArenaStats {
+	s := engine.arenaStats
+	return ArenaStats{
+		Requests: atomic.LoadUint64(&s.requests),
+		Bytes:    atomic.LoadUint64(&s.bytes),
+		MaxBytes: atomic.LoadUint64(&s.maxBytes),
+		Keys:     atomic.LoadUint64(&s.keys),
+	}
+}
+
+// acquireArena gives the context an arena for the request when the engine asks for it.
+func (engine *Engine) acquireArena(c *Context) {
+	if !engine.RequestArena {
+		c.arena = nil
+		return
+	}
+	if c.arena == nil {
+		c.arena = &requestArena{keys: make(map[string]interface{})}
+	}
+}
+
+// releaseArena records the size of the arena of the context and empties it for the next
+// request.
+func (engine *Engine) releaseArena(c *Context) {
+	a := c.arena
+	if a == nil {
+		return
+	}
+	s := engine.arenaStats
+	size := uint64(a.scratch.Cap())
+	atomic.AddUint64(&s.requests, 1)
+	atomic.AddUint64(&s.bytes, size)
+	atomic.AddUint64(&s.keys, uint64(len(a.keys)))
+	for {
+		max := atomic.LoadUint64(&s.maxBytes)
+		if size <= max || atomic.CompareAndSwapUint64(&s.maxBytes, max, size) {
+			break
+		}
+	}
+
+	for key := range a.keys {
+		delete(a.keys, key)
+	}
+	if a.scratch.Cap() > maxArenaScratch {
+		a.scratch = bytes.Buffer{}
+	}
+	c.Keys = nil
+}
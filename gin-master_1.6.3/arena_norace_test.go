@@ -0,0 +1,38 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build !race
+// +build !race
+
+// The arenas only save allocations when they are reused from their pool, which the race
+// detector empties at random.
+
+package gin
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestArenaAllocs(t *testing.T) {
+	allocs := func(arena bool) float64 {
+		router := New()
+		router.RequestArena = arena
+		router.GET("/users/:id", func(c *Context) {
+			c.Set("user", "gin")
+			c.JSON(http.StatusOK, H{"id": c.Param("id"), "name": strings.Repeat("gin", 100)})
+		})
+		w := newMockWriter()
+		req, _ := http.NewRequest(http.MethodGet, "/users/42", nil)
+		router.ServeHTTP(w, req) // warm up the pools of contexts and arenas
+		return testing.AllocsPerRun(100, func() {
+			w.Header().Del("Content-Type")
+			router.ServeHTTP(w, req)
+		})
+	}
+	assert.True(t, allocs(true) < allocs(false), "the arena saves the allocations of the keys and the encoded JSON")
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestArena(t *testing.T) {
+	router := New()
+	router.RequestArena = true
+	var keys map[string]interface{}
+	router.GET("/users/:id", func(c *Context) {
+		assert.Empty(t, c.Keys)
+		c.Set("user", c.Param("id"))
+		c.Set("role", "admin")
+		keys = c.Keys
+		c.JSON(http.StatusOK, H{"id": c.Param("id"), "user": c.MustGet("user")})
+	})
+
+	w := performRequest(router, http.MethodGet, "/users/1")
+	assert.Equal(t, `{"id":"1","user":"1"}`, w.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	// the keys are cleared once the response is complete
+	assert.Empty(t, keys)
+
+	w = performRequest(router, http.MethodGet, "/users/2")
+	assert.Equal(t, `{"id":"2","user":"2"}`, w.Body.String())
+
+	stats := router.ArenaStats()
+	assert.Equal(t, uint64(2), stats.Requests)
+	assert.Equal(t, uint64(4), stats.Keys)
+	assert.NotZero(t, stats.MaxBytes)
+	assert.True(t, stats.Bytes >= stats.MaxBytes)
+
+	// the arenas are dropped once disabled
+	router.RequestArena = false
+	w = performRequest(router, http.MethodGet, "/users/3")
+	assert.Equal(t, `{"id":"3","user":"3"}`, w.Body.String())
+	assert.Equal(t, map[string]interface{}{"user": "3", "role": "admin"}, keys)
+	assert.Equal(t, uint64(2), router.ArenaStats().Requests)
+}
+
+func TestRequestArenaLargeScratch(t *testing.T) {
+	router := New()
+	router.RequestArena = true
+	router.GET("/large", func(c *Context) {
+		c.JSON(http.StatusOK, strings.Repeat("a", maxArenaScratch))
+	})
+
+	w := performRequest(router, http.MethodGet, "/large")
+	assert.Equal(t, maxArenaScratch+2, w.Body.Len())
+	assert.True(t, router.ArenaStats().MaxBytes > maxArenaScratch)
+
+	// the large scratch isn't kept for the next request
+	c := &Context{arena: &requestArena{}}
+	c.arena.scratch.Grow(maxArenaScratch + 1)
+	router.releaseArena(c)
+	assert.Zero(t, c.arena.scratch.Cap())
+	c.arena.scratch = *bytes.NewBuffer(make([]byte, 0, maxArenaScratch))
+	router.releaseArena(c)
+	assert.Equal(t, maxArenaScratch, c.arena.scratch.Cap())
+}
+
+// BenchmarkRequestArena compares the requests setting a key and rendering JSON with and
+// without arenas.
+func BenchmarkRequestArena(b *testing.B) {
+	for _, arena := range []bool{false, true} {
+		router := New()
+		router.RequestArena = arena
+		router.GET("/users/:id", func(c *Context) {
+			c.Set("user", "gin")
+			c.JSON(http.StatusOK, H{"id": c.Param("id"), "name": strings.Repeat("gin", 100)})
+		})
+		w := newMockWriter()
+		req, _ := http.NewRequest(http.MethodGet, "/users/42", nil)
+
+		b.Run(fmt.Sprintf("Arena=%v", arena), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				router.ServeHTTP(w, req)
+			}
+		})
+	}
+}
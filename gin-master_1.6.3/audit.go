@@ -0,0 +1,158 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AuditOutcome is the outcome of an audited request.
+type AuditOutcome string
+
+// The outcomes of the audited requests, by status code.
+const (
+	// AuditSuccess is the outcome of the requests answered with a status below 400.
+	AuditSuccess AuditOutcome = "success"
+	// AuditDenied is the outcome of the requests answered with 401 or 403.
+	AuditDenied AuditOutcome = "denied"
+	// AuditFailure is the outcome of the requests answered with another 4xx status.
+	AuditFailure AuditOutcome = "failure"
+	// AuditError is the outcome of the requests answered with a 5xx status.
+	AuditError AuditOutcome = "error"
+	// AuditPanic is the outcome of the requests whose handlers panicked.
+	AuditPanic AuditOutcome = "panic"
+)
+
+// AuditEvent is the structured event emitted for each audited request.
+type AuditEvent struct {
+	Time      time.Time
+	Actor     string
+	Method    string
+	Route     string // the pattern of the matched route, empty when none matched
+	Path      string
+	Params    map[string]string
+	ClientIP  string
+	RequestID string
+	Status    int
+	Outcome   AuditOutcome
+	Latency   time.Duration
+	Errors    []string
+	Panic     string // the value the handlers panicked with, if any
+}
+
+// AuditSink receives the audit events, e.g. to hand them to a compliance pipeline. It's
+// called synchronously, at the end of the request.
+type AuditSink func(ctx context.Context, event *AuditEvent)
+
+// AuditConfig defines the config for Audit middleware.
+type AuditConfig struct {
+	// Sink receives the events.
+	// Required.
+	Sink AuditSink
+
+	// Actor returns the principal the request is made by.
+	// Optional. Default value is the user set under AuthUserKey, e.g. by BasicAuth or JWT.
+	Actor func(c *Context) string
+
+	// RedactParams are the names of the path parameters whose values are replaced by
+	// RedactedValue in the events.
+	// Optional.
+	RedactParams []string
+
+	// Skip reports whether the request is not audited, e.g. for health checks.
+	// Optional.
+	Skip func(c *Context) bool
+}
+
+// Audit returns a middleware emitting an AuditEvent for each request to the sink. The
+// event is emitted even if the handlers panic, in which case the panic is propagated
+// afterwards, so Audit should be used after Recovery:
+//     router.Use(gin.Recovery(), gin.Audit(gin.AuditConfig{Sink: complianceSink}))
+func Audit(conf AuditConfig) HandlerFunc {
+	assert1(conf.Sink != nil, "audit sink can not be nil")
+	actor := conf.Actor
+	if actor == nil {
+		actor = func(c *Context) string { return c.GetString(AuthUserKey) }
+	}
+	redacted := make(map[string]bool, len(conf.RedactParams))
+	for _, name := range conf.RedactParams {
+		redacted[name] = true
+	}
+
+	return func(c *Context) {
+		if conf.Skip != nil && conf.Skip(c) {
+			return
+		}
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		panicked := true
+		defer func() {
+			if !panicked {
+				return
+			}
+			err := recover()
+			event := newAuditEvent(c, start, path, actor, redacted)
+			event.Status = http.StatusInternalServerError
+			if c.Writer.Written() {
+				event.Status = c.Writer.Status()
+			}
+			event.Outcome = AuditPanic
+			event.Panic = fmt.Sprint(err)
+			conf.Sink(c.Request.Context(), event)
+			panic(err)
+		}()
+
+		c.Next()
+
+		panicked = false
+		event := newAuditEvent(c, start, path, actor, redacted)
+		conf.Sink(c.Request.Context(), event)
+	}
+}
+
+func newAuditEvent(c *Context, start time.Time, path string, actor func(*Context) string, redacted map[string]bool) *AuditEvent {
+	now := time.Now()
+	event := &AuditEvent{
+		Time:      now,
+		Actor:     actor(c),
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		Path:      path,
+		ClientIP:  c.ClientIP(),
+		RequestID: c.RequestID(),
+		Status:    c.Writer.Status(),
+		Latency:   now.Sub(start),
+	}
+	if len(c.Params) > 0 {
+		c.unescapeParams()
+		event.Params = make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			if redacted[param.Key] {
+				event.Params[param.Key] = RedactedValue
+			} else {
+				event.Params[param.Key] = param.Value
+			}
+		}
+	}
+	for _, err := range c.Errors {
+		event.Errors = append(event.Errors, err.Error())
+	}
+
+	switch status := event.Status; {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		event.Outcome = AuditDenied
+	case status >= 500:
+		event.Outcome = AuditError
+	case status >= 400:
+		event.Outcome = AuditFailure
+	default:
+		event.Outcome = AuditSuccess
+	}
+	return event
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	var events []*AuditEvent
+	router := New()
+	router.Use(RecoveryWithWriter(nil), func(c *Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set(AuthUserKey, user)
+		}
+	}, Audit(AuditConfig{
+		Sink:         func(_ context.Context, event *AuditEvent) { events = append(events, event) },
+		RedactParams: []string{"token"},
+		Skip:         func(c *Context) bool { return c.FullPath() == "/health" },
+	}))
+	router.GET("/accounts/:id/tokens/:token", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	router.DELETE("/accounts/:id", func(c *Context) {
+		if c.GetString(AuthUserKey) != "admin" {
+			_ = c.Error(errors.New("not an admin"))
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		panic("database down")
+	})
+	router.GET("/health", func(c *Context) {})
+
+	performRequest(router, "GET", "/accounts/42/tokens/secret", header{"X-User", "bob"})
+	performRequest(router, "DELETE", "/accounts/42", header{"X-User", "bob"})
+	w := performRequest(router, "DELETE", "/accounts/42", header{"X-User", "admin"})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	performRequest(router, "GET", "/health")
+	performRequest(router, "GET", "/missing")
+
+	require.Len(t, events, 4)
+	event := events[0]
+	assert.Equal(t, "bob", event.Actor)
+	assert.Equal(t, "GET", event.Method)
+	assert.Equal(t, "/accounts/:id/tokens/:token", event.Route)
+	assert.Equal(t, "/accounts/42/tokens/secret", event.Path)
+	assert.Equal(t, map[string]string{"id": "42", "token": RedactedValue}, event.Params)
+	assert.Equal(t, "192.0.2.1", event.ClientIP)
+	assert.Equal(t, http.StatusOK, event.Status)
+	assert.Equal(t, AuditSuccess, event.Outcome)
+	assert.False(t, event.Time.IsZero())
+
+	assert.Equal(t, AuditDenied, events[1].Outcome)
+	assert.Equal(t, []string{"not an admin"}, events[1].Errors)
+
+	assert.Equal(t, "admin", events[2].Actor)
+	assert.Equal(t, AuditPanic, events[2].Outcome)
+	assert.Equal(t, http.StatusInternalServerError, events[2].Status)
+	assert.Equal(t, "database down", events[2].Panic)
+
+	assert.Equal(t, AuditFailure, events[3].Outcome)
+	assert.Empty(t, events[3].Route)
+}
+
+func TestAuditPropagatesPanic(t *testing.T) {
+	emitted := false
+	router := New()
+	router.Use(Audit(AuditConfig{Sink: func(context.Context, *AuditEvent) { emitted = true }}))
+	router.GET("/", func(c *Context) { panic(http.ErrAbortHandler) })
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		performRequest(router, "GET", "/")
+	})
+	assert.True(t, emitted)
+}
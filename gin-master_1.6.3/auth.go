@@ -30,12 +30,14 @@ func (a authPairs) searchCredential(authValue string) (string, bool) {
 	if authValue == "" {
 		return "", false
 	}
+	// every pair is compared, so that the time taken doesn't tell which one matched
+	user, found := "", false
 	for _, pair := range a {
-		if subtle.ConstantTimeCompare([]byte(pair.value), []byte(authValue)) == 1 {
-			return pair.user, true
+		if subtle.ConstantTimeCompare([]byte(pair.value), []byte(authValue)) == 1 && !found {
+			user, found = pair.user, true
 		}
 	}
-	return "", false
+	return user, found
 }
 
 // BasicAuthForRealm returns a Basic HTTP Authorization middleware. It takes as arguments a map[string]string where
@@ -64,6 +66,90 @@ func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
 	}
 }
 
+// BasicAuthVerifier reports whether the password of the user is valid, e.g. by comparing
+// it to a bcrypt or argon2 hash, or by asking an identity provider.
+type BasicAuthVerifier func(c *Context, user, password string) bool
+
+// BasicAuthConfig defines the config for BasicAuth middleware.
+type BasicAuthConfig struct {
+	// Accounts are the user names and plain passwords allowed, compared in constant time.
+	// Optional when Verifier is set.
+	Accounts Accounts
+
+	// Verifier verifies the credentials of the users missing from Accounts.
+	// Optional when Accounts is set.
+	Verifier BasicAuthVerifier
+
+	// Realm is the name of the protection space.
+	// Optional. Default value is "Authorization Required".
+	Realm string
+
+	// Allow is called before the credentials are verified, returning false rejects the
+	// request with 429 Too Many Requests, e.g. when the client failed too many times.
+	// Optional.
+	Allow func(c *Context) bool
+
+	// OnFailure is called when the credentials are missing or invalid, before the request
+	// is rejected with 401, e.g. to count the failures of the client. It may abort the
+	// request itself.
+	// Optional.
+	OnFailure func(c *Context, user string)
+}
+
+// BasicAuthWithConfig returns a Basic HTTP Authorization middleware with config. Failures
+// can be rate limited with the hooks, e.g. with a RateLimitStore counting them by client IP:
+//     gin.BasicAuthConfig{
+//         Verifier: verifyHash,
+//         Allow: func(c *gin.Context) bool {
+//             return failures.Count(c.ClientIP()) < 5
+//         },
+//         OnFailure: func(c *gin.Context, user string) {
+//             failures.Add(c.ClientIP())
+//         },
+//     }
+// The user's id is set to key AuthUserKey in the context.
+func BasicAuthWithConfig(conf BasicAuthConfig) HandlerFunc {
+	assert1(len(conf.Accounts) > 0 || conf.Verifier != nil, "Empty list of authorized credentials and no verifier")
+	realm := conf.Realm
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	realm = "Basic realm=" + strconv.Quote(realm)
+	var pairs authPairs
+	if len(conf.Accounts) > 0 {
+		pairs = processAccounts(conf.Accounts)
+	}
+	return func(c *Context) {
+		if conf.Allow != nil && !conf.Allow(c) {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+
+		user, password, ok := c.Request.BasicAuth()
+		found := false
+		if ok {
+			var pairUser string
+			if pairUser, found = pairs.searchCredential(authorizationHeader(user, password)); found {
+				user = pairUser
+			} else if conf.Verifier != nil {
+				found = conf.Verifier(c, user, password)
+			}
+		}
+		if !found {
+			if conf.OnFailure != nil {
+				conf.OnFailure(c, user)
+			}
+			if !c.IsAborted() {
+				c.Header("WWW-Authenticate", realm)
+				c.AbortWithStatus(http.StatusUnauthorized)
+			}
+			return
+		}
+
+		c.Set(AuthUserKey, user)
+	}
+}
+
 // BasicAuth returns a Basic HTTP Authorization middleware. It takes as argument a map[string]string where
 // the key is the user name and the value is the password.
 func BasicAuth(accounts Accounts) HandlerFunc {
@@ -137,3 +137,69 @@ func TestBasicAuth401WithCustomRealm(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 	assert.Equal(t, "Basic realm=\"My Custom \\\"Realm\\\"\"", w.Header().Get("WWW-Authenticate"))
 }
+
+func TestBasicAuthWithConfigVerifier(t *testing.T) {
+	router := New()
+	router.Use(BasicAuthWithConfig(BasicAuthConfig{
+		Accounts: Accounts{"admin": "password"},
+		Verifier: func(c *Context, user, password string) bool {
+			return user == "alice" && password == "hashed"
+		},
+		Realm: "API",
+	}))
+	router.GET("/login", func(c *Context) {
+		c.String(http.StatusOK, c.MustGet(AuthUserKey).(string))
+	})
+
+	for user, password := range map[string]string{"admin": "password", "alice": "hashed"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/login", nil)
+		req.SetBasicAuth(user, password)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, user, w.Body.String())
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	req.SetBasicAuth("admin", "hashed")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Basic realm=\"API\"", w.Header().Get("WWW-Authenticate"))
+
+	assert.Panics(t, func() { BasicAuthWithConfig(BasicAuthConfig{}) })
+}
+
+func TestBasicAuthWithConfigFailureHooks(t *testing.T) {
+	failures := map[string]int{}
+	router := New()
+	router.Use(BasicAuthWithConfig(BasicAuthConfig{
+		Accounts: Accounts{"admin": "password"},
+		Allow: func(c *Context) bool {
+			return failures[c.ClientIP()] < 2
+		},
+		OnFailure: func(c *Context, user string) {
+			failures[c.ClientIP()]++
+			if user == "banned" {
+				c.AbortWithStatus(http.StatusForbidden)
+			}
+		},
+	}))
+	router.GET("/login", func(c *Context) {})
+
+	login := func(user, password string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/login", nil)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, login("", ""))
+	assert.Equal(t, http.StatusForbidden, login("banned", "password"))
+	assert.Equal(t, http.StatusTooManyRequests, login("admin", "password"))
+	assert.Equal(t, 2, failures[""])
+}
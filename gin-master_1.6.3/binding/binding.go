@@ -21,6 +21,7 @@ const (
 	MIMEMSGPACK           = "application/x-msgpack"
 	MIMEMSGPACK2          = "application/msgpack"
 	MIMEYAML              = "application/x-yaml"
+	MIMENDJSON            = "application/x-ndjson"
 )
 
 // Binding describes the interface which needs to be implemented for binding the
@@ -71,17 +72,19 @@ var Validator StructValidator = &defaultValidator{}
 // These implement the Binding interface and can be used to bind the data
 // present in the request to struct instances.
 var (
-	JSON          = jsonBinding{}
-	XML           = xmlBinding{}
-	Form          = formBinding{}
-	Query         = queryBinding{}
-	FormPost      = formPostBinding{}
-	FormMultipart = formMultipartBinding{}
-	ProtoBuf      = protobufBinding{}
-	MsgPack       = msgpackBinding{}
-	YAML          = yamlBinding{}
-	Uri           = uriBinding{}
-	Header        = headerBinding{}
+	JSON           = jsonBinding{}
+	JSONMergePatch = jsonMergePatchBinding{}
+	NDJSON         = ndjsonBinding{}
+	XML            = xmlBinding{}
+	Form           = formBinding{}
+	Query          = queryBinding{}
+	FormPost       = formPostBinding{}
+	FormMultipart  = formMultipartBinding{}
+	ProtoBuf       = protobufBinding{}
+	MsgPack        = msgpackBinding{}
+	YAML           = yamlBinding{}
+	Uri            = uriBinding{}
+	Header         = headerBinding{}
 )
 
 // Default returns the appropriate Binding instance based on the HTTP method
@@ -102,6 +105,8 @@ func Default(method, contentType string) Binding {
 		return MsgPack
 	case MIMEYAML:
 		return YAML
+	case MIMENDJSON:
+		return NDJSON
 	case MIMEMultipartPOSTForm:
 		return FormMultipart
 	default: // case MIMEPOSTForm:
@@ -19,6 +19,7 @@ const (
 	MIMEMultipartPOSTForm = "multipart/form-data"
 	MIMEPROTOBUF          = "application/x-protobuf"
 	MIMEYAML              = "application/x-yaml"
+	MIMENDJSON            = "application/x-ndjson"
 )
 
 // Binding describes the interface which needs to be implemented for binding the
@@ -68,16 +69,18 @@ var Validator StructValidator = &defaultValidator{}
 // These implement the Binding interface and can be used to bind the data
 // present in the request to struct instances.
 var (
-	JSON          = jsonBinding{}
-	XML           = xmlBinding{}
-	Form          = formBinding{}
-	Query         = queryBinding{}
-	FormPost      = formPostBinding{}
-	FormMultipart = formMultipartBinding{}
-	ProtoBuf      = protobufBinding{}
-	YAML          = yamlBinding{}
-	Uri           = uriBinding{}
-	Header        = headerBinding{}
+	JSON           = jsonBinding{}
+	JSONMergePatch = jsonMergePatchBinding{}
+	NDJSON         = ndjsonBinding{}
+	XML            = xmlBinding{}
+	Form           = formBinding{}
+	Query          = queryBinding{}
+	FormPost       = formPostBinding{}
+	FormMultipart  = formMultipartBinding{}
+	ProtoBuf       = protobufBinding{}
+	YAML           = yamlBinding{}
+	Uri            = uriBinding{}
+	Header         = headerBinding{}
 )
 
 // Default returns the appropriate Binding instance based on the HTTP method
@@ -96,6 +99,8 @@ func Default(method, contentType string) Binding {
 		return ProtoBuf
 	case MIMEYAML:
 		return YAML
+	case MIMENDJSON:
+		return NDJSON
 	case MIMEMultipartPOSTForm:
 		return FormMultipart
 	default: // case MIMEPOSTForm:
@@ -165,6 +165,8 @@ func TestBindingDefault(t *testing.T) {
 
 	assert.Equal(t, YAML, Default("POST", MIMEYAML))
 	assert.Equal(t, YAML, Default("PUT", MIMEYAML))
+
+	assert.Equal(t, NDJSON, Default("POST", MIMENDJSON))
 }
 
 func TestBindingJSONNilBody(t *testing.T) {
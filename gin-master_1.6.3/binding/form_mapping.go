@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin/internal/bytesconv"
@@ -28,19 +29,25 @@ func mapForm(ptr interface{}, form map[string][]string) error {
 
 var emptyField = reflect.StructField{}
 
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
 func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
 	// Check if ptr is a map
 	ptrVal := reflect.ValueOf(ptr)
-	var pointed interface{}
-	if ptrVal.Kind() == reflect.Ptr {
+	isPtr := ptrVal.Kind() == reflect.Ptr
+	if isPtr {
 		ptrVal = ptrVal.Elem()
-		pointed = ptrVal.Interface()
 	}
 	// 如果要绑定的值是 map类型，可能是 map[string][]string 或者 map[string]string
 	if ptrVal.Kind() == reflect.Map &&
 		ptrVal.Type().Key().Kind() == reflect.String {
-		if pointed != nil {
-			ptr = pointed
+		// Only dereference for maps: boxing a pointed struct into an
+		// interface{} copies it onto the heap on every call.
+		if isPtr {
+			ptr = ptrVal.Interface()
 		}
 		return setFormMap(ptr, form)
 	}
@@ -68,7 +75,74 @@ func mappingByPtr(ptr interface{}, setter setter, tag string) error {
 }
 
 func mapping(value reflect.Value, field reflect.StructField, setter setter, tag string) (bool, error) {
-	if field.Tag.Get(tag) == "-" { // just ignoring this field
+	plan := newFieldPlan(-1, field, tag)
+	return mappingPlan(value, &plan, setter, tag)
+}
+
+// fieldPlan holds everything mapping needs to know about a struct field, so
+// the struct tags are parsed once per type instead of once per request.
+type fieldPlan struct {
+	index    int
+	field    reflect.StructField
+	tagValue string
+	opt      setOptions
+	ignored  bool
+}
+
+func newFieldPlan(index int, field reflect.StructField, tag string) fieldPlan {
+	plan := fieldPlan{index: index, field: field}
+	tagValue := field.Tag.Get(tag)
+	if tagValue == "-" { // just ignoring this field
+		plan.ignored = true
+		return plan
+	}
+
+	tagValue, opts := head(tagValue, ",")
+	if tagValue == "" { // default value is FieldName
+		tagValue = field.Name
+	}
+	plan.tagValue = tagValue
+
+	var opt string
+	for len(opts) > 0 {
+		opt, opts = head(opts, ",")
+
+		if k, v := head(opt, "="); k == "default" {
+			plan.opt.isDefaultExists = true
+			plan.opt.defaultValue = v
+		}
+	}
+	return plan
+}
+
+type structPlanKey struct {
+	typ reflect.Type
+	tag string
+}
+
+// structPlanCache caches the []fieldPlan of every struct type met by mapping.
+var structPlanCache sync.Map // map[structPlanKey][]fieldPlan
+
+func cachedStructPlan(typ reflect.Type, tag string) []fieldPlan {
+	key := structPlanKey{typ: typ, tag: tag}
+	if plans, ok := structPlanCache.Load(key); ok {
+		return plans.([]fieldPlan)
+	}
+
+	plans := make([]fieldPlan, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+			continue
+		}
+		plans = append(plans, newFieldPlan(i, sf, tag))
+	}
+	actual, _ := structPlanCache.LoadOrStore(key, plans)
+	return actual.([]fieldPlan)
+}
+
+func mappingPlan(value reflect.Value, plan *fieldPlan, setter setter, tag string) (bool, error) {
+	if plan.ignored {
 		return false, nil
 	}
 
@@ -81,7 +155,7 @@ func mapping(value reflect.Value, field reflect.StructField, setter setter, tag
 			isNew = true
 			vPtr = reflect.New(value.Type().Elem())
 		}
-		isSetted, err := mapping(vPtr.Elem(), field, setter, tag)
+		isSetted, err := mappingPlan(vPtr.Elem(), plan, setter, tag)
 		if err != nil {
 			return false, err
 		}
@@ -91,8 +165,8 @@ func mapping(value reflect.Value, field reflect.StructField, setter setter, tag
 		return isSetted, nil
 	}
 
-	if vKind != reflect.Struct || !field.Anonymous {
-		ok, err := tryToSetValue(value, field, setter, tag)
+	if vKind != reflect.Struct || !plan.field.Anonymous {
+		ok, err := tryToSetValue(value, plan, setter)
 		if err != nil {
 			return false, err
 		}
@@ -102,15 +176,11 @@ func mapping(value reflect.Value, field reflect.StructField, setter setter, tag
 	}
 
 	if vKind == reflect.Struct {
-		tValue := value.Type()
+		plans := cachedStructPlan(value.Type(), tag)
 
 		var isSetted bool
-		for i := 0; i < value.NumField(); i++ {
-			sf := tValue.Field(i)
-			if sf.PkgPath != "" && !sf.Anonymous { // unexported
-				continue
-			}
-			ok, err := mapping(value.Field(i), tValue.Field(i), setter, tag)
+		for i := range plans {
+			ok, err := mappingPlan(value.Field(plans[i].index), &plans[i], setter, tag)
 			if err != nil {
 				return false, err
 			}
@@ -126,31 +196,11 @@ type setOptions struct {
 	defaultValue    string
 }
 
-func tryToSetValue(value reflect.Value, field reflect.StructField, setter setter, tag string) (bool, error) {
-	var tagValue string
-	var setOpt setOptions
-
-	tagValue = field.Tag.Get(tag)
-	tagValue, opts := head(tagValue, ",")
-
-	if tagValue == "" { // default value is FieldName
-		tagValue = field.Name
-	}
-	if tagValue == "" { // when field is "emptyField" variable
+func tryToSetValue(value reflect.Value, plan *fieldPlan, setter setter) (bool, error) {
+	if plan.tagValue == "" { // when field is "emptyField" variable
 		return false, nil
 	}
-
-	var opt string
-	for len(opts) > 0 {
-		opt, opts = head(opts, ",")
-
-		if k, v := head(opt, "="); k == "default" {
-			setOpt.isDefaultExists = true
-			setOpt.defaultValue = v
-		}
-	}
-
-	return setter.TrySet(value, field, tagValue, setOpt)
+	return setter.TrySet(value, plan.field, plan.tagValue, plan.opt)
 }
 
 func setByForm(value reflect.Value, field reflect.StructField, form map[string][]string, tagValue string, opt setOptions) (isSetted bool, err error) {
@@ -197,8 +247,7 @@ func setWithProperType(val string, value reflect.Value, field reflect.StructFiel
 	case reflect.Int32:
 		return setIntField(val, 32, value)
 	case reflect.Int64:
-		switch value.Interface().(type) {
-		case time.Duration:
+		if value.Type() == durationType {
 			return setTimeDuration(val, value, field)
 		}
 		return setIntField(val, 64, value)
@@ -221,8 +270,7 @@ func setWithProperType(val string, value reflect.Value, field reflect.StructFiel
 	case reflect.String:
 		value.SetString(val)
 	case reflect.Struct:
-		switch value.Interface().(type) {
-		case time.Time:
+		if value.Type() == timeType {
 			return setTimeField(val, field, value)
 		}
 		return json.Unmarshal(bytesconv.StringToBytes(val), value.Addr().Interface())
@@ -354,7 +402,7 @@ func setTimeDuration(val string, value reflect.Value, field reflect.StructField)
 	if err != nil {
 		return err
 	}
-	value.Set(reflect.ValueOf(d))
+	value.SetInt(int64(d))
 	return nil
 }
 
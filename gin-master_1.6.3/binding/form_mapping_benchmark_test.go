@@ -65,3 +65,50 @@ func BenchmarkMapFormName(b *testing.B) {
 	t := b
 	assert.Equal(t, "mike", s.Name)
 }
+
+type structQuery struct {
+	Page    int           `form:"page,default=1"`
+	PerPage int           `form:"per_page,default=20"`
+	Sort    string        `form:"sort"`
+	Desc    bool          `form:"desc"`
+	Timeout time.Duration `form:"timeout"`
+	Ignored string        `form:"-"`
+}
+
+var query = map[string][]string{
+	"page":    {"3"},
+	"sort":    {"name"},
+	"desc":    {"true"},
+	"timeout": {"5s"},
+}
+
+func BenchmarkMapFormQuery(b *testing.B) {
+	var s structQuery
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		err := mapForm(&s, query)
+		if err != nil {
+			b.Fatalf("Error on a form mapping")
+		}
+	}
+	b.StopTimer()
+
+	t := b
+	assert.Equal(t, 3, s.Page)
+	assert.Equal(t, 20, s.PerPage)
+	assert.Equal(t, "name", s.Sort)
+	assert.True(t, s.Desc)
+	assert.Equal(t, 5*time.Second, s.Timeout)
+}
+
+func BenchmarkMapFormQueryParallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		var s structQuery
+		for pb.Next() {
+			if err := mapForm(&s, query); err != nil {
+				b.Fatalf("Error on a form mapping")
+			}
+		}
+	})
+}
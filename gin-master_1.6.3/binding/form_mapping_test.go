@@ -279,3 +279,48 @@ func TestMappingIgnoredCircularRef(t *testing.T) {
 	err := mappingByPtr(&s, formSource{}, "form")
 	assert.NoError(t, err)
 }
+
+func TestMappingStructPlanCache(t *testing.T) {
+	type cached struct {
+		A       int    `form:"a,default=5"`
+		B       string `form:"-"`
+		C       string
+		private string
+	}
+
+	plans := cachedStructPlan(reflect.TypeOf(cached{}), "form")
+	assert.Len(t, plans, 3)
+	assert.Equal(t, "a", plans[0].tagValue)
+	assert.True(t, plans[0].opt.isDefaultExists)
+	assert.Equal(t, "5", plans[0].opt.defaultValue)
+	assert.True(t, plans[1].ignored)
+	assert.Equal(t, "C", plans[2].tagValue)
+	assert.Equal(t, 2, plans[2].index)
+
+	// a second lookup is served from the cache
+	again := cachedStructPlan(reflect.TypeOf(cached{}), "form")
+	assert.Same(t, &plans[0], &again[0])
+
+	// the plan is keyed by tag, too
+	uriPlans := cachedStructPlan(reflect.TypeOf(cached{}), "uri")
+	assert.Equal(t, "A", uriPlans[0].tagValue)
+
+	var s cached
+	err := mappingByPtr(&s, formSource{"B": {"x"}, "C": {"y"}}, "form")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, s.A)
+	assert.Equal(t, "", s.B)
+	assert.Equal(t, "y", s.C)
+}
+
+func TestMappingNoAllocs(t *testing.T) {
+	var s struct {
+		Name string `form:"name"`
+		Age  int    `form:"age"`
+	}
+	form := formSource{"name": {"mike"}, "age": {"3"}}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = mappingByPtr(&s, form, "form")
+	})
+	assert.Equal(t, float64(0), allocs)
+}
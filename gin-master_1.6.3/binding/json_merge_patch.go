@@ -0,0 +1,184 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package binding
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// Presence records which JSON object keys were present in a request body.
+// Embed it into a struct bound with JSONMergePatch to tell a field that was
+// omitted apart from a field that was explicitly set to its zero value:
+//
+//     type UserPatch struct {
+//         binding.Presence
+//         Name string `json:"name"`
+//         Age  int    `json:"age"`
+//     }
+//
+//     if patch.Has("age") { user.Age = patch.Age }
+//
+// Nested objects are recorded with dot separated keys, e.g. "address.city".
+// The keys are recorded by the JSON name of the field they are decoded into,
+// which encoding/json matches case-insensitively, so that {"Name": "x"} is
+// recorded as "name". The keys matching no field are recorded as they are.
+type Presence struct {
+	fields map[string]struct{}
+}
+
+// Has reports whether the given JSON key (or dotted key path) was present.
+func (p *Presence) Has(key string) bool {
+	_, ok := p.fields[key]
+	return ok
+}
+
+// Fields returns all the present keys in sorted order.
+func (p *Presence) Fields() []string {
+	keys := make([]string, 0, len(p.fields))
+	for k := range p.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (p *Presence) setPresence(fields map[string]struct{}) {
+	p.fields = fields
+}
+
+// presenceRecorder is implemented by every struct which embeds Presence.
+type presenceRecorder interface {
+	setPresence(map[string]struct{})
+}
+
+// jsonMergePatchBinding binds the JSON merge patches of RFC 7386, i.e. the
+// "application/merge-patch+json" bodies, rather than the list of operations
+// of a JSON patch (RFC 6902).
+type jsonMergePatchBinding struct{}
+
+func (jsonMergePatchBinding) Name() string {
+	return "json-merge-patch"
+}
+
+func (b jsonMergePatchBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return b.BindBody(body, obj)
+}
+
+func (jsonMergePatchBinding) BindBody(body []byte, obj interface{}) error {
+	recorder, ok := obj.(presenceRecorder)
+	if !ok {
+		return fmt.Errorf("json-merge-patch binding requires %T to embed binding.Presence", obj)
+	}
+	fields := make(map[string]struct{})
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return err
+	}
+	collectPresence("", raw, reflect.TypeOf(obj), fields)
+	recorder.setPresence(fields)
+	return decodeJSON(bytes.NewReader(body), obj)
+}
+
+// collectPresence records the keys of raw, decoded into a value of type typ.
+func collectPresence(prefix string, raw map[string]json.RawMessage, typ reflect.Type, fields map[string]struct{}) {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	var known []jsonField
+	if typ != nil && typ.Kind() == reflect.Struct {
+		known = jsonFields(typ)
+	}
+	for key, value := range raw {
+		var fieldType reflect.Type
+		if field, ok := matchJSONField(known, key); ok {
+			key, fieldType = field.name, field.typ
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		fields[path] = struct{}{}
+		if v := bytes.TrimSpace(value); len(v) > 0 && v[0] == '{' {
+			var nested map[string]json.RawMessage
+			if json.Unmarshal(v, &nested) == nil {
+				collectPresence(path, nested, fieldType, fields)
+			}
+		}
+	}
+}
+
+// jsonField is a field of a struct as encoding/json names it.
+type jsonField struct {
+	name string
+	typ  reflect.Type
+}
+
+// jsonFields returns the fields of the struct type decoded by encoding/json, followed
+// by the ones promoted from its embedded structs.
+func jsonFields(typ reflect.Type) []jsonField {
+	var fields []jsonField
+	var embedded []reflect.Type
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := tag
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name = tag[:i]
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, ft)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: sf.Type})
+	}
+	for _, t := range embedded {
+		fields = append(fields, jsonFields(t)...)
+	}
+	return fields
+}
+
+// matchJSONField returns the field a key is decoded into, preferring an exact match
+// to a case-insensitive one.
+func matchJSONField(fields []jsonField, key string) (jsonField, bool) {
+	for _, field := range fields {
+		if field.name == key {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+	return jsonField{}, false
+}
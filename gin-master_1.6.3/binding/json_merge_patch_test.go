@@ -0,0 +1,78 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package binding
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userPatch struct {
+	Presence
+	Name    string `json:"name"`
+	Age     int    `json:"age"`
+	Address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip"`
+	} `json:"address"`
+}
+
+func TestJSONMergePatchBindingBindBody(t *testing.T) {
+	var p userPatch
+	err := JSONMergePatch.BindBody([]byte(`{"age": 0, "address": {"city": "Paris"}}`), &p)
+	require.NoError(t, err)
+
+	assert.Equal(t, "json-merge-patch", JSONMergePatch.Name())
+	assert.True(t, p.Has("age"))
+	assert.False(t, p.Has("name"))
+	assert.True(t, p.Has("address"))
+	assert.True(t, p.Has("address.city"))
+	assert.False(t, p.Has("address.zip"))
+	assert.Equal(t, []string{"address", "address.city", "age"}, p.Fields())
+	assert.Equal(t, "Paris", p.Address.City)
+}
+
+func TestJSONMergePatchBindingBind(t *testing.T) {
+	var p userPatch
+	req, _ := http.NewRequest("PATCH", "/", bytes.NewBufferString(`{"name": ""}`))
+	require.NoError(t, JSONMergePatch.Bind(req, &p))
+	assert.True(t, p.Has("name"))
+	assert.False(t, p.Has("age"))
+
+	assert.Error(t, JSONMergePatch.Bind(&http.Request{}, &p))
+}
+
+func TestJSONMergePatchBindingErrors(t *testing.T) {
+	var plain struct {
+		Name string `json:"name"`
+	}
+	assert.Error(t, JSONMergePatch.BindBody([]byte(`{"name": "x"}`), &plain))
+
+	var p userPatch
+	assert.Error(t, JSONMergePatch.BindBody([]byte(`[1, 2]`), &p))
+	assert.Error(t, JSONMergePatch.BindBody([]byte(`{"age": "x"}`), &p))
+}
+
+func TestJSONMergePatchBindingCaseInsensitiveKeys(t *testing.T) {
+	type base struct {
+		ID int `json:"id"`
+	}
+	var p struct {
+		Presence
+		base
+		userPatch
+		Nick *string `json:"nick,omitempty"`
+		Zip  string
+	}
+	body := `{"Name": "bob", "ADDRESS": {"City": "Paris"}, "Nick": "b", "zip": "75001", "Id": 1, "other": {"Key": 1}}`
+	require.NoError(t, JSONMergePatch.BindBody([]byte(body), &p))
+	assert.Equal(t, "bob", p.Name)
+	assert.Equal(t, "Paris", p.Address.City)
+	assert.Equal(t, []string{"Zip", "address", "address.city", "id", "name", "nick", "other", "other.Key"}, p.Fields())
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+type ndjsonBinding struct{}
+
+func (ndjsonBinding) Name() string {
+	return "ndjson"
+}
+
+// Bind decodes every value of the body into obj, which must be a pointer to a slice.
+// Use NewNDJSONReader to process the values one at a time instead.
+func (ndjsonBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	return decodeNDJSON(req.Body, obj)
+}
+
+func (ndjsonBinding) BindBody(body []byte, obj interface{}) error {
+	return decodeNDJSON(bytes.NewReader(body), obj)
+}
+
+func decodeNDJSON(r io.Reader, obj interface{}) error {
+	ptr := reflect.ValueOf(obj)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Slice {
+		return errors.New("ndjson binding requires a pointer to a slice")
+	}
+	slice := ptr.Elem()
+	reader := NewNDJSONReader(r)
+	for {
+		item := reflect.New(slice.Type().Elem())
+		err := reader.Next(item.Interface())
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, item.Elem()))
+	}
+}
+
+// NDJSONReader decodes newline delimited JSON one value at a time, so that
+// bulk uploads can be processed while they are received.
+type NDJSONReader struct {
+	decoder *json.Decoder
+	values  int // the number of values read
+}
+
+// NewNDJSONReader returns an NDJSONReader reading from r, usually the request body.
+// It honors EnableDecoderUseNumber and EnableDecoderDisallowUnknownFields.
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	decoder := json.NewDecoder(r)
+	if EnableDecoderUseNumber {
+		decoder.UseNumber()
+	}
+	if EnableDecoderDisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	return &NDJSONReader{decoder: decoder}
+}
+
+// Next decodes and validates the next value into obj. It returns io.EOF when
+// there are no more values, errors on other values mention their position among
+// the values, which isn't their line number when a value spans several lines or a
+// line holds several values, as the decoder accepts.
+func (r *NDJSONReader) Next(obj interface{}) error {
+	if !r.decoder.More() {
+		return io.EOF
+	}
+	r.values++
+	if err := r.decoder.Decode(obj); err != nil {
+		return fmt.Errorf("ndjson value %d: %w", r.values, err)
+	}
+	if err := validate(obj); err != nil {
+		return fmt.Errorf("ndjson value %d: %w", r.values, err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package binding
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ndjsonEvent struct {
+	ID   int    `json:"id" binding:"required"`
+	Kind string `json:"kind"`
+}
+
+func TestNDJSONBindingBindBody(t *testing.T) {
+	var events []ndjsonEvent
+	err := NDJSON.BindBody([]byte("{\"id\":1,\"kind\":\"a\"}\n\n{\"id\":2}\n"), &events)
+	require.NoError(t, err)
+
+	assert.Equal(t, "ndjson", NDJSON.Name())
+	assert.Equal(t, []ndjsonEvent{{ID: 1, Kind: "a"}, {ID: 2}}, events)
+
+	var pointers []*ndjsonEvent
+	require.NoError(t, NDJSON.BindBody([]byte(`{"id":3}`), &pointers))
+	assert.Equal(t, 3, pointers[0].ID)
+}
+
+func TestNDJSONBindingBind(t *testing.T) {
+	var events []ndjsonEvent
+	req, _ := http.NewRequest("POST", "/", bytes.NewBufferString("{\"id\":1}\n{\"id\":2}\n"))
+	require.NoError(t, NDJSON.Bind(req, &events))
+	assert.Len(t, events, 2)
+
+	assert.Error(t, NDJSON.Bind(&http.Request{}, &events))
+}
+
+func TestNDJSONBindingErrors(t *testing.T) {
+	var event ndjsonEvent
+	assert.Error(t, NDJSON.BindBody([]byte(`{"id":1}`), &event))
+
+	var events []ndjsonEvent
+	err := NDJSON.BindBody([]byte("{\"id\":1}\n{\"kind\":\"x\"}\n"), &events)
+	assert.EqualError(t, err, "ndjson value 2: Key: 'ndjsonEvent.ID' Error:Field validation for 'ID' failed on the 'required' tag")
+
+	err = NDJSON.BindBody([]byte("{\"id\":1}\n{\"id\":\n"), &events)
+	assert.Contains(t, err.Error(), "ndjson value 2: ")
+
+	// the values are counted rather than the lines
+	err = NDJSON.BindBody([]byte("{\"id\":1,\n\"kind\":\"a\"}\n{\"kind\":\"x\"}\n"), &events)
+	assert.Contains(t, err.Error(), "ndjson value 2: ")
+}
+
+func TestNDJSONReader(t *testing.T) {
+	reader := NewNDJSONReader(strings.NewReader("{\"id\":1}\n{\"id\":2}\n"))
+	var ids []int
+	for {
+		var event ndjsonEvent
+		err := reader.Next(&event)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, event.ID)
+	}
+	assert.Equal(t, []int{1, 2}, ids)
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// MaxBytesError is the error returned when reading a request body larger than the
+// limit set by RouterGroup.MaxBodyBytes.
+type MaxBytesError struct {
+	Limit int64
+}
+
+func (e *MaxBytesError) Error() string {
+	return "http: request body too large, limit is " + strconv.FormatInt(e.Limit, 10) + " bytes"
+}
+
+// MaxBodyBytes limits the size of the request bodies of the routes added to the group
+// afterwards. The requests declaring a larger Content-Length are rejected with 413
+// before the handlers run, others fail with a *MaxBytesError when the handlers read
+// past the limit, and Bind rejects them with 413 too. The limit of a group replaces
+// the one of its parent, so that uploads can be allowed more than the rest of the API:
+//     api := router.Group("/api").MaxBodyBytes(1 << 20)
+//     upload := api.Group("/upload").MaxBodyBytes(100 << 20)
+func (group *RouterGroup) MaxBodyBytes(limit int64) *RouterGroup {
+	assert1(limit > 0, "body size limit must be positive")
+	handler := bodyLimit(limit)
+	if group.bodyLimitIndex > 0 {
+		// the limit of the parent is replaced in place, where it runs in the chain
+		handlers := make(HandlersChain, len(group.Handlers))
+		copy(handlers, group.Handlers)
+		handlers[group.bodyLimitIndex-1] = handler
+		group.Handlers = handlers
+	} else {
+		group.Use(handler)
+		group.bodyLimitIndex = len(group.Handlers)
+	}
+	return group
+}
+
+func bodyLimit(limit int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Request.ContentLength > limit {
+			c.AbortWithError(http.StatusRequestEntityTooLarge, &MaxBytesError{Limit: limit}) // nolint: errcheck
+			return
+		}
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			return
+		}
+		c.bodyLimit = &maxBodyReader{
+			reader: http.MaxBytesReader(c.writermem.ResponseWriter, c.Request.Body, limit),
+			limit:  limit,
+		}
+		c.Request.Body = c.bodyLimit
+	}
+}
+
+// maxBodyReader reads the body through http.MaxBytesReader, which asks the server to
+// close the connection once the limit is hit, and reports it with a typed error.
+type maxBodyReader struct {
+	reader io.ReadCloser
+	limit  int64
+	read   int64
+	err    *MaxBytesError
+}
+
+func (r *maxBodyReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if err != nil && err != io.EOF && r.read >= r.limit {
+		r.err = &MaxBytesError{Limit: r.limit}
+		err = r.err
+	}
+	return n, err
+}
+
+func (r *maxBodyReader) Close() error {
+	return r.reader.Close()
+}
+
+// bodyTooLarge returns the error of the request body read past its limit, or nil.
+func (c *Context) bodyTooLarge() *MaxBytesError {
+	if c.bodyLimit == nil {
+		return nil
+	}
+	return c.bodyLimit.err
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func performBodyRequest(r http.Handler, path, body string, chunked bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", MIMEJSON)
+	if chunked {
+		req.ContentLength = -1
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	router := New()
+	var bindErr error
+	api := router.Group("/api").MaxBodyBytes(16)
+	api.POST("/json", func(c *Context) {
+		var obj map[string]interface{}
+		if bindErr = c.BindJSON(&obj); bindErr == nil {
+			c.String(http.StatusOK, "ok")
+		}
+	})
+	api.POST("/raw", func(c *Context) {
+		_, err := ioutil.ReadAll(c.Request.Body)
+		assert.Equal(t, &MaxBytesError{Limit: 16}, err)
+		c.Status(http.StatusRequestEntityTooLarge)
+	})
+	upload := api.Group("/upload").MaxBodyBytes(64)
+	upload.POST("", func(c *Context) {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	})
+
+	small := `{"a":"b"}`
+	large := `{"a":"` + strings.Repeat("b", 32) + `"}`
+
+	w := performBodyRequest(router, "/api/json", small, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// rejected from the Content-Length, before the handler runs
+	bindErr = nil
+	w = performBodyRequest(router, "/api/json", large, false)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.NoError(t, bindErr)
+
+	// rejected while binding
+	w = performBodyRequest(router, "/api/json", large, true)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, &MaxBytesError{Limit: 16}, bindErr)
+	assert.Equal(t, "http: request body too large, limit is 16 bytes", bindErr.Error())
+
+	w = performBodyRequest(router, "/api/raw", large, true)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// the limit of the upload group replaces the one of the api group
+	w = performBodyRequest(router, "/api/upload", large, false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "40", w.Body.String())
+	w = performBodyRequest(router, "/api/upload", large+large, true)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "http: request body too large, limit is 64 bytes", w.Body.String())
+}
+
+func TestMaxBodyBytesKeepsParentChain(t *testing.T) {
+	router := New()
+	api := router.Group("/api").MaxBodyBytes(16)
+	api.Use(func(c *Context) { c.Header("X-Api", "1") })
+	api.Group("/upload").MaxBodyBytes(64).POST("", func(c *Context) {})
+	api.POST("/json", func(c *Context) {})
+
+	assert.Len(t, api.Handlers, 2)
+	w := performBodyRequest(router, "/api/upload", strings.Repeat("a", 32), false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-Api"))
+	w = performBodyRequest(router, "/api/json", strings.Repeat("a", 32), false)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Empty(t, w.Header().Get("X-Api"))
+}
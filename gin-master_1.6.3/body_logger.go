@@ -0,0 +1,332 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// defaultMaxLoggedBody is the size of the bodies logged by BodyLogger by default.
+const defaultMaxLoggedBody = 64 << 10
+
+// BodyRedaction declares the sensitive values replaced by RedactedValue in the logs
+// of the BodyLogger middleware.
+type BodyRedaction struct {
+	// Headers are the names of the headers redacted, in addition to Authorization,
+	// Proxy-Authorization, Cookie, Set-Cookie, X-Api-Key and X-Auth-Token.
+	Headers []string
+
+	// JSONPaths are the paths of the values redacted in JSON bodies, made of the object
+	// keys separated by dots, matched case-insensitively. A "*" matches any key or
+	// array element, e.g. "password", "user.ssn" or "cards.*.number". The JSON bodies
+	// which can't be parsed, e.g. once truncated, are redacted entirely.
+	JSONPaths []string
+
+	// FormKeys are the keys of the values redacted in the query strings and the
+	// urlencoded form bodies, e.g. "password" or "token".
+	FormKeys []string
+}
+
+// BodyLoggerConfig defines the config for BodyLogger middleware.
+type BodyLoggerConfig struct {
+	// Fields are the fields of the logs, the headers and bodies are added to them.
+	// Optional. Default value is gin.DefaultLogFields.
+	Fields []LogField
+
+	// Sink receives the logs.
+	// Optional. The logs are written as JSON lines to Output by default.
+	Sink LogSink
+
+	// Output is a writer where logs are written as JSON lines when Sink is nil.
+	// Optional. Default value is gin.DefaultWriter.
+	Output io.Writer
+
+	// MaxBodySize is the number of bytes of the bodies logged, the rest is dropped.
+	// Optional. Default value is 64 KB.
+	MaxBodySize int
+
+	// Redaction declares the values which must not be logged.
+	// Optional.
+	Redaction BodyRedaction
+
+	// Skip reports whether the request is not logged, e.g. for file uploads.
+	// Optional.
+	Skip func(c *Context) bool
+}
+
+// BodyLogger returns a middleware logging the requests with their headers and bodies,
+// redacted before they reach the sink:
+//     router.Use(gin.BodyLogger(gin.BodyLoggerConfig{
+//         Sink: auditSink,
+//         Redaction: gin.BodyRedaction{
+//             JSONPaths: []string{"password", "cards.*.number"},
+//             FormKeys:  []string{"password"},
+//         },
+//     }))
+// The request body is captured as the handlers read it, and the response body as they
+// write it, so nothing is buffered besides the logged bytes. The bodies which are not
+// text, JSON, XML or forms are logged as their size only.
+func BodyLogger(conf BodyLoggerConfig) HandlerFunc {
+	fields := conf.Fields
+	if fields == nil {
+		fields = DefaultLogFields
+	}
+	sink := conf.Sink
+	if sink == nil {
+		out := conf.Output
+		if out == nil {
+			out = DefaultWriter
+		}
+		sink = jsonLogSink(out)
+	}
+	maxSize := conf.MaxBodySize
+	if maxSize <= 0 {
+		maxSize = defaultMaxLoggedBody
+	}
+	redactor := newBodyRedactor(conf.Redaction)
+
+	return func(c *Context) {
+		if conf.Skip != nil && conf.Skip(c) {
+			return
+		}
+		start := c.engine.clock().Now()
+		path := c.Request.URL.Path
+		raw := c.Request.URL.RawQuery
+
+		request := &boundedBuffer{max: maxSize}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			c.Request.Body = &teeReadCloser{Reader: io.TeeReader(c.Request.Body, request), Closer: c.Request.Body}
+		}
+		w := &bodyLogWriter{ResponseWriter: c.Writer, body: boundedBuffer{max: maxSize}}
+		c.Writer = w
+
+		c.Next()
+
+		c.Writer = w.ResponseWriter
+		param := newLogFormatterParams(c, start, path, redactor.query(raw))
+		attrs := make([]LogAttr, 0, len(fields)+6)
+		for _, field := range fields {
+			if value := field.Value(&param); value != nil {
+				attrs = append(attrs, LogAttr{Key: field.Name, Value: value})
+			}
+		}
+		header := w.Header()
+		attrs = append(attrs,
+			LogAttr{Key: "request_headers", Value: redactor.header(c.Request.Header)},
+			LogAttr{Key: "request_body", Value: redactor.body(c.requestHeader("Content-Type"), request)},
+			LogAttr{Key: "response_headers", Value: redactor.header(header)},
+			LogAttr{Key: "response_body", Value: redactor.body(header.Get("Content-Type"), &w.body)},
+		)
+		if request.truncated || w.body.truncated {
+			attrs = append(attrs, LogAttr{Key: "body_truncated", Value: true})
+		}
+		sink(c.Request.Context(), attrs)
+	}
+}
+
+func jsonLogSink(out io.Writer) LogSink {
+	return func(_ context.Context, attrs []LogAttr) {
+		buf := make([]byte, 0, 1024)
+		buf = append(buf, '{')
+		for i, attr := range attrs {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSON(buf, attr.Key)
+			buf = append(buf, ':')
+			buf = appendJSON(buf, attr.Value)
+		}
+		_, _ = out.Write(append(buf, '}', '\n'))
+	}
+}
+
+// boundedBuffer keeps the first max bytes written to it.
+type boundedBuffer struct {
+	bytes.Buffer
+	max       int
+	size      int
+	truncated bool
+}
+
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.size += n
+	if room := b.max - b.Len(); room < n {
+		b.truncated = true
+		p = p[:room]
+	}
+	b.Buffer.Write(p) // nolint: errcheck
+	return n, nil
+}
+
+// WriteString goes through Write, so that the bound applies.
+func (b *boundedBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyLogWriter copies the body written by the handlers, up to the logged size.
+type bodyLogWriter struct {
+	ResponseWriter
+	body boundedBuffer
+}
+
+func (w *bodyLogWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.body.Write(data[:n]) // nolint: errcheck
+	return n, err
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.body.WriteString(s[:n]) // nolint: errcheck
+	return n, err
+}
+
+func (w *bodyLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.body.truncated = true
+	return w.ResponseWriter.Hijack()
+}
+
+type bodyRedactor struct {
+	headers   map[string]bool
+	jsonPaths [][]string
+	formKeys  map[string]bool
+}
+
+func newBodyRedactor(conf BodyRedaction) *bodyRedactor {
+	r := &bodyRedactor{headers: make(map[string]bool), formKeys: make(map[string]bool)}
+	for _, name := range append(append([]string{"Set-Cookie"}, redactedHeaders...), conf.Headers...) {
+		r.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, p := range conf.JSONPaths {
+		r.jsonPaths = append(r.jsonPaths, strings.Split(strings.ToLower(p), "."))
+	}
+	for _, key := range conf.FormKeys {
+		r.formKeys[key] = true
+	}
+	return r
+}
+
+func (r *bodyRedactor) header(header http.Header) map[string]string {
+	logged := make(map[string]string, len(header))
+	for name, values := range header {
+		if r.headers[http.CanonicalHeaderKey(name)] {
+			logged[name] = RedactedValue
+		} else {
+			logged[name] = strings.Join(values, ", ")
+		}
+	}
+	return logged
+}
+
+func (r *bodyRedactor) query(raw string) string {
+	if raw == "" || len(r.formKeys) == 0 {
+		return raw
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return RedactedValue
+	}
+	return r.form(values).Encode()
+}
+
+func (r *bodyRedactor) form(values url.Values) url.Values {
+	for key, vs := range values {
+		if r.formKeys[key] {
+			for i := range vs {
+				vs[i] = RedactedValue
+			}
+		}
+	}
+	return values
+}
+
+// body returns the logged form of the body: redacted JSON, a redacted form, text, or
+// only the size of other kinds of bodies.
+func (r *bodyRedactor) body(contentType string, body *boundedBuffer) interface{} {
+	if body.size == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == MIMEJSON || strings.HasSuffix(mediaType, "+json"):
+		if len(r.jsonPaths) == 0 {
+			return body.String()
+		}
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body.Bytes()))
+		decoder.UseNumber()
+		if body.truncated || decoder.Decode(&value) != nil {
+			return RedactedValue
+		}
+		for _, p := range r.jsonPaths {
+			redactJSON(value, p)
+		}
+		return value
+	case mediaType == MIMEPOSTForm:
+		if len(r.formKeys) == 0 {
+			return body.String()
+		}
+		values, err := url.ParseQuery(body.String())
+		if body.truncated || err != nil {
+			return RedactedValue
+		}
+		return r.form(values).Encode()
+	case strings.HasPrefix(mediaType, "text/") || mediaType == MIMEXML || strings.HasSuffix(mediaType, "+xml"):
+		return body.String()
+	}
+	return "[" + strconv.Itoa(body.size) + " bytes]"
+}
+
+// redactJSON replaces the values at the path in the decoded JSON value.
+func redactJSON(value interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path[0] != "*" && strings.ToLower(key) != path[0] {
+				continue
+			}
+			if len(path) == 1 {
+				v[key] = RedactedValue
+			} else {
+				redactJSON(child, path[1:])
+			}
+		}
+	case []interface{}:
+		if path[0] != "*" {
+			// the keys apply to the objects of the arrays too, e.g. "users.password"
+			for _, child := range v {
+				redactJSON(child, path)
+			}
+			return
+		}
+		for i, child := range v {
+			if len(path) == 1 {
+				v[i] = RedactedValue
+			} else {
+				redactJSON(child, path[1:])
+			}
+		}
+	}
+}
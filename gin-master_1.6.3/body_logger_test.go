@@ -0,0 +1,126 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/internal/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bodyLoggerRouter(conf BodyLoggerConfig, logs *[]map[string]interface{}) *Engine {
+	conf.Fields = []LogField{LogFieldStatus, LogFieldPath}
+	conf.Sink = func(_ context.Context, attrs []LogAttr) {
+		log := make(map[string]interface{}, len(attrs))
+		for _, attr := range attrs {
+			log[attr.Key] = attr.Value
+		}
+		*logs = append(*logs, log)
+	}
+	router := New()
+	router.Use(BodyLogger(conf))
+	router.POST("/echo", func(c *Context) {
+		body, _ := ioutil.ReadAll(c.Request.Body)
+		c.SetCookie("session", "secret", 0, "/", "", false, true)
+		c.Data(http.StatusOK, c.ContentType(), body)
+	})
+	return router
+}
+
+func TestBodyLoggerRedaction(t *testing.T) {
+	var logs []map[string]interface{}
+	router := bodyLoggerRouter(BodyLoggerConfig{Redaction: BodyRedaction{
+		Headers:   []string{"X-Secret"},
+		JSONPaths: []string{"password", "cards.*.number", "profile.ssn"},
+		FormKeys:  []string{"token"},
+	}}, &logs)
+
+	body := `{"user":"alice","Password":"hunter2","cards":[{"number":"4111","exp":"12/30"}],"profile":{"ssn":"123","age":30}}`
+	req := httptest.NewRequest("POST", "/echo?token=abc&page=2", strings.NewReader(body))
+	req.Header.Set("Content-Type", MIMEJSON)
+	req.Header.Set("Authorization", "Bearer xyz")
+	req.Header.Set("X-Secret", "s")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, logs, 1)
+	log := logs[0]
+	assert.Equal(t, 200, log["status"])
+	assert.Equal(t, "/echo?page=2&token=%5Bredacted%5D", log["path"])
+	headers := log["request_headers"].(map[string]string)
+	assert.Equal(t, RedactedValue, headers["Authorization"])
+	assert.Equal(t, RedactedValue, headers["X-Secret"])
+	assert.Equal(t, MIMEJSON, headers["Content-Type"])
+	assert.Equal(t, RedactedValue, log["response_headers"].(map[string]string)["Set-Cookie"])
+
+	for _, key := range []string{"request_body", "response_body"} {
+		logged, err := json.Marshal(log[key])
+		require.NoError(t, err)
+		assert.Equal(t, `{"Password":"[redacted]","cards":[{"exp":"12/30","number":"[redacted]"}],"profile":{"age":30,"ssn":"[redacted]"},"user":"alice"}`, string(logged))
+	}
+	assert.NotContains(t, log, "body_truncated")
+}
+
+func TestBodyLoggerForms(t *testing.T) {
+	var logs []map[string]interface{}
+	router := bodyLoggerRouter(BodyLoggerConfig{Redaction: BodyRedaction{FormKeys: []string{"password"}}}, &logs)
+
+	req := httptest.NewRequest("POST", "/echo", strings.NewReader("user=alice&password=hunter2"))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "password=%5Bredacted%5D&user=alice", logs[0]["request_body"])
+
+	req = httptest.NewRequest("POST", "/echo", bytes.NewReader([]byte{0, 1, 2, 3}))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	require.Len(t, logs, 2)
+	assert.Equal(t, "[4 bytes]", logs[1]["request_body"])
+	assert.Equal(t, "[4 bytes]", logs[1]["response_body"])
+}
+
+func TestBodyLoggerTruncation(t *testing.T) {
+	var logs []map[string]interface{}
+	router := bodyLoggerRouter(BodyLoggerConfig{MaxBodySize: 8, Redaction: BodyRedaction{JSONPaths: []string{"password"}}}, &logs)
+
+	req := httptest.NewRequest("POST", "/echo", strings.NewReader(`{"password":"hunter2"}`))
+	req.Header.Set("Content-Type", MIMEJSON)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// the response is complete, the truncated JSON can't be redacted so it isn't logged
+	assert.Equal(t, `{"password":"hunter2"}`, w.Body.String())
+	require.Len(t, logs, 1)
+	assert.Equal(t, RedactedValue, logs[0]["request_body"])
+	assert.Equal(t, RedactedValue, logs[0]["response_body"])
+	assert.Equal(t, true, logs[0]["body_truncated"])
+
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader("0123456789"))
+	req.Header.Set("Content-Type", MIMEPlain)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "01234567", logs[1]["request_body"])
+}
+
+func TestBodyLoggerOutput(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(BodyLogger(BodyLoggerConfig{Output: buffer, Fields: []LogField{LogFieldMethod}, Skip: func(c *Context) bool {
+		return c.Request.URL.Path == "/skip"
+	}}))
+	router.GET("/", func(c *Context) { c.String(http.StatusOK, "hello") })
+	router.GET("/skip", func(c *Context) {})
+
+	performRequest(router, "GET", "/skip")
+	assert.Empty(t, buffer.String())
+	performRequest(router, "GET", "/")
+	assert.Equal(t, `{"method":"GET","request_headers":{},"request_body":null,"response_headers":{"Content-Type":"text/plain; charset=utf-8"},"response_body":"hello"}`+"\n", buffer.String())
+}
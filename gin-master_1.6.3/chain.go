@@ -0,0 +1,111 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+// Chain is a reusable sequence of middleware, built once and used by several groups or
+// engines, e.g. the standard chain of a platform:
+//     standard := gin.NewChain().
+//         Named(gin.MiddlewareLogger, gin.Logger()).
+//         Named(gin.MiddlewareRecovery, gin.Recovery()).
+//         Append(gin.RequestID())
+//     router.UseChain(standard)
+//     admin.UseChain(standard.Append(adminOnly))
+// A Chain is immutable: its methods return a new Chain, so it can be shared safely.
+type Chain struct {
+	handlers HandlersChain
+	names    []string
+}
+
+// NewChain returns a Chain of the unnamed middleware.
+func NewChain(middleware ...HandlerFunc) Chain {
+	return Chain{}.Append(middleware...)
+}
+
+// Append returns a Chain with the unnamed middleware added at the end.
+func (ch Chain) Append(middleware ...HandlerFunc) Chain {
+	return ch.with(middleware, make([]string, len(middleware)))
+}
+
+// Named returns a Chain with the middleware added at the end under a name, which is
+// kept by UseChain, see RouterGroup.UseNamed.
+func (ch Chain) Named(name string, middleware HandlerFunc) Chain {
+	assert1(name != "", "middleware name can not be empty")
+	for _, n := range ch.names {
+		assert1(n != name, "middleware '"+name+"' is already in the chain")
+	}
+	return ch.with(HandlersChain{middleware}, []string{name})
+}
+
+// Extend returns a Chain with the middleware of other added at the end.
+func (ch Chain) Extend(other Chain) Chain {
+	for _, name := range other.names {
+		for _, n := range ch.names {
+			assert1(name == "" || n != name, "middleware '"+name+"' is already in the chain")
+		}
+	}
+	return ch.with(other.handlers, other.names)
+}
+
+func (ch Chain) with(handlers HandlersChain, names []string) Chain {
+	next := Chain{
+		handlers: make(HandlersChain, 0, len(ch.handlers)+len(handlers)),
+		names:    make([]string, 0, len(ch.names)+len(names)),
+	}
+	next.handlers = append(append(next.handlers, ch.handlers...), handlers...)
+	next.names = append(append(next.names, ch.names...), names...)
+	return next
+}
+
+// Len returns the number of middleware of the Chain.
+func (ch Chain) Len() int {
+	return len(ch.handlers)
+}
+
+// Handlers returns the middleware of the Chain, in order.
+func (ch Chain) Handlers() HandlersChain {
+	return append(HandlersChain(nil), ch.handlers...)
+}
+
+// Names returns the names of the middleware of the Chain, in order, with empty strings
+// for the unnamed ones.
+func (ch Chain) Names() []string {
+	return append([]string(nil), ch.names...)
+}
+
+// FuncNames returns the function names of the middleware of the Chain, in order, e.g.
+// "github.com/gin-gonic/gin.LoggerWithConfig.func1".
+func (ch Chain) FuncNames() []string {
+	names := make([]string, len(ch.handlers))
+	for i, h := range ch.handlers {
+		names[i] = funcName(h)
+	}
+	return names
+}
+
+// Then returns the middleware of the Chain followed by the handlers, to register a route:
+//     router.GET("/reports", standard.Then(listReports)...)
+func (ch Chain) Then(handlers ...HandlerFunc) HandlersChain {
+	return append(ch.Handlers(), handlers...)
+}
+
+// UseChain adds the middleware of the Chain to the group, keeping their names.
+func (group *RouterGroup) UseChain(ch Chain) IRoutes {
+	for i, h := range ch.handlers {
+		if ch.names[i] != "" {
+			group.UseNamed(ch.names[i], h)
+		} else {
+			group.Use(h)
+		}
+	}
+	return group.returnObj()
+}
+
+// UseChain attaches the middleware of the Chain to the router as global middleware,
+// see RouterGroup.UseChain.
+func (engine *Engine) UseChain(ch Chain) IRoutes {
+	engine.RouterGroup.UseChain(ch)
+	engine.rebuildGlobalHandlers()
+	return engine
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	var trace []string
+	standard := NewChain().
+		Named(MiddlewareLogger, tracer(&trace, "logger")).
+		Named(MiddlewareRecovery, tracer(&trace, "recovery")).
+		Append(tracer(&trace, "request-id"))
+	admin := standard.Append(tracer(&trace, "admin"))
+
+	assert.Equal(t, 3, standard.Len())
+	assert.Equal(t, []string{MiddlewareLogger, MiddlewareRecovery, ""}, standard.Names())
+	assert.Equal(t, []string{MiddlewareLogger, MiddlewareRecovery, "", ""}, admin.Names())
+	assert.Len(t, standard.Handlers(), 3)
+	assert.Equal(t, "github.com/gin-gonic/gin.tracer.func1", standard.FuncNames()[0])
+
+	router := New()
+	router.UseChain(standard)
+	router.UseBefore(MiddlewareRecovery, tracer(&trace, "metrics"))
+	router.GET("/", tracer(&trace, "handler"))
+	other := New()
+	other.GET("/admin", admin.Then(tracer(&trace, "handler"))...)
+	other.Group("/api").UseChain(NewChain(tracer(&trace, "api")).Extend(standard)).GET("/", tracer(&trace, "handler"))
+
+	performRequest(router, "GET", "/")
+	assert.Equal(t, "logger metrics recovery request-id handler", strings.Join(trace, " "))
+	assert.Equal(t, []string{MiddlewareLogger, "", MiddlewareRecovery, ""}, router.MiddlewareNames())
+
+	trace = nil
+	performRequest(other, "GET", "/admin")
+	assert.Equal(t, "logger recovery request-id admin handler", strings.Join(trace, " "))
+	trace = nil
+	performRequest(other, "GET", "/api/")
+	assert.Equal(t, "api logger recovery request-id handler", strings.Join(trace, " "))
+
+	// the chain is not changed by its use
+	assert.Equal(t, 3, standard.Len())
+	assert.Panics(t, func() { standard.Named(MiddlewareLogger, tracer(&trace, "logger")) })
+	assert.Panics(t, func() { standard.Extend(standard) })
+	assert.NotPanics(t, func() { standard.Extend(NewChain(tracer(&trace, "a"))) })
+}
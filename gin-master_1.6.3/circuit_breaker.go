@@ -0,0 +1,232 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets the requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects the requests until the open timeout elapses.
+	CircuitOpen
+	// CircuitHalfOpen lets a few probe requests through to decide whether to close.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerConfig defines the config of CircuitBreaker.
+type CircuitBreakerConfig struct {
+	// Key returns the circuit of the request, e.g. the upstream service it depends on.
+	// Optional. Default value is the method and the route pattern of the request.
+	Key func(c *Context) string
+
+	// FailureThreshold is the number of consecutive failures opening the circuit.
+	// Optional. Default value is 5.
+	FailureThreshold int
+
+	// OpenTimeout is how long the circuit stays open before probing again.
+	// Optional. Default value is 30 seconds.
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is the number of requests let through by a half-open circuit,
+	// the circuit closes when all of them succeed.
+	// Optional. Default value is 1.
+	HalfOpenProbes int
+
+	// IsFailure reports whether the request failed, once served.
+	// Optional. Default reports the 5xx responses as failures.
+	IsFailure func(c *Context) bool
+
+	// Fallback handles the requests rejected by an open circuit.
+	// Optional. Default aborts with 503.
+	Fallback HandlerFunc
+
+	// OnStateChange is called when a circuit changes of state.
+	// Optional.
+	OnStateChange func(key string, from, to CircuitState)
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probes   int
+	passed   int
+	// generation counts the state changes, the outcomes of the requests
+	// allowed before the last change are ignored.
+	generation int
+}
+
+// CircuitBreaker stops serving the requests of a circuit, i.e. a route or an upstream
+// service, after consecutive failures, so that a degraded dependency doesn't hold the
+// workers. Once the open timeout elapsed, a few probe requests decide whether the
+// circuit closes again or stays open for another timeout.
+type CircuitBreaker struct {
+	conf CircuitBreakerConfig
+	now  func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// NewCircuitBreaker returns a CircuitBreaker with every circuit closed.
+func NewCircuitBreaker(conf CircuitBreakerConfig) *CircuitBreaker {
+	if conf.Key == nil {
+		conf.Key = func(c *Context) string {
+			return c.Request.Method + " " + c.FullPath()
+		}
+	}
+	if conf.FailureThreshold <= 0 {
+		conf.FailureThreshold = 5
+	}
+	if conf.OpenTimeout <= 0 {
+		conf.OpenTimeout = 30 * time.Second
+	}
+	if conf.HalfOpenProbes <= 0 {
+		conf.HalfOpenProbes = 1
+	}
+	if conf.IsFailure == nil {
+		conf.IsFailure = func(c *Context) bool {
+			return c.Writer.Status() >= http.StatusInternalServerError
+		}
+	}
+	if conf.Fallback == nil {
+		conf.Fallback = func(c *Context) {
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+		}
+	}
+	return &CircuitBreaker{conf: conf, now: time.Now, circuits: make(map[string]*circuit)}
+}
+
+// Middleware returns a middleware serving the requests through their circuit. The
+// requests rejected are handled by the fallback handler, and the chain is aborted.
+// A panic of the next handlers counts as a failure.
+func (cb *CircuitBreaker) Middleware() HandlerFunc {
+	return func(c *Context) {
+		done, ok := cb.Allow(cb.conf.Key(c))
+		if !ok {
+			cb.conf.Fallback(c)
+			c.Abort()
+			return
+		}
+		finished := false
+		defer func() {
+			if !finished {
+				done(false)
+			}
+		}()
+		c.Next()
+		finished = true
+		done(!cb.conf.IsFailure(c))
+	}
+}
+
+// Allow reports whether a request can go through the circuit of key. When it can,
+// done must be called with its outcome, e.g. around a call to an upstream service:
+//     done, ok := breaker.Allow("payments")
+//     if !ok {
+//         return errPaymentsUnavailable
+//     }
+//     err := charge(ctx)
+//     done(err == nil)
+func (cb *CircuitBreaker) Allow(key string) (done func(success bool), ok bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	ci := cb.circuits[key]
+	if ci == nil {
+		ci = &circuit{}
+		cb.circuits[key] = ci
+	}
+	if ci.state == CircuitOpen && cb.now().Sub(ci.openedAt) >= cb.conf.OpenTimeout {
+		cb.setState(key, ci, CircuitHalfOpen)
+	}
+	switch ci.state {
+	case CircuitOpen:
+		return nil, false
+	case CircuitHalfOpen:
+		if ci.probes >= cb.conf.HalfOpenProbes {
+			return nil, false
+		}
+		ci.probes++
+	}
+	var once sync.Once
+	generation := ci.generation
+	return func(success bool) {
+		once.Do(func() { cb.record(key, ci, generation, success) })
+	}, true
+}
+
+func (cb *CircuitBreaker) record(key string, ci *circuit, generation int, success bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if ci.generation != generation {
+		return
+	}
+	switch ci.state {
+	case CircuitClosed:
+		if success {
+			ci.failures = 0
+			return
+		}
+		ci.failures++
+		if ci.failures >= cb.conf.FailureThreshold {
+			cb.setState(key, ci, CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		if !success {
+			cb.setState(key, ci, CircuitOpen)
+			return
+		}
+		ci.passed++
+		if ci.passed >= cb.conf.HalfOpenProbes {
+			cb.setState(key, ci, CircuitClosed)
+		}
+	}
+}
+
+// setState changes the state of the circuit, with cb.mu held.
+func (cb *CircuitBreaker) setState(key string, ci *circuit, state CircuitState) {
+	from := ci.state
+	ci.state, ci.failures, ci.probes, ci.passed = state, 0, 0, 0
+	ci.generation++
+	if state == CircuitOpen {
+		ci.openedAt = cb.now()
+	}
+	if cb.conf.OnStateChange != nil {
+		cb.conf.OnStateChange(key, from, state)
+	}
+}
+
+// State returns the state of the circuit of key, e.g. for a health check.
+func (cb *CircuitBreaker) State(key string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if ci := cb.circuits[key]; ci != nil {
+		if ci.state == CircuitOpen && cb.now().Sub(ci.openedAt) >= cb.conf.OpenTimeout {
+			return CircuitHalfOpen
+		}
+		return ci.state
+	}
+	return CircuitClosed
+}
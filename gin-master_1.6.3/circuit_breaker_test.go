@@ -0,0 +1,108 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	var changes []string
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		OnStateChange: func(key string, from, to CircuitState) {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", key, from, to))
+		},
+	})
+	breaker.now = clock.now
+	failing := true
+	router := New()
+	router.Use(breaker.Middleware())
+	router.GET("/upstream/:id", func(c *Context) {
+		if failing {
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	assert.Equal(t, http.StatusBadGateway, performRequest(router, "GET", "/upstream/1").Code)
+	assert.Equal(t, http.StatusBadGateway, performRequest(router, "GET", "/upstream/2").Code)
+	assert.Equal(t, CircuitOpen, breaker.State("GET /upstream/:id"))
+	assert.Equal(t, http.StatusServiceUnavailable, performRequest(router, "GET", "/upstream/3").Code)
+
+	// the probe fails, the circuit opens again
+	clock.t = clock.t.Add(time.Minute)
+	assert.Equal(t, CircuitHalfOpen, breaker.State("GET /upstream/:id"))
+	assert.Equal(t, http.StatusBadGateway, performRequest(router, "GET", "/upstream/1").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, performRequest(router, "GET", "/upstream/1").Code)
+
+	clock.t = clock.t.Add(time.Minute)
+	failing = false
+	assert.Equal(t, http.StatusOK, performRequest(router, "GET", "/upstream/1").Code)
+	assert.Equal(t, CircuitClosed, breaker.State("GET /upstream/:id"))
+	assert.Equal(t, []string{
+		"GET /upstream/:id: closed -> open",
+		"GET /upstream/:id: open -> half-open",
+		"GET /upstream/:id: half-open -> open",
+		"GET /upstream/:id: open -> half-open",
+		"GET /upstream/:id: half-open -> closed",
+	}, changes)
+}
+
+func TestCircuitBreakerAllow(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, HalfOpenProbes: 2})
+	breaker.now = clock.now
+
+	stale, ok := breaker.Allow("payments")
+	assert.True(t, ok)
+	done, _ := breaker.Allow("payments")
+	done(false)
+	done(true) // only the first call counts
+	assert.Equal(t, CircuitOpen, breaker.State("payments"))
+	assert.Equal(t, CircuitClosed, breaker.State("other"))
+	_, ok = breaker.Allow("payments")
+	assert.False(t, ok)
+
+	clock.t = clock.t.Add(30 * time.Second)
+	probe1, ok := breaker.Allow("payments")
+	assert.True(t, ok)
+	probe2, ok := breaker.Allow("payments")
+	assert.True(t, ok)
+	_, ok = breaker.Allow("payments")
+	assert.False(t, ok)
+
+	stale(true) // allowed before the circuit opened
+	probe1(true)
+	assert.Equal(t, CircuitHalfOpen, breaker.State("payments"))
+	probe2(true)
+	assert.Equal(t, CircuitClosed, breaker.State("payments"))
+}
+
+func TestCircuitBreakerFallbackAndPanic(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		Key:              func(c *Context) string { return "upstream" },
+		FailureThreshold: 1,
+		Fallback:         func(c *Context) { c.String(http.StatusOK, "cached") },
+	})
+	router := New()
+	router.Use(RecoveryWithWriter(ioutil.Discard), breaker.Middleware())
+	router.GET("/", func(c *Context) { panic("boom") })
+
+	assert.Equal(t, http.StatusInternalServerError, performRequest(router, "GET", "/").Code)
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "cached", w.Body.String())
+	assert.Equal(t, "unknown", CircuitState(42).String())
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time to the time-dependent middleware: the timestamps and latencies
+// of the loggers, the Timeout middleware, the ages of the cached responses, the expiry of
+// the JWT tokens, and the default store of RateLimit. The in-memory rate limit and
+// response cache stores created explicitly are given theirs with SetClock. Tests use a
+// FakeClock to advance the time instead of sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) ClockTimer
+}
+
+// ClockTimer is a timer of a Clock, see time.Timer.
+type ClockTimer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// SystemClock is the Clock of the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) ClockTimer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// clock returns the Clock of the engine.
+func (engine *Engine) clock() Clock {
+	if engine == nil || engine.Clock == nil {
+		return SystemClock
+	}
+	return engine.Clock
+}
+
+// FakeClock is a Clock whose time only moves with Advance and Set:
+//     clock := gin.NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+//     router.Clock = clock
+//     ...
+//     clock.Advance(time.Minute) // fires the timers due in the minute
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements Clock.
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer implements Clock, the timer fires when the clock is advanced to its deadline.
+func (f *FakeClock) NewTimer(d time.Duration) ClockTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers which are due.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	now := f.now.Add(d)
+	f.mu.Unlock()
+	f.Set(now)
+}
+
+// Set moves the clock to now, firing the timers which are due.
+func (f *FakeClock) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if now.Before(t.deadline) {
+			pending = append(pending, t)
+		} else {
+			t.c <- now
+		}
+	}
+	f.timers = pending
+}
+
+// Timers returns the number of timers which didn't fire nor were stopped, e.g. to wait
+// for a middleware to start its timer before advancing the clock.
+func (f *FakeClock) Timers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fakeClockStart = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// waitFor polls the condition until it holds or a second elapses, as assert.Eventually
+// does, without its goroutine which may send on a closed channel in testify 1.4.
+func waitFor(t *testing.T, condition func() bool) bool {
+	for deadline := time.Now().Add(time.Second); !condition(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			return assert.Fail(t, "Condition never satisfied")
+		}
+	}
+	return true
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(fakeClockStart)
+	assert.Equal(t, fakeClockStart, clock.Now())
+
+	first := clock.NewTimer(time.Second)
+	second := clock.NewTimer(time.Minute)
+	stopped := clock.NewTimer(time.Second)
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+	assert.Equal(t, 2, clock.Timers())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, fakeClockStart.Add(time.Second), <-first.C())
+	assert.Len(t, second.C(), 0)
+	assert.Len(t, stopped.C(), 0)
+	assert.False(t, first.Stop())
+
+	clock.Set(fakeClockStart.Add(time.Hour))
+	assert.Equal(t, fakeClockStart.Add(time.Hour), <-second.C())
+	assert.Equal(t, 0, clock.Timers())
+
+	expired := clock.NewTimer(0)
+	assert.Equal(t, fakeClockStart.Add(time.Hour), <-expired.C())
+}
+
+func TestSystemClock(t *testing.T) {
+	assert.WithinDuration(t, time.Now(), SystemClock.Now(), time.Second)
+	timer := SystemClock.NewTimer(time.Millisecond)
+	<-timer.C()
+	assert.False(t, timer.Stop())
+	assert.Equal(t, SystemClock, New().clock())
+}
+
+func TestClockLogger(t *testing.T) {
+	clock := NewFakeClock(fakeClockStart)
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Clock = clock
+	router.Use(LoggerWithConfig(LoggerConfig{
+		Output: buffer,
+		Formatter: func(param LogFormatterParams) string {
+			return param.TimeStamp.Format(time.RFC3339) + " " + param.Latency.String()
+		},
+	}))
+	router.GET("/slow", func(c *Context) { clock.Advance(1500 * time.Millisecond) })
+
+	performRequest(router, http.MethodGet, "/slow")
+	assert.Equal(t, "2021-01-01T00:00:01Z 1.5s", buffer.String())
+}
+
+func TestClockTimeout(t *testing.T) {
+	clock := NewFakeClock(fakeClockStart)
+	router := New()
+	router.Clock = clock
+	router.Use(Timeout(time.Minute))
+	release := make(chan struct{})
+	router.GET("/slow", func(c *Context) {
+		<-release
+		c.String(http.StatusOK, "late")
+	})
+
+	done := make(chan int)
+	go func() {
+		done <- performRequest(router, http.MethodGet, "/slow").Code
+	}()
+	waitFor(t, func() bool { return clock.Timers() == 1 })
+	clock.Advance(59 * time.Second)
+	assert.Equal(t, 1, clock.Timers())
+	clock.Advance(time.Second)
+	close(release)
+	assert.Equal(t, http.StatusServiceUnavailable, <-done)
+}
+
+func TestClockResponseCache(t *testing.T) {
+	clock := NewFakeClock(fakeClockStart)
+	store := NewMemoryResponseCache()
+	store.SetClock(clock)
+	router := New()
+	router.Clock = clock
+	router.ResponseCache = store
+	calls := 0
+	router.GET("/report", Cache(CacheConfig{TTL: time.Minute}), func(c *Context) {
+		calls++
+		c.String(http.StatusOK, "report")
+	})
+
+	performRequest(router, http.MethodGet, "/report")
+	clock.Advance(30 * time.Second)
+	w := performRequest(router, http.MethodGet, "/report")
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.Equal(t, "30", w.Header().Get("Age"))
+
+	clock.Advance(31 * time.Second)
+	w = performRequest(router, http.MethodGet, "/report")
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	assert.Equal(t, 2, calls)
+}
+
+func TestClockRateLimit(t *testing.T) {
+	clock := NewFakeClock(fakeClockStart)
+	store := NewSlidingWindowStore(0)
+	store.SetClock(clock)
+	router := New()
+	router.GET("/login", RateLimit(RateLimitConfig{Limit: 1, Period: time.Minute, Store: store}), func(c *Context) {})
+
+	assert.Equal(t, http.StatusOK, performRequest(router, http.MethodGet, "/login").Code)
+	assert.Equal(t, http.StatusTooManyRequests, performRequest(router, http.MethodGet, "/login").Code)
+	clock.Advance(2 * time.Minute)
+	assert.Equal(t, http.StatusOK, performRequest(router, http.MethodGet, "/login").Code)
+}
+
+func TestClockRateLimitDefaultStore(t *testing.T) {
+	clock := NewFakeClock(fakeClockStart)
+	router := New()
+	router.Clock = clock
+	router.GET("/login", RateLimit(RateLimitConfig{Limit: 1, Period: time.Minute}), func(c *Context) {})
+
+	assert.Equal(t, http.StatusOK, performRequest(router, http.MethodGet, "/login").Code)
+	assert.Equal(t, http.StatusTooManyRequests, performRequest(router, http.MethodGet, "/login").Code)
+	clock.Advance(time.Minute)
+	assert.Equal(t, http.StatusOK, performRequest(router, http.MethodGet, "/login").Code)
+}
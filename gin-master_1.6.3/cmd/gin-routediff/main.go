@@ -0,0 +1,84 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Command gin-routediff compares two route tables exported with Engine.RouteTable and
+// RouteTable.WriteJSON, e.g. of the deployed release and of the next one:
+//
+//     gin-routediff -fail-on removed,added old.json new.json
+//
+// It prints the added, removed and changed routes and exits with status 1 when there
+// are differences of the kinds given by -fail-on, 2 on error.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	failOn := flag.String("fail-on", "removed", "comma-separated kinds of differences which fail: added, removed, changed")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gin-routediff [-fail-on kinds] old.json new.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	os.Exit(run(flag.Arg(0), flag.Arg(1), *failOn))
+}
+
+func run(oldName, newName, failOn string) int {
+	oldTable, err := readTable(oldName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gin-routediff:", err)
+		return 2
+	}
+	newTable, err := readTable(newName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gin-routediff:", err)
+		return 2
+	}
+	diff := gin.DiffRouteTables(oldTable, newTable)
+	fmt.Print(diff)
+	if failed(diff, failOn) {
+		return 1
+	}
+	return 0
+}
+
+func readTable(name string) (gin.RouteTable, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return gin.ReadRouteTable(f)
+}
+
+func failed(diff gin.RouteDiff, failOn string) bool {
+	for _, kind := range strings.Split(failOn, ",") {
+		switch strings.TrimSpace(kind) {
+		case "added":
+			if len(diff.Added) > 0 {
+				return true
+			}
+		case "removed":
+			if len(diff.Removed) > 0 {
+				return true
+			}
+		case "changed":
+			if len(diff.Changed) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
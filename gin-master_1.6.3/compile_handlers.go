@@ -0,0 +1,53 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+// compileHandlers returns a function running the handlers as Context.Next does from a
+// reset Context, with the handlers of the short chains called directly instead of
+// indexed in a loop, see Engine.CompileHandlers. The Context must run the handlers:
+// the middleware calling Next or Abort go on with c.handlers, and the handlers after
+// them are skipped by the function as they are by Next.
+func compileHandlers(handlers HandlersChain) HandlerFunc {
+	switch len(handlers) {
+	case 1:
+		h0 := handlers[0]
+		return func(c *Context) {
+			c.index = 0
+			h0(c)
+			c.index++
+		}
+	case 2:
+		h0, h1 := handlers[0], handlers[1]
+		return func(c *Context) {
+			c.index = 0
+			h0(c)
+			c.index++
+			if c.index == 1 {
+				h1(c)
+				c.index++
+			}
+		}
+	case 3:
+		h0, h1, h2 := handlers[0], handlers[1], handlers[2]
+		return func(c *Context) {
+			c.index = 0
+			h0(c)
+			c.index++
+			if c.index == 1 {
+				h1(c)
+				c.index++
+			}
+			if c.index == 2 {
+				h2(c)
+				c.index++
+			}
+		}
+	}
+	return func(c *Context) {
+		for c.index = 0; c.index < int8(len(handlers)); c.index++ {
+			handlers[c.index](c)
+		}
+	}
+}
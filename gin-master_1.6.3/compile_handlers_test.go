@@ -0,0 +1,108 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// compileTestChains returns chains of up to 5 handlers, each handler doing nothing,
+// calling Next or aborting, which record the order they run in.
+func compileTestChains(trace *[]string) []HandlersChain {
+	kinds := []string{"plain", "next", "abort"}
+	handler := func(i int, kind string) HandlerFunc {
+		return func(c *Context) {
+			*trace = append(*trace, fmt.Sprintf("%d:%s", i, kind))
+			switch kind {
+			case "next":
+				c.Next()
+				*trace = append(*trace, fmt.Sprintf("%d:after", i))
+			case "abort":
+				c.Abort()
+			}
+		}
+	}
+	var chains []HandlersChain
+	var build func(chain HandlersChain)
+	build = func(chain HandlersChain) {
+		if len(chain) > 0 {
+			chains = append(chains, chain)
+		}
+		if len(chain) == 5 {
+			return
+		}
+		for _, kind := range kinds {
+			build(append(chain[:len(chain):len(chain)], handler(len(chain), kind)))
+		}
+	}
+	build(nil)
+	return chains
+}
+
+func TestCompileHandlersRunsLikeNext(t *testing.T) {
+	var trace []string
+	for _, chain := range compileTestChains(&trace) {
+		c, _ := CreateTestContext(newMockWriter())
+		c.handlers = chain
+		trace = trace[:0]
+		c.Next()
+		expected := strings.Join(trace, " ")
+		expectedIndex := c.index
+
+		c.reset()
+		c.handlers = chain
+		trace = trace[:0]
+		compileHandlers(chain)(c)
+		assert.Equal(t, expected, strings.Join(trace, " "))
+		assert.Equal(t, expectedIndex, c.index, expected)
+	}
+}
+
+func TestEngineCompileHandlers(t *testing.T) {
+	router := New()
+	router.CompileHandlers = true
+	router.Use(func(c *Context) {
+		c.Header("X-Before", "1")
+		c.Next()
+	})
+	router.GET("/users/:id", func(c *Context) { c.String(http.StatusOK, c.Param("id")) })
+	router.GET("/admin", func(c *Context) { c.AbortWithStatus(http.StatusForbidden) }, func(c *Context) {
+		c.String(http.StatusOK, "admin")
+	})
+
+	w := performRequest(router, http.MethodGet, "/users/42")
+	assert.Equal(t, "42", w.Body.String())
+	assert.Equal(t, "1", w.Header().Get("X-Before"))
+
+	w = performRequest(router, http.MethodGet, "/admin")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	value := router.routeTrees().index.get(http.MethodGet).getValue("/users/42", nil, false)
+	assert.NotNil(t, value.run)
+}
+
+func BenchmarkCompileHandlers(b *testing.B) {
+	for _, compile := range []bool{false, true} {
+		router := New()
+		router.CompileHandlers = compile
+		router.Use(func(c *Context) {}, func(c *Context) {})
+		router.GET("/ping", func(c *Context) {})
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		w := newMockWriter()
+
+		b.Run(fmt.Sprintf("Compiled=%v", compile), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				router.ServeHTTP(w, req)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// EncoderFunc returns a writer compressing into w with a content-coding such as
+// gzip, br or zstd. Closing it must flush the compressed data, not close w.
+type EncoderFunc func(w io.Writer) (io.WriteCloser, error)
+
+type namedEncoder struct {
+	name    string
+	encoder EncoderFunc
+}
+
+// GzipEncoder returns an EncoderFunc for the gzip content-coding at the given level.
+func GzipEncoder(level int) EncoderFunc {
+	return func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level)
+	}
+}
+
+// RegisterEncoder enables the compression of the rendered responses with the
+// given content-coding. The encoding is negotiated with the Accept-Encoding
+// header of the request, the preference of the client first, then the order of
+// registration. The standard library only implements gzip, br and zstd need an
+// encoder from a third party package:
+//     router.RegisterEncoder("zstd", func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) })
+//     router.RegisterEncoder("gzip", gin.GzipEncoder(gzip.DefaultCompression))
+// Registering a name again replaces its encoder. Routes can opt out with NoCompression.
+func (engine *Engine) RegisterEncoder(name string, encoder EncoderFunc) {
+	name = strings.ToLower(name)
+	for i, e := range engine.encoders {
+		if e.name == name {
+			engine.encoders[i].encoder = encoder
+			return
+		}
+	}
+	engine.encoders = append(engine.encoders, namedEncoder{name: name, encoder: encoder})
+}
+
+// NoCompression returns a middleware disabling the compression of the responses of a route or group,
+// by Context.Render as well as by the Compress middleware.
+func NoCompression() HandlerFunc {
+	return func(c *Context) {
+		c.noCompression = true
+	}
+}
+
+// negotiateEncoding returns the registered encoder best matching the Accept-Encoding header.
+func (engine *Engine) negotiateEncoding(acceptEncoding string) (namedEncoder, bool) {
+	return negotiateEncoding(engine.encoders, acceptEncoding)
+}
+
+// negotiateEncoding returns the encoder best matching the Accept-Encoding header.
+func negotiateEncoding(encoders []namedEncoder, acceptEncoding string) (namedEncoder, bool) {
+	if len(encoders) == 0 || acceptEncoding == "" {
+		return namedEncoder{}, false
+	}
+	var (
+		best     namedEncoder
+		bestQ    float64
+		wildcard = -1.0
+		accepted = make(map[string]float64)
+	)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseQuality(part)
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		accepted[name] = q
+	}
+	for _, e := range encoders {
+		q, ok := accepted[e.name]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// parseQuality splits an Accept-Encoding element such as "gzip;q=0.8" into its lowercased name and weight.
+func parseQuality(part string) (string, float64) {
+	name, params := strings.TrimSpace(part), ""
+	if i := strings.IndexByte(name, ';'); i >= 0 {
+		name, params = strings.TrimSpace(name[:i]), name[i+1:]
+	}
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return strings.ToLower(name), q
+}
+
+// incompressibleTypes are the content types which are already compressed.
+var incompressibleTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-brotli", "application/x-7z-compressed", "application/x-rar-compressed",
+	"application/vnd.openxmlformats-officedocument.",
+}
+
+func isCompressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, t := range incompressibleTypes {
+		if strings.HasPrefix(contentType, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// compressWriter compresses what is written to it once the headers show the
+// response is worth it, i.e. not already encoded nor of a compressed type.
+type compressWriter struct {
+	http.ResponseWriter
+	encoder  namedEncoder
+	writer   io.WriteCloser
+	decided  bool
+	compress bool
+}
+
+func (w *compressWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || !isCompressible(header.Get("Content-Type")) {
+		return nil
+	}
+	writer, err := w.encoder.encoder(w.ResponseWriter)
+	if err != nil {
+		return err
+	}
+	w.writer, w.compress = writer, true
+	header.Set("Content-Encoding", w.encoder.name)
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+	// the compressed representation is not byte for byte the one the ETag was computed on
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+	return nil
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	if w.compress {
+		return w.writer.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) Flush() {
+	if f, ok := w.writer.(interface{ Flush() error }); ok && w.compress {
+		_ = f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close flushes the compressed data, if any.
+func (w *compressWriter) Close() error {
+	if w.compress {
+		return w.writer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// defaultCompressMinSize is the size from which the Compress middleware compresses the bodies.
+const defaultCompressMinSize = 1024
+
+var defaultEncoders = []namedEncoder{{name: "gzip", encoder: GzipEncoder(gzip.DefaultCompression)}}
+
+// CompressConfig defines the config for Compress middleware.
+type CompressConfig struct {
+	// MinSize is the body size from which the responses are compressed, smaller
+	// bodies are not worth it.
+	// Optional. Default value is 1024.
+	MinSize int
+
+	// ExcludedContentTypes are prefixes of the content types not compressed, in
+	// addition to the compressed formats such as images or archives.
+	// Optional.
+	ExcludedContentTypes []string
+}
+
+// Compress returns a middleware compressing every response, not only the renders, with
+// the encoders of the engine (see Engine.RegisterEncoder) or gzip when none is registered.
+// The beginning of the body is buffered to decide whether to compress it: bodies smaller
+// than MinSize, already encoded or compressed content types and server-sent events are
+// written as is. Flush writes the buffered data, compressed or not, so streaming works.
+// WebSocket upgrades are left alone and routes can opt out with NoCompression.
+func Compress(conf CompressConfig) HandlerFunc {
+	minSize := conf.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressMinSize
+	}
+	return func(c *Context) {
+		if c.noCompression || c.Request.Method == http.MethodHead || isWebSocketUpgrade(c.Request) {
+			return
+		}
+		encoders := c.engine.encoders
+		if len(encoders) == 0 {
+			encoders = defaultEncoders
+		}
+		encoder, ok := negotiateEncoding(encoders, c.requestHeader("Accept-Encoding"))
+		if !ok {
+			return
+		}
+
+		original := c.Writer
+		w := &bufferedCompressWriter{
+			ResponseWriter: original,
+			c:              c,
+			encoder:        encoder,
+			minSize:        minSize,
+			excluded:       conf.ExcludedContentTypes,
+		}
+		c.Writer = w
+		c.compressing = true
+		finished := false
+		defer func() {
+			c.Writer = original
+			c.compressing = false
+			if !finished {
+				// a panic is being handled, the buffered body is dropped
+				return
+			}
+			if err := w.close(); err != nil {
+				_ = c.Error(err)
+			}
+		}()
+		c.Next()
+		finished = true
+	}
+}
+
+func isWebSocketUpgrade(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(req.Header.Get("Connection")), "upgrade")
+}
+
+// bufferedCompressWriter buffers the body until it knows whether to compress it,
+// i.e. when the body reaches the minimum size, is flushed or ends.
+type bufferedCompressWriter struct {
+	ResponseWriter
+	c        *Context
+	encoder  namedEncoder
+	minSize  int
+	excluded []string
+
+	buf       []byte
+	headerNow bool
+	decided   bool
+	out       io.Writer
+	cw        *compressWriter
+}
+
+// decide chooses where the body goes, large reports whether it is worth compressing.
+func (w *bufferedCompressWriter) decide(large bool) error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// the compressed body couldn't be sniffed by net/http
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	w.out = w.ResponseWriter
+	if large && w.compressible() {
+		w.cw = &compressWriter{ResponseWriter: w.ResponseWriter, encoder: w.encoder}
+		w.out = w.cw
+	}
+	if len(w.buf) == 0 {
+		if w.headerNow {
+			w.ResponseWriter.WriteHeaderNow()
+		}
+		return nil
+	}
+	_, err := w.out.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *bufferedCompressWriter) compressible() bool {
+	if w.c.noCompression || !bodyAllowedForStatus(w.Status()) || w.Status() == http.StatusPartialContent {
+		return false
+	}
+	if w.Header().Get("Content-Encoding") != "" {
+		// already encoded by the handler
+		return false
+	}
+	contentType := strings.ToLower(w.Header().Get("Content-Type"))
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		return false
+	}
+	for _, excluded := range w.excluded {
+		if strings.HasPrefix(contentType, strings.ToLower(excluded)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *bufferedCompressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.out == nil {
+			return 0, http.ErrHijacked
+		}
+		return w.out.Write(data)
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *bufferedCompressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *bufferedCompressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.headerNow = true
+}
+
+func (w *bufferedCompressWriter) Written() bool {
+	if w.decided {
+		return w.ResponseWriter.Written()
+	}
+	return w.headerNow || len(w.buf) > 0
+}
+
+func (w *bufferedCompressWriter) Size() int {
+	if w.decided {
+		return w.ResponseWriter.Size()
+	}
+	if w.Written() {
+		return len(w.buf)
+	}
+	return noWritten
+}
+
+// Flush writes the buffered body, which is compressed when the response is
+// compressible whatever its size: flushing means it is streamed.
+func (w *bufferedCompressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			_ = w.c.Error(err)
+			return
+		}
+	}
+	if w.cw != nil {
+		w.cw.Flush()
+		return
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Hijack hands the connection over when nothing was written yet.
+func (w *bufferedCompressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !w.decided && len(w.buf) == 0 {
+		w.decided = true
+	}
+	return w.ResponseWriter.Hijack()
+}
+
+// close writes the buffered body and finishes the compressed stream, if any.
+func (w *bufferedCompressWriter) close() error {
+	if !w.decided {
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+	if w.cw != nil {
+		return w.cw.Close()
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gunzip(t *testing.T, w *httptest.ResponseRecorder) string {
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	large := strings.Repeat("compress me ", 100)
+	router := New()
+	router.Use(Compress(CompressConfig{ExcludedContentTypes: []string{"application/pdf"}}))
+	router.GET("/large", func(c *Context) {
+		_, _ = c.Writer.WriteString(large[:500])
+		_, _ = c.Writer.WriteString(large[500:])
+	})
+	router.GET("/json", func(c *Context) { c.JSON(http.StatusOK, H{"text": large}) })
+	router.GET("/small", func(c *Context) { c.String(http.StatusOK, "small") })
+	router.GET("/png", func(c *Context) { c.Data(http.StatusOK, "image/png", []byte(large)) })
+	router.GET("/pdf", func(c *Context) { c.Data(http.StatusOK, "application/pdf", []byte(large)) })
+	router.GET("/off", NoCompression(), func(c *Context) { c.String(http.StatusOK, large) })
+	router.GET("/empty", func(c *Context) { c.Status(http.StatusNoContent) })
+
+	w := performRequest(router, "GET", "/large", header{"Accept-Encoding", "gzip"})
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, large, gunzip(t, w))
+
+	// renders are not compressed twice
+	router.RegisterEncoder("gzip", GzipEncoder(gzip.BestSpeed))
+	w = performRequest(router, "GET", "/json", header{"Accept-Encoding", "gzip"})
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"text":"`+large+`"}`, gunzip(t, w))
+
+	for _, path := range []string{"/small", "/png", "/pdf", "/off"} {
+		w = performRequest(router, "GET", path, header{"Accept-Encoding", "gzip"})
+		assert.Empty(t, w.Header().Get("Content-Encoding"), path)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+	assert.Equal(t, large, w.Body.String())
+
+	w = performRequest(router, "GET", "/empty", header{"Accept-Encoding", "gzip"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+
+	w = performRequest(router, "GET", "/large")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, w.Body.String())
+
+	w = performRequest(router, "GET", "/large", header{"Accept-Encoding", "gzip"},
+		header{"Upgrade", "websocket"}, header{"Connection", "Upgrade"})
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+func TestCompressMiddlewareStreaming(t *testing.T) {
+	router := New()
+	router.Use(Compress(CompressConfig{MinSize: 10000}))
+	var flushedSize int
+	router.GET("/stream", func(c *Context) {
+		c.Header("Content-Type", "application/x-ndjson")
+		_, _ = c.Writer.WriteString("{}\n")
+		c.Writer.Flush()
+		flushedSize = c.Writer.Size()
+		_, _ = c.Writer.WriteString("{}\n")
+	})
+	router.GET("/events", func(c *Context) {
+		c.SSEvent("message", "hello")
+		c.Writer.Flush()
+	})
+	router.GET("/status", func(c *Context) {
+		c.Status(http.StatusAccepted)
+		c.Writer.WriteHeaderNow()
+		assert.True(t, c.Writer.Written())
+		assert.Equal(t, 0, c.Writer.Size())
+	})
+
+	w := performRequest(router, "GET", "/stream", header{"Accept-Encoding", "gzip"})
+	assert.True(t, w.Flushed)
+	assert.True(t, flushedSize > 0)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "{}\n{}\n", gunzip(t, w))
+
+	w = performRequest(router, "GET", "/events", header{"Accept-Encoding", "gzip"})
+	assert.True(t, w.Flushed)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "event:message\ndata:hello\n\n", w.Body.String())
+
+	w = performRequest(router, "GET", "/status", header{"Accept-Encoding", "gzip"})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
+
+func TestCompressMiddlewarePanic(t *testing.T) {
+	router := New()
+	router.Use(RecoveryWithWriter(ioutil.Discard), Compress(CompressConfig{}))
+	router.GET("/", func(c *Context) {
+		_, _ = c.Writer.WriteString("partial")
+		panic("boom")
+	})
+
+	w := performRequest(router, "GET", "/", header{"Accept-Encoding", "gzip"})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Body.String())
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deflateEncoder(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.BestSpeed)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	router := New()
+	_, ok := router.negotiateEncoding("gzip")
+	assert.False(t, ok)
+
+	router.RegisterEncoder("deflate", deflateEncoder)
+	router.RegisterEncoder("GZIP", GzipEncoder(gzip.BestSpeed))
+	router.RegisterEncoder("gzip", GzipEncoder(gzip.DefaultCompression))
+	assert.Len(t, router.encoders, 2)
+
+	for accept, expected := range map[string]string{
+		"gzip":                      "gzip",
+		"gzip, deflate":             "deflate",
+		"gzip;q=1.0, deflate;q=0.5": "gzip",
+		"br, *;q=0.1":               "deflate",
+		"*, deflate;q=0":            "gzip",
+		"deflate; foo=bar; q=0.9":   "deflate",
+		"br":                        "",
+		"gzip;q=0, *;q=0":           "",
+		"":                          "",
+	} {
+		encoder, ok := router.negotiateEncoding(accept)
+		assert.Equal(t, expected != "", ok, accept)
+		assert.Equal(t, expected, encoder.name, accept)
+	}
+}
+
+func TestRenderCompressed(t *testing.T) {
+	router := New()
+	router.RegisterEncoder("gzip", GzipEncoder(gzip.DefaultCompression))
+	router.GET("/json", func(c *Context) {
+		c.Header("Content-Length", "999")
+		c.Header("ETag", `"v1"`)
+		c.JSON(http.StatusOK, H{"foo": strings.Repeat("bar", 100)})
+	})
+	router.GET("/png", func(c *Context) {
+		c.Data(http.StatusOK, "image/png", []byte("png"))
+	})
+	router.GET("/off", NoCompression(), func(c *Context) {
+		c.String(http.StatusOK, "plain")
+	})
+
+	w := performRequest(router, "GET", "/json", header{Key: "Accept-Encoding", Value: "gzip"})
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, `W/"v1"`, w.Header().Get("ETag"))
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"`+strings.Repeat("bar", 100)+`"}`, string(body))
+
+	w = performRequest(router, "GET", "/json")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Body.String(), "barbar")
+
+	w = performRequest(router, "GET", "/png", header{Key: "Accept-Encoding", Value: "gzip"})
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "png", w.Body.String())
+
+	w = performRequest(router, "GET", "/off", header{Key: "Accept-Encoding", Value: "gzip"})
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "plain", w.Body.String())
+}
+
+func TestRenderCompressedStream(t *testing.T) {
+	router := New()
+	router.RegisterEncoder("deflate", deflateEncoder)
+	router.GET("/stream", func(c *Context) {
+		c.NDJSON(http.StatusOK, func(yield func(v interface{}) bool) {
+			yield(1)
+			yield(2)
+		})
+	})
+
+	w := performRequest(router, "GET", "/stream", header{Key: "Accept-Encoding", Value: "deflate"})
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	assert.True(t, w.Flushed)
+	body, err := ioutil.ReadAll(flate.NewReader(w.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "1\n2\n", string(body))
+}
+
+func TestRenderCompressionEncoderError(t *testing.T) {
+	router := New()
+	router.RegisterEncoder("gzip", GzipEncoder(42))
+	router.GET("/", func(c *Context) {
+		assert.Panics(t, func() { c.String(http.StatusOK, "x") })
+	})
+	performRequest(router, "GET", "/", header{Key: "Accept-Encoding", Value: "gzip"})
+}
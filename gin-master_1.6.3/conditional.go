@@ -0,0 +1,84 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+// Conditional returns a middleware answering the conditional GET and HEAD requests
+// with 304 Not Modified as soon as the handlers declare a validator matching the
+// request, with Context.SetETag or Context.SetLastModified, before they render:
+//     router.GET("/articles/:id", gin.Conditional(), func(c *gin.Context) {
+//         article := articles.Get(c.Param("id"))
+//         if c.SetLastModified(article.UpdatedAt) {
+//             return
+//         }
+//         c.HTML(http.StatusOK, "article.tmpl", article)
+//     })
+// The renders called once the 304 was sent write nothing.
+func Conditional() HandlerFunc {
+	return func(c *Context) {
+		c.conditional = true
+	}
+}
+
+// SetETag sets the ETag header of the response, quoting etag when it is not.
+// Under the Conditional middleware, it answers the request with 304 Not Modified
+// when its If-None-Match header matches the ETag, and returns true: the handler
+// doesn't need to render the response.
+func (c *Context) SetETag(etag string) bool {
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	c.Header("ETag", etag)
+	if !c.conditionalRequest() {
+		return false
+	}
+	ifNoneMatch := c.requestHeader("If-None-Match")
+	if ifNoneMatch == "" || !render.ETagMatch(ifNoneMatch, etag) {
+		return false
+	}
+	return c.notModified()
+}
+
+// SetLastModified sets the Last-Modified header of the response. Under the Conditional
+// middleware, it answers the request with 304 Not Modified when it has no If-None-Match
+// header and its If-Modified-Since header is not before modtime, and returns true: the
+// handler doesn't need to render the response.
+func (c *Context) SetLastModified(modtime time.Time) bool {
+	if modtime.IsZero() {
+		return false
+	}
+	modtime = modtime.UTC().Truncate(time.Second)
+	c.Header("Last-Modified", modtime.Format(http.TimeFormat))
+	// If-None-Match takes precedence, see RFC 7232 section 6
+	if !c.conditionalRequest() || c.requestHeader("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(c.requestHeader("If-Modified-Since"))
+	if err != nil || modtime.After(since) {
+		return false
+	}
+	return c.notModified()
+}
+
+func (c *Context) conditionalRequest() bool {
+	return c.conditional && c.Request != nil &&
+		(c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead)
+}
+
+func (c *Context) notModified() bool {
+	header := c.Writer.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	c.AbortWithStatus(http.StatusNotModified)
+	c.sentNotModified = true
+	return true
+}
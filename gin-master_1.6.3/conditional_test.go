@@ -0,0 +1,115 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionalETag(t *testing.T) {
+	rendered := 0
+	router := New()
+	router.Use(Conditional())
+	router.GET("/", func(c *Context) {
+		if c.SetETag("v1") {
+			return
+		}
+		rendered++
+		c.String(http.StatusOK, "content")
+	})
+	router.GET("/ignored", func(c *Context) {
+		c.SetETag(`W/"v1"`)
+		rendered++
+		c.JSON(http.StatusOK, H{"rendered": true})
+	})
+	router.POST("/", func(c *Context) {
+		assert.False(t, c.SetETag("v1"))
+		c.Status(http.StatusNoContent)
+	})
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	assert.Equal(t, 1, rendered)
+
+	w = performRequest(router, "GET", "/", header{"If-None-Match", `"v0", W/"v1"`})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, 1, rendered)
+
+	w = performRequest(router, "GET", "/", header{"If-None-Match", `"v0"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, rendered)
+
+	// the render after the 304 writes nothing
+	w = performRequest(router, "GET", "/ignored", header{"If-None-Match", `"v1"`})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Type"))
+
+	w = performRequest(router, "POST", "/", header{"If-None-Match", `"v1"`})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestConditionalLastModified(t *testing.T) {
+	modtime := time.Date(2021, 3, 4, 5, 6, 7, 800, time.UTC)
+	router := New()
+	router.GET("/", Conditional(), func(c *Context) {
+		if c.SetLastModified(modtime) {
+			return
+		}
+		c.String(http.StatusOK, "content")
+	})
+	router.GET("/both", Conditional(), func(c *Context) {
+		if c.SetETag("v1") || c.SetLastModified(modtime) {
+			return
+		}
+		c.String(http.StatusOK, "content")
+	})
+	router.GET("/off", func(c *Context) {
+		assert.False(t, c.SetLastModified(modtime))
+		assert.False(t, c.SetLastModified(time.Time{}))
+		c.String(http.StatusOK, "content")
+	})
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Thu, 04 Mar 2021 05:06:07 GMT", w.Header().Get("Last-Modified"))
+
+	w = performRequest(router, "GET", "/", header{"If-Modified-Since", "Thu, 04 Mar 2021 05:06:07 GMT"})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	w = performRequest(router, "GET", "/", header{"If-Modified-Since", "Thu, 04 Mar 2021 05:06:06 GMT"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", "/", header{"If-Modified-Since", "garbage"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// If-None-Match takes precedence
+	w = performRequest(router, "GET", "/both",
+		header{"If-None-Match", `"v0"`},
+		header{"If-Modified-Since", "Thu, 04 Mar 2021 05:06:07 GMT"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", "/off", header{"If-Modified-Since", "Thu, 04 Mar 2021 05:06:07 GMT"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConditionalAutoETag(t *testing.T) {
+	router := New()
+	router.AutoETag = true
+	router.GET("/", Conditional(), func(c *Context) {
+		if c.SetETag("declared") {
+			return
+		}
+		c.String(http.StatusOK, "content")
+	})
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, `"declared"`, w.Header().Get("ETag"))
+}
@@ -34,8 +34,15 @@ const (
 	MIMEPOSTForm          = binding.MIMEPOSTForm
 	MIMEMultipartPOSTForm = binding.MIMEMultipartPOSTForm
 	MIMEYAML              = binding.MIMEYAML
+	MIMENDJSON            = binding.MIMENDJSON
 )
 
+// jsonStreamFlushEvery is the number of elements Context.JSONStream writes between flushes.
+const jsonStreamFlushEvery = 100
+
+// csvFlushEvery is the number of rows Context.CSV writes between flushes.
+const csvFlushEvery = 100
+
 // BodyBytesKey indicates a default body bytes key.
 const BodyBytesKey = "_gin-gonic/gin/bodybyteskey"
 
@@ -56,6 +63,16 @@ type Context struct {
 
 	engine *Engine
 	params *Params
+	// hostTrees are the routes of the host of the request, see RouterGroup.Host, nil
+	// when it's served by the routes without host.
+	hostTrees *routeTrees
+
+	// escapedParams has the bit i set while c.Params[i] is still escaped, see
+	// Engine.LazyUnescapePathValues.
+	escapedParams uint64
+
+	// arena holds the request-scoped allocations, see Engine.RequestArena.
+	arena *requestArena
 
 	// This mutex protect Keys map
 	mu sync.RWMutex
@@ -79,6 +96,21 @@ type Context struct {
 	// SameSite allows a server to define a cookie attribute making it impossible for
 	// the browser to send this cookie along with cross-site requests.
 	sameSite http.SameSite
+
+	// noCompression is set by the NoCompression middleware.
+	noCompression bool
+	// compressing is set by the Compress middleware, which compresses the renders too.
+	compressing bool
+
+	// conditional is set by the Conditional middleware, sentNotModified once it answered 304.
+	conditional     bool
+	sentNotModified bool
+
+	// profile is set by the UseResponseProfile middleware.
+	profile *ResponseProfile
+
+	// bodyLimit is the request body limited by RouterGroup.MaxBodyBytes.
+	bodyLimit *maxBodyReader
 }
 
 /************************************/
@@ -88,6 +120,7 @@ type Context struct {
 func (c *Context) reset() {
 	c.Writer = &c.writermem
 	c.Params = c.Params[0:0] // 就算是nil也可以 [0:0]
+	c.escapedParams = 0
 	c.handlers = nil
 	c.index = -1
 
@@ -97,13 +130,21 @@ func (c *Context) reset() {
 	c.Accepted = nil
 	c.queryCache = nil
 	c.formCache = nil
+	c.noCompression = false
+	c.compressing = false
+	c.conditional = false
+	c.sentNotModified = false
+	c.profile = nil
+	c.bodyLimit = nil
 	*c.params = (*c.params)[0:0]
+	c.hostTrees = nil
 }
 
 // Copy returns a copy of the current context that can be safely used outside the request's scope.
 // This has to be used when the context has to be passed to a goroutine.
 // 需要把context给协程的时候，要copy
 func (c *Context) Copy() *Context {
+	c.unescapeParams()
 	cp := Context{
 		writermem: c.writermem,
 		Request:   c.Request,
@@ -247,7 +288,11 @@ func (c *Context) Error(err error) *Error {
 func (c *Context) Set(key string, value interface{}) {
 	c.mu.Lock()
 	if c.Keys == nil {
-		c.Keys = make(map[string]interface{})
+		if c.arena != nil {
+			c.Keys = c.arena.keys
+		} else {
+			c.Keys = make(map[string]interface{})
+		}
 	}
 
 	c.Keys[key] = value
@@ -386,6 +431,9 @@ func (c *Context) GetStringMapStringSlice(key string) (smss map[string][]string)
 //         id := c.Param("id") // id == "john"
 //     })
 func (c *Context) Param(key string) string {
+	if c.escapedParams != 0 {
+		return c.unescapedParam(key)
+	}
 	return c.Params.ByName(key)
 }
 
@@ -634,6 +682,11 @@ func (c *Context) BindYAML(obj interface{}) error {
 	return c.MustBindWith(obj, binding.YAML)
 }
 
+// BindNDJSON is a shortcut for c.MustBindWith(obj, binding.NDJSON).
+func (c *Context) BindNDJSON(obj interface{}) error {
+	return c.MustBindWith(obj, binding.NDJSON)
+}
+
 // BindHeader is a shortcut for c.MustBindWith(obj, binding.Header).
 func (c *Context) BindHeader(obj interface{}) error {
 	return c.MustBindWith(obj, binding.Header)
@@ -654,7 +707,11 @@ func (c *Context) BindUri(obj interface{}) error {
 // See the binding package.
 func (c *Context) MustBindWith(obj interface{}, b binding.Binding) error {
 	if err := c.ShouldBindWith(obj, b); err != nil {
-		c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind) // nolint: errcheck
+		status := http.StatusBadRequest
+		if _, ok := err.(*MaxBytesError); ok {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.AbortWithError(status, err).SetType(ErrorTypeBind) // nolint: errcheck
 		return err
 	}
 	return nil
@@ -693,6 +750,12 @@ func (c *Context) ShouldBindYAML(obj interface{}) error {
 	return c.ShouldBindWith(obj, binding.YAML)
 }
 
+// ShouldBindNDJSON is a shortcut for c.ShouldBindWith(obj, binding.NDJSON).
+// obj must be a pointer to a slice, see binding.NewNDJSONReader to stream the values.
+func (c *Context) ShouldBindNDJSON(obj interface{}) error {
+	return c.ShouldBindWith(obj, binding.NDJSON)
+}
+
 // ShouldBindHeader is a shortcut for c.ShouldBindWith(obj, binding.Header).
 func (c *Context) ShouldBindHeader(obj interface{}) error {
 	return c.ShouldBindWith(obj, binding.Header)
@@ -700,17 +763,26 @@ func (c *Context) ShouldBindHeader(obj interface{}) error {
 
 // ShouldBindUri binds the passed struct pointer using the specified binding engine.
 func (c *Context) ShouldBindUri(obj interface{}) error {
+	c.unescapeParams()
 	m := make(map[string][]string)
 	for _, v := range c.Params {
 		m[v.Key] = []string{v.Value}
 	}
-	return binding.Uri.BindUri(m, obj)
+	err := binding.Uri.BindUri(m, obj)
+	c.traceBinding(binding.Uri.Name(), obj, err)
+	return err
 }
 
 // ShouldBindWith binds the passed struct pointer using the specified binding engine.
 // See the binding package.
 func (c *Context) ShouldBindWith(obj interface{}, b binding.Binding) error {
-	return b.Bind(c.Request, obj)
+	err := b.Bind(c.Request, obj)
+	if tooLarge := c.bodyTooLarge(); err != nil && tooLarge != nil {
+		// the binding may have wrapped it, or failed on the truncated body
+		err = tooLarge
+	}
+	c.traceBinding(b.Name(), obj, err)
+	return err
 }
 
 // ShouldBindBodyWith is similar with ShouldBindWith, but it stores the request
@@ -732,7 +804,9 @@ func (c *Context) ShouldBindBodyWith(obj interface{}, bb binding.BindingBody) (e
 		}
 		c.Set(BodyBytesKey, body)
 	}
-	return bb.BindBody(body, obj)
+	err = bb.BindBody(body, obj)
+	c.traceBinding(bb.Name(), obj, err)
+	return err
 }
 
 // ClientIP implements a best effort algorithm to return the real client IP, it parses
@@ -864,6 +938,9 @@ func (c *Context) Cookie(name string) (string, error) {
 
 // Render writes the response headers and calls render.Render to render data.
 func (c *Context) Render(code int, r render.Render) {
+	if c.sentNotModified {
+		return
+	}
 	c.Status(code)
 
 	if !bodyAllowedForStatus(code) {
@@ -872,7 +949,40 @@ func (c *Context) Render(code int, r render.Render) {
 		return
 	}
 
-	if err := r.Render(c.Writer); err != nil {
+	for _, hook := range c.engine.beforeRender {
+		r = hook(c, r)
+	}
+	rendered := r
+
+	if c.engine.ContentLengthLimit > 0 && !render.IsStreaming(r) {
+		r = render.ContentLength{Body: r, Limit: c.engine.ContentLengthLimit, Buffers: c.engine.buffers}
+	}
+	if c.engine.AutoETag && code == http.StatusOK && c.Request != nil && c.Writer.Header().Get("ETag") == "" &&
+		(c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) && !render.IsStreaming(r) {
+		r = render.ETag{Body: r, IfNoneMatch: c.requestHeader("If-None-Match"), Buffers: c.engine.buffers}
+	}
+
+	var w http.ResponseWriter = c.Writer
+	if !c.noCompression && !c.compressing && c.Request != nil {
+		if encoder, ok := c.engine.negotiateEncoding(c.requestHeader("Accept-Encoding")); ok {
+			cw := &compressWriter{ResponseWriter: c.Writer, encoder: encoder}
+			defer func() {
+				if err := cw.Close(); err != nil {
+					panic(err)
+				}
+			}()
+			w = cw
+		}
+	}
+
+	err := r.Render(w)
+	if c.engine.tracing(TraceRender) {
+		tracef(TraceRender, "%T %d", rendered, code)
+	}
+	for _, hook := range c.engine.afterRender {
+		hook(c, rendered, err)
+	}
+	if err != nil {
 		panic(err)
 	}
 }
@@ -881,8 +991,46 @@ func (c *Context) Render(code int, r render.Render) {
 // It also updates the HTTP code and sets the Content-Type as "text/html".
 // See http://golang.org/doc/articles/wiki/
 func (c *Context) HTML(code int, name string, obj interface{}) {
-	instance := c.engine.HTMLRender.Instance(name, obj)
-	c.Render(code, instance)
+	var instance render.Render
+	if r, ok := c.engine.HTMLRender.(render.HTMLLocaleRender); ok {
+		instance = r.InstanceForLocale(c.NegotiateLanguage(r.Locales()...), name, obj)
+	} else {
+		instance = c.engine.HTMLRender.Instance(name, obj)
+	}
+	c.Render(code, c.minifyHTML(instance))
+}
+
+// HTMLWithLayout renders the HTTP template specified by its file name inside the layout template.
+// The layout renders the page with {{ block "content" . }}{{ end }} and the page can override
+// other blocks of the layout by defining "<name>:<block>" templates, see render.HTMLLayoutRender.
+//     c.HTMLWithLayout(http.StatusOK, "layout.tmpl", "users.tmpl", gin.H{"users": users})
+// It also updates the HTTP code and sets the Content-Type as "text/html".
+func (c *Context) HTMLWithLayout(code int, layout, name string, obj interface{}) {
+	r, ok := c.engine.HTMLRender.(render.HTMLLayoutRender)
+	if !ok {
+		panic("the HTML render does not support layouts")
+	}
+	c.Render(code, c.minifyHTML(r.InstanceWithLayout(layout, name, obj)))
+}
+
+// minifyHTML wraps the given HTML render with render.MinifiedHTML when the engine asks for it.
+func (c *Context) minifyHTML(r render.Render) render.Render {
+	if c.engine.MinifyHTML && modeName == ReleaseMode {
+		return render.MinifiedHTML{HTML: r, Buffers: c.engine.buffers}
+	}
+	return r
+}
+
+// HTMLStream renders the HTTP template specified by its file name like HTML, but sends
+// the output to the client at every {{ flush }} point of the template instead of at
+// the end. The rendering is aborted when the client disconnects.
+//     <html><head>...</head>{{ flush }}<body>{{ .expensive }}</body></html>
+// It also updates the HTTP code and sets the Content-Type as "text/html".
+func (c *Context) HTMLStream(code int, name string, obj interface{}) {
+	c.Render(code, render.HTMLStream{
+		Context: c.Request.Context(),
+		HTML:    c.engine.HTMLRender.Instance(name, obj),
+	})
 }
 
 // IndentedJSON serializes the given struct as pretty JSON (indented + endlines) into the response body.
@@ -890,14 +1038,20 @@ func (c *Context) HTML(code int, name string, obj interface{}) {
 // WARNING: we recommend to use this only for development purposes since printing pretty JSON is
 // more CPU and bandwidth consuming. Use Context.JSON() instead.
 func (c *Context) IndentedJSON(code int, obj interface{}) {
-	c.Render(code, render.IndentedJSON{Data: obj})
+	c.Render(code, c.jsonRender(render.IndentedJSON{Data: obj}, obj, func(opts *render.JSONOptions) {
+		opts.Indent = "    "
+	}))
 }
 
 // SecureJSON serializes the given struct as Secure JSON into the response body.
 // Default prepends "while(1)," to response body if the given struct is array values.
 // It also sets the Content-Type as "application/json".
 func (c *Context) SecureJSON(code int, obj interface{}) {
-	c.Render(code, render.SecureJSON{Prefix: c.engine.secureJSONPrefix, Data: obj})
+	c.Render(code, c.jsonRender(render.SecureJSON{Prefix: c.engine.secureJSONPrefix, Data: obj}, obj, func(opts *render.JSONOptions) {
+		if opts.Prefix == "" {
+			opts.Prefix = c.engine.secureJSONPrefix
+		}
+	}))
 }
 
 // JSONP serializes the given struct as JSON into the response body.
@@ -906,28 +1060,126 @@ func (c *Context) SecureJSON(code int, obj interface{}) {
 func (c *Context) JSONP(code int, obj interface{}) {
 	callback := c.DefaultQuery("callback", "")
 	if callback == "" {
-		c.Render(code, render.JSON{Data: obj})
+		c.Render(code, c.jsonRender(c.plainJSON(obj), obj, nil))
 		return
 	}
 	c.Render(code, render.JsonpJSON{Callback: callback, Data: obj})
 }
 
+// plainJSON returns the render of Context.JSON, encoding obj in the arena of the request
+// if any.
+func (c *Context) plainJSON(obj interface{}) render.Render {
+	if c.arena != nil {
+		return render.ScratchJSON{Data: obj, Scratch: &c.arena.scratch}
+	}
+	return render.JSON{Data: obj}
+}
+
 // JSON serializes the given struct as JSON into the response body.
 // It also sets the Content-Type as "application/json".
 func (c *Context) JSON(code int, obj interface{}) {
-	c.Render(code, render.JSON{Data: obj})
+	c.Render(code, c.jsonRender(c.plainJSON(obj), obj, nil))
 }
 
 // AsciiJSON serializes the given struct as JSON into the response body with unicode to ASCII string.
 // It also sets the Content-Type as "application/json".
 func (c *Context) AsciiJSON(code int, obj interface{}) {
-	c.Render(code, render.AsciiJSON{Data: obj})
+	c.Render(code, c.jsonRender(render.AsciiJSON{Data: obj}, obj, func(opts *render.JSONOptions) {
+		opts.ASCII = true
+	}))
 }
 
 // PureJSON serializes the given struct as JSON into the response body.
 // PureJSON, unlike JSON, does not replace special html characters with their unicode entities.
 func (c *Context) PureJSON(code int, obj interface{}) {
-	c.Render(code, render.PureJSON{Data: obj})
+	c.Render(code, c.jsonRender(render.PureJSON{Data: obj}, obj, func(opts *render.JSONOptions) {
+		opts.EscapeHTML = false
+	}))
+}
+
+// jsonRender returns r, unless a response profile is used by the route or a JSONConfig
+// was set on the engine. In that case the data is rendered by a render.OptionsJSON built
+// from the profile or the config, tweak applies the behavior specific to the calling
+// method on top of it.
+func (c *Context) jsonRender(r render.Render, obj interface{}, tweak func(opts *render.JSONOptions)) render.Render {
+	var opts render.JSONOptions
+	switch {
+	case c.profile != nil:
+		opts = c.profile.jsonOptions()
+	case c.engine.jsonConfig != nil:
+		opts = c.engine.jsonConfig.jsonOptions()
+	default:
+		return r
+	}
+	if tweak != nil {
+		tweak(&opts)
+	}
+	return render.OptionsJSON{Data: obj, Options: opts}
+}
+
+// JSONStream writes the values produced by items as a JSON array into the response body,
+// element by element, instead of buffering the whole result set.
+// The response is flushed every 100 elements and the stream stops when the request
+// context is done. It also sets the Content-Type as "application/json".
+//     c.JSONStream(http.StatusOK, func(yield func(v interface{}) bool) {
+//         for rows.Next() {
+//             if !yield(scan(rows)) {
+//                 return
+//             }
+//         }
+//     })
+func (c *Context) JSONStream(code int, items func(yield func(v interface{}) bool)) {
+	c.Render(code, render.JSONStream{
+		Context:    c.Request.Context(),
+		Items:      items,
+		FlushEvery: jsonStreamFlushEvery,
+	})
+}
+
+// CSV streams the headers and the rows produced by rows as CSV into the response body.
+// It also sets the Content-Type as "text/csv". Fields are quoted when needed.
+// Use c.Render with a render.CSV for a byte order mark or another delimiter.
+func (c *Context) CSV(code int, headers []string, rows func(yield func(row []string) bool)) {
+	c.Render(code, render.CSV{
+		Context:    c.Request.Context(),
+		Headers:    headers,
+		Rows:       rows,
+		FlushEvery: csvFlushEvery,
+	})
+}
+
+// CSVAttachment streams the rows like CSV does, as an attachment the client
+// downloads under the given filename. A byte order mark is written so that
+// spreadsheet applications detect the UTF-8 encoding.
+func (c *Context) CSVAttachment(code int, filename string, headers []string, rows func(yield func(row []string) bool)) {
+	c.Render(code, render.CSV{
+		Context:    c.Request.Context(),
+		Headers:    headers,
+		Rows:       rows,
+		BOM:        true,
+		Filename:   filename,
+		FlushEvery: csvFlushEvery,
+	})
+}
+
+// XLSX streams the given sheets as a spreadsheet attachment named filename.
+// The document is encoded by engine.SheetWriter, a minimal xlsx writer by default.
+func (c *Context) XLSX(code int, filename string, sheets ...render.Sheet) {
+	c.Render(code, render.XLSX{
+		Context:  c.Request.Context(),
+		Sheets:   sheets,
+		Writer:   c.engine.SheetWriter,
+		Filename: filename,
+	})
+}
+
+// NDJSON streams the values produced by items as newline delimited JSON,
+// flushing after every line. It also sets the Content-Type as "application/x-ndjson".
+func (c *Context) NDJSON(code int, items func(yield func(v interface{}) bool)) {
+	c.Render(code, render.NDJSON{
+		Context: c.Request.Context(),
+		Items:   items,
+	})
 }
 
 // XML serializes the given struct as XML into the response body.
@@ -969,13 +1221,19 @@ func (c *Context) Data(code int, contentType string, data []byte) {
 }
 
 // DataFromReader writes the specified reader into the body stream and updates the HTTP code.
+// A negative contentLength sends the body chunked. An *os.File reader is sent with sendfile
+// when possible, other readers stop being copied when the request context is done.
 func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) {
-	c.Render(code, render.Reader{
+	r := render.Reader{
 		Headers:       extraHeaders,
 		ContentType:   contentType,
 		ContentLength: contentLength,
 		Reader:        reader,
-	})
+	}
+	if c.Request != nil {
+		r.Context = c.Request.Context()
+	}
+	c.Render(code, r)
 }
 
 // File writes the specified file into the body stream in an efficient way.
@@ -1044,8 +1302,15 @@ type Negotiate struct {
 }
 
 // Negotiate calls different Render according acceptable Accept format.
+// The renders registered with engine.RegisterRender are used for their content
+// type, the Data of the config is given to them.
 func (c *Context) Negotiate(code int, config Negotiate) {
-	switch c.NegotiateFormat(config.Offered...) {
+	format := c.NegotiateFormat(config.Offered...)
+	if c.renderRegistered(code, format, config.Data) {
+		return
+	}
+
+	switch format {
 	case binding.MIMEJSON:
 		data := chooseData(config.JSONData, config.Data)
 		c.JSON(code, data)
@@ -1067,6 +1332,32 @@ func (c *Context) Negotiate(code int, config Negotiate) {
 	}
 }
 
+// Respond renders obj in the format the client accepts among JSON, the formats
+// registered with engine.RegisterRender, XML and YAML. JSON is used when the
+// request doesn't have an Accept header.
+//     c.Respond(http.StatusOK, user)
+func (c *Context) Respond(code int, obj interface{}) {
+	offered := make([]string, 0, len(c.engine.renderTypes)+3)
+	offered = append(offered, binding.MIMEJSON)
+	offered = append(offered, c.engine.renderTypes...)
+	offered = append(offered, binding.MIMEXML, binding.MIMEYAML)
+	c.Negotiate(code, Negotiate{Offered: offered, Data: obj})
+}
+
+// renderRegistered renders obj with the render registered for format, if any.
+func (c *Context) renderRegistered(code int, format string, obj interface{}) bool {
+	factory, ok := c.engine.renders[format]
+	if !ok {
+		return false
+	}
+	r := factory(obj)
+	if c.Writer.Header().Get("Content-Type") == "" {
+		c.Header("Content-Type", negotiatedContentType(r, format))
+	}
+	c.Render(code, r)
+	return true
+}
+
 // NegotiateFormat returns an acceptable Accept format.
 func (c *Context) NegotiateFormat(offered ...string) string {
 	assert1(len(offered) > 0, "you must provide at least one offer")
@@ -1098,6 +1389,50 @@ func (c *Context) NegotiateFormat(offered ...string) string {
 	return ""
 }
 
+// NegotiateLanguage returns the offered language tag best matching the Accept-Language
+// header, by order of preference of the client. A tag such as "de-CH" falls back to
+// "de" when only the latter is offered, and the other way around. It returns the first
+// offer when the header is missing and "" when nothing matches. The locale resolved by
+// the Locale middleware, if any, is preferred to the header.
+func (c *Context) NegotiateLanguage(offered ...string) string {
+	assert1(len(offered) > 0, "you must provide at least one offer")
+
+	if locale := c.Locale(); locale != "" {
+		if offer := matchLanguage(normalizeLanguageTag(locale), offered); offer != "" {
+			return offer
+		}
+	}
+	accepted := parseAcceptLanguage(c.requestHeader("Accept-Language"))
+	if len(accepted) == 0 {
+		return offered[0]
+	}
+	for _, tag := range accepted {
+		if tag == "*" {
+			return offered[0]
+		}
+		if offer := matchLanguage(tag, offered); offer != "" {
+			return offer
+		}
+	}
+	return ""
+}
+
+// matchLanguage returns the offer equal to the normalized tag, or else the first one
+// of the same primary language.
+func matchLanguage(tag string, offered []string) string {
+	for _, offer := range offered {
+		if normalizeLanguageTag(offer) == tag {
+			return offer
+		}
+	}
+	for _, offer := range offered {
+		if primaryLanguage(normalizeLanguageTag(offer)) == primaryLanguage(tag) {
+			return offer
+		}
+	}
+	return ""
+}
+
 // SetAccepted sets Accept header data.
 func (c *Context) SetAccepted(formats ...string) {
 	c.Accepted = formats
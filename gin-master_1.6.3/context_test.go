@@ -7,10 +7,12 @@ package gin
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -23,6 +25,7 @@ import (
 
 	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 
@@ -836,6 +839,280 @@ func TestContextRenderPureJSON(t *testing.T) {
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 }
 
+func TestContextRenderJSONStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+
+	c.JSONStream(http.StatusCreated, func(yield func(v interface{}) bool) {
+		for _, name := range []string{"foo", "<b>"} {
+			if !yield(H{"name": name}) {
+				return
+			}
+		}
+	})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "[{\"name\":\"foo\"},{\"name\":\"\\u003cb\\u003e\"}]", w.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
+func TestContextRenderNDJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+
+	c.NDJSON(http.StatusOK, func(yield func(v interface{}) bool) {
+		yield(H{"name": "foo"})
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"name\":\"foo\"}\n", w.Body.String())
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+}
+
+func TestContextBindNDJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("{\"foo\":\"a\"}\n{\"foo\":\"b\"}\n"))
+	c.Request.Header.Add("Content-Type", MIMENDJSON)
+
+	var objs []struct {
+		Foo string `json:"foo"`
+	}
+	assert.NoError(t, c.ShouldBind(&objs))
+	assert.Len(t, objs, 2)
+	assert.Equal(t, "b", objs[1].Foo)
+
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("{\"foo\":1}\n"))
+	assert.Error(t, c.BindNDJSON(&objs))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	objs = nil
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("{\"foo\":\"c\"}"))
+	assert.NoError(t, c.ShouldBindNDJSON(&objs))
+	assert.Equal(t, "c", objs[0].Foo)
+}
+
+func TestContextRenderMinifiedHTML(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, router := CreateTestContext(w)
+	templ := template.Must(template.New("t").Parse("<p>  Hello  {{.name}}  </p>"))
+	router.SetHTMLTemplate(templ)
+	router.MinifyHTML = true
+
+	c.HTML(http.StatusOK, "t", H{"name": "alexandernyquist"})
+	assert.Equal(t, "<p>  Hello  alexandernyquist  </p>", w.Body.String())
+
+	SetMode(ReleaseMode)
+	defer SetMode(TestMode)
+	w = httptest.NewRecorder()
+	c.writermem.reset(w)
+	c.HTML(http.StatusOK, "t", H{"name": "alexandernyquist"})
+	assert.Equal(t, "<p> Hello alexandernyquist </p>", w.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
+func TestContextRenderAutoETag(t *testing.T) {
+	router := New()
+	router.AutoETag = true
+	router.GET("/json", func(c *Context) {
+		c.JSON(http.StatusOK, H{"foo": "bar"})
+	})
+	router.GET("/stream", func(c *Context) {
+		c.NDJSON(http.StatusOK, func(yield func(v interface{}) bool) { yield(1) })
+	})
+	router.POST("/json", func(c *Context) {
+		c.JSON(http.StatusOK, H{"foo": "bar"})
+	})
+
+	w := performRequest(router, "GET", "/json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, `{"foo":"bar"}`, w.Body.String())
+
+	w = performRequest(router, "GET", "/json", header{Key: "If-None-Match", Value: etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = performRequest(router, "GET", "/stream")
+	assert.Empty(t, w.Header().Get("ETag"))
+	w = performRequest(router, "POST", "/json", header{Key: "If-None-Match", Value: etag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestContextRenderContentLength(t *testing.T) {
+	router := New()
+	router.ContentLengthLimit = 4096
+	router.GET("/json", func(c *Context) {
+		c.JSON(http.StatusOK, H{"foo": strings.Repeat("a", 3000)})
+	})
+	router.GET("/large", func(c *Context) {
+		c.String(http.StatusOK, strings.Repeat("a", 5000))
+	})
+	router.GET("/stream", func(c *Context) {
+		c.NDJSON(http.StatusOK, func(yield func(v interface{}) bool) { yield(1) })
+	})
+
+	w := performRequest(router, "GET", "/json")
+	assert.Equal(t, "3010", w.Header().Get("Content-Length"))
+	assert.Equal(t, 3010, w.Body.Len())
+
+	w = performRequest(router, "GET", "/large")
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Equal(t, 5000, w.Body.Len())
+
+	w = performRequest(router, "GET", "/stream")
+	assert.Empty(t, w.Header().Get("Content-Length"))
+
+	// the 3010 bytes of /json moved to the 4 KB class, the 5000 bytes of /large were
+	// passed through without being buffered
+	stats := router.BufferPoolStats()
+	assert.Equal(t, uint64(2), stats.Classes[0].Gets)
+	assert.Equal(t, uint64(1), stats.Classes[2].Gets)
+	assert.Zero(t, stats.Oversized)
+}
+
+func TestContextRenderHTMLWithLayout(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, router := CreateTestContext(w)
+	router.LoadHTMLGlob("./testdata/layout/*")
+
+	c.HTMLWithLayout(http.StatusCreated, "layout.tmpl", "about.tmpl", H{"name": "gin"})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "<title>Default</title><main><p>About gin</p></main>", w.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	router.HTMLRender = nil
+	assert.Panics(t, func() { c.HTMLWithLayout(http.StatusOK, "layout.tmpl", "about.tmpl", nil) })
+}
+
+func TestContextRenderHTMLStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, router := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	router.LoadHTMLGlob("./testdata/stream/*")
+
+	c.HTMLStream(http.StatusOK, "page.tmpl", H{"title": "t", "body": "b"})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "<head>t</head><body>b</body>", w.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
+func TestContextRenderCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+
+	c.CSV(http.StatusCreated, []string{"id", "name"}, render.SliceRows([][]string{{"1", "a,b"}}))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "id,name\n1,\"a,b\"\n", w.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
+func TestContextRenderCSVAttachment(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+
+	c.CSVAttachment(http.StatusOK, "users.csv", []string{"id"}, render.SliceRows([][]string{{"1"}}))
+
+	assert.Equal(t, "\xef\xbb\xbfid\n1\n", w.Body.String())
+	assert.Equal(t, "attachment; filename=users.csv", w.Header().Get("Content-Disposition"))
+}
+
+type csvSheetWriter struct {
+	*csv.Writer
+}
+
+func (w csvSheetWriter) NewSheet(name string) error { return nil }
+
+func (w csvSheetWriter) WriteRow(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		record[i] = fmt.Sprint(cell)
+	}
+	return w.Write(record)
+}
+
+func (w csvSheetWriter) Close() error {
+	w.Flush()
+	return w.Error()
+}
+
+func TestContextRenderXLSX(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, router := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	router.SheetWriter = func(w io.Writer) render.SheetWriter { return csvSheetWriter{csv.NewWriter(w)} }
+
+	c.XLSX(http.StatusOK, "users.xlsx", render.Sheet{
+		Headers: []string{"id", "name"},
+		Rows: func(yield func(row []interface{}) bool) {
+			yield([]interface{}{1, "foo"})
+		},
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id,name\n1,foo\n", w.Body.String())
+	assert.Equal(t, "attachment; filename=users.xlsx", w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", w.Header().Get("Content-Type"))
+}
+
+func TestContextRenderJSONConfig(t *testing.T) {
+	data := struct {
+		B string  `json:"b"`
+		A float64 `json:"a"`
+	}{B: "<b>", A: math.NaN()}
+
+	w := httptest.NewRecorder()
+	c, router := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/?callback=", nil)
+	router.SetJSONConfig(JSONConfig{SortKeys: true, NonFinite: render.NonFiniteNull})
+	c.JSON(http.StatusOK, data)
+	assert.Equal(t, `{"a":null,"b":"\u003cb\u003e"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	c.writermem.reset(w)
+	c.JSONP(http.StatusOK, data)
+	assert.Equal(t, `{"a":null,"b":"\u003cb\u003e"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	c.writermem.reset(w)
+	c.PureJSON(http.StatusOK, data)
+	assert.Equal(t, `{"a":null,"b":"<b>"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	c.writermem.reset(w)
+	c.IndentedJSON(http.StatusOK, []int{1})
+	assert.Equal(t, "[\n    1\n]", w.Body.String())
+
+	w = httptest.NewRecorder()
+	c.writermem.reset(w)
+	c.AsciiJSON(http.StatusOK, "é")
+	assert.Equal(t, `"\u00e9"`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	router.SetJSONConfig(JSONConfig{SecurePrefix: ")]}',\n", DisableHTMLEscaping: true, IndentInDebug: true})
+	w = httptest.NewRecorder()
+	c.writermem.reset(w)
+	c.SecureJSON(http.StatusOK, []string{"<b>"})
+	assert.Equal(t, ")]}',\n[\"<b>\"]", w.Body.String())
+
+	SetMode(DebugMode)
+	defer SetMode(TestMode)
+	w = httptest.NewRecorder()
+	c.writermem.reset(w)
+	c.JSON(http.StatusOK, H{"a": 1})
+	assert.Equal(t, "{\n    \"a\": 1\n}", w.Body.String())
+}
+
 // Tests that the response executes the templates
 // and responds with Content-Type set to text/html
 func TestContextRenderHTML(t *testing.T) {
@@ -858,7 +1135,7 @@ func TestContextRenderHTML2(t *testing.T) {
 
 	// print debug warning log when Engine.trees > 0
 	router.addRoute("GET", "/", HandlersChain{func(_ *Context) {}})
-	assert.Len(t, router.trees, 1)
+	assert.Len(t, router.routeTrees().trees, 1)
 
 	templ := template.Must(template.New("t").Parse(`Hello {{.name}}`))
 	re := captureOutput(t, func() {
@@ -1255,6 +1532,23 @@ func TestContextNegotiationFormatWithWildcardAccept(t *testing.T) {
 	assert.Equal(t, c.NegotiateFormat(MIMEHTML), MIMEHTML)
 }
 
+func TestContextNegotiateLanguage(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	assert.Equal(t, "en", c.NegotiateLanguage("en", "de"))
+
+	c.Request.Header.Set("Accept-Language", "fr-CH, fr;q=0.9, de;q=0.7, *;q=0.5")
+	assert.Equal(t, "fr", c.NegotiateLanguage("en", "fr", "de"))
+	assert.Equal(t, "de", c.NegotiateLanguage("en", "de"))
+	assert.Equal(t, "fr_FR", c.NegotiateLanguage("en", "fr_FR"))
+	assert.Equal(t, "en", c.NegotiateLanguage("en", "it"))
+
+	c.Request.Header.Set("Accept-Language", "de;q=0.5, it;q=0, es")
+	assert.Equal(t, "de", c.NegotiateLanguage("it", "de"))
+	assert.Equal(t, "", c.NegotiateLanguage("it", "en"))
+	assert.Panics(t, func() { c.NegotiateLanguage() })
+}
+
 func TestContextNegotiationFormatCustom(t *testing.T) {
 	c, _ := CreateTestContext(httptest.NewRecorder())
 	c.Request, _ = http.NewRequest("POST", "/", nil)
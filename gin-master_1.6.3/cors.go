@@ -0,0 +1,261 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins, e.g. "https://example.com". An origin
+	// may contain a single wildcard such as "https://*.example.com", "*" allows any origin.
+	AllowOrigins []string
+	// AllowOriginRegexps lists the patterns matching allowed origins.
+	AllowOriginRegexps []*regexp.Regexp
+	// AllowOriginFunc, when set, is asked about the origins no other rule allows.
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods lists the methods answered to preflight requests,
+	// GET, POST, PUT, PATCH, DELETE and HEAD when empty.
+	AllowMethods []string
+	// AllowHeaders lists the request headers answered to preflight requests,
+	// the headers requested by the preflight are allowed when empty.
+	AllowHeaders []string
+	// ExposeHeaders lists the response headers readable by the client.
+	ExposeHeaders []string
+	// AllowCredentials lets the requests include cookies and HTTP authentication.
+	AllowCredentials bool
+	// MaxAge is how long the result of a preflight request can be cached.
+	MaxAge time.Duration
+	// AllowPrivateNetwork answers the preflight requests of public websites to
+	// a private network, see https://wicg.github.io/private-network-access/.
+	AllowPrivateNetwork bool
+}
+
+var defaultCORSMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead,
+}
+
+type originPattern struct {
+	prefix, suffix string
+}
+
+type corsPolicy struct {
+	anyOrigin     bool
+	origins       map[string]bool
+	patterns      []originPattern
+	regexps       []*regexp.Regexp
+	originFunc    func(string) bool
+	methods       string
+	headers       string
+	expose        string
+	credentials   bool
+	maxAge        string
+	privateAccess bool
+}
+
+// CORS returns a middleware implementing Cross-Origin Resource Sharing for the routes
+// it's used by. Preflight requests are answered with 204 by the middleware, including
+// those to routes having no OPTIONS handler: the engine runs the handlers of the route
+// for the requested method up to the CORS middleware, which is found when the route is
+// added, also when it's wrapped with When or UseIf. Preflight requests from an origin
+// which isn't allowed are aborted with 403, other requests are served without CORS headers.
+//     api := router.Group("/api", gin.CORS(gin.CORSConfig{AllowOrigins: []string{"https://*.example.com"}}))
+func CORS(config CORSConfig) HandlerFunc {
+	p := &corsPolicy{
+		origins:       make(map[string]bool),
+		regexps:       config.AllowOriginRegexps,
+		originFunc:    config.AllowOriginFunc,
+		headers:       strings.Join(config.AllowHeaders, ", "),
+		expose:        strings.Join(config.ExposeHeaders, ", "),
+		credentials:   config.AllowCredentials,
+		privateAccess: config.AllowPrivateNetwork,
+	}
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(origin)
+		switch i := strings.IndexByte(origin, '*'); {
+		case origin == "*":
+			p.anyOrigin = true
+		case i >= 0:
+			p.patterns = append(p.patterns, originPattern{prefix: origin[:i], suffix: origin[i+1:]})
+		default:
+			p.origins[origin] = true
+		}
+	}
+	methods := config.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	p.methods = strings.ToUpper(strings.Join(methods, ", "))
+	if config.MaxAge > 0 {
+		p.maxAge = strconv.FormatInt(int64(config.MaxAge/time.Second), 10)
+	}
+	return p.handle
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if p.origins[lower] {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if len(lower) > len(pattern.prefix)+len(pattern.suffix) &&
+			strings.HasPrefix(lower, pattern.prefix) && strings.HasSuffix(lower, pattern.suffix) {
+			return true
+		}
+	}
+	for _, re := range p.regexps {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return p.originFunc != nil && p.originFunc(origin)
+}
+
+func (p *corsPolicy) handle(c *Context) {
+	origin := c.requestHeader("Origin")
+	if origin == "" {
+		return
+	}
+	preflight := isPreflight(c.Request)
+	header := c.Writer.Header()
+	header.Add("Vary", "Origin")
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if !p.allowOrigin(origin) {
+		if preflight {
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+		return
+	}
+
+	if p.anyOrigin && !p.credentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if p.expose != "" {
+			header.Set("Access-Control-Expose-Headers", p.expose)
+		}
+		return
+	}
+
+	header.Set("Access-Control-Allow-Methods", p.methods)
+	if headers := p.headers; headers != "" {
+		header.Set("Access-Control-Allow-Headers", headers)
+	} else if requested := c.requestHeader("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if p.maxAge != "" {
+		header.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	if p.privateAccess && c.requestHeader("Access-Control-Request-Private-Network") == "true" {
+		header.Set("Access-Control-Allow-Private-Network", "true")
+	}
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+func isPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// corsCode and whenCORSCode are the code of the middleware returned by CORS, and of the
+// ones wrapping them with When, which tell them apart from the other handlers when the
+// routes are added, without keeping track of the middleware.
+var (
+	corsCode     = reflect.ValueOf(HandlerFunc((&corsPolicy{}).handle)).Pointer()
+	whenCORSCode = reflect.ValueOf(whenCORS(nil, nil)).Pointer()
+)
+
+// isCORS reports whether the handler is a CORS middleware, or wraps one with When.
+func isCORS(handler HandlerFunc) bool {
+	code := reflect.ValueOf(handler).Pointer()
+	return code == corsCode || code == whenCORSCode
+}
+
+// whenCORS is When for a CORS middleware, see isCORS.
+func whenCORS(predicate func(c *Context) bool, middleware HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		if predicate(c) {
+			middleware(c)
+		}
+	}
+}
+
+// corsIndex returns the position in the handlers, plus one, of their CORS middleware, or
+// 0 when they have none.
+func corsIndex(handlers HandlersChain) int {
+	for i, handler := range handlers {
+		if isCORS(handler) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// withCORS returns a copy of the snapshot where the CORS middleware of the route is at
+// index, see corsIndex. The maps are copied rather than changed, as withMeta does.
+func (rt *routeTrees) withCORS(method, path string, index int) *routeTrees {
+	if rt.cors[method][path] == index {
+		return rt
+	}
+	next := *rt
+	next.cors = make(map[string]map[string]int, len(rt.cors)+1)
+	for m, routes := range rt.cors {
+		next.cors[m] = routes
+	}
+	routes := make(map[string]int, len(rt.cors[method])+1)
+	for p, i := range rt.cors[method] {
+		if p != path {
+			routes[p] = i
+		}
+	}
+	if index > 0 {
+		routes[path] = index
+	}
+	next.cors[method] = routes
+	return &next
+}
+
+// servePreflight answers a preflight request to a route without OPTIONS handler by
+// running the handlers of the route for the requested method, up to its CORS middleware.
+func (engine *Engine) servePreflight(c *Context, trees *routeTrees, rPath string, unescape bool) bool {
+	if !isPreflight(c.Request) || c.requestHeader("Origin") == "" {
+		return false
+	}
+	method := c.requestHeader("Access-Control-Request-Method")
+	root := trees.index.get(method)
+	if root == nil {
+		return false
+	}
+	*c.params = (*c.params)[:0]
+	value := root.getValue(rPath, c.params, unescape)
+	i := trees.cors[method][value.fullPath]
+	if value.handlers == nil || i == 0 {
+		return false
+	}
+	if value.params != nil {
+		c.Params = *value.params
+	}
+	c.handlers = value.handlers[:i]
+	c.fullPath = value.fullPath
+	c.Next()
+	c.writermem.WriteHeaderNow()
+	return true
+}
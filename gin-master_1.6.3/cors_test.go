@@ -0,0 +1,135 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func performCORSRequest(r http.Handler, method, path, origin string, headers ...header) *httptest.ResponseRecorder {
+	return performRequest(r, method, path, append(headers, header{"Origin", origin})...)
+}
+
+func TestCORSOrigins(t *testing.T) {
+	router := New()
+	router.Use(CORS(CORSConfig{
+		AllowOrigins:       []string{"https://example.com", "https://*.example.org"},
+		AllowOriginRegexps: []*regexp.Regexp{regexp.MustCompile(`^http://localhost:\d+$`)},
+		AllowOriginFunc:    func(origin string) bool { return origin == "null" },
+		ExposeHeaders:      []string{"X-Total"},
+	}))
+	router.GET("/", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	for _, origin := range []string{"https://example.com", "https://api.example.org", "http://localhost:8080", "null"} {
+		w := performCORSRequest(router, "GET", "/", origin)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, origin, w.Header().Get("Access-Control-Allow-Origin"), origin)
+		assert.Equal(t, "X-Total", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+	}
+
+	for _, origin := range []string{"https://evil.com", "https://.example.org", "https://example.org.evil.com", "http://localhost"} {
+		w := performCORSRequest(router, "GET", "/", origin)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), origin)
+	}
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Empty(t, w.Header().Get("Vary"))
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	router := New()
+	router.GET("/public", CORS(CORSConfig{AllowOrigins: []string{"*"}}), func(c *Context) {})
+	router.GET("/private", CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}), func(c *Context) {})
+
+	w := performCORSRequest(router, "GET", "/public", "https://example.com")
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w = performCORSRequest(router, "GET", "/private", "https://example.com")
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORSPreflight(t *testing.T) {
+	router := New()
+	router.HandleMethodNotAllowed = true
+	called := false
+	api := router.Group("/api", CORS(CORSConfig{
+		AllowOrigins:        []string{"https://example.com"},
+		AllowHeaders:        []string{"Content-Type", "Authorization"},
+		MaxAge:              time.Hour,
+		AllowPrivateNetwork: true,
+	}), func(c *Context) { called = true })
+	api.POST("/users/:id", func(c *Context) { called = true })
+	router.POST("/other", func(c *Context) {})
+
+	w := performCORSRequest(router, "OPTIONS", "/api/users/1", "https://example.com",
+		header{"Access-Control-Request-Method", "POST"},
+		header{"Access-Control-Request-Private-Network", "true"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.False(t, called)
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, HEAD", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Private-Network"))
+	assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header()["Vary"])
+
+	w = performCORSRequest(router, "OPTIONS", "/api/users/1", "https://evil.com", header{"Access-Control-Request-Method", "POST"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, called)
+
+	// no CORS middleware or no route for the requested method
+	w = performCORSRequest(router, "OPTIONS", "/other", "https://example.com", header{"Access-Control-Request-Method", "POST"})
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	w = performCORSRequest(router, "OPTIONS", "/api/users/1", "https://example.com", header{"Access-Control-Request-Method", "GET"})
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	w = performCORSRequest(router, "OPTIONS", "/missing", "https://example.com", header{"Access-Control-Request-Method", "POST"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCORSPreflightRequestedHeaders(t *testing.T) {
+	router := New()
+	router.OPTIONS("/", CORS(CORSConfig{AllowOrigins: []string{"https://example.com"}, AllowMethods: []string{"get", "post"}}))
+
+	w := performCORSRequest(router, "OPTIONS", "/", "https://example.com",
+		header{"Access-Control-Request-Method", "POST"},
+		header{"Access-Control-Request-Headers", "X-Custom"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "X-Custom", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Private-Network"))
+}
+
+func TestCORSPreflightWrapped(t *testing.T) {
+	router := New()
+	var params Params
+	router.OPTIONS("/items/:name/meta", func(c *Context) {})
+	api := router.Group("/", func(c *Context) { params = c.Params })
+	api.UseIf(MatchNot(MatchPath("/healthz")), CORS(CORSConfig{AllowOrigins: []string{"https://example.com"}}))
+	api.POST("/items/:name/tags", func(c *Context) {})
+
+	w := performCORSRequest(router, "OPTIONS", "/items/foo/tags", "https://example.com",
+		header{"Access-Control-Request-Method", "POST"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, Params{{Key: "name", Value: "foo"}}, params)
+
+	// the route added again without CORS middleware
+	router.RemoveRoute(http.MethodPost, "/items/:name/tags")
+	router.POST("/items/:name/tags", func(c *Context) {})
+	w = performCORSRequest(router, "OPTIONS", "/items/foo/tags", "https://example.com",
+		header{"Access-Control-Request-Method", "POST"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
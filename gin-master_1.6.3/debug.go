@@ -25,13 +25,17 @@ var DebugPrintRouteFunc func(httpMethod, absolutePath, handlerName string, nuHan
 
 func debugPrintRoute(httpMethod, absolutePath string, handlers HandlersChain) {
 	if IsDebugging() {
-		nuHandlers := len(handlers)
-		handlerName := nameOfFunction(handlers.Last())
-		if DebugPrintRouteFunc == nil {
-			debugPrint("%-6s %-25s --> %s (%d handlers)\n", httpMethod, absolutePath, handlerName, nuHandlers)
-		} else {
-			DebugPrintRouteFunc(httpMethod, absolutePath, handlerName, nuHandlers)
-		}
+		printRoute(httpMethod, absolutePath, handlers)
+	}
+}
+
+func printRoute(httpMethod, absolutePath string, handlers HandlersChain) {
+	nuHandlers := len(handlers)
+	handlerName := nameOfFunction(handlers.Last())
+	if DebugPrintRouteFunc == nil {
+		printDebug("%-6s %-25s --> %s (%d handlers)\n", httpMethod, absolutePath, handlerName, nuHandlers)
+	} else {
+		DebugPrintRouteFunc(httpMethod, absolutePath, handlerName, nuHandlers)
 	}
 }
 
@@ -49,11 +53,15 @@ func debugPrintLoadTemplate(tmpl *template.Template) {
 
 func debugPrint(format string, values ...interface{}) {
 	if IsDebugging() {
-		if !strings.HasSuffix(format, "\n") {
-			format += "\n"
-		}
-		fmt.Fprintf(DefaultWriter, "[GIN-debug] "+format, values...)
+		printDebug(format, values...)
+	}
+}
+
+func printDebug(format string, values ...interface{}) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
 	}
+	fmt.Fprintf(DefaultWriter, "[GIN-debug] "+format, values...)
 }
 
 func getMinVer(v string) (uint64, error) {
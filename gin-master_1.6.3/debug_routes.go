@@ -0,0 +1,115 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+// RouteTreeNode is a node of the radix tree of the routes of a method, as rendered by
+// DebugRoutes.
+type RouteTreeNode struct {
+	// Path is the part of the path of the node, e.g. "users/" or ":id".
+	Path string `json:"path"`
+	// Type is "static", "root", "param" or "catchAll".
+	Type string `json:"type"`
+	// Priority is the number of routes below the node, the children are looked up by
+	// decreasing priority.
+	Priority  uint32 `json:"priority"`
+	WildChild bool   `json:"wild_child,omitempty"`
+	// Route, Handler and Handlers are set for the nodes of a route.
+	Route    string           `json:"route,omitempty"`
+	Handler  string           `json:"handler,omitempty"`
+	Handlers int              `json:"handlers,omitempty"`
+	Children []*RouteTreeNode `json:"children,omitempty"`
+}
+
+// RouteTree is the radix tree of the routes of a method.
+type RouteTree struct {
+	Method string         `json:"method"`
+	Root   *RouteTreeNode `json:"root"`
+}
+
+var nodeTypeNames = [...]string{static: "static", root: "root", param: "param", catchAll: "catchAll"}
+
+// RouteTrees returns the radix trees of the routes, by method.
+func (engine *Engine) RouteTrees() []RouteTree {
+	current := engine.routeTrees().trees
+	trees := make([]RouteTree, 0, len(current))
+	for _, tree := range current {
+		trees = append(trees, RouteTree{Method: tree.method, Root: newRouteTreeNode(tree.root)})
+	}
+	return trees
+}
+
+func newRouteTreeNode(n *node) *RouteTreeNode {
+	tn := &RouteTreeNode{
+		Path:      n.path,
+		Type:      nodeTypeNames[n.nType],
+		Priority:  n.priority,
+		WildChild: n.wildChild,
+	}
+	if len(n.handlers) > 0 {
+		tn.Route = n.fullPath
+		tn.Handler = nameOfFunction(n.handlers.Last())
+		tn.Handlers = len(n.handlers)
+	}
+	for i := range n.children {
+		tn.Children = append(tn.Children, newRouteTreeNode(&n.children[i]))
+	}
+	return tn
+}
+
+var debugRoutesTemplate = template.Must(template.New("routes").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Routes</title>
+<style>
+body { font-family: monospace; }
+ul { list-style: none; border-left: 1px dotted #999; padding-left: 1.5em; }
+.param, .catchAll { color: #a31515; }
+.route { color: #0451a5; }
+.meta { color: #888; }
+</style></head>
+<body>
+{{- range .}}
+<h2>{{.Method}}</h2>
+<ul>{{template "node" .Root}}</ul>
+{{- end}}
+</body></html>
+{{- define "node"}}
+<li><span class="{{.Type}}">{{if .Path}}{{.Path}}{{else}}&lt;empty&gt;{{end}}</span>
+<span class="meta">{{.Type}}, priority {{.Priority}}{{if .WildChild}}, wildcard child{{end}}</span>
+{{- if .Route}}
+<span class="route">{{.Route}} &rarr; {{.Handler}} ({{.Handlers}} handlers)</span>
+{{- end}}
+{{- if .Children}}
+<ul>{{range .Children}}{{template "node" .}}{{end}}</ul>
+{{- end}}
+</li>
+{{- end}}`))
+
+// DebugRoutes registers a GET route serving the radix trees of the routes of the engine,
+// with their priorities, wildcards and handler names, as JSON or as an HTML tree view for
+// the browsers. The route is served only through the guard, since it discloses the API:
+//     router.DebugRoutes("/debug/routes", gin.BasicAuth(gin.Accounts{"admin": "secret"}))
+// The format is negotiated with the Accept header, or given by the "format" query
+// parameter: "json" or "html".
+func (group *RouterGroup) DebugRoutes(relativePath string, guard HandlerFunc) IRoutes {
+	assert1(guard != nil, "the debug routes must be guarded")
+	return group.GET(relativePath, guard, func(c *Context) {
+		trees := c.engine.RouteTrees()
+		format := c.Query("format")
+		if format == "" && c.NegotiateFormat(MIMEJSON, MIMEHTML) == MIMEHTML {
+			format = "html"
+		}
+		if format == "html" {
+			c.Render(http.StatusOK, render.HTML{Template: debugRoutesTemplate, Name: "routes", Data: trees})
+			return
+		}
+		c.JSON(http.StatusOK, trees)
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugRoutes(t *testing.T) {
+	router := New()
+	router.GET("/users", listUsers)
+	router.GET("/users/:id", listUsers)
+	router.GET("/src/*filepath", listUsers)
+	router.POST("/users", createUser)
+	router.DebugRoutes("/debug/routes", BasicAuth(Accounts{"admin": "secret"}))
+
+	w := performRequest(router, "GET", "/debug/routes")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	auth := header{"Authorization", authorizationHeader("admin", "secret")}
+	w = performRequest(router, "GET", "/debug/routes", auth)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var trees []RouteTree
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &trees))
+	require.Len(t, trees, 2)
+	assert.Equal(t, "GET", trees[0].Method)
+	assert.Equal(t, "/", trees[0].Root.Path)
+	assert.Equal(t, uint32(4), trees[0].Root.Priority)
+	assert.Equal(t, &RouteTreeNode{
+		Path: "/", Type: "static", Priority: 1, WildChild: true,
+		Children: []*RouteTreeNode{{
+			Path: ":id", Type: "param", Priority: 1,
+			Route: "/users/:id", Handler: "github.com/gin-gonic/gin.listUsers", Handlers: 1,
+		}},
+	}, findTreeNode(trees[0].Root, "/users").Children[0])
+	assert.Equal(t, "catchAll", findTreeNode(trees[0].Root, "/src/*filepath").Type)
+	assert.Equal(t, "github.com/gin-gonic/gin.createUser", trees[1].Root.Handler)
+
+	w = performRequest(router, "GET", "/debug/routes", auth, header{"Accept", "text/html,*/*"})
+	assert.Equal(t, MIMEHTML+"; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `<span class="param">:id</span>`)
+	assert.Contains(t, w.Body.String(), `/users/:id &rarr; github.com/gin-gonic/gin.listUsers (1 handlers)`)
+	w = performRequest(router, "GET", "/debug/routes?format=html", auth)
+	assert.Contains(t, w.Body.String(), "<h2>POST</h2>")
+
+	assert.Panics(t, func() { router.DebugRoutes("/routes", nil) })
+}
+
+func findTreeNode(n *RouteTreeNode, route string) *RouteTreeNode {
+	if n.Route == route {
+		return n
+	}
+	for _, child := range n.Children {
+		if found := findTreeNode(child, route); found != nil {
+			return found
+		}
+	}
+	return nil
+}
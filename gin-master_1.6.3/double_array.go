@@ -0,0 +1,237 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"sort"
+	"strings"
+)
+
+// RouteMatcher selects how an Engine looks up the routes, see Engine.Matcher.
+type RouteMatcher uint8
+
+const (
+	// MatcherTree looks up the routes in the radix trees the routes are added to.
+	// It's the default.
+	MatcherTree RouteMatcher = iota
+	// MatcherDoubleArray looks up the static routes, the ones without params, in
+	// double-array tries compiled from the registered routes when the first request
+	// is served or with CompileRoutes, and the other routes in the radix trees. The
+	// lookups of the static routes take an array access by branch of the path instead
+	// of a scan of the indices of the nodes, which is faster for large sets of static
+	// routes, but no route can be added once the tries are compiled.
+	MatcherDoubleArray
+)
+
+// CompileRoutes compiles the lookup structures of the Matcher of the engine, e.g. before
+// it serves requests so that the first request doesn't pay for it. It does nothing for
+// MatcherTree. No route can be added once the routes are compiled.
+func (engine *Engine) CompileRoutes() {
+	if engine.Matcher != MatcherDoubleArray || engine.compiledRoutes() != nil {
+		return
+	}
+	engine.compileMu.Lock()
+	defer engine.compileMu.Unlock()
+	if engine.compiledRoutes() != nil {
+		return
+	}
+	engine.compiled.Store(compileRoutes(engine.routeTrees().trees))
+}
+
+// compileRoutes builds the double-array tries of the static routes of the trees.
+func compileRoutes(trees methodTrees) *compiledRoutes {
+	routes := &compiledRoutes{tries: make(map[string]*doubleArray, len(trees))}
+	for _, tree := range trees {
+		routes.tries[tree.method] = newDoubleArray(staticRoutes(tree.root, nil))
+	}
+	return routes
+}
+
+// compiledRoutes returns the routes compiled by CompileRoutes, nil before.
+func (engine *Engine) compiledRoutes() *compiledRoutes {
+	routes, _ := engine.compiled.Load().(*compiledRoutes)
+	return routes
+}
+
+// lookupRoute finds the route of the path in the static routes of the method collected
+// by Optimize, then in its compiled routes, then in its tree root.
+func (engine *Engine) lookupRoute(trees *routeTrees, method string, root *node, path string, params *Params, unescape bool) nodeValue {
+	if n := trees.static[method][path]; n != nil {
+		return nodeValue{handlers: n.handlers, run: n.run, fullPath: n.fullPath}
+	}
+	if engine.Matcher == MatcherDoubleArray && trees.host == "" {
+		routes := engine.compiledRoutes()
+		if routes == nil {
+			engine.CompileRoutes()
+			routes = engine.compiledRoutes()
+		}
+		if trie := routes.tries[method]; trie != nil {
+			if n := trie.get(path); n != nil {
+				return nodeValue{handlers: n.handlers, run: n.run, fullPath: n.fullPath}
+			}
+		}
+	}
+	return root.getValue(path, params, unescape)
+}
+
+// compiledRoutes are the lookup structures of MatcherDoubleArray, by method.
+type compiledRoutes struct {
+	tries map[string]*doubleArray
+}
+
+// staticRouteMap returns the nodes of the tree holding the handlers of static routes, by
+// path.
+func staticRouteMap(root *node) map[string]*node {
+	nodes := staticRoutes(root, nil)
+	routes := make(map[string]*node, len(nodes))
+	for _, n := range nodes {
+		routes[n.fullPath] = n
+	}
+	return routes
+}
+
+// staticRoutes appends the nodes of the tree holding the handlers of static routes.
+func staticRoutes(n *node, nodes []*node) []*node {
+	if n.handlers != nil && !strings.ContainsAny(n.fullPath, ":*") {
+		nodes = append(nodes, n)
+	}
+	for i := range n.children {
+		nodes = staticRoutes(&n.children[i], nodes)
+	}
+	return nodes
+}
+
+// doubleArray is a double-array trie mapping static paths to the nodes holding their
+// handlers. The transition from the state s by the byte c goes to the state
+// t = base[s]+c+1 when check[t] == s, the state 0 is the root and the code 0 marks the
+// end of a path, whose state holds the index of the node in leaves as -base-1. The bytes
+// shared by all the paths below a state are stored as a segment of the state instead of
+// a chain of states, so that they are compared at once.
+type doubleArray struct {
+	units    []daUnit
+	segments []string
+	leaves   []*node
+}
+
+type daUnit struct {
+	base    int32
+	check   int32
+	segment int32 // index in segments of the bytes consumed by the state, 0 for none
+}
+
+// newDoubleArray builds the trie of the paths of the nodes.
+func newDoubleArray(nodes []*node) *doubleArray {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].fullPath < nodes[j].fullPath })
+	da := &doubleArray{segments: []string{""}, leaves: nodes}
+	da.grow(1)
+	da.units[0].check = 0
+	if len(nodes) > 0 {
+		b := daBuilder{da: da, nextFree: 1}
+		b.build(0, 0, 0, len(nodes))
+	}
+	return da
+}
+
+// get returns the node of the path, nil when it's not a static route.
+func (da *doubleArray) get(path string) *node {
+	units := da.units
+	s := int32(0)
+	for i := 0; ; i++ {
+		u := units[s]
+		if u.segment != 0 {
+			segment := da.segments[u.segment]
+			if len(path)-i < len(segment) || path[i:i+len(segment)] != segment {
+				return nil
+			}
+			i += len(segment)
+		}
+		t := u.base
+		if i < len(path) {
+			t += int32(path[i]) + 1
+		}
+		if t <= 0 || int(t) >= len(units) || units[t].check != s {
+			return nil
+		}
+		if i == len(path) {
+			return da.leaves[-units[t].base-1]
+		}
+		s = t
+	}
+}
+
+// grow extends the units to n states at least, the new states are free.
+func (da *doubleArray) grow(n int) {
+	for len(da.units) < n {
+		da.units = append(da.units, daUnit{check: -1})
+	}
+}
+
+type daBuilder struct {
+	da       *doubleArray
+	nextFree int32 // no state before is free
+}
+
+// build adds the states of the paths of leaves[from:to], which share their first depth
+// bytes, below the state s.
+func (b *daBuilder) build(s int32, depth, from, to int) {
+	da := b.da
+	first, last := da.leaves[from].fullPath[depth:], da.leaves[to-1].fullPath[depth:]
+	if shared := longestCommonPrefix(first, last); shared > 0 {
+		da.units[s].segment = int32(len(da.segments))
+		da.segments = append(da.segments, first[:shared])
+		depth += shared
+	}
+
+	var codes []int32
+	var bounds []int
+	for i := from; i < to; i++ {
+		path := da.leaves[i].fullPath
+		code := int32(0)
+		if len(path) > depth {
+			code = int32(path[depth]) + 1
+		}
+		if len(codes) == 0 || codes[len(codes)-1] != code {
+			codes = append(codes, code)
+			bounds = append(bounds, i)
+		}
+	}
+	bounds = append(bounds, to)
+
+	base := b.findBase(codes)
+	da.units[s].base = base
+	for _, code := range codes {
+		da.units[base+code].check = s
+	}
+	for b.nextFree < int32(len(da.units)) && da.units[b.nextFree].check >= 0 {
+		b.nextFree++
+	}
+	for i, code := range codes {
+		t := base + code
+		if code == 0 {
+			da.units[t].base = -int32(bounds[i]) - 1
+			continue
+		}
+		b.build(t, depth+1, bounds[i], bounds[i+1])
+	}
+}
+
+// findBase returns the first base for which the states of all the codes are free.
+func (b *daBuilder) findBase(codes []int32) int32 {
+	da := b.da
+	base := b.nextFree - codes[0]
+	if base < 1 {
+		base = 1
+	}
+search:
+	for ; ; base++ {
+		da.grow(int(base + codes[len(codes)-1] + 1))
+		for _, code := range codes {
+			if da.units[base+code].check >= 0 {
+				continue search
+			}
+		}
+		return base
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoubleArray(t *testing.T) {
+	tree := &node{}
+	var paths []string
+	for _, r := range githubAPI {
+		if r.method == http.MethodGet {
+			tree.addRoute(r.path, fakeHandler(r.path))
+			paths = append(paths, r.path)
+		}
+	}
+	da := newDoubleArray(staticRoutes(tree, nil))
+
+	for _, path := range paths {
+		n := da.get(path)
+		if strings.ContainsAny(path, ":*") {
+			assert.True(t, n == nil, path)
+			continue
+		}
+		if assert.NotNil(t, n, path) {
+			assert.Equal(t, path, n.fullPath)
+		}
+	}
+	for _, path := range []string{"", "/", "/use", "/users/", "/emojis/", "/Emojis", "/repos/:owner/:repo", "\xff"} {
+		assert.True(t, da.get(path) == nil, path)
+	}
+	assert.NotNil(t, da.get("/emojis"))
+}
+
+func TestDoubleArrayRandomPaths(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tree := &node{}
+	seen := map[string]bool{}
+	const alphabet = "abc/-.XY\x80"
+	for len(seen) < 2000 {
+		b := make([]byte, 1+rnd.Intn(12))
+		b[0] = '/'
+		for i := 1; i < len(b); i++ {
+			b[i] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		path := string(b)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		tree.addRoute(path, fakeHandler(path))
+	}
+	da := newDoubleArray(staticRoutes(tree, nil))
+	for path := range seen {
+		if n := da.get(path); assert.NotNil(t, n, path) {
+			assert.Equal(t, path, n.fullPath)
+		}
+		if !seen[path+"z"] {
+			assert.True(t, da.get(path+"z") == nil, path)
+		}
+	}
+}
+
+func TestDoubleArrayEmpty(t *testing.T) {
+	da := newDoubleArray(nil)
+	assert.Nil(t, da.get("/"))
+	assert.Nil(t, da.get(""))
+}
+
+func TestMatcherDoubleArray(t *testing.T) {
+	router := New()
+	router.Matcher = MatcherDoubleArray
+	router.GET("/", func(c *Context) { c.String(http.StatusOK, "home") })
+	router.GET("/accounts/new", func(c *Context) { c.String(http.StatusOK, "new "+c.FullPath()) })
+	router.GET("/users/:id/edit", func(c *Context) { c.String(http.StatusOK, "edit "+c.Param("id")) })
+	router.GET("/assets/*filepath", func(c *Context) { c.String(http.StatusOK, c.Param("filepath")) })
+	router.POST("/accounts/new", func(c *Context) { c.String(http.StatusCreated, "created") })
+
+	w := performRequest(router, http.MethodGet, "/")
+	assert.Equal(t, "home", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/accounts/new")
+	assert.Equal(t, "new /accounts/new", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/users/42/edit")
+	assert.Equal(t, "edit 42", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/assets/app.js")
+	assert.Equal(t, "/app.js", w.Body.String())
+	w = performRequest(router, http.MethodPost, "/accounts/new")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = performRequest(router, http.MethodGet, "/accounts/new/")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	w = performRequest(router, http.MethodGet, "/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	match, ok := router.Match(http.MethodGet, "/accounts/new")
+	assert.True(t, ok)
+	assert.Equal(t, "/accounts/new", match.Path)
+
+	assert.Panics(t, func() { router.GET("/late", func(c *Context) {}) })
+}
+
+func TestCompileRoutes(t *testing.T) {
+	router := New()
+	router.GET("/a", func(c *Context) {})
+	router.CompileRoutes()
+	assert.Nil(t, router.compiledRoutes(), "MatcherTree compiles nothing")
+	router.GET("/b", func(c *Context) {})
+
+	router.Matcher = MatcherDoubleArray
+	router.CompileRoutes()
+	assert.NotNil(t, router.compiledRoutes())
+	assert.NotNil(t, router.compiledRoutes().tries[http.MethodGet].get("/b"))
+}
+
+func BenchmarkMatcherDoubleArray(b *testing.B) {
+	for _, matcher := range []RouteMatcher{MatcherTree, MatcherDoubleArray} {
+		router := New()
+		router.Matcher = matcher
+		requests := make([]*http.Request, 0, 5000)
+		for i := 0; i < cap(requests); i++ {
+			path := fmt.Sprintf("/docs/section-%02d/chapter-%02d/page-%d", i%50, i/50%20, i)
+			router.GET(path, func(c *Context) {})
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			requests = append(requests, req)
+		}
+		router.CompileRoutes()
+		w := newMockWriter()
+
+		name := "Tree"
+		if matcher == MatcherDoubleArray {
+			name = "DoubleArray"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				router.ServeHTTP(w, requests[i%len(requests)])
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// FaultKey is the key the name of the fault injected in a request is set under.
+const FaultKey = "fault"
+
+// FaultRule is a fault injected by a FaultInjector: a delay, followed by an error status
+// or a connection reset, or else by the normal handling of the request.
+type FaultRule struct {
+	// Name identifies the fault, it's set in the Context under FaultKey, e.g. for the logs.
+	// Optional.
+	Name string
+
+	// Match selects the requests the fault is injected in, e.g.
+	// gin.MatchAll(gin.MatchRoute("/orders/:id"), gin.MatchHeader("X-Chaos", "on")).
+	// Optional. All the requests match by default.
+	Match RequestMatcher
+
+	// Percentage is the percentage of the matching requests the fault is injected in,
+	// from 0 to 100.
+	// Required.
+	Percentage float64
+
+	// Delay delays the request, or until the request is canceled.
+	// Optional.
+	Delay time.Duration
+
+	// Status aborts the request with the status code, e.g. 503.
+	// Optional.
+	Status int
+
+	// Reset closes the connection without any response, as if it was reset.
+	// Optional.
+	Reset bool
+}
+
+// faultRandom returns the random numbers deciding whether a fault is injected.
+var faultRandom = rand.Float64
+
+// FaultInjector injects faults in the requests, e.g. to run chaos experiments. It is
+// disabled until Enable is called, and its rules can be changed while serving, e.g.
+// from an admin route:
+//     chaos := gin.NewFaultInjector(gin.FaultRule{
+//         Match:      gin.MatchRoute("/orders/:id"),
+//         Percentage: 5,
+//         Delay:      2 * time.Second,
+//         Status:     http.StatusServiceUnavailable,
+//     })
+//     router.Use(chaos.Middleware())
+//     admin.POST("/chaos/on", func(c *gin.Context) { chaos.Enable() })
+// The rules are tried in order, the first one drawn is injected.
+type FaultInjector struct {
+	enabled uint32
+	rules   atomic.Value // []FaultRule
+}
+
+// NewFaultInjector returns a disabled FaultInjector of the rules.
+func NewFaultInjector(rules ...FaultRule) *FaultInjector {
+	f := &FaultInjector{}
+	f.SetRules(rules...)
+	return f
+}
+
+// Enable starts injecting the faults.
+func (f *FaultInjector) Enable() {
+	atomic.StoreUint32(&f.enabled, 1)
+}
+
+// Disable stops injecting the faults.
+func (f *FaultInjector) Disable() {
+	atomic.StoreUint32(&f.enabled, 0)
+}
+
+// Enabled reports whether the faults are injected.
+func (f *FaultInjector) Enabled() bool {
+	return atomic.LoadUint32(&f.enabled) == 1
+}
+
+// SetRules replaces the rules.
+func (f *FaultInjector) SetRules(rules ...FaultRule) {
+	for _, rule := range rules {
+		assert1(rule.Percentage >= 0 && rule.Percentage <= 100, "fault percentage must be between 0 and 100")
+		assert1(rule.Status == 0 || (rule.Status >= 100 && rule.Status <= 999), "invalid fault status code")
+	}
+	f.rules.Store(append([]FaultRule(nil), rules...))
+}
+
+// Rules returns the rules.
+func (f *FaultInjector) Rules() []FaultRule {
+	return append([]FaultRule(nil), f.rules.Load().([]FaultRule)...)
+}
+
+// Middleware returns the middleware injecting the faults.
+func (f *FaultInjector) Middleware() HandlerFunc {
+	return func(c *Context) {
+		if !f.Enabled() {
+			return
+		}
+		for _, rule := range f.rules.Load().([]FaultRule) {
+			if rule.Match != nil && !rule.Match(c) {
+				continue
+			}
+			if rule.Percentage < 100 && faultRandom()*100 >= rule.Percentage {
+				continue
+			}
+			f.inject(c, rule)
+			return
+		}
+	}
+}
+
+func (f *FaultInjector) inject(c *Context, rule FaultRule) {
+	if rule.Name != "" {
+		c.Set(FaultKey, rule.Name)
+	}
+	if rule.Delay > 0 {
+		timer := time.NewTimer(rule.Delay)
+		select {
+		case <-timer.C:
+		case <-c.Request.Context().Done():
+			timer.Stop()
+			c.Abort()
+			return
+		}
+	}
+	switch {
+	case rule.Reset:
+		c.Abort()
+		resetConnection(c)
+	case rule.Status != 0:
+		c.AbortWithStatus(rule.Status)
+	}
+}
+
+// resetConnection closes the connection of the request, with a TCP reset when possible.
+// It aborts the handler when the connection can't be hijacked, e.g. over HTTP/2, so that
+// the server resets the stream.
+func resetConnection(c *Context) {
+	if !c.Writer.Written() {
+		if conn, err := hijack(c.Writer); err == nil {
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				_ = tcp.SetLinger(0)
+			}
+			_ = conn.Close()
+			return
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// hijack hijacks the connection, the writers which don't support it panic.
+func hijack(w ResponseWriter) (conn net.Conn, err error) {
+	defer func() {
+		if recover() != nil {
+			err = http.ErrNotSupported
+		}
+	}()
+	conn, _, err = w.Hijack()
+	return conn, err
+}
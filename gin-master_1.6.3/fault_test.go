@@ -0,0 +1,100 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultInjector(t *testing.T) {
+	chaos := NewFaultInjector(
+		FaultRule{Name: "unavailable", Match: MatchHeader("X-Chaos", "on"), Percentage: 50, Status: http.StatusServiceUnavailable},
+		FaultRule{Name: "slow", Match: MatchRoute("/orders/:id"), Percentage: 100, Delay: 20 * time.Millisecond},
+	)
+	router := New()
+	router.Use(chaos.Middleware())
+	router.GET("/orders/:id", func(c *Context) {
+		c.String(http.StatusOK, c.GetString(FaultKey))
+	})
+	router.GET("/users", func(c *Context) {
+		c.String(http.StatusOK, c.GetString(FaultKey))
+	})
+
+	w := performRequest(router, http.MethodGet, "/users", header{"X-Chaos", "on"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	chaos.Enable()
+	assert.True(t, chaos.Enabled())
+
+	defer func(random func() float64) { faultRandom = random }(faultRandom)
+	random := 0.2
+	faultRandom = func() float64 { return random }
+	w = performRequest(router, http.MethodGet, "/users", header{"X-Chaos", "on"})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	random = 0.7
+	w = performRequest(router, http.MethodGet, "/users", header{"X-Chaos", "on"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	start := time.Now()
+	w = performRequest(router, http.MethodGet, "/orders/1")
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "slow", w.Body.String())
+
+	chaos.SetRules()
+	assert.Empty(t, chaos.Rules())
+	w = performRequest(router, http.MethodGet, "/users", header{"X-Chaos", "on"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	chaos.SetRules(FaultRule{Percentage: 100, Status: http.StatusBadGateway})
+	assert.Len(t, chaos.Rules(), 1)
+	chaos.Disable()
+	w = performRequest(router, http.MethodGet, "/users")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Panics(t, func() { chaos.SetRules(FaultRule{Percentage: 101}) })
+	assert.Panics(t, func() { NewFaultInjector(FaultRule{Percentage: 10, Status: 42}) })
+}
+
+func TestFaultInjectorDelayCanceled(t *testing.T) {
+	chaos := NewFaultInjector(FaultRule{Percentage: 100, Delay: time.Minute})
+	chaos.Enable()
+	router := New()
+	router.Use(chaos.Middleware())
+	called := false
+	router.GET("/", func(c *Context) { called = true })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.False(t, called)
+}
+
+func TestFaultInjectorReset(t *testing.T) {
+	chaos := NewFaultInjector(FaultRule{Percentage: 100, Reset: true})
+	chaos.Enable()
+	router := New()
+	router.Use(Recovery(), chaos.Middleware())
+	router.GET("/", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	_, err := http.Get(srv.URL)
+	assert.Error(t, err)
+
+	// without hijacking, the handler is aborted
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		performRequest(router, http.MethodGet, "/")
+	})
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package gin
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// The fuzz targets of the router, run with e.g.:
+//     go test -run '^$' -fuzz FuzzTreeGetValue
+
+var fuzzRoutes = []string{
+	"/",
+	"/users",
+	"/users/:id",
+	"/users/:id/files/*filepath",
+	"/search/",
+	"/src/*filepath",
+	"/cmd/:tool/:sub",
+	"/cmd/:tool/",
+	"/αβγ/:name",
+	"/info/:user/public",
+	"/info/:user/project/:project",
+}
+
+func FuzzCleanPath(f *testing.F) {
+	for _, seed := range []string{"", "/", "a/b", "//a//b/", "/a/./b/../c", "/../..", "/%2e%2e/x", "/ü/../ß/."} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		cleaned := cleanPath(p)
+		if !strings.HasPrefix(cleaned, "/") {
+			t.Fatalf("cleanPath(%q) = %q doesn't start with /", p, cleaned)
+		}
+		if again := cleanPath(cleaned); again != cleaned {
+			t.Fatalf("cleanPath(%q) = %q is not clean: %q", p, cleaned, again)
+		}
+		for _, s := range []string{"//", "/./", "/../"} {
+			if strings.Contains(cleaned, s) {
+				t.Fatalf("cleanPath(%q) = %q contains %q", p, cleaned, s)
+			}
+		}
+	})
+}
+
+func FuzzTreeAddRoute(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"/users/:id", "/users/:name"},
+		{"/src/*filepath", "/src/x"},
+		{"/a/:b/c", "/a/:b/d"},
+		{"/:a:b", "/x"},
+		{"/" + strings.Repeat("a", 1000), "/" + strings.Repeat("a", 999) + "b"},
+		{"/ü/:x", "/ü/y"},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+	f.Fuzz(func(t *testing.T, a, b string) {
+		if !strings.HasPrefix(a, "/") || !strings.HasPrefix(b, "/") {
+			return
+		}
+		tree := &node{fullPath: "/"}
+		var added []string
+		for _, route := range []string{a, b} {
+			if !addFuzzRoute(t, tree, route) {
+				return
+			}
+			added = append(added, route)
+		}
+		for _, route := range added {
+			if strings.ContainsAny(route, ":*") {
+				continue
+			}
+			value := tree.getValue(route, getParams(), false)
+			if value.handlers == nil || value.fullPath != route {
+				t.Fatalf("route %q added with %q is not found, got %q", route, added, value.fullPath)
+			}
+		}
+	})
+}
+
+// addFuzzRoute adds the route and reports whether it succeeded. Only the documented
+// conflicts may panic, with a message.
+func addFuzzRoute(t *testing.T, tree *node, route string) (ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			if _, documented := err.(string); !documented {
+				if _, isRuntime := err.(runtime.Error); isRuntime {
+					t.Fatalf("adding %q panicked: %v", route, err)
+				}
+				t.Fatalf("adding %q panicked with %T: %v", route, err, err)
+			}
+			ok = false
+		}
+	}()
+	tree.addRoute(route, fakeHandler(route))
+	return true
+}
+
+func FuzzTreeGetValue(f *testing.F) {
+	for _, seed := range []string{
+		"/", "/users/42", "/users/42/files/a/b", "/src/", "/cmd/go/build", "/αβγ/%C3%BC",
+		"/users/%2e%2e/files", "/users//files/", "/" + strings.Repeat("x/", 500), "/info/\x00/public",
+	} {
+		f.Add(seed)
+	}
+	tree := &node{fullPath: "/"}
+	for _, route := range fuzzRoutes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		if !strings.HasPrefix(p, "/") {
+			return
+		}
+		tree.findCaseInsensitivePath(p, true)
+		tree.getValue(p, getParams(), true)
+
+		value := tree.getValue(p, getParams(), false)
+		if value.handlers == nil {
+			return
+		}
+		var params Params
+		if value.params != nil {
+			params = *value.params
+		}
+		if got := expandRoute(value.fullPath, params); got != p {
+			t.Fatalf("%q matched %q with params %v, which give %q", p, value.fullPath, params, got)
+		}
+	})
+}
+
+// expandRoute replaces the wildcards of the route by the values of the params.
+func expandRoute(route string, params Params) string {
+	var b strings.Builder
+	used := 0
+	for i := 0; i < len(route); i++ {
+		switch route[i] {
+		case ':', '*':
+			end := strings.IndexByte(route[i:], '/')
+			if end < 0 || route[i] == '*' {
+				end = len(route) - i
+			}
+			value, _ := params.Get(route[i+1 : i+end])
+			if route[i] == '*' {
+				// the value of a catch-all includes the leading slash
+				value = strings.TrimPrefix(value, "/")
+			}
+			b.WriteString(value)
+			used++
+			i += end - 1
+		default:
+			b.WriteByte(route[i])
+		}
+	}
+	if used != len(params) {
+		return "<" + route + " has not all the params>"
+	}
+	return b.String()
+}
@@ -6,15 +6,20 @@
 package gin
 
 import (
+	"crypto/tls"
 	"fmt"
 	"html/template"
+	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/gin-gonic/gin/internal/bytesconv"
 	"github.com/gin-gonic/gin/render"
 )
 
@@ -23,6 +28,7 @@ const defaultMultipartMemory = 32 << 20 // 32 MB
 var (
 	default404Body = []byte("404 page not found")
 	default405Body = []byte("405 method not allowed")
+	default503Body = []byte("503 service unavailable: under maintenance")
 )
 
 var defaultAppEngine bool
@@ -47,6 +53,9 @@ type RouteInfo struct {
 	Path        string
 	Handler     string
 	HandlerFunc HandlerFunc
+	Meta        RouteMeta
+	Name        string // set with RouterGroup.Named or Name, empty for the unnamed routes
+	Host        string // the pattern set with RouterGroup.Host, empty for the routes of all hosts
 }
 
 // RoutesInfo defines a RouteInfo array.
@@ -62,6 +71,7 @@ type Engine struct {
 	// For example if /foo/ is requested but a route only exists for /foo, the
 	// client is redirected to /foo with http status code 301 for GET requests
 	// and 307 for all other request methods.
+	// The routes may override it, see RouterGroup.TrailingSlash.
 	RedirectTrailingSlash bool
 
 	// If enabled, the router tries to fix the current request path, if no
@@ -75,6 +85,13 @@ type Engine struct {
 	// RedirectTrailingSlash is independent of this option.
 	RedirectFixedPath bool
 
+	// If enabled, the requests whose path only matches a route case-insensitively are
+	// served by the route instead of being redirected, e.g. /FOO/Bar by /foo/:name,
+	// for the links pasted with mixed case. The request keeps its URL and the params
+	// their case. The routes matching the path exactly are tried first, and
+	// RedirectFixedPath still applies to the paths which must also be cleaned.
+	MatchCaseInsensitive bool
+
 	// If enabled, the router checks if another method is allowed for the
 	// current route, if the current request can not be routed.
 	// If this is the case, the request is answered with 'Method Not Allowed'
@@ -104,17 +121,120 @@ type Engine struct {
 	// See the PR #1817 and issue #1644
 	RemoveExtraSlash bool
 
+	// NormalizePath, if set, rewrites the paths of the requests before they're looked up,
+	// e.g. to the Unicode normalization form C, so that the routes registered with
+	// non-ASCII segments in that form match the requests made with the decomposed form:
+	//     router.NormalizePath = norm.NFC.String // golang.org/x/text/unicode/norm
+	// or also to fold their case for a locale. The routes must be registered with
+	// normalized paths. The values of the params are taken from the normalized path, the
+	// request is left unchanged. It's applied after RemoveExtraSlash, and to the escaped
+	// path when UseRawPath is enabled.
+	NormalizePath func(path string) string
+
+	// If enabled, the output of the HTML templates is minified in release mode:
+	// whitespace is collapsed and comments are stripped, see render.MinifyHTML.
+	// HTMLStream output is never minified.
+	MinifyHTML bool
+
+	// If enabled, the successful responses to GET and HEAD requests are buffered
+	// and tagged with a strong ETag, matching If-None-Match headers are answered
+	// with 304 Not Modified. Streamed responses are left alone, see render.ETag.
+	AutoETag bool
+
+	// If positive, the responses which are not streamed are buffered up to that
+	// many bytes, and the ones fitting are sent with a Content-Length header
+	// instead of the chunked transfer encoding, see render.ContentLength.
+	ContentLengthLimit int
+
+	// If enabled, Static, StaticFS and StaticFile serve the sidecar files compressed
+	// ahead of time, e.g. "app.js.br", "app.js.zst" or "app.js.gz" for "app.js", with the
+	// content-coding best accepted by the client instead of the file itself.
+	ServePrecompressed bool
+
+	// If set, the routes are sent to the sink as structured records when registered,
+	// whatever the mode, instead of being printed in debug mode, see JSONRouteSink.
+	RouteSink RouteSink
+
+	// If set, the time-dependent middleware tell the time with the Clock instead of
+	// the time package, e.g. a FakeClock in tests, see Clock.
+	Clock Clock
+
+	// Matcher selects how the routes are looked up, MatcherDoubleArray compiles the
+	// static routes into double-array tries when the first request is served, after
+	// which no route can be added.
+	Matcher RouteMatcher
+
+	// If enabled, the handlers of the routes added after are compiled into a single
+	// function when registered, which calls the handlers of the chains of up to three
+	// handlers directly instead of indexing them in the loop of Context.Next, see
+	// BenchmarkCompileHandlers. The middleware calling Next or Abort work as before.
+	CompileHandlers bool
+
+	// If enabled along with UseRawPath and UnescapePathValues, the path params are
+	// unescaped when first read with Context.Param rather than when the route is
+	// matched, so that the params no handler reads are never unescaped. The values
+	// read from Context.Params directly are still escaped until then.
+	LazyUnescapePathValues bool
+
+	// If enabled, the children of the nodes of the trees are kept in the order their
+	// routes are added instead of being reordered by priority each time, until Optimize
+	// sorts them once, so that the layout of the trees doesn't depend on the order
+	// the routes are added in.
+	FreezeRoutePriorities bool
+
+	// TLSPreset selects the TLS versions and cipher suites accepted by RunTLS, the
+	// crypto/tls defaults by default, see TLSConfig.
+	TLSPreset TLSPreset
+
+	// If set, RunTLS encrypts the session tickets with the keys of the source, which are
+	// rotated every SessionTicketKeyRotation, or every day when it's not set, instead of
+	// the keys crypto/tls generates for the process.
+	SessionTicketKeys        SessionTicketKeySource
+	SessionTicketKeyRotation time.Duration
+
+	// If enabled (experimental), the Keys map and the encoded JSON of Context.JSON of
+	// each request are kept by its Context and reused by the next request once the
+	// response is complete, instead of being allocated for each request, see ArenaStats.
+	// The Params are always reused. The handlers must not keep the Keys after the
+	// request, Context.Copy copies them.
+	RequestArena bool
+
 	delims           render.Delims
 	secureJSONPrefix string
+	jsonConfig       *JSONConfig
 	HTMLRender       render.HTMLRender
 	FuncMap          template.FuncMap
+	SheetWriter      render.SheetWriterFactory // encodes Context.XLSX, nil for the built-in xlsx writer
+	FragmentStore    render.FragmentStore      // stores the fragments of the cache template function, nil disables it
+	ResponseCache    ResponseCacheStore        // stores the responses of the Cache middleware
+	encoders         []namedEncoder            // registered with RegisterEncoder, by order of preference
+	beforeRender     []BeforeRenderFunc
+	afterRender      []AfterRenderFunc
+	renders          map[string]RenderFactory // registered with RegisterRender, by content type
+	renderTypes      []string                 // content types of renders, by order of registration
+	profiles         map[string]*ResponseProfile
+	panicReporters   []PanicReporter
+	htmlLoader       func() // reloads the templates of the last LoadHTMLGlob/LoadHTMLFiles call
 	allNoRoute       HandlersChain // engine上的全部中间件 + noRoute中间件
 	allNoMethod      HandlersChain  // engine上的全部中间件 + noMethod中间件
 	noRoute          HandlersChain
 	noMethod         HandlersChain
+	allMaintenance   HandlersChain // global middleware + onMaintenance handlers
+	onMaintenance    HandlersChain
+	maintenance      atomic.Value // *maintenanceMode, nil when disabled
+	mode             atomic.Value // set with SetMode, the global mode when empty
+	trace            uint32       // TraceFlags set with SetTrace
+	latency          atomic.Value // *latencyHistograms, nil when disabled
 	pool             sync.Pool
-	trees            methodTrees
+	trees            atomic.Value                    // *routeTrees, replaced when a route is added
+	treesMu          sync.Mutex                      // serializes the changes of the trees
 	maxParams        uint16
+	paramsCap        uint32       // capacity of the pooled Params, read atomically
+	minParamsCap     uint32       // set with SetParamsCapacity
+	compiled         atomic.Value // *compiledRoutes, set by CompileRoutes
+	compileMu        sync.Mutex
+	buffers          *render.BufferPool // holds the buffers of the renders, see BufferPoolStats
+	arenaStats       *arenaStats
 }
 
 // 确保Engine上定义的方法不会不小心不兼容的改写了RouterGroup的方法
@@ -146,11 +266,15 @@ func New() *Engine {
 		RemoveExtraSlash:       false,
 		UnescapePathValues:     true,
 		MaxMultipartMemory:     defaultMultipartMemory,
-		trees:                  make(methodTrees, 0, 9),
 		delims:                 render.Delims{Left: "{{", Right: "}}"},
 		secureJSONPrefix:       "while(1);",
+		FragmentStore:          render.NewMemoryFragmentStore(),
+		ResponseCache:          NewMemoryResponseCache(),
+		buffers:                new(render.BufferPool),
+		arenaStats:             new(arenaStats),
 	}
 	engine.RouterGroup.engine = engine
+	engine.trees.Store(&routeTrees{trees: make(methodTrees, 0, 9)})
 	// context 有对象池
 	engine.pool.New = func() interface{} {
 		return engine.allocateContext()
@@ -158,22 +282,59 @@ func New() *Engine {
 	return engine
 }
 
+// BufferPoolStats returns the counts of the buffers the renders took from the pool of
+// the engine, by size class, to tell whether the responses reuse them, e.g.:
+//     stats := router.BufferPoolStats()
+//     log.Printf("render buffers reused: %.0f%%", 100*stats.HitRate())
+func (engine *Engine) BufferPoolStats() render.BufferPoolStats {
+	return engine.buffers.Stats()
+}
+
 // Default returns an Engine instance with the Logger and Recovery middleware already attached.
 // Default在New的基础上，添加了默认的两个中间件， 日志和panic自动恢复
 func Default() *Engine {
 	debugPrintWARNINGDefault()
 	engine := New()
-	engine.Use(Logger(), Recovery())
+	engine.UseNamed(MiddlewareLogger, Logger())
+	engine.UseNamed(MiddlewareRecovery, Recovery())
 	return engine
 }
 
 // 创建context对象
 func (engine *Engine) allocateContext() *Context {
 	// maxParams是所有路由里面参数最多的数量
-	v := make(Params, 0, engine.maxParams)
+	v := make(Params, 0, engine.paramsCapacity())
 	return &Context{engine: engine, params: &v}
 }
 
+// SetParamsCapacity sets the capacity of the Params preallocated for the requests, which
+// is the most params of the routes by default, e.g. to preallocate for the routes added
+// while serving so that the pooled contexts don't need to be reallocated:
+//     router.SetParamsCapacity(8)
+// The capacity is never less than the most params of the routes, 0 restores the default.
+func (engine *Engine) SetParamsCapacity(n int) {
+	assert1(n >= 0 && n <= math.MaxUint16, "params capacity out of range")
+	atomic.StoreUint32(&engine.minParamsCap, uint32(n))
+	engine.updateParamsCapacity()
+}
+
+// ParamsCapacity returns the capacity of the Params preallocated for the requests.
+func (engine *Engine) ParamsCapacity() int {
+	return engine.paramsCapacity()
+}
+
+func (engine *Engine) paramsCapacity() int {
+	return int(atomic.LoadUint32(&engine.paramsCap))
+}
+
+func (engine *Engine) updateParamsCapacity() {
+	capacity := atomic.LoadUint32(&engine.minParamsCap)
+	if uint32(engine.maxParams) > capacity {
+		capacity = uint32(engine.maxParams)
+	}
+	atomic.StoreUint32(&engine.paramsCap, capacity)
+}
+
 // Delims sets template left and right delims and returns a Engine instance.
 func (engine *Engine) Delims(left, right string) *Engine {
 	engine.delims = render.Delims{Left: left, Right: right}
@@ -186,16 +347,63 @@ func (engine *Engine) SecureJsonPrefix(prefix string) *Engine {
 	return engine
 }
 
+// JSONConfig defines how the JSON renders of the Context encode their data, see Engine.SetJSONConfig.
+type JSONConfig struct {
+	// DisableHTMLEscaping keeps <, > and & as is instead of escaping them, like PureJSON does.
+	DisableHTMLEscaping bool
+
+	// IndentInDebug indents the JSON output when gin runs in debug mode.
+	IndentInDebug bool
+
+	// SecurePrefix replaces the prefix used by SecureJSON, see SecureJsonPrefix.
+	// Optional.
+	SecurePrefix string
+
+	// SortKeys sorts the keys of every JSON object, struct fields included.
+	SortKeys bool
+
+	// NonFinite selects how NaN and ±Inf are encoded. By default encoding fails.
+	NonFinite render.NonFiniteMode
+}
+
+func (conf *JSONConfig) jsonOptions() render.JSONOptions {
+	opts := render.JSONOptions{
+		EscapeHTML: !conf.DisableHTMLEscaping,
+		SortKeys:   conf.SortKeys,
+		NonFinite:  conf.NonFinite,
+	}
+	if conf.IndentInDebug && IsDebugging() {
+		opts.Indent = "    "
+	}
+	return opts
+}
+
+// SetJSONConfig applies conf to all the JSON renders of the Context: JSON, IndentedJSON,
+// PureJSON, SecureJSON, AsciiJSON and JSONP without callback. Each method keeps its own
+// behavior on top of it, e.g. PureJSON never escapes HTML and IndentedJSON always indents.
+func (engine *Engine) SetJSONConfig(conf JSONConfig) *Engine {
+	if conf.SecurePrefix != "" {
+		engine.secureJSONPrefix = conf.SecurePrefix
+	}
+	engine.jsonConfig = &conf
+	return engine
+}
+
+// htmlReloadInterval is how often, at most, the templates loaded in debug mode are
+// checked for changes.
+const htmlReloadInterval = 250 * time.Millisecond
+
 // LoadHTMLGlob loads HTML files identified by glob pattern
 // and associates the result with HTML renderer.
 func (engine *Engine) LoadHTMLGlob(pattern string) {
+	defer func() { engine.htmlLoader = func() { engine.LoadHTMLGlob(pattern) } }()
 	left := engine.delims.Left
 	right := engine.delims.Right
-	templ := template.Must(template.New("").Delims(left, right).Funcs(engine.FuncMap).ParseGlob(pattern))
+	templ := template.Must(template.New("").Delims(left, right).Funcs(engine.htmlFuncMap()).ParseGlob(pattern))
 
 	if IsDebugging() {
 		debugPrintLoadTemplate(templ)
-		engine.HTMLRender = render.HTMLDebug{Glob: pattern, FuncMap: engine.FuncMap, Delims: engine.delims}
+		engine.HTMLRender = render.HTMLDebug{Glob: pattern, FuncMap: engine.htmlFuncMap(), Delims: engine.delims}.WithReload(htmlReloadInterval)
 		return
 	}
 
@@ -205,22 +413,82 @@ func (engine *Engine) LoadHTMLGlob(pattern string) {
 // LoadHTMLFiles loads a slice of HTML files
 // and associates the result with HTML renderer.
 func (engine *Engine) LoadHTMLFiles(files ...string) {
+	defer func() { engine.htmlLoader = func() { engine.LoadHTMLFiles(files...) } }()
 	if IsDebugging() {
-		engine.HTMLRender = render.HTMLDebug{Files: files, FuncMap: engine.FuncMap, Delims: engine.delims}
+		engine.HTMLRender = render.HTMLDebug{Files: files, FuncMap: engine.htmlFuncMap(), Delims: engine.delims}.WithReload(htmlReloadInterval)
 		return
 	}
 
-	templ := template.Must(template.New("").Delims(engine.delims.Left, engine.delims.Right).Funcs(engine.FuncMap).ParseFiles(files...))
+	templ := template.Must(template.New("").Delims(engine.delims.Left, engine.delims.Right).Funcs(engine.htmlFuncMap()).ParseFiles(files...))
 	engine.SetHTMLTemplate(templ)
 }
 
+// LoadHTMLLocales loads one template tree per locale, from the subdirectories of
+// root named after the locale language tag, e.g. templates/en and templates/de-ch:
+//     router.LoadHTMLLocales("templates", "en")
+// c.HTML then renders the template of the locale best matching the Accept-Language
+// header of the request, or of the fallback locale when there is none or when the
+// locale doesn't have that template.
+func (engine *Engine) LoadHTMLLocales(root, fallback string) {
+	defer func() { engine.htmlLoader = func() { engine.LoadHTMLLocales(root, fallback) } }()
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		panic(err)
+	}
+
+	renders := make(map[string]render.HTMLRender)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		locale := normalizeLanguageTag(entry.Name())
+		pattern := filepath.Join(root, entry.Name(), "*")
+		templ := template.Must(template.New("").Delims(engine.delims.Left, engine.delims.Right).Funcs(engine.htmlFuncMap()).ParseGlob(pattern))
+		if IsDebugging() {
+			debugPrintLoadTemplate(templ)
+			renders[locale] = render.HTMLDebug{Glob: pattern, FuncMap: engine.htmlFuncMap(), Delims: engine.delims}.WithReload(htmlReloadInterval)
+			continue
+		}
+		renders[locale] = render.NewHTMLProduction(templ, engine.delims)
+	}
+	fallback = normalizeLanguageTag(fallback)
+	assert1(renders[fallback] != nil, "the templates of the fallback locale "+fallback+" are missing")
+
+	if len(engine.routeTrees().trees) > 0 && !IsDebugging() {
+		debugPrintWARNINGSetHTMLTemplate()
+	}
+	engine.HTMLRender = render.HTMLLocalized{Renders: renders, Fallback: fallback}
+}
+
 // SetHTMLTemplate associate a template with HTML renderer.
 func (engine *Engine) SetHTMLTemplate(templ *template.Template) {
-	if len(engine.trees) > 0 {
+	if len(engine.routeTrees().trees) > 0 {
 		debugPrintWARNINGSetHTMLTemplate()
 	}
 
-	engine.HTMLRender = render.HTMLProduction{Template: templ.Funcs(engine.FuncMap)}
+	engine.htmlLoader = nil
+	engine.HTMLRender = render.NewHTMLProduction(templ.Funcs(engine.htmlFuncMap()), engine.delims)
+}
+
+// htmlFuncMap returns the functions templates are parsed with: the engine's FuncMap
+// plus the flush function used by Context.HTMLStream and the fragment cache function,
+// unless they were overridden.
+func (engine *Engine) htmlFuncMap() template.FuncMap {
+	funcMap := template.FuncMap{}
+	for name, fn := range render.StreamFuncs() {
+		funcMap[name] = fn
+	}
+	fragmentFuncs := render.FragmentFuncs(
+		func() render.FragmentStore { return engine.FragmentStore },
+		func() render.HTMLRender { return engine.HTMLRender },
+	)
+	for name, fn := range fragmentFuncs {
+		funcMap[name] = fn
+	}
+	for name, fn := range engine.FuncMap {
+		funcMap[name] = fn
+	}
+	return funcMap
 }
 
 // SetFuncMap sets the FuncMap used for template.FuncMap.
@@ -228,6 +496,26 @@ func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 	engine.FuncMap = funcMap
 }
 
+// AddTemplateFuncs merges funcMap into the engine's FuncMap, a later registration of
+// the same name wins. It can be called any number of times, e.g. once per package
+// shipping template helpers:
+//     router.AddTemplateFuncs(gin.TemplateHelpers())
+//     router.AddTemplateFuncs(template.FuncMap{"avatar": avatarURL})
+// If templates were already loaded with LoadHTMLGlob or LoadHTMLFiles they are parsed
+// again, so the new functions can be used by them.
+// Like SetHTMLTemplate it is not thread-safe and should only be called at initialization.
+func (engine *Engine) AddTemplateFuncs(funcMap template.FuncMap) {
+	if engine.FuncMap == nil {
+		engine.FuncMap = template.FuncMap{}
+	}
+	for name, fn := range funcMap {
+		engine.FuncMap[name] = fn
+	}
+	if engine.htmlLoader != nil {
+		engine.htmlLoader()
+	}
+}
+
 // NoRoute adds handlers for NoRoute. It return a 404 code by default.
 func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
 	engine.noRoute = handlers
@@ -245,9 +533,15 @@ func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
 // For example, this is the right place for a logger or error management middleware.
 func (engine *Engine) Use(middleware ...HandlerFunc) IRoutes {
 	engine.RouterGroup.Use(middleware...)
+	engine.rebuildGlobalHandlers()
+	return engine
+}
+
+// rebuildGlobalHandlers combines the global middleware with the handlers of the errors.
+func (engine *Engine) rebuildGlobalHandlers() {
 	engine.rebuild404Handlers()
 	engine.rebuild405Handlers()
-	return engine
+	engine.rebuild503Handlers()
 }
 
 func (engine *Engine) rebuild404Handlers() {
@@ -261,34 +555,200 @@ func (engine *Engine) rebuild405Handlers() {
 // routerGroup的各种路由注册方法最终会调用group.handle拼装path和组装handlers, 然后调用group.engine.addRoute
 // 参数handlers已经包含了中间件
 func (engine *Engine) addRoute(method, path string, handlers HandlersChain) {
+	if err := engine.addRouteMeta(method, path, handlers, routeOptions{}); err != nil {
+		panic(err.Error())
+	}
+}
+
+// addRouteMeta adds the route with the options of its group, or returns the error of the
+// tree when it conflicts with the routes already added, which are then left unchanged.
+// It can be called while requests are served, see RouterGroup.Handle. The route is added
+// to the routes of the host pattern, if any, see RouterGroup.Host.
+func (engine *Engine) addRouteMeta(method, path string, handlers HandlersChain, opts routeOptions) error {
 	assert1(path[0] == '/', "path must begin with '/'")
 	assert1(method != "", "HTTP method can not be empty")
 	assert1(len(handlers) > 0, "there must be at least one handler")
+	assert1(engine.compiledRoutes() == nil, "routes can not be added once compiled by the matcher")
+
+	// a route ending with an optional param is added with and without it
+	paths := optionalParamPaths(path)
+
+	// the route is added to a copy of the tree, which replaces it once complete, so that
+	// the requests being served keep on reading the former tree without locking
+	engine.treesMu.Lock()
+	defer engine.treesMu.Unlock()
+	all := engine.routeTrees()
+	trees := all
+	if opts.host != "" {
+		trees = all.hostTrees(opts.host)
+	}
+	root := new(node)
+	if current := trees.index.get(method); current != nil {
+		*root = *current
+	} else {
+		root.fullPath = "/"
+	}
+	if opts.name != "" {
+		var err error
+		if trees, err = trees.withHostName(all, opts.name, method, path); err != nil {
+			return err
+		}
+	}
+	var run HandlerFunc
+	if engine.CompileHandlers {
+		run = compileHandlers(handlers)
+	}
+	for _, path := range paths {
+		// 路由数上添加路由
+		leaf, err := root.tryAddRoute(path, handlers, !engine.FreezeRoutePriorities)
+		if err != nil {
+			return err
+		}
+		leaf.run = run
+		trees = trees.withMeta(method, path, opts.meta).withTrailingSlash(method, path, opts.trailingSlash).
+			withCORS(method, path, corsIndex(handlers))
+	}
+	trees = trees.with(method, root)
+	if opts.host != "" {
+		trees = all.withHost(trees)
+	}
+	engine.trees.Store(trees)
+
+	hostParams := countParams(opts.host)
+	for _, path := range paths {
+		if engine.RouteSink != nil {
+			engine.RouteSink(newRouteRecord(method, path, handlers))
+		} else if engine.IsDebugging() {
+			printRoute(method, path, handlers)
+		}
+
+		// Update maxParams
+		if paramsCount := countParams(path) + hostParams; paramsCount > engine.maxParams {
+			engine.maxParams = paramsCount
+			engine.updateParamsCapacity()
+		}
+	}
+	return nil
+}
 
-	debugPrintRoute(method, path, handlers)
+// RemoveRoute removes the route of the method and path, as registered, e.g.
+// "/plugins/:name/status", and returns whether it was registered:
+//     router.RemoveRoute(http.MethodGet, "/plugins/reports/export")
+// Both the routes of a path ending with an optional param are removed. Like the routes
+// added while requests are served, the removal applies to the requests looked up
+// after it, the ones being served keep on using the route. The static routes collected by
+// Optimize for the method are collected again, and the routes compiled by the Matcher
+// are compiled again.
+func (engine *Engine) RemoveRoute(method, path string) bool {
+	if paths := optionalParamPaths(path); len(paths) > 1 {
+		removed := false
+		for _, path := range paths {
+			removed = engine.RemoveRoute(method, path) || removed
+		}
+		return removed
+	}
 
-	root := engine.trees.get(method)
-	if root == nil {
-		root = new(node)
+	engine.treesMu.Lock()
+	defer engine.treesMu.Unlock()
+	trees := engine.routeTrees()
+	current := trees.index.get(method)
+	if current == nil {
+		return false
+	}
+	root := new(node)
+	*root = *current
+	if !root.removeRoute(path, !engine.FreezeRoutePriorities) {
+		return false
+	}
+	if root.path == "" && len(root.children) == 0 {
 		root.fullPath = "/"
-		engine.trees = append(engine.trees, methodTree{method: method, root: root})
 	}
-	// 路由数上添加路由
-	root.addRoute(path, handlers)
+	trees = trees.with(method, root).withMeta(method, path, nil).withTrailingSlash(method, path, TrailingSlashDefault).
+		withCORS(method, path, 0).withoutName(method, path)
+	if engine.compiledRoutes() != nil {
+		engine.compileMu.Lock()
+		engine.compiled.Store(compileRoutes(trees.trees))
+		engine.compileMu.Unlock()
+	}
+	engine.trees.Store(trees)
+	return true
+}
+
+// routeTrees returns the current snapshot of the method trees.
+func (engine *Engine) routeTrees() *routeTrees {
+	if trees, _ := engine.trees.Load().(*routeTrees); trees != nil {
+		return trees
+	}
+	return &routeTrees{}
+}
+
+// Optimize sorts the children of the nodes of the trees by priority, so that the most
+// used branches are tried first, e.g. once all the routes are added when
+// FreezeRoutePriorities is enabled, and collects the static routes, the ones without
+// params, in maps by path which are looked up before the trees, at the cost of a map
+// lookup for the other routes, see BenchmarkOptimizedRouterLookup. The static routes of
+// a method are then collected again when a route is added or removed, which walks its
+// tree, so it's best called once most of the routes are added. The routes added later
+// are sorted when Optimize is called again. Like addRoute, it replaces the trees with
+// sorted copies, so it can be called while requests are served. The routes of the hosts
+// are optimized too.
+func (engine *Engine) Optimize() {
+	engine.treesMu.Lock()
+	defer engine.treesMu.Unlock()
+	all := engine.routeTrees()
+	trees := all.optimized()
+	for _, h := range all.hosts {
+		trees = trees.withHost(h.trees.optimized())
+	}
+	engine.trees.Store(trees)
+}
 
-	// Update maxParams
-	if paramsCount := countParams(path); paramsCount > engine.maxParams {
-		engine.maxParams = paramsCount
+// optimized returns a copy of the snapshot with sorted trees and the static routes
+// collected, see Optimize.
+func (rt *routeTrees) optimized() *routeTrees {
+	// the static routes are collected once the trees are sorted
+	trees := &routeTrees{}
+	*trees = *rt
+	trees.static = nil
+	for _, tree := range trees.trees {
+		root := new(node)
+		*root = *tree.root
+		root.sortChildren()
+		root.updateLowercase()
+		trees = trees.with(tree.method, root)
 	}
+	static := make(map[string]map[string]*node, len(trees.trees))
+	for _, tree := range trees.trees {
+		static[tree.method] = staticRouteMap(tree.root)
+	}
+	next := *trees
+	next.static = static
+	return &next
 }
 
 // Routes returns a slice of registered routes, including some useful information, such as:
 // the http method, path and the handler name.
 // 返回全部注册路由列表，包含method， path, handler
 func (engine *Engine) Routes() (routes RoutesInfo) {
-	for _, tree := range engine.trees {
+	trees := engine.routeTrees()
+	routes = trees.routes(routes)
+	for _, h := range trees.hosts {
+		routes = h.trees.routes(routes)
+	}
+	return routes
+}
+
+// routes appends the routes of the snapshot.
+func (rt *routeTrees) routes(routes RoutesInfo) RoutesInfo {
+	start := len(routes)
+	for _, tree := range rt.trees {
 		routes = iterate("", tree.method, routes, tree.root)
 	}
+	for i := start; i < len(routes); i++ {
+		routes[i].Meta = rt.meta[routes[i].Method][routes[i].Path]
+		routes[i].Name = rt.routeNames[routes[i].Method][routes[i].Path]
+		routes[i].Host = rt.host
+	}
 	return routes
 }
 
@@ -304,8 +764,8 @@ func iterate(path, method string, routes RoutesInfo, root *node) RoutesInfo {
 			HandlerFunc: handlerFunc,
 		})
 	}
-	for _, child := range root.children {
-		routes = iterate(path, method, routes, child)
+	for i := range root.children {
+		routes = iterate(path, method, routes, &root.children[i])
 	}
 	return routes
 }
@@ -317,20 +777,43 @@ func (engine *Engine) Run(addr ...string) (err error) {
 	defer func() { debugPrintError(err) }()
 
 	address := resolveAddress(addr)
-	debugPrint("Listening and serving HTTP on %s\n", address)
+	engine.debugPrint("Listening and serving HTTP on %s\n", address)
 	// engine需要实现 ServeHTTP(ResponseWriter, *Request)方法
 	err = http.ListenAndServe(address, engine)
 	return
 }
 
 // RunTLS attaches the router to a http.Server and starts listening and serving HTTPS (secure) requests.
-// It is a shortcut for http.ListenAndServeTLS(addr, certFile, keyFile, router)
+// It is like http.ListenAndServeTLS(addr, certFile, keyFile, router), with the TLSConfig of the
+// engine and its SessionTicketKeys.
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
 func (engine *Engine) RunTLS(addr, certFile, keyFile string) (err error) {
-	debugPrint("Listening and serving HTTPS on %s\n", addr)
+	engine.debugPrint("Listening and serving HTTPS on %s with the %s TLS preset\n", addr, engine.TLSPreset)
 	defer func() { debugPrintError(err) }()
 
-	err = http.ListenAndServeTLS(addr, certFile, keyFile, engine)
+	config := engine.TLSConfig()
+	config.NextProtos = []string{"h2", "http/1.1"}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return
+	}
+	config.Certificates = []tls.Certificate{cert}
+	// the keys are rotated in config, which http.Server would clone in ListenAndServeTLS
+	stop, err := engine.startSessionTicketKeyRotation(config)
+	if err != nil {
+		return
+	}
+	defer stop()
+
+	if addr == "" {
+		addr = ":https"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return
+	}
+	server := &http.Server{Addr: addr, Handler: engine, TLSConfig: config}
+	err = server.Serve(tls.NewListener(listener, config))
 	return
 }
 
@@ -338,7 +821,7 @@ func (engine *Engine) RunTLS(addr, certFile, keyFile string) (err error) {
 // through the specified unix socket (ie. a file).
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
 func (engine *Engine) RunUnix(file string) (err error) {
-	debugPrint("Listening and serving HTTP on unix:/%s", file)
+	engine.debugPrint("Listening and serving HTTP on unix:/%s", file)
 	defer func() { debugPrintError(err) }()
 
 	listener, err := net.Listen("unix", file)
@@ -356,7 +839,7 @@ func (engine *Engine) RunUnix(file string) (err error) {
 // through the specified file descriptor.
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
 func (engine *Engine) RunFd(fd int) (err error) {
-	debugPrint("Listening and serving HTTP on fd@%d", fd)
+	engine.debugPrint("Listening and serving HTTP on fd@%d", fd)
 	defer func() { debugPrintError(err) }()
 
 	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd@%d", fd))
@@ -372,7 +855,7 @@ func (engine *Engine) RunFd(fd int) (err error) {
 // RunListener attaches the router to a http.Server and starts listening and serving HTTP requests
 // through the specified net.Listener
 func (engine *Engine) RunListener(listener net.Listener) (err error) {
-	debugPrint("Listening and serving HTTP on listener what's bind with address@%s", listener.Addr())
+	engine.debugPrint("Listening and serving HTTP on listener what's bind with address@%s", listener.Addr())
 	defer func() { debugPrintError(err) }()
 	err = http.Serve(listener, engine)
 	return
@@ -382,12 +865,32 @@ func (engine *Engine) RunListener(listener net.Listener) (err error) {
 // 请求处理的入口，从对象池取个context，重置，处理请求，放回context
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c := engine.pool.Get().(*Context)
+	if capacity := engine.paramsCapacity(); cap(*c.params) < capacity {
+		// the context was pooled before a route with more params was added
+		v := make(Params, 0, capacity)
+		c.params = &v
+	}
 	c.writermem.reset(w)
 	c.Request = req
 	c.reset()
+	engine.acquireArena(c)
+
+	if h, _ := engine.latency.Load().(*latencyHistograms); h != nil {
+		start := time.Now()
+		engine.handleHTTPRequest(c)
+		h.observe(req.Method, c.FullPath(), time.Since(start))
+	} else {
+		engine.handleHTTPRequest(c)
+	}
+	if engine.tracing(TraceRouter) {
+		route := c.FullPath()
+		if route == "" {
+			route = "<no route>"
+		}
+		tracef(TraceRouter, "%s %s --> %s %d", req.Method, req.URL.Path, route, c.Writer.Status())
+	}
 
-	engine.handleHTTPRequest(c)
-
+	engine.releaseArena(c)
 	engine.pool.Put(c)
 }
 
@@ -415,31 +918,65 @@ func (engine *Engine) handleHTTPRequest(c *Context) {
 	if engine.RemoveExtraSlash {
 		rPath = cleanPath(rPath)
 	}
+	if engine.NormalizePath != nil {
+		rPath = engine.NormalizePath(rPath)
+	}
+
+	if engine.maintenanceBlocks(rPath) {
+		c.handlers = engine.allMaintenance
+		serveError(c, http.StatusServiceUnavailable, default503Body)
+		return
+	}
 
 	// Find root of the tree for the given HTTP method
-	t := engine.trees
-	for i, tl := 0, len(t); i < tl; i++ {
-		if t[i].method != httpMethod {
-			continue
+	trees := engine.routeTrees()
+	var hostParams Params
+	if len(trees.hosts) > 0 {
+		if hostTrees, params := trees.matchHost(c.Request.Host); hostTrees != nil {
+			trees, hostParams = hostTrees, params
+			c.hostTrees = hostTrees
 		}
-		root := t[i].root
+	}
+	if root := trees.index.get(httpMethod); root != nil {
 		// Find route in tree
-		value := root.getValue(rPath, c.params, unescape)
+		lazy := unescape && engine.LazyUnescapePathValues
+		value := engine.lookupRoute(trees, httpMethod, root, rPath, c.params, unescape && !lazy)
+		policy := TrailingSlashStrict
+		if value.tsr && httpMethod != "CONNECT" && rPath != "/" {
+			var tsrPath string
+			if policy, tsrPath = engine.trailingSlashPolicy(trees, httpMethod, root, rPath); policy == TrailingSlashMatch {
+				*c.params = (*c.params)[:0]
+				value = engine.lookupRoute(trees, httpMethod, root, tsrPath, c.params, unescape && !lazy)
+			}
+		}
+		if value.handlers == nil && engine.MatchCaseInsensitive {
+			if v := engine.caseInsensitiveValue(trees, httpMethod, root, rPath, c.params, unescape && !lazy); v.handlers != nil {
+				value = v
+			}
+		}
 		// ??
 		if value.params != nil {
 			c.Params = *value.params
+			if lazy {
+				c.markEscapedParams()
+			}
 		}
+		c.Params = append(c.Params, hostParams...)
 		// 处理请求
 		if value.handlers != nil {
 			c.handlers = value.handlers
 			c.fullPath = value.fullPath
 			// 执行handlers
-			c.Next()
+			if value.run != nil {
+				value.run(c)
+			} else {
+				c.Next()
+			}
 			c.writermem.WriteHeaderNow()
 			return
 		}
 		if httpMethod != "CONNECT" && rPath != "/" {
-			if value.tsr && engine.RedirectTrailingSlash {
+			if value.tsr && policy == TrailingSlashRedirect {
 				redirectTrailingSlash(c)
 				return
 			}
@@ -447,11 +984,15 @@ func (engine *Engine) handleHTTPRequest(c *Context) {
 				return
 			}
 		}
-		break
+	}
+
+	// preflight requests to routes using the CORS middleware, see CORS
+	if httpMethod == http.MethodOptions && engine.servePreflight(c, trees, rPath, unescape) {
+		return
 	}
 
 	if engine.HandleMethodNotAllowed {
-		for _, tree := range engine.trees {
+		for _, tree := range trees.trees {
 			if tree.method == httpMethod {
 				continue
 			}
@@ -476,6 +1017,9 @@ func serveError(c *Context, code int, defaultMessage []byte) {
 	}
 	if c.writermem.Status() == code {
 		c.writermem.Header()["Content-Type"] = mimePlain
+		if id := c.RequestID(); id != "" {
+			defaultMessage = append(append(defaultMessage[:len(defaultMessage):len(defaultMessage)], "\nrequest id: "...), id...)
+		}
 		_, err := c.Writer.Write(defaultMessage)
 		if err != nil {
 			debugPrint("cannot write message to writer during serve error: %v", err)
@@ -499,12 +1043,24 @@ func redirectTrailingSlash(c *Context) {
 	redirectRequest(c)
 }
 
+// caseInsensitiveValue looks up the route matching the path case-insensitively, see
+// MatchCaseInsensitive. The path it's found with has the case of the route, but for the
+// values of the params.
+func (engine *Engine) caseInsensitiveValue(trees *routeTrees, method string, root *node, path string, params *Params, unescape bool) nodeValue {
+	fixedPath, ok := root.findCaseInsensitivePathString(path, false)
+	if !ok || fixedPath == path {
+		return nodeValue{}
+	}
+	*params = (*params)[:0]
+	return engine.lookupRoute(trees, method, root, fixedPath, params, unescape)
+}
+
 func redirectFixedPath(c *Context, root *node, trailingSlash bool) bool {
 	req := c.Request
 	rPath := req.URL.Path
 
-	if fixedPath, ok := root.findCaseInsensitivePath(cleanPath(rPath), trailingSlash); ok {
-		req.URL.Path = bytesconv.BytesToString(fixedPath)
+	if fixedPath, ok := root.findCaseInsensitivePathString(cleanPath(rPath), trailingSlash); ok {
+		req.URL.Path = fixedPath
 		redirectRequest(c)
 		return true
 	}
@@ -520,7 +1076,7 @@ func redirectRequest(c *Context) {
 	if req.Method != http.MethodGet {
 		code = http.StatusTemporaryRedirect
 	}
-	debugPrint("redirecting request %d: %s --> %s", code, rPath, rURL)
+	c.engine.debugPrint("redirecting request %d: %s --> %s", code, rPath, rURL)
 	http.Redirect(c.Writer, req, rURL, code)
 	c.writermem.WriteHeaderNow()
 }
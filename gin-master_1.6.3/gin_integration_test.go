@@ -6,10 +6,16 @@ package gin
 
 import (
 	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"html/template"
 	"io/ioutil"
+	"math/big"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -71,6 +77,88 @@ func TestRunTLS(t *testing.T) {
 	testRequest(t, "https://localhost:8443/example")
 }
 
+func TestRunTLSPreset(t *testing.T) {
+	router := New()
+	router.TLSPreset = TLSModern
+	router.GET("/example", func(c *Context) { c.String(http.StatusOK, "it worked") })
+	go func() {
+		assert.NoError(t, router.RunTLS(":8451", "./testdata/certificate/cert.pem", "./testdata/certificate/key.pem"))
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testRequest(t, "https://localhost:8451/example")
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12},
+	}}
+	_, err := client.Get("https://localhost:8451/example")
+	assert.Error(t, err)
+}
+
+func TestRunTLSSessionTicketKeys(t *testing.T) {
+	keys := SessionTicketKeyFunc(func() ([][32]byte, error) {
+		return [][32]byte{{1, 2, 3}}, nil
+	})
+	// the clients don't resume the sessions of expired certificates, as the one of testdata
+	certFile, keyFile := writeTestCertificate(t)
+	defer os.Remove(certFile)
+	defer os.Remove(keyFile)
+	for _, addr := range []string{":8452", ":8453"} {
+		router := New()
+		router.SessionTicketKeys = keys
+		router.GET("/example", func(c *Context) { c.String(http.StatusOK, "it worked") })
+		go func(addr string) {
+			assert.NoError(t, router.RunTLS(addr, certFile, keyFile))
+		}(addr)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// the servers sharing the keys resume the sessions of each other
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+			ClientSessionCache: tls.NewLRUClientSessionCache(1),
+		},
+		DisableKeepAlives: true,
+	}}
+	for i, url := range []string{"https://localhost:8452/example", "https://localhost:8453/example"} {
+		resp, err := client.Get(url)
+		if assert.NoError(t, err) {
+			_, err = ioutil.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, i > 0, resp.TLS.DidResume)
+		}
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate of localhost valid for an hour
+// and its key to temporary files.
+func writeTestCertificate(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	write := func(pattern, blockType string, der []byte) string {
+		f, err := ioutil.TempFile("", pattern)
+		assert.NoError(t, err)
+		defer f.Close()
+		assert.NoError(t, pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}))
+		return f.Name()
+	}
+	return write("cert*.pem", "CERTIFICATE", der), write("key*.pem", "EC PRIVATE KEY", keyDER)
+}
+
 func TestPusher(t *testing.T) {
 	var html = template.Must(template.New("https").Parse(`
 <html>
@@ -13,10 +13,12 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin/render"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -176,6 +178,100 @@ func TestCreateEngine(t *testing.T) {
 	assert.Empty(t, router.Handlers)
 }
 
+func TestAddTemplateFuncs(t *testing.T) {
+	router := New()
+	router.AddTemplateFuncs(template.FuncMap{"shout": strings.ToUpper})
+	router.AddTemplateFuncs(template.FuncMap{"upper": func(s string) string { return "overridden" }})
+	router.AddTemplateFuncs(TemplateHelpers())
+	assert.Contains(t, router.FuncMap, "shout")
+	assert.Contains(t, router.FuncMap, "lower")
+
+	router.LoadHTMLGlob("./testdata/funcmap/*")
+	router.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "shout.tmpl", H{"name": "gin"})
+	})
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, "GIN GIN", w.Body.String())
+}
+
+func TestAddTemplateFuncsAfterLoad(t *testing.T) {
+	SetMode(ReleaseMode)
+	defer SetMode(TestMode)
+
+	router := New()
+	router.SetFuncMap(nil)
+	router.AddTemplateFuncs(template.FuncMap{"shout": strings.ToUpper, "upper": strings.ToUpper})
+	router.LoadHTMLFiles("./testdata/funcmap/shout.tmpl")
+
+	// templates are parsed again with the new functions
+	router.AddTemplateFuncs(template.FuncMap{"shout": func(s string) string { return s + "!" }})
+	router.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "shout.tmpl", H{"name": "gin"})
+	})
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, "gin! GIN", w.Body.String())
+
+	// a template set by hand is left alone
+	router.SetHTMLTemplate(template.Must(template.New("shout.tmpl").Parse("manual")))
+	router.AddTemplateFuncs(template.FuncMap{"other": strings.ToLower})
+	w = performRequest(router, "GET", "/")
+	assert.Equal(t, "manual", w.Body.String())
+}
+
+func TestTemplateFragmentCache(t *testing.T) {
+	for _, mode := range []string{DebugMode, ReleaseMode} {
+		SetMode(mode)
+		calls := 0
+		router := New()
+		router.SetFuncMap(template.FuncMap{"count": func() int { calls++; return calls }})
+		router.LoadHTMLGlob("./testdata/fragment/*")
+		router.GET("/:name", func(c *Context) {
+			c.HTML(http.StatusOK, "page.tmpl", H{"name": c.Param("name")})
+		})
+
+		w := performRequest(router, "GET", "/foo")
+		assert.Equal(t, "<main><aside>1 foo</aside></main>", w.Body.String(), mode)
+		w = performRequest(router, "GET", "/bar")
+		assert.Equal(t, "<main><aside>1 foo</aside></main>", w.Body.String(), mode)
+
+		router.FragmentStore = nil
+		w = performRequest(router, "GET", "/bar")
+		assert.Equal(t, "<main><aside>2 bar</aside></main>", w.Body.String(), mode)
+	}
+	SetMode(TestMode)
+}
+
+func TestLoadHTMLLocales(t *testing.T) {
+	for _, mode := range []string{DebugMode, ReleaseMode} {
+		SetMode(mode)
+		router := New()
+		router.LoadHTMLLocales("./testdata/locale", "EN")
+		router.GET("/:page", func(c *Context) {
+			c.HTML(http.StatusOK, c.Param("page")+".tmpl", H{"name": "gin"})
+		})
+
+		for accept, expected := range map[string]string{
+			"":                          "Hello gin",
+			"de-DE,de;q=0.9":            "Hallo gin",
+			"fr, pt-BR;q=0.8, en;q=0.5": "Olá gin",
+			"pt":                        "Olá gin",
+			"fr":                        "Hello gin",
+		} {
+			w := performRequest(router, "GET", "/index", header{Key: "Accept-Language", Value: accept})
+			assert.Equal(t, expected, w.Body.String(), mode+" "+accept)
+		}
+		w := performRequest(router, "GET", "/about", header{Key: "Accept-Language", Value: "de"})
+		assert.Equal(t, "About", w.Body.String(), mode)
+
+		assert.Equal(t, []string{"en", "de", "pt-br"}, router.HTMLRender.(render.HTMLLocaleRender).Locales())
+	}
+	SetMode(TestMode)
+
+	router := New()
+	assert.Panics(t, func() { router.LoadHTMLLocales("./testdata/locale", "fr") })
+	assert.Panics(t, func() { router.LoadHTMLLocales("./testdata/missing", "en") })
+}
+
 func TestLoadHTMLFilesTestMode(t *testing.T) {
 	ts := setupHTMLFiles(
 		t,
@@ -287,18 +383,100 @@ func TestAddRoute(t *testing.T) {
 	router := New()
 	router.addRoute("GET", "/", HandlersChain{func(_ *Context) {}})
 
-	assert.Len(t, router.trees, 1)
-	assert.NotNil(t, router.trees.get("GET"))
-	assert.Nil(t, router.trees.get("POST"))
+	assert.Len(t, router.routeTrees().trees, 1)
+	assert.NotNil(t, router.routeTrees().trees.get("GET"))
+	assert.Nil(t, router.routeTrees().trees.get("POST"))
 
 	router.addRoute("POST", "/", HandlersChain{func(_ *Context) {}})
 
-	assert.Len(t, router.trees, 2)
-	assert.NotNil(t, router.trees.get("GET"))
-	assert.NotNil(t, router.trees.get("POST"))
+	assert.Len(t, router.routeTrees().trees, 2)
+	assert.NotNil(t, router.routeTrees().trees.get("GET"))
+	assert.NotNil(t, router.routeTrees().trees.get("POST"))
 
 	router.addRoute("POST", "/post", HandlersChain{func(_ *Context) {}})
-	assert.Len(t, router.trees, 2)
+	assert.Len(t, router.routeTrees().trees, 2)
+}
+
+func TestCustomMethodRoutes(t *testing.T) {
+	router := New()
+	router.Handle("PROPFIND", "/files/*path", func(c *Context) { c.String(http.StatusOK, "propfind "+c.Param("path")) })
+	router.Handle("LINK", "/files/*path", func(c *Context) { c.String(http.StatusOK, "link") })
+	router.GET("/files/*path", func(c *Context) { c.String(http.StatusOK, "get") })
+
+	w := performRequest(router, "PROPFIND", "/files/a.txt")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "propfind /a.txt", w.Body.String())
+
+	w = performRequest(router, "LINK", "/files/a.txt")
+	assert.Equal(t, "link", w.Body.String())
+
+	w = performRequest(router, "GET", "/files/a.txt")
+	assert.Equal(t, "get", w.Body.String())
+
+	w = performRequest(router, "UNLINK", "/files/a.txt")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAddRouteCopiesTrees(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", func(c *Context) {})
+	before := router.routeTrees()
+	beforeRoot := before.index.get(http.MethodGet)
+
+	router.GET("/users/:id/repos", func(c *Context) {})
+	router.GET("/teams", func(c *Context) {})
+	router.POST("/users", func(c *Context) {})
+
+	// the former snapshot is left alone
+	assert.Len(t, before.trees, 1)
+	assert.Nil(t, before.index.get(http.MethodPost))
+	assert.Nil(t, beforeRoot.getValue("/users/42/repos", nil, false).handlers)
+	assert.Nil(t, beforeRoot.getValue("/teams", nil, false).handlers)
+	assert.NotNil(t, beforeRoot.getValue("/users/42", nil, false).handlers)
+
+	after := router.routeTrees()
+	assert.Len(t, after.trees, 2)
+	assert.NotNil(t, after.index.get(http.MethodGet).getValue("/users/42/repos", nil, false).handlers)
+	assert.NotNil(t, after.index.get(http.MethodGet).getValue("/teams", nil, false).handlers)
+}
+
+func TestAddRouteFailureKeepsTrees(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", func(c *Context) { c.String(http.StatusOK, c.Param("id")) })
+	assert.Panics(t, func() {
+		router.GET("/users/:name/repos", func(c *Context) {})
+	})
+
+	w := performRequest(router, http.MethodGet, "/users/42")
+	assert.Equal(t, "42", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/users/42/repos")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAddRouteWhileServing(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", func(c *Context) { c.String(http.StatusOK, c.Param("id")) })
+
+	var stop int32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for atomic.LoadInt32(&stop) == 0 {
+			w := performRequest(router, http.MethodGet, "/users/42")
+			if w.Body.String() != "42" {
+				t.Errorf("unexpected response %q", w.Body.String())
+				return
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		router.GET(fmt.Sprintf("/route-%d/:param", i), func(c *Context) {})
+	}
+	atomic.StoreInt32(&stop, 1)
+	<-done
+
+	w := performRequest(router, http.MethodGet, "/route-199/value")
+	assert.Equal(t, http.StatusOK, w.Code)
 }
 
 func TestAddRouteFails(t *testing.T) {
@@ -544,3 +722,69 @@ func assertRoutePresent(t *testing.T, gotRoutes RoutesInfo, wantRoute RouteInfo)
 
 func handlerTest1(c *Context) {}
 func handlerTest2(c *Context) {}
+
+func TestOptimizeFrozenRoutePriorities(t *testing.T) {
+	router := New()
+	router.FreezeRoutePriorities = true
+	router.RedirectFixedPath = true
+	router.GET("/a", func(c *Context) { c.String(http.StatusOK, "a") })
+	router.GET("/b/:id", func(c *Context) { c.String(http.StatusOK, c.Param("id")) })
+	router.GET("/b/:id/x", func(c *Context) {})
+	router.POST("/a", func(c *Context) {})
+
+	before := router.routeTrees()
+	assert.Equal(t, "ab", before.index.get(http.MethodGet).indices)
+
+	router.Optimize()
+	assert.Equal(t, "ab", before.index.get(http.MethodGet).indices)
+	assert.Equal(t, "ba", router.routeTrees().index.get(http.MethodGet).indices)
+	assert.Len(t, router.routeTrees().trees, 2)
+
+	w := performRequest(router, http.MethodGet, "/b/42")
+	assert.Equal(t, "42", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/A")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/a", w.Header().Get("Location"))
+}
+
+func TestOptimizeStaticRoutes(t *testing.T) {
+	router := New()
+	router.GET("/users", func(c *Context) { c.String(http.StatusOK, "users") })
+	router.GET("/users/:id", func(c *Context) { c.String(http.StatusOK, c.Param("id")) })
+	router.POST("/users", func(c *Context) { c.String(http.StatusCreated, "created") })
+	router.Optimize()
+
+	trees := router.routeTrees()
+	assert.Len(t, trees.static, 2)
+	assert.Len(t, trees.static[http.MethodGet], 1)
+	assert.Equal(t, "/users", trees.static[http.MethodGet]["/users"].fullPath)
+	assert.NotNil(t, trees.static[http.MethodPost]["/users"])
+
+	w := performRequest(router, http.MethodGet, "/users")
+	assert.Equal(t, "users", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/users/42")
+	assert.Equal(t, "42", w.Body.String())
+	w = performRequest(router, http.MethodPost, "/users")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// the static routes of the method are collected again once one is added or removed
+	router.GET("/teams", func(c *Context) { c.String(http.StatusOK, "teams") })
+	router.GET("/teams/:id", func(c *Context) {})
+	router.PUT("/teams", func(c *Context) { c.String(http.StatusOK, "replaced") })
+	trees = router.routeTrees()
+	assert.Len(t, trees.static[http.MethodGet], 2)
+	assert.NotNil(t, trees.static[http.MethodGet]["/teams"])
+	assert.NotNil(t, trees.static[http.MethodPost]["/users"])
+	assert.NotNil(t, trees.static[http.MethodPut]["/teams"])
+	w = performRequest(router, http.MethodGet, "/teams")
+	assert.Equal(t, "teams", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/users")
+	assert.Equal(t, "users", w.Body.String())
+	w = performRequest(router, http.MethodPut, "/teams")
+	assert.Equal(t, "replaced", w.Body.String())
+
+	assert.True(t, router.RemoveRoute(http.MethodGet, "/users"))
+	assert.Nil(t, router.routeTrees().static[http.MethodGet]["/users"])
+	w = performRequest(router, http.MethodGet, "/users")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// UpdateGoldenFiles makes AssertGolden write the golden files instead of comparing the
+// responses to them. It's set by the GIN_UPDATE_GOLDEN environment variable, or bound to
+// a flag of the tests:
+//     func init() {
+//         flag.BoolVar(&gin.UpdateGoldenFiles, "update", false, "update the golden files")
+//     }
+var UpdateGoldenFiles = os.Getenv("GIN_UPDATE_GOLDEN") != ""
+
+// GoldenIgnored replaces the values of the ignored JSON paths in the golden files.
+const GoldenIgnored = "<ignored>"
+
+// GoldenConfig defines the config of TestResponse.AssertGoldenWithConfig.
+type GoldenConfig struct {
+	// Headers are the response headers written in the golden file.
+	// Optional.
+	Headers []string
+
+	// IgnoreJSONPaths are the paths of the JSON body whose values change from run to
+	// run, e.g. "id" or "users.*.created_at", they are replaced by GoldenIgnored. The
+	// paths are the ones of JSONPath, where "*" matches every key or index.
+	// Optional.
+	IgnoreJSONPaths []string
+}
+
+// AssertGolden asserts the status, the headers and the body of the response against
+// the golden file, see AssertGoldenWithConfig.
+func (w *TestResponse) AssertGolden(file string, headers ...string) *TestResponse {
+	w.t.Helper()
+	return w.AssertGoldenWithConfig(file, GoldenConfig{Headers: headers})
+}
+
+// AssertGoldenWithConfig asserts the status, the selected headers and the body of the
+// response against the golden file, e.g. for contract tests of the rendered output:
+//     client.GET("/users/42").Do(t).AssertGoldenWithConfig("testdata/user.golden", gin.GoldenConfig{
+//         Headers:         []string{"Content-Type"},
+//         IgnoreJSONPaths: []string{"updated_at"},
+//     })
+// The JSON bodies are indented with their keys sorted, and the trailing spaces of the
+// lines of the other text bodies are trimmed, so that the golden files are stable and
+// readable. The golden files are written when UpdateGoldenFiles is set.
+func (w *TestResponse) AssertGoldenWithConfig(file string, conf GoldenConfig) *TestResponse {
+	w.t.Helper()
+	got, err := w.snapshot(conf)
+	if err != nil {
+		w.t.Errorf("%s: %v", w.request, err)
+		return w
+	}
+	if UpdateGoldenFiles {
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err == nil {
+			err = ioutil.WriteFile(file, got, 0644)
+		}
+		if err != nil {
+			w.t.Errorf("%s: cannot update golden file: %v", w.request, err)
+		}
+		return w
+	}
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		w.t.Errorf("%s: cannot read golden file, set GIN_UPDATE_GOLDEN=1 to write it: %v", w.request, err)
+		return w
+	}
+	want = bytes.Replace(want, []byte("\r\n"), []byte("\n"), -1)
+	if !bytes.Equal(want, got) {
+		w.t.Errorf("%s: response differs from %s at line %d\n--- expected\n%s\n--- got\n%s",
+			w.request, file, firstDifferentLine(want, got), want, got)
+	}
+	return w
+}
+
+// snapshot returns the golden file content of the response.
+func (w *TestResponse) snapshot(conf GoldenConfig) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "HTTP %d %s\n", w.Code, http.StatusText(w.Code))
+	for _, name := range conf.Headers {
+		for _, value := range w.Header()[http.CanonicalHeaderKey(name)] {
+			fmt.Fprintf(buf, "%s: %s\n", http.CanonicalHeaderKey(name), value)
+		}
+	}
+	if w.Body.Len() == 0 {
+		return buf.Bytes(), nil
+	}
+	buf.WriteByte('\n')
+
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mediaType == MIMEJSON || strings.HasSuffix(mediaType, "+json") {
+		body, err := normalizeJSON(w.Body.Bytes(), conf.IgnoreJSONPaths)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+	text := strings.Replace(w.Body.String(), "\r\n", "\n", -1)
+	for _, line := range strings.Split(strings.TrimRight(text, " \t\n"), "\n") {
+		buf.WriteString(strings.TrimRight(line, " \t"))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func normalizeJSON(body []byte, ignore []string) ([]byte, error) {
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	for _, path := range ignore {
+		value = ignoreJSONPath(value, strings.Split(path, "."))
+	}
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ignoreJSONPath returns the value with the values at the path replaced by GoldenIgnored.
+func ignoreJSONPath(value interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return GoldenIgnored
+	}
+	segment, rest := path[0], path[1:]
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if segment == "*" || segment == key {
+				v[key] = ignoreJSONPath(field, rest)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if segment == "*" || segment == strconv.Itoa(i) {
+				v[i] = ignoreJSONPath(item, rest)
+			}
+		}
+	}
+	return value
+}
+
+func firstDifferentLine(a, b []byte) int {
+	linesA, linesB := bytes.Split(a, []byte("\n")), bytes.Split(b, []byte("\n"))
+	for i := 0; i < len(linesA) && i < len(linesB); i++ {
+		if !bytes.Equal(linesA[i], linesB[i]) {
+			return i + 1
+		}
+	}
+	if len(linesA) < len(linesB) {
+		return len(linesA) + 1
+	}
+	return len(linesB) + 1
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func goldenRouter() *Engine {
+	router := New()
+	router.GET("/users/:id", func(c *Context) {
+		c.Header("X-Request-ID", "changes-every-time")
+		c.JSON(http.StatusOK, H{
+			"name":       "bob <admin>",
+			"id":         c.Param("id"),
+			"updated_at": time.Now(),
+			"roles":      []H{{"name": "admin", "granted_at": time.Now()}, {"name": "dev", "granted_at": time.Now()}},
+			"score":      12.50,
+		})
+	})
+	router.GET("/page", func(c *Context) {
+		c.Data(http.StatusOK, MIMEHTML+"; charset=utf-8", []byte("<html>  \r\n<body>hello</body>\t\n</html>\n\n"))
+	})
+	router.DELETE("/users/:id", func(c *Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+func TestAssertGolden(t *testing.T) {
+	client := NewTestClient(goldenRouter())
+	client.GET("/users/42").Do(t).AssertGoldenWithConfig("testdata/golden/user.golden", GoldenConfig{
+		Headers:         []string{"content-type"},
+		IgnoreJSONPaths: []string{"updated_at", "roles.*.granted_at"},
+	})
+	client.GET("/page").Do(t).AssertGolden("testdata/golden/page.golden", "Content-Type")
+	client.DELETE("/users/42").Do(t).AssertGolden("testdata/golden/deleted.golden")
+}
+
+func TestAssertGoldenMismatch(t *testing.T) {
+	client := NewTestClient(goldenRouter())
+
+	rt := &recordingT{}
+	client.GET("/users/42").Do(rt).AssertGolden("testdata/golden/user.golden", "Content-Type")
+	require.Len(t, rt.errors, 1)
+	assert.Contains(t, rt.errors[0], "GET /users/42: response differs from testdata/golden/user.golden at line 9")
+
+	rt = &recordingT{}
+	client.GET("/page").Do(rt).AssertGolden("testdata/golden/missing.golden")
+	require.Len(t, rt.errors, 1)
+	assert.Contains(t, rt.errors[0], "cannot read golden file, set GIN_UPDATE_GOLDEN=1 to write it")
+}
+
+func TestUpdateGoldenFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golden")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "nested", "user.golden")
+
+	defer func(update bool) { UpdateGoldenFiles = update }(UpdateGoldenFiles)
+	UpdateGoldenFiles = true
+	client := NewTestClient(goldenRouter())
+	client.GET("/users/7").Do(t).AssertGoldenWithConfig(file, GoldenConfig{IgnoreJSONPaths: []string{"updated_at", "roles"}})
+
+	content, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, `HTTP 200 OK
+
+{
+  "id": "7",
+  "name": "bob <admin>",
+  "roles": "<ignored>",
+  "score": 12.5,
+  "updated_at": "<ignored>"
+}
+`, string(content))
+
+	UpdateGoldenFiles = false
+	client.GET("/users/7").Do(t).AssertGoldenWithConfig(file, GoldenConfig{IgnoreJSONPaths: []string{"updated_at", "roles"}})
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// handlerNames are the namedHandlers, by handlerKey.
+var handlerNames sync.Map
+
+// namedHandler keeps the handler along with its name, so that its function value isn't
+// collected and its key can't be reused by another handler.
+type namedHandler struct {
+	name    string
+	handler HandlerFunc
+}
+
+// handlerKey identifies a handler by its function value rather than by its code, so
+// that the closures of the same function, e.g. two BasicAuth middleware, are told apart.
+func handlerKey(handler HandlerFunc) uintptr {
+	return uintptr(*(*unsafe.Pointer)(unsafe.Pointer(&handler)))
+}
+
+// NameHandler registers a human-readable name for the handler, used instead of its
+// function name by Routes, the debug output, HandlerName, the tracing spans and the
+// panic reports, since the function names of the closures, e.g.
+// "github.com/gin-gonic/gin.BasicAuthForRealm.func1", say little about them:
+//     router.GET("/admin", gin.NameHandler("admin-auth", gin.BasicAuth(accounts)), admin)
+// It returns the handler. The closures which capture no variable share the name, as
+// they are a single function value. The middleware added with UseNamed are named after their
+// name unless they already have one. The named handlers are kept for the life of the
+// process, so handlers should be named once, when they are created.
+func NameHandler(name string, handler HandlerFunc) HandlerFunc {
+	assert1(name != "", "handler name can not be empty")
+	assert1(handler != nil, "handler can not be nil")
+	handlerNames.Store(handlerKey(handler), namedHandler{name: name, handler: handler})
+	return handler
+}
+
+// HandlerFuncName returns the name registered for the handler with NameHandler, or else
+// its function name.
+func HandlerFuncName(handler HandlerFunc) string {
+	if handler == nil {
+		return ""
+	}
+	if named, ok := handlerNames.Load(handlerKey(handler)); ok {
+		return named.(namedHandler).name
+	}
+	return funcName(handler)
+}
+
+// defaultHandlerName names the handler unless it already has a name.
+func defaultHandlerName(name string, handler HandlerFunc) {
+	handlerNames.LoadOrStore(handlerKey(handler), namedHandler{name: name, handler: handler})
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func namedTestMiddleware(header string) HandlerFunc {
+	return func(c *Context) {
+		c.Header(header, "1")
+		c.Next()
+	}
+}
+
+func TestNameHandler(t *testing.T) {
+	first, second := namedTestMiddleware("X-First"), namedTestMiddleware("X-Second")
+	assert.Equal(t, "github.com/gin-gonic/gin.namedTestMiddleware.func1", HandlerFuncName(first))
+
+	assert.Equal(t, handlerKey(first), handlerKey(NameHandler("first", first)))
+	assert.Equal(t, "first", HandlerFuncName(first))
+	assert.Equal(t, "github.com/gin-gonic/gin.namedTestMiddleware.func1", HandlerFuncName(second))
+	assert.Equal(t, "", HandlerFuncName(nil))
+
+	assert.Panics(t, func() { NameHandler("", second) })
+	assert.Panics(t, func() { NameHandler("second", nil) })
+}
+
+func TestHandlerNamesInRoutes(t *testing.T) {
+	router := New()
+	router.UseNamed("named-by-use", namedTestMiddleware("X-Use"))
+	auth := NameHandler("auth", namedTestMiddleware("X-Auth"))
+	router.UseNamed("auth-middleware", auth)
+	router.GET("/users", NameHandler("list-users", func(c *Context) {
+		assert.Equal(t, "list-users", c.HandlerName())
+		assert.Equal(t, []string{"named-by-use", "auth", "list-users"}, c.HandlerNames())
+	}))
+
+	assert.Equal(t, []string{"named-by-use", "auth-middleware"}, router.MiddlewareNames())
+	assert.Equal(t, "list-users", router.Routes()[0].Handler)
+	table := router.RouteTable()
+	assert.Equal(t, []string{"named-by-use", "auth"}, table[0].Middleware)
+
+	w := performRequest(router, http.MethodGet, "/users")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	re := captureOutput(t, func() {
+		router.SetMode(DebugMode)
+		router.GET("/reports", NameHandler("list-reports", func(c *Context) {}))
+	})
+	assert.Contains(t, re, "--> list-reports (3 handlers)")
+}
+
+func TestHandlerNameInPanicReport(t *testing.T) {
+	var report PanicReport
+	router := New()
+	router.Use(RecoveryWithConfig(RecoveryConfig{
+		Reporters: []PanicReporter{PanicReporterFunc(func(_ context.Context, r PanicReport) { report = r })},
+	}))
+	router.GET("/panic", NameHandler("panicking", func(c *Context) { panic("oops") }))
+
+	performRequest(router, http.MethodGet, "/panic")
+	assert.Equal(t, "panicking", report.Handler)
+}
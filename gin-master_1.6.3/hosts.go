@@ -0,0 +1,145 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"sort"
+	"strings"
+)
+
+// Host returns a copy of the group whose routes are only served for the requests made
+// to the hosts matching the pattern, instead of switching between several engines:
+//     api := router.Host("api.example.com")
+//     api.GET("/users/:id", getUser)
+//     admin := router.Host("admin.example.com")
+//     admin.GET("/users/:id", editUser)
+//     tenants := router.Host(":tenant.example.com")
+//     tenants.GET("/", func(c *gin.Context) {
+//         c.String(http.StatusOK, "welcome %s", c.Param("tenant"))
+//     })
+// The routes of each host have their own trees, so that the hosts can register the same
+// or conflicting paths, and the trailing slash redirects and the 405 responses only
+// depend on the routes of the host. A label of the pattern starting with ':' is a param
+// matching one label of the host, whose value is added to the params of the route.
+// The host of a request is matched without its port and case-insensitively, against the
+// patterns with fewer params first, then in the order they were added. The requests to
+// the other hosts are served by the routes without host, to which RemoveRoute and the
+// Matcher only apply. The groups created from it inherit the host.
+func (group *RouterGroup) Host(pattern string) *RouterGroup {
+	labels := hostLabels(pattern)
+	for _, label := range labels {
+		assert1(label != "" && label != ":", "invalid host pattern '"+pattern+"'")
+	}
+	g := *group
+	g.root = false
+	g.host = strings.Join(labels, ".")
+	g.lastPath, g.lastMethods = "", nil
+	return &g
+}
+
+// hostRoutes are the routes of a host pattern, split into its labels.
+type hostRoutes struct {
+	labels []string
+	params int
+	trees  *routeTrees
+}
+
+// hostLabels returns the labels of a host pattern, in lower case except for the names of
+// its params, which are kept as written.
+func hostLabels(pattern string) []string {
+	labels := strings.Split(strings.TrimSuffix(pattern, "."), ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(label, ":") {
+			labels[i] = strings.ToLower(label)
+		}
+	}
+	return labels
+}
+
+// match reports whether the host matches the pattern, and appends the values of its
+// params.
+func (h *hostRoutes) match(host string, params Params) (Params, bool) {
+	for i, label := range h.labels {
+		value := host
+		if i < len(h.labels)-1 {
+			end := strings.IndexByte(host, '.')
+			if end < 0 {
+				return nil, false
+			}
+			value, host = host[:end], host[end+1:]
+		} else if strings.IndexByte(host, '.') >= 0 {
+			return nil, false
+		}
+		if label[0] == ':' {
+			if value == "" {
+				return nil, false
+			}
+			params = append(params, Param{Key: label[1:], Value: value})
+		} else if label != value {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// matchHost returns the snapshot of the routes of the host of a request, with the values
+// of the params of its pattern, or nil when no pattern matches the host.
+func (rt *routeTrees) matchHost(host string) (*routeTrees, Params) {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && strings.IndexByte(host[i:], ']') < 0 {
+		host = host[:i]
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, h := range rt.hosts {
+		if params, ok := h.match(host, nil); ok {
+			return h.trees, params
+		}
+	}
+	return nil, nil
+}
+
+// hostTrees returns the snapshot of the routes of the host pattern, which is empty
+// until a route is added to it.
+func (rt *routeTrees) hostTrees(pattern string) *routeTrees {
+	for _, h := range rt.hosts {
+		if h.trees.host == pattern {
+			return h.trees
+		}
+	}
+	return &routeTrees{host: pattern}
+}
+
+// withHost returns a copy of the snapshot where trees are the routes of their host.
+func (rt *routeTrees) withHost(trees *routeTrees) *routeTrees {
+	next := *rt
+	next.hosts = make([]*hostRoutes, 0, len(rt.hosts)+1)
+	replaced := false
+	for _, h := range rt.hosts {
+		if h.trees.host == trees.host {
+			h, replaced = &hostRoutes{labels: h.labels, params: h.params, trees: trees}, true
+		}
+		next.hosts = append(next.hosts, h)
+	}
+	if !replaced {
+		h := &hostRoutes{labels: strings.Split(trees.host, "."), trees: trees}
+		for _, label := range h.labels {
+			if label[0] == ':' {
+				h.params++
+			}
+		}
+		i := sort.Search(len(next.hosts), func(i int) bool { return next.hosts[i].params > h.params })
+		next.hosts = append(next.hosts, nil)
+		copy(next.hosts[i+1:], next.hosts[i:])
+		next.hosts[i] = h
+	}
+	return &next
+}
+
+// routeTrees returns the snapshot of the routes the request was looked up in.
+func (c *Context) routeTrees() *routeTrees {
+	if c.hostTrees != nil {
+		return c.hostTrees
+	}
+	return c.engine.routeTrees()
+}
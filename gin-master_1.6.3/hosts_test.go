@@ -0,0 +1,130 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func performHostRequest(r http.Handler, method, host, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = host
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouterGroupHost(t *testing.T) {
+	router := New()
+	router.HandleMethodNotAllowed = true
+	serve := func(body string) HandlerFunc {
+		return func(c *Context) { c.String(http.StatusOK, body+c.Param("tenant")+c.Param("id")) }
+	}
+	router.GET("/users/:id", serve("default "))
+	router.Host("api.example.com").GET("/users/:id", serve("api "))
+	admin := router.Host("Admin.Example.com.").Group("/admin")
+	admin.GET("/users/new", serve("admin new"))
+	admin.GET("/accounts/:name/edit", serve("admin edit"))
+	router.Host(":tenant.example.com").GET("/users/:id", serve("tenant "))
+	router.Host(":tenant.example.com").POST("/users/", serve("tenant post"))
+
+	w := performHostRequest(router, http.MethodGet, "example.org", "/users/1")
+	assert.Equal(t, "default 1", w.Body.String())
+	w = performHostRequest(router, http.MethodGet, "api.example.com:8080", "/users/1")
+	assert.Equal(t, "api 1", w.Body.String())
+	w = performHostRequest(router, http.MethodGet, "admin.example.com", "/admin/users/new")
+	assert.Equal(t, "admin new", w.Body.String())
+	w = performHostRequest(router, http.MethodGet, "ADMIN.example.com", "/admin/accounts/bob/edit")
+	assert.Equal(t, "admin edit", w.Body.String())
+	w = performHostRequest(router, http.MethodGet, "acme.example.com", "/users/2")
+	assert.Equal(t, "tenant acme2", w.Body.String())
+
+	// the hosts have their own 404, 405 and trailing slash redirects
+	w = performHostRequest(router, http.MethodGet, "admin.example.com", "/users/1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performHostRequest(router, http.MethodDelete, "api.example.com", "/users/1")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	w = performHostRequest(router, http.MethodPost, "acme.example.com", "/users")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "/users/", w.Header().Get("Location"))
+	w = performHostRequest(router, http.MethodPost, "example.org", "/users")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// a param matches one label
+	w = performHostRequest(router, http.MethodGet, "a.b.example.com", "/users/3")
+	assert.Equal(t, "default 3", w.Body.String())
+
+	routes := router.Routes()
+	assert.Len(t, routes, 6)
+	hosts := map[string]int{}
+	for _, route := range routes {
+		hosts[route.Host]++
+	}
+	assert.Equal(t, map[string]int{"": 1, "api.example.com": 1, "admin.example.com": 2, ":tenant.example.com": 2}, hosts)
+
+	match, ok := router.Match(http.MethodGet, "http://acme.example.com/users/4")
+	if assert.True(t, ok) {
+		assert.Equal(t, ":tenant.example.com", match.Host)
+		assert.Equal(t, Params{{Key: "id", Value: "4"}, {Key: "tenant", Value: "acme"}}, match.Params)
+	}
+	match, ok = router.Match(http.MethodGet, "/users/4")
+	if assert.True(t, ok) {
+		assert.Empty(t, match.Host)
+	}
+
+	router.Optimize()
+	w = performHostRequest(router, http.MethodGet, "admin.example.com", "/admin/users/new")
+	assert.Equal(t, "admin new", w.Body.String())
+
+	assert.Panics(t, func() { router.Host("") })
+	assert.Panics(t, func() { router.Host("a..com") })
+	assert.Panics(t, func() { router.Host(":.example.com") })
+}
+
+func TestRouterGroupHostOrder(t *testing.T) {
+	router := New()
+	router.Host(":tenant.example.com").GET("/", func(c *Context) { c.String(http.StatusOK, "tenant") })
+	router.Host("www.example.com").GET("/", func(c *Context) { c.String(http.StatusOK, "www") })
+
+	w := performHostRequest(router, http.MethodGet, "www.example.com", "/")
+	assert.Equal(t, "www", w.Body.String())
+	w = performHostRequest(router, http.MethodGet, "shop.example.com", "/")
+	assert.Equal(t, "tenant", w.Body.String())
+}
+
+func TestRouterGroupHostMetaAndNames(t *testing.T) {
+	router := New()
+	var name string
+	var meta interface{}
+	handler := func(c *Context) {
+		name = c.RouteName()
+		meta, _ = c.RouteMeta("scope")
+	}
+	router.GET("/users/:id", handler).Name("user")
+	router.Host("api.example.com").WithMeta("scope", "api").GET("/users/:id", handler).Name("api-user")
+
+	performHostRequest(router, http.MethodGet, "api.example.com", "/users/1")
+	assert.Equal(t, "api-user", name)
+	assert.Equal(t, "api", meta)
+	performHostRequest(router, http.MethodGet, "example.com", "/users/1")
+	assert.Equal(t, "user", name)
+	assert.Nil(t, meta)
+
+	url, err := router.URLFor("api-user", H{"id": 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/2", url)
+	url, err = router.URLFor("user", H{"id": 3})
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/3", url)
+
+	// a name belongs to one host
+	assert.Panics(t, func() { router.Host("admin.example.com").GET("/users/:id", handler).Name("api-user") })
+	assert.Panics(t, func() { router.Host("admin.example.com").GET("/accounts/:id", handler).Name("user") })
+	assert.Panics(t, func() { router.Named("api-user").GET("/api/users/:id", handler) })
+}
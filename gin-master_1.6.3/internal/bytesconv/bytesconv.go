@@ -1,7 +1,10 @@
-// Copyright 2020 Gin Core Team. All rights reserved.
+// Copyright 2021 Gin Core Team.  All rights reserved.
 // Use of this source code is governed by a MIT style
 // license that can be found in the LICENSE file.
 
+//go:build go1.20
+// +build go1.20
+
 package bytesconv
 
 import (
@@ -9,16 +12,13 @@ import (
 )
 
 // StringToBytes converts string to byte slice without a memory allocation.
+// The bytes must not be modified.
 func StringToBytes(s string) []byte {
-	return *(*[]byte)(unsafe.Pointer(
-		&struct {
-			string
-			Cap int
-		}{s, len(s)},
-	))
+	return unsafe.Slice(unsafe.StringData(s), len(s))
 }
 
 // BytesToString converts byte slice to string without a memory allocation.
+// The bytes must not be modified while the string is in use.
 func BytesToString(b []byte) string {
-	return *(*string)(unsafe.Pointer(&b))
+	return unsafe.String(unsafe.SliceData(b), len(b))
 }
@@ -72,6 +72,61 @@ func TestStringToBytes(t *testing.T) {
 	}
 }
 
+// The conversions must hold under the pointer checks of the runtime, run:
+//     go test -race -gcflags=all=-d=checkptr ./internal/bytesconv
+
+func TestConversionsEdgeCases(t *testing.T) {
+	if BytesToString(nil) != "" {
+		t.Fatal("nil bytes don't convert to the empty string")
+	}
+	if s := BytesToString([]byte{}); s != "" {
+		t.Fatalf("empty bytes convert to %q", s)
+	}
+	if b := StringToBytes(""); len(b) != 0 || cap(b) != 0 {
+		t.Fatalf("empty string converts to len %d cap %d", len(b), cap(b))
+	}
+
+	for n := 0; n <= 1024; n++ {
+		s := RandStringBytesMaskImprSrcSB(n)
+		b := StringToBytes(s)
+		if len(b) != n || cap(b) != n || string(b) != s {
+			t.Fatalf("string of %d bytes converts to len %d cap %d", n, len(b), cap(b))
+		}
+		if BytesToString(b) != s {
+			t.Fatalf("round trip of %d bytes doesn't match", n)
+		}
+	}
+}
+
+func TestConversionsShareMemory(t *testing.T) {
+	data := []byte("hello, gopher")
+	for i := 0; i <= len(data); i++ {
+		for j := i; j <= len(data); j++ {
+			if s := BytesToString(data[i:j]); s != string(data[i:j]) {
+				t.Fatalf("data[%d:%d] converts to %q", i, j, s)
+			}
+		}
+	}
+
+	s := BytesToString(data[7:])
+	data[7] = 'G'
+	if s != "Gopher" {
+		t.Fatalf("the string doesn't share the bytes: %q", s)
+	}
+	if b := StringToBytes(s); &b[0] != &data[7] {
+		t.Fatal("the bytes don't share the string")
+	}
+}
+
+func TestConversionsZeroAllocs(t *testing.T) {
+	if allocs := testing.AllocsPerRun(100, func() { BytesToString(testBytes) }); allocs != 0 {
+		t.Fatalf("BytesToString allocates %v times", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { StringToBytes(testString) }); allocs != 0 {
+		t.Fatalf("StringToBytes allocates %v times", allocs)
+	}
+}
+
 // go test -v -run=none -bench=^BenchmarkBytesConv -benchmem=true
 
 func BenchmarkBytesConvBytesToStrRaw(b *testing.B) {
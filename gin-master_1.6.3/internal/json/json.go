@@ -20,3 +20,12 @@ var (
 	// NewEncoder is exported by gin/json package.
 	NewEncoder = json.NewEncoder
 )
+
+// RawMessage is exported by gin/json package.
+type RawMessage = json.RawMessage
+
+// Marshaler is exported by gin/json package.
+type Marshaler = json.Marshaler
+
+// Decoder is exported by gin/json package.
+type Decoder = json.Decoder
@@ -6,7 +6,11 @@
 
 package json
 
-import jsoniter "github.com/json-iterator/go"
+import (
+	stdjson "encoding/json"
+
+	jsoniter "github.com/json-iterator/go"
+)
 
 var (
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -21,3 +25,12 @@ var (
 	// NewEncoder is exported by gin/json package.
 	NewEncoder = json.NewEncoder
 )
+
+// RawMessage is exported by gin/json package.
+type RawMessage = jsoniter.RawMessage
+
+// Marshaler is exported by gin/json package, jsoniter honors the standard one.
+type Marshaler = stdjson.Marshaler
+
+// Decoder is exported by gin/json package.
+type Decoder = jsoniter.Decoder
@@ -0,0 +1,222 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// CIDRSet is a set of IPv4 and IPv6 networks, stored in binary tries so that looking up
+// an address takes at most one step per bit of its longest matching prefix.
+type CIDRSet struct {
+	v4   cidrNode
+	v6   cidrNode
+	size int
+}
+
+type cidrNode struct {
+	children [2]*cidrNode
+	terminal bool
+}
+
+// NewCIDRSet returns the set of the networks given in CIDR notation, such as
+// "10.0.0.0/8" or "2001:db8::/32". Single addresses are accepted too.
+func NewCIDRSet(cidrs ...string) (*CIDRSet, error) {
+	s := &CIDRSet{}
+	for _, cidr := range cidrs {
+		if err := s.Add(cidr); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add adds the network to the set, it must not be called while the set is in use.
+func (s *CIDRSet) Add(cidr string) error {
+	cidr = strings.TrimSpace(cidr)
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return fmt.Errorf("invalid IP address %q", cidr)
+		}
+		if ip.To4() != nil {
+			cidr += "/32"
+		} else {
+			cidr += "/128"
+		}
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	ones, bits := network.Mask.Size()
+	node := s.root(network.IP)
+	ip := normalizeIP(network.IP)
+	if bits == 8*net.IPv6len && len(ip) == net.IPv4len {
+		// an IPv4-mapped IPv6 network, such as ::ffff:10.0.0.0/104
+		ones -= 8 * (net.IPv6len - net.IPv4len)
+	}
+	for i := 0; i < ones; i++ {
+		if node.terminal {
+			// a larger network of the set already contains it
+			return nil
+		}
+		bit := ip[i/8] >> (7 - uint(i%8)) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &cidrNode{}
+		}
+		node = node.children[bit]
+	}
+	if !node.terminal {
+		node.terminal = true
+		node.children = [2]*cidrNode{}
+		s.size++
+	}
+	return nil
+}
+
+// Contains reports whether the address belongs to a network of the set.
+func (s *CIDRSet) Contains(ip net.IP) bool {
+	if s == nil {
+		return false
+	}
+	node := s.root(ip)
+	ip = normalizeIP(ip)
+	if ip == nil {
+		return false
+	}
+	for i := 0; node != nil; i++ {
+		if node.terminal {
+			return true
+		}
+		if i == len(ip)*8 {
+			return false
+		}
+		node = node.children[ip[i/8]>>(7-uint(i%8))&1]
+	}
+	return false
+}
+
+// Len returns the number of networks of the set, not counting the ones contained by others.
+func (s *CIDRSet) Len() int {
+	return s.size
+}
+
+func (s *CIDRSet) root(ip net.IP) *cidrNode {
+	if ip.To4() != nil {
+		return &s.v4
+	}
+	return &s.v6
+}
+
+// normalizeIP returns the 4 bytes form of IPv4 addresses, including IPv4-mapped IPv6
+// addresses, so that they match the IPv4 networks.
+func normalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	if len(ip) != net.IPv6len {
+		return nil
+	}
+	return ip
+}
+
+// IPFilterConfig defines the config of IPFilter.
+type IPFilterConfig struct {
+	// Allow are the networks allowed in CIDR notation, the others are denied. When empty,
+	// every address not denied is allowed.
+	// Optional.
+	Allow []string
+
+	// Deny are the networks denied in CIDR notation, it takes precedence over Allow.
+	// Optional.
+	Deny []string
+
+	// ClientIP returns the address of the client.
+	// Optional. Default value is Context.ClientIP.
+	ClientIP func(c *Context) string
+
+	// Forbidden handles the requests denied.
+	// Optional. Default aborts with 403.
+	Forbidden HandlerFunc
+}
+
+// IPFilter allows or denies the requests by the address of the client.
+type IPFilter struct {
+	conf  IPFilterConfig
+	lists atomic.Value // *ipFilterLists
+}
+
+type ipFilterLists struct {
+	allow *CIDRSet
+	deny  *CIDRSet
+}
+
+// NewIPFilter returns an IPFilter with the lists of the config, or the error of an
+// invalid network.
+func NewIPFilter(conf IPFilterConfig) (*IPFilter, error) {
+	if conf.ClientIP == nil {
+		conf.ClientIP = (*Context).ClientIP
+	}
+	f := &IPFilter{conf: conf}
+	if err := f.Reload(conf.Allow, conf.Deny); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload replaces the lists, it's safe to call while serving, e.g. when the partners
+// change. The lists are kept when one of the networks is invalid.
+func (f *IPFilter) Reload(allow, deny []string) error {
+	lists := &ipFilterLists{}
+	var err error
+	if len(allow) > 0 {
+		if lists.allow, err = NewCIDRSet(allow...); err != nil {
+			return err
+		}
+	}
+	if lists.deny, err = NewCIDRSet(deny...); err != nil {
+		return err
+	}
+	f.lists.Store(lists)
+	return nil
+}
+
+// Allowed reports whether the address is allowed, invalid addresses are not.
+func (f *IPFilter) Allowed(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	lists := f.lists.Load().(*ipFilterLists)
+	if lists.deny.Contains(addr) {
+		return false
+	}
+	return lists.allow == nil || lists.allow.Contains(addr)
+}
+
+// Middleware returns the middleware rejecting the requests of the addresses denied:
+//     partners, err := gin.NewIPFilter(gin.IPFilterConfig{Allow: []string{"203.0.113.0/24", "2001:db8::/32"}})
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//     router.Group("/partners", partners.Middleware())
+func (f *IPFilter) Middleware() HandlerFunc {
+	return func(c *Context) {
+		if f.Allowed(f.conf.ClientIP(c)) {
+			return
+		}
+		if f.conf.Forbidden != nil {
+			f.conf.Forbidden(c)
+			c.Abort()
+			return
+		}
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
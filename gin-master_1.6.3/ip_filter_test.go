@@ -0,0 +1,98 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIDRSet(t *testing.T) {
+	set, err := NewCIDRSet("10.0.0.0/8", "192.168.1.0/24", "10.1.0.0/16", "203.0.113.7", "2001:db8::/32", "::ffff:172.16.0.0/108")
+	require.NoError(t, err)
+	// 10.1.0.0/16 is contained by 10.0.0.0/8
+	assert.Equal(t, 5, set.Len())
+
+	for ip, contained := range map[string]bool{
+		"10.2.3.4":           true,
+		"10.1.0.1":           true,
+		"11.0.0.1":           false,
+		"192.168.1.255":      true,
+		"192.168.2.1":        false,
+		"203.0.113.7":        true,
+		"203.0.113.8":        false,
+		"172.16.5.5":         true,
+		"172.32.0.1":         false,
+		"::ffff:10.0.0.1":    true,
+		"2001:db8:1::1":      true,
+		"2001:db9::1":        false,
+		"::1":                false,
+		"::ffff:192.168.1.1": true,
+	} {
+		assert.Equal(t, contained, set.Contains(net.ParseIP(ip)), ip)
+	}
+
+	_, err = NewCIDRSet("10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = NewCIDRSet("not an ip")
+	assert.Error(t, err)
+}
+
+func TestIPFilter(t *testing.T) {
+	filter, err := NewIPFilter(IPFilterConfig{
+		Allow: []string{"192.0.2.0/24", "2001:db8::/32"},
+		Deny:  []string{"192.0.2.66"},
+	})
+	require.NoError(t, err)
+
+	router := New()
+	router.Use(filter.Middleware())
+	router.GET("/", func(c *Context) {})
+
+	// the client of httptest requests is 192.0.2.1
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.True(t, filter.Allowed("2001:db8::1"))
+	assert.False(t, filter.Allowed("192.0.2.66"))
+	assert.False(t, filter.Allowed("198.51.100.1"))
+	assert.False(t, filter.Allowed("invalid"))
+
+	require.NoError(t, filter.Reload([]string{"198.51.100.0/24"}, nil))
+	w = performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.True(t, filter.Allowed("198.51.100.1"))
+
+	// the lists are kept when the new ones are invalid
+	assert.Error(t, filter.Reload([]string{"198.51.100.0/24", "bad"}, nil))
+	assert.True(t, filter.Allowed("198.51.100.1"))
+
+	// without allow list, every address not denied is allowed
+	require.NoError(t, filter.Reload(nil, []string{"192.0.2.0/24"}))
+	assert.True(t, filter.Allowed("198.51.100.1"))
+	assert.False(t, filter.Allowed("192.0.2.1"))
+}
+
+func TestIPFilterForbidden(t *testing.T) {
+	filter, err := NewIPFilter(IPFilterConfig{
+		Deny:      []string{"0.0.0.0/0"},
+		ClientIP:  func(c *Context) string { return c.GetHeader("X-Client") },
+		Forbidden: func(c *Context) { c.String(http.StatusForbidden, "partners only") },
+	})
+	require.NoError(t, err)
+	router := New()
+	router.Use(filter.Middleware())
+	router.GET("/", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	w := performRequest(router, "GET", "/", header{"X-Client", "10.0.0.1"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "partners only", w.Body.String())
+	w = performRequest(router, "GET", "/", header{"X-Client", "2001:db8::1"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// IsolationConfig defines the config for Isolate middleware.
+type IsolationConfig struct {
+	// Budget is how long the handlers may run before they're abandoned.
+	// Optional. By default, the handlers are never abandoned.
+	Budget time.Duration
+
+	// Status is the status code of the response sent when the handlers panic or are
+	// abandoned.
+	// Optional. Default value is 500 on panic, 503 on abandon.
+	Status int
+
+	// Reporters receive the panics of the handlers, in addition to the reporters of the
+	// engine, see Engine.AddPanicReporter.
+	// Optional.
+	Reporters []PanicReporter
+
+	// OnAbandon is called with the abandoned requests, whose stack shows where the
+	// handlers are stuck.
+	// Optional. The requests are written to gin.DefaultErrorWriter by default.
+	OnAbandon func(req *SlowRequest)
+}
+
+// Isolate returns a middleware running the next handlers in their own goroutine, on a
+// copy of the Context, with a recover boundary and a time budget:
+//     router.GET("/report", gin.Isolate(gin.IsolationConfig{Budget: 30 * time.Second}), buildReport)
+// A panic of the handlers is reported and answered with 500, and it doesn't reach the
+// middleware before Isolate. Once the budget is spent, the request context of the
+// handlers is canceled, the request is answered with 503 and the handlers are
+// abandoned: they keep running in their goroutine but can't write to the response
+// anymore, and the serving goroutine moves on.
+//
+// The response of the handlers is buffered until they return, as with the Timeout
+// middleware, so streaming and hijacking are not supported. Beware that the fatal
+// errors of the Go runtime, e.g. a stack overflow, can't be recovered and still stop
+// the process; debug.SetMaxStack bounds the stack of the goroutines.
+func Isolate(conf IsolationConfig) HandlerFunc {
+	onAbandon := conf.OnAbandon
+	if onAbandon == nil {
+		onAbandon = func(req *SlowRequest) {
+			fmt.Fprintf(DefaultErrorWriter, "[ISOLATION] %s %s (%s) abandoned after %v, request id %q\n%s\n",
+				req.Method, req.Path, req.Route, req.Elapsed, req.RequestID, req.Stack)
+		}
+	}
+
+	return func(c *Context) {
+		start := time.Now()
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+
+		original := c.Writer
+		tw := &timeoutWriter{
+			ResponseWriter: original,
+			header:         original.Header().Clone(),
+			size:           noWritten,
+			status:         defaultStatus,
+		}
+		cp := c.isolatedCopy()
+		cp.Writer = tw
+		cp.Request = cp.Request.WithContext(ctx)
+
+		var (
+			done       = make(chan struct{})
+			goroutine  = make(chan []byte, 1)
+			panicked   bool
+			panicValue interface{}
+		)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked, panicValue = true, p
+					if p != http.ErrAbortHandler {
+						reportPanic(cp, conf.Reporters, p, stack(3))
+					}
+				}
+				close(done)
+			}()
+			goroutine <- currentGoroutine()
+			cp.Next()
+		}()
+
+		var budget <-chan time.Time
+		if conf.Budget > 0 {
+			timer := time.NewTimer(conf.Budget)
+			defer timer.Stop()
+			budget = timer.C
+		}
+		select {
+		case <-done:
+			c.rejoin(cp)
+			switch {
+			case panicValue == http.ErrAbortHandler:
+				panic(panicValue)
+			case panicked:
+				status := conf.Status
+				if status == 0 {
+					status = http.StatusInternalServerError
+				}
+				c.AbortWithStatus(status)
+			default:
+				tw.commit()
+			}
+		case <-budget:
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			cancel()
+			status := conf.Status
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+			c.AbortWithStatus(status)
+			onAbandon(&SlowRequest{
+				Method:    c.Request.Method,
+				Path:      c.Request.URL.Path,
+				Route:     c.FullPath(),
+				RequestID: c.RequestID(),
+				Elapsed:   time.Since(start),
+				Stack:     goroutineStack(<-goroutine),
+			})
+		}
+	}
+}
+
+// isolatedCopy returns a copy of the Context which runs the next handlers, see Isolate.
+func (c *Context) isolatedCopy() *Context {
+	cp := c.Copy()
+	cp.handlers = c.handlers
+	cp.index = c.index
+	cp.fullPath = c.fullPath
+	cp.Errors = append(errorMsgs(nil), c.Errors...)
+	cp.Accepted = c.Accepted
+	cp.sameSite = c.sameSite
+	cp.noCompression = c.noCompression
+	cp.compressing = c.compressing
+	cp.conditional = c.conditional
+	cp.profile = c.profile
+	cp.bodyLimit = c.bodyLimit
+	return cp
+}
+
+// rejoin applies the state of the copy to the Context once the handlers returned.
+func (c *Context) rejoin(cp *Context) {
+	c.index = cp.index
+	c.Keys = cp.Keys
+	c.Errors = cp.Errors
+	c.Accepted = cp.Accepted
+	c.noCompression = cp.noCompression
+	c.sentNotModified = cp.sentNotModified
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsolateContainsPanics(t *testing.T) {
+	var reports []PanicReport
+	router := New()
+	router.Use(func(c *Context) {
+		c.Next()
+		c.Header("X-Outer", c.GetString("inner"))
+	})
+	isolate := Isolate(IsolationConfig{Reporters: []PanicReporter{
+		PanicReporterFunc(func(_ context.Context, report PanicReport) { reports = append(reports, report) }),
+	}})
+	router.GET("/ok", isolate, func(c *Context) {
+		c.Set("inner", "set")
+		c.String(http.StatusCreated, "ok")
+	})
+	router.GET("/panic", isolate, func(c *Context) {
+		c.Header("X-Partial", "1")
+		panic("runaway handler")
+	})
+	router.GET("/abort", isolate, func(c *Context) { panic(http.ErrAbortHandler) })
+
+	w := performRequest(router, "GET", "/ok")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, "set", w.Header().Get("X-Outer"))
+	assert.Empty(t, reports)
+
+	w = performRequest(router, "GET", "/panic")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("X-Partial"))
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, "runaway handler", reports[0].Value)
+		assert.Contains(t, string(reports[0].Stack), "isolation_test.go")
+		assert.Equal(t, "/panic", reports[0].Request.URL)
+	}
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		performRequest(router, "GET", "/abort")
+	})
+}
+
+func TestIsolateAbandonsHandlersOverBudget(t *testing.T) {
+	abandoned := make(chan *SlowRequest, 1)
+	release := make(chan struct{})
+	writeErr := make(chan error, 1)
+	router := New()
+	router.GET("/stuck/:id", Isolate(IsolationConfig{
+		Budget:    10 * time.Millisecond,
+		OnAbandon: func(req *SlowRequest) { abandoned <- req },
+	}), func(c *Context) {
+		<-c.Request.Context().Done()
+		<-release
+		_, err := c.Writer.WriteString("too late")
+		writeErr <- err
+	})
+
+	w := performRequest(router, "GET", "/stuck/1")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	req := <-abandoned
+	assert.Equal(t, "/stuck/1", req.Path)
+	assert.Equal(t, "/stuck/:id", req.Route)
+	assert.True(t, req.Elapsed >= 10*time.Millisecond)
+	assert.Contains(t, string(req.Stack), "isolation_test.go")
+
+	close(release)
+	assert.Equal(t, http.ErrHandlerTimeout, <-writeErr)
+}
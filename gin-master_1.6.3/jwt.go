@@ -0,0 +1,656 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // register the SHA-256 hash for the RS256, PS256 and ES256 algorithms
+	_ "crypto/sha512" // register the SHA-384 and SHA-512 hashes
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// JWTClaimsKey is the key the claims of the verified token are set under in the Context,
+// see Context.JWTClaims.
+const JWTClaimsKey = "jwtClaims"
+
+// The errors returned when a token is rejected.
+var (
+	ErrJWTMissing     = errors.New("gin: jwt: missing token")
+	ErrJWTMalformed   = errors.New("gin: jwt: malformed token")
+	ErrJWTAlgorithm   = errors.New("gin: jwt: algorithm not allowed")
+	ErrJWTUnknownKey  = errors.New("gin: jwt: unknown key")
+	ErrJWTSignature   = errors.New("gin: jwt: invalid signature")
+	ErrJWTExpired     = errors.New("gin: jwt: token is expired")
+	ErrJWTNotValidYet = errors.New("gin: jwt: token is not valid yet")
+	ErrJWTIssuer      = errors.New("gin: jwt: invalid issuer")
+	ErrJWTAudience    = errors.New("gin: jwt: invalid audience")
+)
+
+// JWTClaims are the claims of a verified token. Numbers are decoded as float64.
+type JWTClaims map[string]interface{}
+
+// Subject returns the "sub" claim.
+func (claims JWTClaims) Subject() string {
+	sub, _ := claims["sub"].(string)
+	return sub
+}
+
+// Issuer returns the "iss" claim.
+func (claims JWTClaims) Issuer() string {
+	iss, _ := claims["iss"].(string)
+	return iss
+}
+
+// Scopes returns the "scope" claim, a space-separated list, or else the "scp" claim,
+// which may be a string or a list of strings.
+func (claims JWTClaims) Scopes() []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	switch scp := claims["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []interface{}:
+		scopes := make([]string, 0, len(scp))
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
+
+// Audience returns the "aud" claim, which may be a string or a list of strings.
+func (claims JWTClaims) Audience() []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		auds := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+		return auds
+	}
+	return nil
+}
+
+// time returns the NumericDate claim named name, and whether it's present.
+func (claims JWTClaims) time(name string) (time.Time, bool, error) {
+	v, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false, ErrJWTMalformed
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true, nil
+}
+
+// JWTKeySet returns the key verifying the tokens signed by kid with the algorithm alg.
+// The keys are []byte for the HMAC algorithms, *rsa.PublicKey, *ecdsa.PublicKey or
+// ed25519.PublicKey.
+type JWTKeySet interface {
+	Key(ctx context.Context, kid, alg string) (interface{}, error)
+}
+
+// JWTKeys is a static JWTKeySet mapping the key IDs to the keys. The tokens without a
+// "kid" header are verified by the key of empty ID, or by the only key of the set.
+type JWTKeys map[string]interface{}
+
+// Key implements JWTKeySet.
+func (keys JWTKeys) Key(_ context.Context, kid, _ string) (interface{}, error) {
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, ErrJWTUnknownKey
+}
+
+// JWTConfig defines the config for JWT middleware.
+type JWTConfig struct {
+	// Keys verifies the signatures, e.g. JWTKeys{"": secret} or NewJWKSKeySet(url, JWKSConfig{}).
+	// Required.
+	Keys JWTKeySet
+
+	// Algorithms are the signing algorithms accepted, "none" is never accepted.
+	// Optional. Default value accepts every supported algorithm matching the type of the key.
+	Algorithms []string
+
+	// Issuer is the value required in the "iss" claim.
+	// Optional.
+	Issuer string
+
+	// Audience are the values the "aud" claim must contain one of.
+	// Optional.
+	Audience []string
+
+	// Leeway is the clock skew tolerated when validating "exp" and "nbf".
+	// Optional.
+	Leeway time.Duration
+
+	// Token returns the token of the request.
+	// Optional. Default value reads the Bearer token of the Authorization header.
+	Token func(c *Context) string
+
+	// OnError is called when the token is missing or invalid, before the request is
+	// rejected with 401. It may abort the request itself.
+	// Optional.
+	OnError func(c *Context, err error)
+
+	// Clock tells the time "exp" and "nbf" are validated at.
+	// Optional. Default value is the Clock of the engine, SystemClock for VerifyJWT.
+	Clock Clock
+}
+
+// JWT returns a middleware authenticating the requests with a JSON Web Token. The
+// signature is verified with the key of the "kid" header, then the "exp", "nbf",
+// "iss" and "aud" claims are validated. The claims are set in the Context under
+// JWTClaimsKey and the subject under AuthUserKey:
+//     router.Use(gin.JWT(gin.JWTConfig{
+//         Keys:     gin.NewJWKSKeySet("https://example.com/.well-known/jwks.json", gin.JWKSConfig{}),
+//         Issuer:   "https://example.com/",
+//         Audience: []string{"api"},
+//     }))
+// Invalid requests are rejected with 401 and a WWW-Authenticate header.
+func JWT(conf JWTConfig) HandlerFunc {
+	assert1(conf.Keys != nil, "JWT key set can not be nil")
+	token := conf.Token
+	if token == nil {
+		token = bearerToken
+	}
+
+	return func(c *Context) {
+		clock := conf.Clock
+		if clock == nil {
+			clock = c.engine.clock()
+		}
+		claims, err := verifyJWT(c.Request.Context(), token(c), conf, clock)
+		if err != nil {
+			_ = c.Error(err)
+			if conf.OnError != nil {
+				conf.OnError(c, err)
+			}
+			if !c.IsAborted() {
+				challenge := "Bearer"
+				if err != ErrJWTMissing {
+					challenge += ` error="invalid_token"`
+				}
+				c.Header("WWW-Authenticate", challenge)
+				c.AbortWithStatus(http.StatusUnauthorized)
+			}
+			return
+		}
+		c.Set(JWTClaimsKey, claims)
+		if sub := claims.Subject(); sub != "" {
+			c.Set(AuthUserKey, sub)
+		}
+	}
+}
+
+// JWTClaims returns the claims set by the JWT middleware, or nil.
+func (c *Context) JWTClaims() JWTClaims {
+	claims, _ := c.Get(JWTClaimsKey)
+	jc, _ := claims.(JWTClaims)
+	return jc
+}
+
+func bearerToken(c *Context) string {
+	auth := c.requestHeader("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// VerifyJWT verifies the token in compact serialization as the JWT middleware does, and
+// returns its claims, e.g. to authenticate a WebSocket with a token given in a message.
+func VerifyJWT(ctx context.Context, token string, conf JWTConfig) (JWTClaims, error) {
+	clock := conf.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	return verifyJWT(ctx, token, conf, clock)
+}
+
+func verifyJWT(ctx context.Context, token string, conf JWTConfig, clock Clock) (JWTClaims, error) {
+	if token == "" {
+		return nil, ErrJWTMissing
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if !jwtAlgorithmAllowed(conf.Algorithms, header.Alg) {
+		return nil, ErrJWTAlgorithm
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+	key, err := conf.Keys.Key(ctx, header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err = decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err = validateJWTClaims(claims, conf, clock.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	if err = json.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return ErrJWTMalformed
+	}
+	return nil
+}
+
+func jwtAlgorithmAllowed(allowed []string, alg string) bool {
+	if _, ok := jwtHashes[alg]; !ok && alg != "EdDSA" {
+		return false
+	}
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+var jwtHashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512,
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// verifyJWTSignature checks the signature with the key, which must be of the type of
+// the algorithm so that a public key can't be used as an HMAC secret.
+func verifyJWTSignature(alg string, key interface{}, signed string, signature []byte) error {
+	if alg == "EdDSA" {
+		k, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrJWTAlgorithm
+		}
+		if !ed25519.Verify(k, []byte(signed), signature) {
+			return ErrJWTSignature
+		}
+		return nil
+	}
+
+	hash := jwtHashes[alg]
+	h := hash.New()
+	h.Write([]byte(signed)) // nolint: errcheck
+	digest := h.Sum(nil)
+
+	valid := false
+	switch k := key.(type) {
+	case []byte:
+		if alg[:2] != "HS" {
+			return ErrJWTAlgorithm
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signed)) // nolint: errcheck
+		valid = hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			valid = rsa.VerifyPKCS1v15(k, hash, digest, signature) == nil
+		case "PS":
+			valid = rsa.VerifyPSS(k, hash, digest, signature, nil) == nil
+		default:
+			return ErrJWTAlgorithm
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg[:2] != "ES" || k.Curve.Params().BitSize != jwtCurveBits[alg] {
+			return ErrJWTAlgorithm
+		}
+		if len(signature) != 2*size {
+			return ErrJWTSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		valid = ecdsa.Verify(k, digest, r, s)
+	default:
+		return ErrJWTAlgorithm
+	}
+	if !valid {
+		return ErrJWTSignature
+	}
+	return nil
+}
+
+var jwtCurveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+func validateJWTClaims(claims JWTClaims, conf JWTConfig, now time.Time) error {
+	exp, ok, err := claims.time("exp")
+	if err != nil {
+		return err
+	}
+	if ok && !now.Before(exp.Add(conf.Leeway)) {
+		return ErrJWTExpired
+	}
+	nbf, ok, err := claims.time("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(conf.Leeway).Before(nbf) {
+		return ErrJWTNotValidYet
+	}
+
+	if conf.Issuer != "" && claims.Issuer() != conf.Issuer {
+		return ErrJWTIssuer
+	}
+	if len(conf.Audience) > 0 {
+		found := false
+		for _, aud := range claims.Audience() {
+			for _, want := range conf.Audience {
+				found = found || aud == want
+			}
+		}
+		if !found {
+			return ErrJWTAudience
+		}
+	}
+	return nil
+}
+
+// JWKSConfig defines the config of a JWKSKeySet.
+type JWKSConfig struct {
+	// Client fetches the key set.
+	// Optional. Default value is a client with a 10 seconds timeout.
+	Client *http.Client
+
+	// RefreshInterval is how long the keys are cached before being fetched again.
+	// Optional. Default value is 1 hour.
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval is the minimum time between two fetches, which happen early when
+	// a token is signed by an unknown key, e.g. after the keys were rotated.
+	// Optional. Default value is 1 minute.
+	MinRefreshInterval time.Duration
+
+	// FetchTimeout bounds a fetch of the key set, which isn't canceled with the request
+	// needing it, so that a client going away doesn't fail the fetch for the others.
+	// Optional. Default value is 10 seconds.
+	FetchTimeout time.Duration
+
+	// Clock tells the time the keys are cached for with.
+	// Optional. Default value is SystemClock.
+	Clock Clock
+}
+
+// JWKSKeySet is a JWTKeySet fetching the keys from a JSON Web Key Set URL. The keys are
+// cached, and kept when fetching them fails. The set is fetched at most once per
+// MinRefreshInterval, so that unknown keys or an unavailable URL don't flood it, and
+// without holding the lock, so that the cached keys are still served meanwhile. The
+// lookups needing the set being fetched wait for it, until their context is done.
+type JWKSKeySet struct {
+	url  string
+	conf JWKSConfig
+
+	mu          sync.Mutex
+	keys        map[string]jwk
+	fetched     time.Time
+	lastAttempt time.Time
+	err         error
+	// fetching is closed once the set being fetched is cached, nil when it isn't fetched.
+	fetching chan struct{}
+}
+
+type jwk struct {
+	alg string
+	key interface{}
+}
+
+// NewJWKSKeySet returns a JWKSKeySet fetching the keys from url when they are first needed.
+func NewJWKSKeySet(url string, conf JWKSConfig) *JWKSKeySet {
+	if conf.Client == nil {
+		conf.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if conf.RefreshInterval <= 0 {
+		conf.RefreshInterval = time.Hour
+	}
+	if conf.MinRefreshInterval <= 0 {
+		conf.MinRefreshInterval = time.Minute
+	}
+	if conf.FetchTimeout <= 0 {
+		conf.FetchTimeout = 10 * time.Second
+	}
+	if conf.Clock == nil {
+		conf.Clock = SystemClock
+	}
+	return &JWKSKeySet{url: url, conf: conf}
+}
+
+// Key implements JWTKeySet.
+func (s *JWKSKeySet) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.conf.Clock.Now()
+	var err error
+	canRefresh := s.lastAttempt.IsZero() || now.Sub(s.lastAttempt) >= s.conf.MinRefreshInterval
+	if canRefresh && (s.keys == nil || now.Sub(s.fetched) >= s.conf.RefreshInterval) {
+		err = s.refresh(ctx, now)
+		canRefresh = false
+	}
+	k, ok := s.lookup(kid)
+	if !ok && err == nil && (canRefresh || s.fetching != nil) {
+		err = s.refresh(ctx, now)
+		k, ok = s.lookup(kid)
+	}
+	if !ok {
+		if err != nil {
+			return nil, err
+		}
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, ErrJWTUnknownKey
+	}
+	if k.alg != "" && k.alg != alg {
+		return nil, ErrJWTAlgorithm
+	}
+	return k.key, nil
+}
+
+func (s *JWKSKeySet) lookup(kid string) (jwk, bool) {
+	if k, ok := s.keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	return jwk{}, false
+}
+
+// refresh fetches the set unless it's being fetched, then waits for it, or returns the
+// error of ctx once done. It is called with s.mu held, which it releases meanwhile.
+func (s *JWKSKeySet) refresh(ctx context.Context, now time.Time) error {
+	done := s.fetching
+	if done == nil {
+		done = make(chan struct{})
+		s.fetching = done
+		s.lastAttempt = now
+		go func() {
+			fetchCtx, cancel := context.WithTimeout(context.Background(), s.conf.FetchTimeout)
+			defer cancel()
+			keys, err := s.fetch(fetchCtx)
+			s.mu.Lock()
+			if err == nil {
+				s.keys = keys
+				s.fetched = now
+			}
+			s.err = err
+			s.fetching = nil
+			s.mu.Unlock()
+			close(done)
+		}()
+	}
+	s.mu.Unlock()
+	defer s.mu.Lock()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *JWKSKeySet) fetch(ctx context.Context) (map[string]jwk, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.conf.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gin: jwks: unexpected status %d from %s", resp.StatusCode, s.url)
+	}
+
+	var set struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("gin: jwks: %w", err)
+	}
+	keys := make(map[string]jwk, len(set.Keys))
+	for _, raw := range set.Keys {
+		if use, _ := raw["use"].(string); use != "" && use != "sig" {
+			continue
+		}
+		key, err := parseJWK(raw)
+		if err != nil {
+			// the keys of unsupported types are skipped, others may still be usable
+			continue
+		}
+		kid, _ := raw["kid"].(string)
+		alg, _ := raw["alg"].(string)
+		keys[kid] = jwk{alg: alg, key: key}
+	}
+	return keys, nil
+}
+
+func parseJWK(raw map[string]interface{}) (interface{}, error) {
+	field := func(name string) ([]byte, error) {
+		s, _ := raw[name].(string)
+		if s == "" {
+			return nil, fmt.Errorf("gin: jwks: missing %q", name)
+		}
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	kty, _ := raw["kty"].(string)
+	crv, _ := raw["crv"].(string)
+	switch kty {
+	case "RSA":
+		n, err := field("n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := field("e")
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("gin: jwks: invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("gin: jwks: unsupported curve %q", crv)
+		}
+		x, err := field("x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := field("y")
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("gin: jwks: invalid EC point")
+		}
+		return key, nil
+	case "OKP":
+		if crv != "Ed25519" {
+			return nil, fmt.Errorf("gin: jwks: unsupported curve %q", crv)
+		}
+		x, err := field("x")
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("gin: jwks: invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("gin: jwks: unsupported key type %q", kty)
+}
@@ -0,0 +1,282 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin/internal/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	p, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed)) // nolint: errcheck
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(signature[32-len(rb):32], rb)
+		copy(signature[64-len(sb):], sb)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTHMAC(t *testing.T) {
+	secret := []byte("secret")
+
+	router := New()
+	router.Clock = NewFakeClock(time.Unix(1000, 0))
+	router.Use(JWT(JWTConfig{
+		Keys:     JWTKeys{"": secret},
+		Issuer:   "issuer",
+		Audience: []string{"api"},
+		Leeway:   10 * time.Second,
+	}))
+	router.GET("/", func(c *Context) {
+		c.String(http.StatusOK, c.MustGet(AuthUserKey).(string)+" "+c.JWTClaims()["role"].(string))
+	})
+
+	claims := func(exp float64) map[string]interface{} {
+		return map[string]interface{}{"sub": "alice", "role": "admin", "iss": "issuer", "aud": []string{"web", "api"}, "exp": exp}
+	}
+	bearer := func(token string) header { return header{"Authorization", "Bearer " + token} }
+
+	w := performRequest(router, "GET", "/", bearer(signJWT(t, "HS256", "", secret, claims(1005))))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice admin", w.Body.String())
+
+	// within the leeway
+	w = performRequest(router, "GET", "/", bearer(signJWT(t, "HS256", "", secret, claims(995))))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", "/", bearer(signJWT(t, "HS256", "", secret, claims(990))))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+
+	w = performRequest(router, "GET", "/", bearer(signJWT(t, "HS256", "", []byte("other"), claims(1005))))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	wrongAudience := claims(1005)
+	wrongAudience["aud"] = "web"
+	w = performRequest(router, "GET", "/", bearer(signJWT(t, "HS256", "", secret, wrongAudience)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+}
+
+func TestVerifyJWTErrors(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	conf := JWTConfig{Keys: JWTKeys{"hmac": secret, "rsa": &rsaKey.PublicKey}, Clock: NewFakeClock(time.Unix(1000, 0))}
+	ctx := context.Background()
+
+	_, err = VerifyJWT(ctx, "", conf)
+	assert.Equal(t, ErrJWTMissing, err)
+	_, err = VerifyJWT(ctx, "a.b", conf)
+	assert.Equal(t, ErrJWTMalformed, err)
+
+	none := signJWT(t, "none", "hmac", secret, map[string]interface{}{})
+	_, err = VerifyJWT(ctx, none, conf)
+	assert.Equal(t, ErrJWTAlgorithm, err)
+
+	// an RSA public key is never used as an HMAC secret
+	confused := signJWT(t, "HS256", "rsa", secret, map[string]interface{}{})
+	_, err = VerifyJWT(ctx, confused, conf)
+	assert.Equal(t, ErrJWTAlgorithm, err)
+
+	_, err = VerifyJWT(ctx, signJWT(t, "HS256", "unknown", secret, map[string]interface{}{}), conf)
+	assert.Equal(t, ErrJWTUnknownKey, err)
+
+	_, err = VerifyJWT(ctx, signJWT(t, "HS256", "hmac", secret, map[string]interface{}{"nbf": 1100}), conf)
+	assert.Equal(t, ErrJWTNotValidYet, err)
+
+	_, err = VerifyJWT(ctx, signJWT(t, "HS256", "hmac", secret, map[string]interface{}{"iss": "other"}), JWTConfig{Keys: conf.Keys, Issuer: "issuer"})
+	assert.Equal(t, ErrJWTIssuer, err)
+
+	_, err = VerifyJWT(ctx, signJWT(t, "RS256", "rsa", rsaKey, map[string]interface{}{}), JWTConfig{Keys: conf.Keys, Algorithms: []string{"ES256"}})
+	assert.Equal(t, ErrJWTAlgorithm, err)
+
+	claims, err := VerifyJWT(ctx, signJWT(t, "RS256", "rsa", rsaKey, map[string]interface{}{"sub": "bob"}), conf)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", claims.Subject())
+}
+
+func TestJWKSKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	rotated := false
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		keys := []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "alg": "RS256", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "oct", "kid": "secret", "k": b64([]byte("secret"))},
+		}
+		if rotated {
+			keys = append(keys, map[string]string{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys}) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	clock := NewFakeClock(time.Unix(1000, 0))
+	set := NewJWKSKeySet(server.URL, JWKSConfig{Clock: clock})
+	conf := JWTConfig{Keys: set}
+	ctx := context.Background()
+
+	claims, err := VerifyJWT(ctx, signJWT(t, "RS256", "rsa", rsaKey, map[string]interface{}{"sub": "alice"}), conf)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject())
+	assert.Equal(t, 1, fetches)
+
+	// the unsupported keys are skipped
+	_, err = VerifyJWT(ctx, signJWT(t, "HS256", "secret", []byte("secret"), map[string]interface{}{}), conf)
+	assert.Equal(t, ErrJWTUnknownKey, err)
+
+	// unknown keys are refreshed at most once per MinRefreshInterval
+	rotated = true
+	ecToken := signJWT(t, "ES256", "ec", ecKey, map[string]interface{}{"sub": "bob"})
+	_, err = VerifyJWT(ctx, ecToken, conf)
+	assert.Equal(t, ErrJWTUnknownKey, err)
+	assert.Equal(t, 1, fetches)
+
+	clock.Advance(time.Minute)
+	claims, err = VerifyJWT(ctx, ecToken, conf)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", claims.Subject())
+	assert.Equal(t, 2, fetches)
+
+	// the algorithm of the key is enforced
+	_, err = VerifyJWT(ctx, signJWT(t, "PS256", "rsa", rsaKey, map[string]interface{}{}), conf)
+	assert.Equal(t, ErrJWTAlgorithm, err)
+
+	// the keys are kept when the refresh fails
+	server.Close()
+	clock.Advance(2 * time.Hour)
+	_, err = VerifyJWT(ctx, ecToken, conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches)
+}
+
+func TestJWKSKeySetConcurrentRefresh(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwk := func(kid string) map[string]string {
+		return map[string]string{"kty": "RSA", "kid": kid, "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())}
+	}
+	var mu sync.Mutex
+	fetches := 0
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetches++
+		n := fetches
+		mu.Unlock()
+		keys := []map[string]string{jwk("a")}
+		if n > 1 {
+			<-release
+			keys = append(keys, jwk("b"))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys}) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	clock := NewFakeClock(time.Unix(1000, 0))
+	set := NewJWKSKeySet(server.URL, JWKSConfig{Clock: clock})
+	ctx := context.Background()
+	_, err = set.Key(ctx, "a", "RS256")
+	require.NoError(t, err)
+
+	// the set is fetched once for the unknown key, without blocking the cached ones
+	clock.Advance(time.Minute)
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := set.Key(ctx, "b", "RS256")
+			results <- err
+		}()
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetches == 2
+	})
+	_, err = set.Key(ctx, "a", "RS256")
+	assert.NoError(t, err)
+
+	close(release)
+	assert.NoError(t, <-results)
+	assert.NoError(t, <-results)
+	mu.Lock()
+	assert.Equal(t, 2, fetches)
+	mu.Unlock()
+}
+
+func TestJWKSKeySetCanceledRequest(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		keys := []map[string]string{{"kty": "RSA", "kid": "a", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())}}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys}) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	// the request giving up doesn't fail the fetch for the next ones
+	set := NewJWKSKeySet(server.URL, JWKSConfig{Clock: NewFakeClock(time.Unix(1000, 0))})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = set.Key(ctx, "a", "RS256")
+	assert.Equal(t, context.Canceled, err)
+
+	close(release)
+	_, err = set.Key(context.Background(), "a", "RS256")
+	assert.NoError(t, err)
+}
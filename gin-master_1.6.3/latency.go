@@ -0,0 +1,145 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the buckets of the latency histograms,
+// the same as DefaultDurationBuckets.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// RouteLatency is a snapshot of the latency histogram of a route.
+type RouteLatency struct {
+	Method string
+	// Route is the route pattern, e.g. "/users/:id", empty for the requests matching no route.
+	Route string
+	// Buckets are the upper bounds of the buckets, Counts the number of requests of each
+	// bucket, not cumulative, and the last count is the number of requests slower than
+	// the last bound.
+	Buckets []time.Duration
+	Counts  []uint64
+	Count   uint64
+	Sum     time.Duration
+}
+
+// Mean returns the mean latency.
+func (l RouteLatency) Mean() time.Duration {
+	if l.Count == 0 {
+		return 0
+	}
+	return l.Sum / time.Duration(l.Count)
+}
+
+// Quantile returns the upper bound of the bucket of the q-quantile, e.g. 0.99, which is
+// an upper estimate of the quantile. It's the last bound when the quantile is slower.
+func (l RouteLatency) Quantile(q float64) time.Duration {
+	if l.Count == 0 || len(l.Buckets) == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(l.Count))
+	var seen uint64
+	for i, bound := range l.Buckets {
+		seen += l.Counts[i]
+		if seen > rank || seen == l.Count {
+			return bound
+		}
+	}
+	return l.Buckets[len(l.Buckets)-1]
+}
+
+type routeKey struct {
+	method, route string
+}
+
+type latencyHistograms struct {
+	buckets []time.Duration
+	routes  sync.Map // routeKey -> *latencyHistogram
+}
+
+type latencyHistogram struct {
+	count  uint64 // first for 64-bit alignment of the atomic operations
+	sum    int64
+	counts []uint64
+}
+
+func (h *latencyHistograms) observe(method, route string, elapsed time.Duration) {
+	key := routeKey{method: method, route: route}
+	v, ok := h.routes.Load(key)
+	if !ok {
+		v, _ = h.routes.LoadOrStore(key, &latencyHistogram{counts: make([]uint64, len(h.buckets)+1)})
+	}
+	lh := v.(*latencyHistogram)
+	i := sort.Search(len(h.buckets), func(i int) bool { return elapsed <= h.buckets[i] })
+	atomic.AddUint64(&lh.counts[i], 1)
+	atomic.AddInt64(&lh.sum, int64(elapsed))
+	atomic.AddUint64(&lh.count, 1)
+}
+
+// EnableLatencyHistograms makes the engine measure the latency of the requests in a
+// histogram per route pattern and method, read with LatencyHistograms, e.g. by an
+// exporter, without any metrics middleware:
+//     router.EnableLatencyHistograms()
+//     ...
+//     for _, l := range router.LatencyHistograms() {
+//         log.Printf("%s %s: p99 < %s", l.Method, l.Route, l.Quantile(0.99))
+//     }
+// The buckets are DefaultLatencyBuckets by default. Enabling them again resets the
+// histograms.
+func (engine *Engine) EnableLatencyHistograms(buckets ...time.Duration) {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	engine.latency.Store(&latencyHistograms{buckets: buckets})
+}
+
+// DisableLatencyHistograms stops measuring the latency of the requests and drops the
+// histograms.
+func (engine *Engine) DisableLatencyHistograms() {
+	engine.latency.Store((*latencyHistograms)(nil))
+}
+
+// LatencyHistograms returns a snapshot of the latency histograms, sorted by route and
+// method, or nil when they are not enabled.
+func (engine *Engine) LatencyHistograms() []RouteLatency {
+	h, _ := engine.latency.Load().(*latencyHistograms)
+	if h == nil {
+		return nil
+	}
+	var latencies []RouteLatency
+	h.routes.Range(func(k, v interface{}) bool {
+		key, lh := k.(routeKey), v.(*latencyHistogram)
+		l := RouteLatency{
+			Method:  key.method,
+			Route:   key.route,
+			Buckets: h.buckets,
+			Counts:  make([]uint64, len(lh.counts)),
+		}
+		for i := range lh.counts {
+			l.Counts[i] = atomic.LoadUint64(&lh.counts[i])
+			l.Count += l.Counts[i]
+		}
+		l.Sum = time.Duration(atomic.LoadInt64(&lh.sum))
+		latencies = append(latencies, l)
+		return true
+	})
+	sort.Slice(latencies, func(i, j int) bool {
+		if latencies[i].Route != latencies[j].Route {
+			return latencies[i].Route < latencies[j].Route
+		}
+		return latencies[i].Method < latencies[j].Method
+	})
+	return latencies
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistograms(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", func(c *Context) {})
+	router.GET("/slow", func(c *Context) { time.Sleep(30 * time.Millisecond) })
+
+	performRequest(router, http.MethodGet, "/users/1")
+	assert.Nil(t, router.LatencyHistograms())
+
+	router.EnableLatencyHistograms(50*time.Millisecond, 20*time.Millisecond)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			performRequest(router, http.MethodGet, "/users/42")
+		}()
+	}
+	wg.Wait()
+	performRequest(router, http.MethodGet, "/slow")
+	performRequest(router, http.MethodGet, "/missing")
+
+	latencies := router.LatencyHistograms()
+	assert.Len(t, latencies, 3)
+
+	assert.Equal(t, "", latencies[0].Route)
+	assert.Equal(t, uint64(1), latencies[0].Count)
+
+	slow := latencies[1]
+	assert.Equal(t, "/slow", slow.Route)
+	assert.Equal(t, http.MethodGet, slow.Method)
+	assert.Equal(t, []time.Duration{20 * time.Millisecond, 50 * time.Millisecond}, slow.Buckets)
+	assert.Equal(t, uint64(1), slow.Count)
+	assert.True(t, slow.Sum >= 30*time.Millisecond)
+	assert.Equal(t, slow.Sum, slow.Mean())
+	assert.Equal(t, uint64(0), slow.Counts[0])
+
+	users := latencies[2]
+	assert.Equal(t, "/users/:id", users.Route)
+	assert.Equal(t, uint64(10), users.Count)
+	assert.Equal(t, []uint64{10, 0, 0}, users.Counts)
+	assert.Equal(t, 20*time.Millisecond, users.Quantile(0.99))
+
+	router.EnableLatencyHistograms()
+	assert.Empty(t, router.LatencyHistograms())
+	router.DisableLatencyHistograms()
+	assert.Nil(t, router.LatencyHistograms())
+}
+
+func TestRouteLatencyQuantile(t *testing.T) {
+	l := RouteLatency{
+		Buckets: []time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond},
+		Counts:  []uint64{50, 40, 9, 1},
+		Count:   100,
+	}
+	assert.Equal(t, time.Millisecond, l.Quantile(0.25))
+	assert.Equal(t, 10*time.Millisecond, l.Quantile(0.5))
+	assert.Equal(t, 10*time.Millisecond, l.Quantile(0.89))
+	assert.Equal(t, 100*time.Millisecond, l.Quantile(0.95))
+	assert.Equal(t, 100*time.Millisecond, l.Quantile(0.999))
+	assert.Equal(t, time.Duration(0), RouteLatency{}.Quantile(0.5))
+	assert.Equal(t, time.Duration(0), RouteLatency{}.Mean())
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+)
+
+// LocaleKey is the key the locale is set under in the Context, see Context.Locale.
+const LocaleKey = "locale"
+
+type localeContextKey struct{}
+
+// LocaleSource returns the supported locale requested by the client, or an empty string.
+type LocaleSource func(c *Context, supported []string) string
+
+// LocaleFromQuery reads the locale from a query parameter, e.g. "?lang=de".
+func LocaleFromQuery(name string) LocaleSource {
+	return func(c *Context, supported []string) string {
+		return matchLanguage(normalizeLanguageTag(c.Query(name)), supported)
+	}
+}
+
+// LocaleFromCookie reads the locale from a cookie, e.g. set when the user picked a language.
+func LocaleFromCookie(name string) LocaleSource {
+	return func(c *Context, supported []string) string {
+		value, err := c.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return matchLanguage(normalizeLanguageTag(value), supported)
+	}
+}
+
+// LocaleFromParam reads the locale from a path parameter, e.g. with a group of path "/:lang".
+func LocaleFromParam(name string) LocaleSource {
+	return func(c *Context, supported []string) string {
+		return matchLanguage(normalizeLanguageTag(c.Param(name)), supported)
+	}
+}
+
+// LocaleFromAcceptLanguage negotiates the locale with the Accept-Language header.
+func LocaleFromAcceptLanguage() LocaleSource {
+	return func(c *Context, supported []string) string {
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		for _, tag := range parseAcceptLanguage(c.requestHeader("Accept-Language")) {
+			if tag == "*" {
+				return ""
+			}
+			if locale := matchLanguage(tag, supported); locale != "" {
+				return locale
+			}
+		}
+		return ""
+	}
+}
+
+// LocaleConfig defines the config for Locale middleware.
+type LocaleConfig struct {
+	// Supported are the locales of the application, the first one is the default.
+	// Required.
+	Supported []string
+
+	// Sources are asked for the locale in order, until one returns a supported locale.
+	// Optional. Default value is the "lang" query parameter, then the "lang" cookie,
+	// then the Accept-Language header.
+	Sources []LocaleSource
+}
+
+// Locale returns a middleware resolving the locale of the request among the supported
+// ones. The locale is set in the Context under LocaleKey, in the context of the request
+// (see LocaleFromContext) and in the Content-Language header. It's preferred by
+// Context.NegotiateLanguage, and thereby selects the templates of the locale loaded
+// with LoadHTMLLocales:
+//     router.Use(gin.Locale(gin.LocaleConfig{Supported: []string{"en", "de", "fr"}}))
+//     router.LoadHTMLLocales("templates", "en")
+func Locale(conf LocaleConfig) HandlerFunc {
+	assert1(len(conf.Supported) > 0, "you must provide at least one supported locale")
+	sources := conf.Sources
+	if sources == nil {
+		sources = []LocaleSource{LocaleFromQuery("lang"), LocaleFromCookie("lang"), LocaleFromAcceptLanguage()}
+	}
+
+	return func(c *Context) {
+		locale := ""
+		for _, source := range sources {
+			if locale = source(c, conf.Supported); locale != "" {
+				break
+			}
+		}
+		if locale == "" {
+			locale = conf.Supported[0]
+		}
+		c.Set(LocaleKey, locale)
+		c.Header("Content-Language", locale)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), localeContextKey{}, locale))
+	}
+}
+
+// Locale returns the locale resolved by the Locale middleware, or an empty string.
+func (c *Context) Locale() string {
+	return c.GetString(LocaleKey)
+}
+
+// LocaleFromContext returns the locale resolved by the Locale middleware from the
+// context of the request, e.g. to translate the messages of a service.
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeContextKey{}).(string)
+	return locale
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocaleSources(t *testing.T) {
+	router := New()
+	router.Use(Locale(LocaleConfig{Supported: []string{"en", "de", "pt-BR"}}))
+	router.GET("/", func(c *Context) {
+		assert.Equal(t, c.Locale(), LocaleFromContext(c.Request.Context()))
+		c.String(http.StatusOK, c.Locale())
+	})
+
+	for _, tt := range []struct {
+		path    string
+		headers []header
+		locale  string
+	}{
+		{"/", nil, "en"},
+		{"/", []header{{"Accept-Language", "de-CH,de;q=0.9"}}, "de"},
+		{"/", []header{{"Accept-Language", "pt"}}, "pt-BR"},
+		{"/", []header{{"Accept-Language", "fr"}}, "en"},
+		{"/", []header{{"Accept-Language", "de"}, {"Cookie", "lang=pt_BR"}}, "pt-BR"},
+		{"/?lang=de", []header{{"Cookie", "lang=pt-BR"}}, "de"},
+		// unsupported locales are skipped
+		{"/?lang=fr", []header{{"Accept-Language", "de"}}, "de"},
+	} {
+		w := performRequest(router, "GET", tt.path, tt.headers...)
+		assert.Equal(t, tt.locale, w.Body.String(), tt.path, tt.headers)
+		assert.Equal(t, tt.locale, w.Header().Get("Content-Language"))
+	}
+}
+
+func TestLocaleCustomSources(t *testing.T) {
+	router := New()
+	group := router.Group("/:lang", Locale(LocaleConfig{
+		Supported: []string{"en", "de"},
+		Sources:   []LocaleSource{LocaleFromParam("lang")},
+	}))
+	group.GET("/", func(c *Context) {
+		c.String(http.StatusOK, c.NegotiateLanguage("de", "en"))
+	})
+
+	w := performRequest(router, "GET", "/en/", header{"Accept-Language", "de"})
+	assert.Equal(t, "en", w.Body.String())
+	assert.Empty(t, w.Header().Get("Vary"))
+	w = performRequest(router, "GET", "/it/", header{"Accept-Language", "it"})
+	assert.Equal(t, "en", w.Body.String())
+}
+
+func TestLocaleSelectsTemplates(t *testing.T) {
+	router := New()
+	router.Use(Locale(LocaleConfig{Supported: []string{"en", "de", "pt-BR"}}))
+	router.LoadHTMLLocales("./testdata/locale", "en")
+	router.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "index.tmpl", H{"name": "gin"})
+	})
+
+	w := performRequest(router, "GET", "/?lang=de", header{"Accept-Language", "pt-BR"})
+	assert.Equal(t, "Hallo gin", w.Body.String())
+	w = performRequest(router, "GET", "/", header{"Accept-Language", "pt-BR"})
+	assert.Equal(t, "Olá gin", w.Body.String())
+}
@@ -47,6 +47,19 @@ type LoggerConfig struct {
 	// SkipPaths is a url path array which logs are not written.
 	// Optional.
 	SkipPaths []string
+
+	// SkipPathPrefixes are the prefixes of the url paths which logs are not written,
+	// e.g. "/health".
+	// Optional.
+	SkipPathPrefixes []string
+
+	// Sampling selects the requests logged by status and latency.
+	// Optional. All the requests are logged by default.
+	Sampling *LogSampling
+
+	// Async formats and writes the logs in its goroutine instead of the one of the request.
+	// Optional. The logs are written before the middleware returns by default.
+	Async *AsyncLogger
 }
 
 // LogFormatter gives the signature of the formatter function passed to LoggerWithFormatter
@@ -68,6 +81,8 @@ type LogFormatterParams struct {
 	Method string
 	// Path is a path the client requests.
 	Path string
+	// FullPath is the pattern of the matched route, see Context.FullPath.
+	FullPath string
 	// ErrorMessage is set if error has occurred in processing the request.
 	ErrorMessage string
 	// isTerm shows whether does gin's output descriptor refers to a terminal.
@@ -76,6 +91,8 @@ type LogFormatterParams struct {
 	BodySize int
 	// Keys are the keys set on the request's context.
 	Keys map[string]interface{}
+	// RequestID is the ID set by the RequestID middleware, if any.
+	RequestID string
 }
 
 // StatusCodeColor is the ANSI color for appropriately logging http status code to a terminal.
@@ -141,13 +158,18 @@ var defaultLogFormatter = func(param LogFormatterParams) string {
 		// Truncate in a golang < 1.8 safe way
 		param.Latency = param.Latency - param.Latency%time.Second
 	}
-	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+	var requestID string
+	if param.RequestID != "" {
+		requestID = " | " + param.RequestID
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v%s\n%s",
 		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
 		statusColor, param.StatusCode, resetColor,
 		param.Latency,
 		param.ClientIP,
 		methodColor, param.Method, resetColor,
 		param.Path,
+		requestID,
 		param.ErrorMessage,
 	)
 }
@@ -212,8 +234,6 @@ func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
 		out = DefaultWriter
 	}
 
-	notlogged := conf.SkipPaths
-
 	isTerm := true
 
 	if w, ok := out.(*os.File); !ok || os.Getenv("TERM") == "dumb" ||
@@ -221,19 +241,13 @@ func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
 		isTerm = false
 	}
 
-	var skip map[string]struct{}
-
-	if length := len(notlogged); length > 0 {
-		skip = make(map[string]struct{}, length)
-
-		for _, path := range notlogged {
-			skip[path] = struct{}{}
-		}
-	}
+	skip := newLogSkipper(conf.SkipPaths, conf.SkipPathPrefixes)
+	sampling := conf.Sampling
+	async := conf.Async
 
 	return func(c *Context) {
 		// Start timer
-		start := time.Now()
+		start := c.engine.clock().Now()
 		path := c.Request.URL.Path
 		raw := c.Request.URL.RawQuery
 
@@ -241,31 +255,53 @@ func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
 		c.Next()
 
 		// Log only when path is not being skipped
-		if _, ok := skip[path]; !ok {
-			param := LogFormatterParams{
-				Request: c.Request,
-				isTerm:  isTerm,
-				Keys:    c.Keys,
+		if !skip.skipped(path) {
+			param := newLogFormatterParams(c, start, path, raw)
+			if !sampling.sampled(&param) {
+				return
 			}
+			param.isTerm = isTerm
+			if async != nil {
+				if c.arena != nil && param.Keys != nil {
+					// the keys of the arena are cleared once the response is complete
+					keys := make(map[string]interface{}, len(param.Keys))
+					for k, v := range param.Keys {
+						keys[k] = v
+					}
+					param.Keys = keys
+				}
+				async.log(out, formatter, param)
+				return
+			}
+			fmt.Fprint(out, formatter(param))
+		}
+	}
+}
 
-			// Stop timer
-			param.TimeStamp = time.Now()
-			param.Latency = param.TimeStamp.Sub(start)
-
-			param.ClientIP = c.ClientIP()
-			param.Method = c.Request.Method
-			param.StatusCode = c.Writer.Status()
-			param.ErrorMessage = c.Errors.ByType(ErrorTypePrivate).String()
+// newLogFormatterParams returns the params of a request served since start.
+func newLogFormatterParams(c *Context, start time.Time, path, raw string) LogFormatterParams {
+	param := LogFormatterParams{
+		Request: c.Request,
+		Keys:    c.Keys,
+	}
 
-			param.BodySize = c.Writer.Size()
+	// Stop timer
+	param.TimeStamp = c.engine.clock().Now()
+	param.Latency = param.TimeStamp.Sub(start)
 
-			if raw != "" {
-				path = path + "?" + raw
-			}
+	param.ClientIP = c.ClientIP()
+	param.Method = c.Request.Method
+	param.StatusCode = c.Writer.Status()
+	param.ErrorMessage = c.Errors.ByType(ErrorTypePrivate).String()
 
-			param.Path = path
+	param.BodySize = c.Writer.Size()
+	param.RequestID = c.RequestID()
+	param.FullPath = c.FullPath()
 
-			fmt.Fprint(out, formatter(param))
-		}
+	if raw != "" {
+		path = path + "?" + raw
 	}
+
+	param.Path = path
+	return param
 }
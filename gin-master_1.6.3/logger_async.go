@@ -0,0 +1,102 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncLogger formats and writes the logs of the Logger middlewares in a background
+// goroutine instead of the goroutines of the requests, so that a slow output doesn't
+// slow the responses down:
+//     logs := gin.NewAsyncLogger(4096)
+//     defer logs.Close()
+//     router.Use(gin.LoggerWithConfig(gin.LoggerConfig{Async: logs}))
+// The logs wait in a queue of bounded size, the ones of the requests ending while it's
+// full are dropped and counted instead of waiting, see Dropped. An AsyncLogger can be
+// shared by several middlewares.
+type AsyncLogger struct {
+	dropped uint64
+	mu      sync.RWMutex // held to queue the logs, excluding Close
+	closed  bool
+	entries chan asyncLogEntry
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type asyncLogEntry struct {
+	out       io.Writer
+	formatter LogFormatter
+	param     LogFormatterParams
+}
+
+// NewAsyncLogger returns an AsyncLogger queuing up to size logs and starts its goroutine.
+func NewAsyncLogger(size int) *AsyncLogger {
+	assert1(size > 0, "the size of the log queue must be positive")
+	l := &AsyncLogger{
+		entries: make(chan asyncLogEntry, size),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *AsyncLogger) run() {
+	defer close(l.done)
+	for {
+		select {
+		case e := <-l.entries:
+			e.write()
+		case <-l.stop:
+			for {
+				select {
+				case e := <-l.entries:
+					e.write()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *asyncLogEntry) write() {
+	fmt.Fprint(e.out, e.formatter(e.param))
+}
+
+// log queues the log, or drops it when the queue is full or the logger closed.
+func (l *AsyncLogger) log(out io.Writer, formatter LogFormatter, param LogFormatterParams) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if !l.closed {
+		select {
+		case l.entries <- asyncLogEntry{out: out, formatter: formatter, param: param}:
+			return
+		default:
+		}
+	}
+	atomic.AddUint64(&l.dropped, 1)
+}
+
+// Dropped returns the number of logs dropped so far.
+func (l *AsyncLogger) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// Close writes the queued logs, then stops the goroutine of the logger. The logs of the
+// requests ending after are dropped.
+func (l *AsyncLogger) Close() {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.stop)
+	}
+	l.mu.Unlock()
+	<-l.done
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingWriter blocks its writes until released, as a slow pipe.
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.writing <- struct{}{}
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestAsyncLogger(t *testing.T) {
+	assert.Panics(t, func() { NewAsyncLogger(0) })
+
+	buffer := new(bytes.Buffer)
+	logs := NewAsyncLogger(16)
+	router := New()
+	router.Use(LoggerWithConfig(LoggerConfig{Output: buffer, Async: logs}))
+	router.GET("/example", func(c *Context) {})
+	performRequest(router, "GET", "/example?a=100")
+	performRequest(router, "POST", "/notfound")
+	logs.Close()
+	logs.Close()
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "200")
+	assert.Contains(t, lines[0], "/example?a=100")
+	assert.Contains(t, lines[1], "404")
+	assert.Contains(t, lines[1], "POST")
+	assert.Zero(t, logs.Dropped())
+
+	// the logs of the requests ending once closed are dropped
+	performRequest(router, "GET", "/example")
+	assert.Len(t, strings.Split(strings.TrimSpace(buffer.String()), "\n"), 2)
+	assert.Equal(t, uint64(1), logs.Dropped())
+}
+
+func TestAsyncLoggerSlowOutput(t *testing.T) {
+	out := &blockingWriter{writing: make(chan struct{}), release: make(chan struct{})}
+	logs := NewAsyncLogger(1)
+	router := New()
+	router.Use(StructuredLogger(StructuredLoggerConfig{Output: out, Async: logs}))
+	router.GET("/example", func(c *Context) {})
+
+	// the first log is being written, the second one is queued and the third one dropped,
+	// none of the requests waits for the output
+	performRequest(router, "GET", "/example")
+	<-out.writing
+	performRequest(router, "GET", "/example")
+	w := performRequest(router, "GET", "/example")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint64(1), logs.Dropped())
+
+	close(out.release)
+	go func() {
+		for range out.writing {
+		}
+	}()
+	logs.Close()
+	close(out.writing)
+	assert.Equal(t, 2, strings.Count(out.buf.String(), "\n"))
+	assert.Contains(t, out.buf.String(), `"path":"/example"`)
+}
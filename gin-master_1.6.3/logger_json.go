@@ -0,0 +1,212 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// LogField is a field of the structured access logs. Fields whose value is nil are omitted.
+type LogField struct {
+	Name  string
+	Value func(param *LogFormatterParams) interface{}
+}
+
+// The fields of the structured access logs provided by gin, they can be renamed:
+//     route := gin.LogFieldRoute
+//     route.Name = "http.route"
+var (
+	LogFieldTime = LogField{"time", func(p *LogFormatterParams) interface{} {
+		return p.TimeStamp.Format(time.RFC3339Nano)
+	}}
+	LogFieldStatus = LogField{"status", func(p *LogFormatterParams) interface{} {
+		return p.StatusCode
+	}}
+	// LogFieldLatency is the latency in milliseconds.
+	LogFieldLatency = LogField{"latency_ms", func(p *LogFormatterParams) interface{} {
+		return durationMillis(p.Latency)
+	}}
+	LogFieldClientIP = LogField{"client_ip", func(p *LogFormatterParams) interface{} {
+		return p.ClientIP
+	}}
+	LogFieldMethod = LogField{"method", func(p *LogFormatterParams) interface{} {
+		return p.Method
+	}}
+	LogFieldPath = LogField{"path", func(p *LogFormatterParams) interface{} {
+		return p.Path
+	}}
+	// LogFieldRoute is the pattern of the matched route, omitted when none matched.
+	LogFieldRoute = LogField{"route", func(p *LogFormatterParams) interface{} {
+		return nilIfEmpty(p.FullPath)
+	}}
+	// LogFieldRequestID is the ID set by the RequestID middleware, if any.
+	LogFieldRequestID = LogField{"request_id", func(p *LogFormatterParams) interface{} {
+		return nilIfEmpty(p.RequestID)
+	}}
+	LogFieldBodySize = LogField{"body_size", func(p *LogFormatterParams) interface{} {
+		return p.BodySize
+	}}
+	LogFieldError = LogField{"error", func(p *LogFormatterParams) interface{} {
+		return nilIfEmpty(p.ErrorMessage)
+	}}
+)
+
+// DefaultLogFields are the fields of the structured access logs when none is given.
+var DefaultLogFields = []LogField{
+	LogFieldTime, LogFieldStatus, LogFieldLatency, LogFieldClientIP, LogFieldMethod,
+	LogFieldPath, LogFieldRoute, LogFieldRequestID, LogFieldBodySize, LogFieldError,
+}
+
+// LogKeyField returns a field whose value is set in the Context under key, e.g. the
+// ID of the authenticated user or the latency of an upstream service. Durations are
+// logged in milliseconds.
+func LogKeyField(name, key string) LogField {
+	return LogField{name, func(p *LogFormatterParams) interface{} {
+		value := p.Keys[key]
+		if d, ok := value.(time.Duration); ok {
+			return durationMillis(d)
+		}
+		return value
+	}}
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func nilIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// LogAttr is a key-value pair of a structured access log.
+type LogAttr struct {
+	Key   string
+	Value interface{}
+}
+
+// LogSink receives the structured access logs, e.g. to hand them to a slog.Logger:
+//     func(ctx context.Context, attrs []gin.LogAttr) {
+//         args := make([]slog.Attr, len(attrs))
+//         for i, attr := range attrs {
+//             args[i] = slog.Any(attr.Key, attr.Value)
+//         }
+//         logger.LogAttrs(ctx, slog.LevelInfo, "request", args...)
+//     }
+type LogSink func(ctx context.Context, attrs []LogAttr)
+
+// StructuredLoggerConfig defines the config for StructuredLogger middleware.
+type StructuredLoggerConfig struct {
+	// Fields are the fields of the logs.
+	// Optional. Default value is gin.DefaultLogFields.
+	Fields []LogField
+
+	// Output is a writer where logs are written as JSON lines.
+	// Optional. Default value is gin.DefaultWriter.
+	Output io.Writer
+
+	// Sink receives the logs instead of Output.
+	// Optional.
+	Sink LogSink
+
+	// SkipPaths is a url path array which logs are not written.
+	// Optional.
+	SkipPaths []string
+
+	// SkipPathPrefixes are the prefixes of the url paths which logs are not written.
+	// Optional.
+	SkipPathPrefixes []string
+
+	// Sampling selects the requests logged by status and latency.
+	// Optional. All the requests are logged by default.
+	Sampling *LogSampling
+
+	// Async encodes and writes the logs to Output in its goroutine, it's ignored with Sink.
+	// Optional. The logs are written before the middleware returns by default.
+	Async *AsyncLogger
+}
+
+// StructuredLogger returns a Logger middleware writing a JSON object per request,
+// or handing the fields to a LogSink, with the given config.
+func StructuredLogger(conf StructuredLoggerConfig) HandlerFunc {
+	fields := conf.Fields
+	if fields == nil {
+		fields = DefaultLogFields
+	}
+	if conf.Sink == nil {
+		return LoggerWithConfig(LoggerConfig{
+			Formatter:        JSONLogFormatter(fields...),
+			Output:           conf.Output,
+			SkipPaths:        conf.SkipPaths,
+			SkipPathPrefixes: conf.SkipPathPrefixes,
+			Sampling:         conf.Sampling,
+			Async:            conf.Async,
+		})
+	}
+
+	skip := newLogSkipper(conf.SkipPaths, conf.SkipPathPrefixes)
+	return func(c *Context) {
+		start := c.engine.clock().Now()
+		path := c.Request.URL.Path
+		raw := c.Request.URL.RawQuery
+
+		c.Next()
+
+		if !skip.skipped(path) {
+			param := newLogFormatterParams(c, start, path, raw)
+			if !conf.Sampling.sampled(&param) {
+				return
+			}
+			attrs := make([]LogAttr, 0, len(fields))
+			for _, field := range fields {
+				if value := field.Value(&param); value != nil {
+					attrs = append(attrs, LogAttr{Key: field.Name, Value: value})
+				}
+			}
+			conf.Sink(c.Request.Context(), attrs)
+		}
+	}
+}
+
+// JSONLogFormatter returns a LogFormatter writing the given fields as a JSON object
+// on a line, DefaultLogFields when none is given. Values which can't be encoded
+// are written as strings.
+func JSONLogFormatter(fields ...LogField) LogFormatter {
+	if len(fields) == 0 {
+		fields = DefaultLogFields
+	}
+	return func(param LogFormatterParams) string {
+		buf := make([]byte, 0, 256)
+		buf = append(buf, '{')
+		for _, field := range fields {
+			value := field.Value(&param)
+			if value == nil {
+				continue
+			}
+			if len(buf) > 1 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSON(buf, field.Name)
+			buf = append(buf, ':')
+			buf = appendJSON(buf, value)
+		}
+		return string(append(buf, '}', '\n'))
+	}
+}
+
+func appendJSON(buf []byte, value interface{}) []byte {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	return append(buf, data...)
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredLogger(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(RequestID(), StructuredLogger(StructuredLoggerConfig{Output: buffer, SkipPaths: []string{"/skipped"}}))
+	router.GET("/users/:id", func(c *Context) {
+		_ = c.Error(errors.New("stale cache")).SetType(ErrorTypePrivate)
+		c.String(http.StatusOK, "user")
+	})
+	router.GET("/skipped", func(c *Context) {})
+
+	performRequest(router, "GET", "/users/1?full=1", header{"X-Request-ID", "abc"})
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, float64(http.StatusOK), entry["status"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/users/1?full=1", entry["path"])
+	assert.Equal(t, "/users/:id", entry["route"])
+	assert.Equal(t, "abc", entry["request_id"])
+	assert.Equal(t, float64(4), entry["body_size"])
+	assert.Equal(t, "Error #01: stale cache\n", entry["error"])
+	assert.Contains(t, entry, "time")
+	assert.Contains(t, entry, "latency_ms")
+	assert.Contains(t, entry, "client_ip")
+
+	buffer.Reset()
+	performRequest(router, "GET", "/skipped")
+	assert.Empty(t, buffer.String())
+}
+
+func TestJSONLogFormatter(t *testing.T) {
+	route := LogFieldRoute
+	route.Name = "http.route"
+	formatter := JSONLogFormatter(
+		LogFieldStatus,
+		route,
+		LogFieldRequestID,
+		LogKeyField("user", "user"),
+		LogKeyField("upstream_ms", "upstream"),
+		LogKeyField("bad", "bad"),
+	)
+	line := formatter(LogFormatterParams{
+		StatusCode: http.StatusNotFound,
+		Keys:       map[string]interface{}{"user": "alice", "upstream": 1500 * time.Microsecond, "bad": math.NaN()},
+	})
+	assert.Equal(t, `{"status":404,"user":"alice","upstream_ms":1.5,"bad":"NaN"}`+"\n", line)
+
+	line = JSONLogFormatter()(LogFormatterParams{TimeStamp: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC), Latency: 2 * time.Millisecond})
+	assert.Equal(t, `{"time":"2021-01-02T03:04:05Z","status":0,"latency_ms":2,"client_ip":"","method":"","path":"","body_size":0}`+"\n", line)
+}
+
+func TestStructuredLoggerSink(t *testing.T) {
+	var logged []LogAttr
+	var loggedCtx context.Context
+	router := New()
+	router.Use(StructuredLogger(StructuredLoggerConfig{
+		Fields: []LogField{LogFieldMethod, LogFieldRoute, LogFieldStatus},
+		Sink: func(ctx context.Context, attrs []LogAttr) {
+			loggedCtx, logged = ctx, attrs
+		},
+		SkipPaths: []string{"/skipped"},
+	}))
+	router.GET("/", func(c *Context) {})
+	router.GET("/skipped", func(c *Context) {})
+
+	performRequest(router, "GET", "/missing")
+	assert.NotNil(t, loggedCtx)
+	assert.Equal(t, []LogAttr{{"method", "GET"}, {"status", http.StatusNotFound}}, logged)
+
+	performRequest(router, "GET", "/")
+	assert.Equal(t, []LogAttr{{"method", "GET"}, {"route", "/"}, {"status", http.StatusOK}}, logged)
+
+	logged = nil
+	performRequest(router, "GET", "/skipped")
+	assert.Nil(t, logged)
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogSampling selects the requests logged by the Logger middlewares, so that the
+// logs of high traffic services keep the interesting requests only:
+//     gin.LogSampling{
+//         Rates:      map[string]float64{"2xx": 0.01, "304": 0},
+//         SlowerThan: time.Second,
+//     }
+// logs 1% of the successful requests, no 304, every slow request and every 5xx.
+type LogSampling struct {
+	// Rates maps status codes, e.g. "404", or status classes, e.g. "2xx", to the
+	// ratio of the requests logged. Codes take precedence over classes, the
+	// requests matching neither are all logged.
+	Rates map[string]float64
+
+	// AlwaysStatus is the status code from which every request is logged.
+	// Optional. Default value is 500.
+	AlwaysStatus int
+
+	// SlowerThan logs every request served in more than the given latency.
+	// Optional.
+	SlowerThan time.Duration
+}
+
+// logSampleRandom returns the random numbers deciding whether a request is sampled.
+var logSampleRandom = rand.Float64
+
+func (s *LogSampling) sampled(param *LogFormatterParams) bool {
+	if s == nil {
+		return true
+	}
+	always := s.AlwaysStatus
+	if always == 0 {
+		always = 500
+	}
+	if param.StatusCode >= always || (s.SlowerThan > 0 && param.Latency > s.SlowerThan) {
+		return true
+	}
+	rate, ok := s.Rates[strconv.Itoa(param.StatusCode)]
+	if !ok {
+		rate, ok = s.Rates[strconv.Itoa(param.StatusCode/100)+"xx"]
+	}
+	if !ok || rate >= 1 {
+		return true
+	}
+	return rate > 0 && logSampleRandom() < rate
+}
+
+// logSkipper skips the paths configured by SkipPaths and SkipPathPrefixes.
+type logSkipper struct {
+	paths    map[string]struct{}
+	prefixes []string
+}
+
+func newLogSkipper(paths, prefixes []string) logSkipper {
+	var skip logSkipper
+	if length := len(paths); length > 0 {
+		skip.paths = make(map[string]struct{}, length)
+
+		for _, path := range paths {
+			skip.paths[path] = struct{}{}
+		}
+	}
+	skip.prefixes = prefixes
+	return skip
+}
+
+func (s logSkipper) skipped(path string) bool {
+	if _, ok := s.paths[path]; ok {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSampling(t *testing.T) {
+	defer func(random func() float64) { logSampleRandom = random }(logSampleRandom)
+	random := 0.5
+	logSampleRandom = func() float64 { return random }
+
+	sampling := &LogSampling{
+		Rates:      map[string]float64{"2xx": 0.01, "304": 0, "404": 0.9, "4xx": 0},
+		SlowerThan: time.Second,
+	}
+	sampled := func(status int, latency time.Duration) bool {
+		return sampling.sampled(&LogFormatterParams{StatusCode: status, Latency: latency})
+	}
+	assert.False(t, sampled(http.StatusOK, 0))
+	assert.True(t, sampled(http.StatusOK, 2*time.Second))
+	assert.False(t, sampled(http.StatusNotModified, 0))
+	assert.True(t, sampled(http.StatusFound, 0))
+	assert.True(t, sampled(http.StatusNotFound, 0))
+	assert.False(t, sampled(http.StatusBadRequest, 0))
+	assert.True(t, sampled(http.StatusInternalServerError, 0))
+
+	random = 0.001
+	assert.True(t, sampled(http.StatusOK, 0))
+	assert.False(t, sampled(http.StatusNotModified, 0))
+
+	sampling.AlwaysStatus = 400
+	assert.True(t, sampled(http.StatusBadRequest, 0))
+
+	sampling = nil
+	assert.True(t, sampled(http.StatusOK, 0))
+}
+
+func TestLoggerSampling(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(LoggerWithConfig(LoggerConfig{
+		Output:           buffer,
+		SkipPathPrefixes: []string{"/health"},
+		Sampling:         &LogSampling{Rates: map[string]float64{"2xx": 0}},
+	}))
+	router.GET("/ok", func(c *Context) {})
+	router.GET("/fail", func(c *Context) { c.Status(http.StatusBadGateway) })
+	router.GET("/health/live", func(c *Context) { c.Status(http.StatusServiceUnavailable) })
+
+	performRequest(router, "GET", "/ok")
+	assert.Empty(t, buffer.String())
+	performRequest(router, "GET", "/health/live")
+	assert.Empty(t, buffer.String())
+	performRequest(router, "GET", "/fail")
+	assert.Contains(t, buffer.String(), "/fail")
+}
+
+func TestStructuredLoggerSampling(t *testing.T) {
+	var logged []LogAttr
+	router := New()
+	router.Use(StructuredLogger(StructuredLoggerConfig{
+		Fields:           []LogField{LogFieldStatus},
+		Sink:             func(_ context.Context, attrs []LogAttr) { logged = attrs },
+		SkipPathPrefixes: []string{"/health"},
+		Sampling:         &LogSampling{Rates: map[string]float64{"404": 0}},
+	}))
+	router.GET("/health", func(c *Context) {})
+
+	performRequest(router, "GET", "/missing")
+	assert.Nil(t, logged)
+	performRequest(router, "GET", "/health")
+	assert.Nil(t, logged)
+}
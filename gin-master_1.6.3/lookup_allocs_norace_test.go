@@ -0,0 +1,50 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build !race
+// +build !race
+
+// The allocations through ServeHTTP are only counted without the race detector, under
+// which sync.Pool drops the pooled contexts at random.
+
+package gin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPZeroAllocs(t *testing.T) {
+	router := New()
+	for _, r := range zeroAllocRoutes {
+		router.GET(r.route, func(c *Context) {})
+	}
+	w := newMockWriter()
+	for _, r := range zeroAllocRoutes {
+		req, _ := http.NewRequest(http.MethodGet, r.path, nil)
+		router.ServeHTTP(w, req) // warm up the pool of contexts
+		allocs := testing.AllocsPerRun(100, func() {
+			router.ServeHTTP(w, req)
+		})
+		assert.Zero(t, allocs, "serving %s allocates", r.path)
+	}
+}
+
+func TestDynamicRoutesZeroAllocs(t *testing.T) {
+	router := New()
+	router.SetParamsCapacity(4)
+	router.GET("/users/:id", func(c *Context) {})
+	req, _ := http.NewRequest(http.MethodGet, "/users/42/repos/gin/issues/7", nil)
+	w := newMockWriter()
+	router.ServeHTTP(w, req) // pools a context before the route is added
+
+	router.GET("/users/:id/repos/:repo/issues/:number", func(c *Context) {})
+	router.ServeHTTP(w, req)
+	allocs := testing.AllocsPerRun(100, func() {
+		router.ServeHTTP(w, req)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
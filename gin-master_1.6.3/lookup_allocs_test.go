@@ -0,0 +1,94 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var zeroAllocRoutes = []struct {
+	route, path string
+}{
+	{"/", "/"},
+	{"/users", "/users"},
+	{"/users/:id", "/users/42"},
+	{"/users/:id/repos/:repo/issues/:number", "/users/42/repos/gin/issues/7"},
+	{"/static/*filepath", "/static/css/app.css"},
+	{"/teams/:team/*rest", "/teams/core/members/active"},
+}
+
+func TestGetValueZeroAllocs(t *testing.T) {
+	tree := &node{}
+	var maxParams uint16
+	for _, r := range zeroAllocRoutes {
+		tree.addRoute(r.route, fakeHandler(r.route))
+		if n := countParams(r.route); n > maxParams {
+			maxParams = n
+		}
+	}
+	params := make(Params, 0, maxParams)
+	for _, r := range zeroAllocRoutes {
+		allocs := testing.AllocsPerRun(100, func() {
+			params = params[:0]
+			value := tree.getValue(r.path, &params, false)
+			if value.handlers == nil {
+				t.Fatalf("no route for %s", r.path)
+			}
+		})
+		assert.Zero(t, allocs, "lookup of %s allocates", r.path)
+	}
+}
+
+func TestParamsBeyondPooledCapacity(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", func(c *Context) {})
+	performRequest(router, http.MethodGet, "/users/42") // pools a context for one param
+
+	router.GET("/users/:id/repos/:repo", func(c *Context) {
+		c.String(http.StatusOK, c.Param("id")+" "+c.Param("repo"))
+	})
+	w := performRequest(router, http.MethodGet, "/users/42/repos/gin")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "42 gin", w.Body.String())
+}
+
+func BenchmarkLookupAllocs(b *testing.B) {
+	router := New()
+	for _, r := range zeroAllocRoutes {
+		router.GET(r.route, func(c *Context) {})
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/users/42/repos/gin/issues/7", nil)
+	w := newMockWriter()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, req)
+	}
+}
+
+func TestSetParamsCapacity(t *testing.T) {
+	router := New()
+	assert.Equal(t, 0, router.ParamsCapacity())
+
+	router.GET("/users/:id/repos/:repo", func(c *Context) {})
+	assert.Equal(t, 2, router.ParamsCapacity())
+
+	router.SetParamsCapacity(8)
+	assert.Equal(t, 8, router.ParamsCapacity())
+	c := router.allocateContext()
+	assert.Equal(t, 8, cap(*c.params))
+
+	router.SetParamsCapacity(1)
+	assert.Equal(t, 2, router.ParamsCapacity(), "the capacity is at least the most params")
+
+	router.SetParamsCapacity(0)
+	router.GET("/a/:a/b/:b/c/:c", func(c *Context) {})
+	assert.Equal(t, 3, router.ParamsCapacity())
+
+	assert.Panics(t, func() { router.SetParamsCapacity(-1) })
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+// maintenanceMode holds the paths served while in maintenance, see SetMaintenance.
+type maintenanceMode struct {
+	allowlist pathPatterns
+}
+
+// SetMaintenance switches the maintenance mode, it's safe to call while serving, e.g. from
+// an admin route or on a signal. In maintenance the requests are answered with 503 Service
+// Unavailable, or by the handlers of OnMaintenance, except the ones whose path matches
+// the allowlist. The patterns are matched with path.Match, except for a trailing "/*"
+// which matches every path below:
+//     router.SetMaintenance(true, "/healthz", "/admin/*")
+func (engine *Engine) SetMaintenance(enabled bool, allowlist ...string) {
+	if !enabled {
+		engine.maintenance.Store((*maintenanceMode)(nil))
+		return
+	}
+	engine.maintenance.Store(&maintenanceMode{allowlist: newPathPatterns(allowlist)})
+}
+
+// InMaintenance reports whether the maintenance mode is enabled.
+func (engine *Engine) InMaintenance() bool {
+	m, _ := engine.maintenance.Load().(*maintenanceMode)
+	return m != nil
+}
+
+// OnMaintenance sets the handlers answering the requests in maintenance, after the
+// global middleware, e.g. to render a page or JSON. The status is 503 by default:
+//     router.OnMaintenance(func(c *gin.Context) {
+//         c.Header("Retry-After", "600")
+//         c.JSON(http.StatusServiceUnavailable, gin.H{"error": "back in 10 minutes"})
+//     })
+func (engine *Engine) OnMaintenance(handlers ...HandlerFunc) {
+	engine.onMaintenance = handlers
+	engine.rebuild503Handlers()
+}
+
+func (engine *Engine) rebuild503Handlers() {
+	engine.allMaintenance = engine.combineHandlers(engine.onMaintenance)
+}
+
+// maintenanceBlocks reports whether the request of path p is held by the maintenance mode.
+func (engine *Engine) maintenanceBlocks(p string) bool {
+	m, _ := engine.maintenance.Load().(*maintenanceMode)
+	return m != nil && !m.allowlist.match(p)
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	router := New()
+	logged := 0
+	router.Use(func(c *Context) { logged++ })
+	ok := func(c *Context) { c.String(http.StatusOK, "ok") }
+	router.GET("/users", ok)
+	router.GET("/healthz", ok)
+	router.GET("/admin/settings", ok)
+
+	assert.False(t, router.InMaintenance())
+	router.SetMaintenance(true, "/healthz", "/admin/*")
+	assert.True(t, router.InMaintenance())
+
+	w := performRequest(router, "GET", "/users")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "503 service unavailable: under maintenance", w.Body.String())
+	assert.Equal(t, 1, logged)
+
+	// unknown routes are held too
+	w = performRequest(router, "GET", "/unknown")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = performRequest(router, "GET", "/healthz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", "/admin/settings")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	router.SetMaintenance(false)
+	assert.False(t, router.InMaintenance())
+	w = performRequest(router, "GET", "/users")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMaintenanceModeHandlers(t *testing.T) {
+	router := New()
+	router.OnMaintenance(func(c *Context) {
+		c.Header("Retry-After", "600")
+		c.JSON(http.StatusServiceUnavailable, H{"error": "back soon"})
+	})
+	router.GET("/", func(c *Context) {})
+	router.SetMaintenance(true)
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "600", w.Header().Get("Retry-After"))
+	assert.Equal(t, `{"error":"back soon"}`, w.Body.String())
+}
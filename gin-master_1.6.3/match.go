@@ -0,0 +1,87 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/url"
+)
+
+// RouteMatch is the route a request would be served by, see Engine.Match.
+type RouteMatch struct {
+	RouteInfo
+	// Params are the values of the path parameters.
+	Params Params
+}
+
+// Match looks up the route a request of the method to the URL path would be served by,
+// as ServeHTTP does, but without running the handlers, e.g. to answer "what would this
+// URL hit?" in tests, tools or admin pages:
+//     if match, ok := router.Match("GET", "/users/42"); ok {
+//         fmt.Println(match.Path, match.Handler, match.Params.ByName("id"))
+//     }
+// The path may be escaped and have a query, which is ignored, and may be an absolute URL
+// whose host selects the routes of RouterGroup.Host. It reports false when no route
+// matches, including when the request would be redirected, see RouterGroup.TrailingSlash.
+func (engine *Engine) Match(method, path string) (RouteMatch, bool) {
+	u, err := url.ParseRequestURI(path)
+	if err != nil {
+		return RouteMatch{}, false
+	}
+	rPath := u.Path
+	unescape := false
+	if engine.UseRawPath && len(u.RawPath) > 0 {
+		rPath = u.RawPath
+		unescape = engine.UnescapePathValues
+	}
+	if engine.RemoveExtraSlash {
+		rPath = cleanPath(rPath)
+	}
+	if engine.NormalizePath != nil {
+		rPath = engine.NormalizePath(rPath)
+	}
+
+	trees := engine.routeTrees()
+	var hostParams Params
+	if u.Host != "" && len(trees.hosts) > 0 {
+		if hostTrees, params := trees.matchHost(u.Host); hostTrees != nil {
+			trees, hostParams = hostTrees, params
+		}
+	}
+	root := trees.index.get(method)
+	if root == nil {
+		return RouteMatch{}, false
+	}
+	params := make(Params, 0, engine.paramsCapacity())
+	value := engine.lookupRoute(trees, method, root, rPath, &params, unescape)
+	if value.tsr && method != "CONNECT" && rPath != "/" {
+		if policy, tsrPath := engine.trailingSlashPolicy(trees, method, root, rPath); policy == TrailingSlashMatch {
+			params = params[:0]
+			value = engine.lookupRoute(trees, method, root, tsrPath, &params, unescape)
+		}
+	}
+	if value.handlers == nil && engine.MatchCaseInsensitive {
+		value = engine.caseInsensitiveValue(trees, method, root, rPath, &params, unescape)
+	}
+	if value.handlers == nil {
+		return RouteMatch{}, false
+	}
+	handler := value.handlers.Last()
+	match := RouteMatch{
+		RouteInfo: RouteInfo{
+			Method:      method,
+			Path:        value.fullPath,
+			Handler:     nameOfFunction(handler),
+			HandlerFunc: handler,
+			Meta:        trees.meta[method][value.fullPath],
+			Name:        trees.routeNames[method][value.fullPath],
+			Host:        trees.host,
+		},
+	}
+	if value.params != nil {
+		match.Params = *value.params
+	}
+	match.Params = append(match.Params, hostParams...)
+	return match, true
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineMatch(t *testing.T) {
+	called := false
+	router := New()
+	router.Use(func(c *Context) { called = true })
+	router.WithMeta("owner", "accounts").GET("/users/:id", listUsers)
+	router.GET("/src/*filepath", listUsers)
+	router.POST("/users", createUser)
+
+	match, ok := router.Match("GET", "/users/42?expand=1")
+	assert.True(t, ok)
+	assert.Equal(t, "GET", match.Method)
+	assert.Equal(t, "/users/:id", match.Path)
+	assert.Equal(t, "github.com/gin-gonic/gin.listUsers", match.Handler)
+	assert.NotNil(t, match.HandlerFunc)
+	assert.Equal(t, "accounts", match.Meta["owner"])
+	assert.Equal(t, Params{{Key: "id", Value: "42"}}, match.Params)
+
+	match, ok = router.Match("GET", "/src/css/main.css")
+	assert.True(t, ok)
+	assert.Equal(t, "/css/main.css", match.Params.ByName("filepath"))
+
+	match, ok = router.Match("POST", "/users")
+	assert.True(t, ok)
+	assert.Empty(t, match.Params)
+
+	for _, tt := range [][2]string{{"GET", "/users"}, {"DELETE", "/users/42"}, {"GET", "/users/42/"}, {"GET", "%zz"}} {
+		_, ok = router.Match(tt[0], tt[1])
+		assert.False(t, ok, tt)
+	}
+	assert.False(t, called)
+
+	router.UseRawPath = true
+	match, ok = router.Match("GET", "/users/a%2Fb")
+	assert.True(t, ok)
+	assert.Equal(t, "a/b", match.Params.ByName("id"))
+	router.RemoveExtraSlash = true
+	_, ok = router.Match("GET", "//users//42")
+	assert.True(t, ok)
+}
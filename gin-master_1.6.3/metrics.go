@@ -0,0 +1,243 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bufio"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the buckets of the request duration histogram.
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the upper bounds, in bytes, of the buckets of the response size histogram.
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// MetricsConfig defines the config of Metrics.
+type MetricsConfig struct {
+	// Namespace prefixes the metric names.
+	// Optional. Default value is "gin".
+	Namespace string
+
+	// DurationBuckets are the buckets of the request duration histogram.
+	// Optional. Default value is DefaultDurationBuckets.
+	DurationBuckets []float64
+
+	// SizeBuckets are the buckets of the response size histogram.
+	// Optional. Default value is DefaultSizeBuckets.
+	SizeBuckets []float64
+}
+
+type metricLabels struct {
+	method, route, status string
+}
+
+type routeMetrics struct {
+	duration histogram
+	size     histogram
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Metrics collects the HTTP metrics of an engine and exposes them in the
+// Prometheus text format. The metrics are labeled by method, route pattern and
+// status class, e.g. method="GET", route="/users/:id", status="2xx", so that
+// their number is bounded. The requests matching no route have an empty route.
+type Metrics struct {
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+
+	mu       sync.Mutex
+	routes   map[metricLabels]*routeMetrics
+	inFlight map[metricLabels]int64
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics(conf MetricsConfig) *Metrics {
+	m := &Metrics{
+		namespace:       conf.Namespace,
+		durationBuckets: conf.DurationBuckets,
+		sizeBuckets:     conf.SizeBuckets,
+		routes:          make(map[metricLabels]*routeMetrics),
+		inFlight:        make(map[metricLabels]int64),
+	}
+	if m.namespace == "" {
+		m.namespace = "gin"
+	}
+	if m.durationBuckets == nil {
+		m.durationBuckets = DefaultDurationBuckets
+	}
+	if m.sizeBuckets == nil {
+		m.sizeBuckets = DefaultSizeBuckets
+	}
+	return m
+}
+
+// Middleware returns a middleware measuring the requests, to be used by the engine:
+//     metrics := gin.NewMetrics(gin.MetricsConfig{})
+//     router.Use(metrics.Middleware())
+//     router.GET("/metrics", metrics.Handler())
+func (m *Metrics) Middleware() HandlerFunc {
+	return func(c *Context) {
+		start := time.Now()
+		flight := metricLabels{method: c.Request.Method, route: c.FullPath()}
+		m.mu.Lock()
+		m.inFlight[flight]++
+		m.mu.Unlock()
+
+		c.Next()
+
+		labels := flight
+		labels.status = strconv.Itoa(c.Writer.Status()/100) + "xx"
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		m.mu.Lock()
+		m.inFlight[flight]--
+		rm := m.routes[labels]
+		if rm == nil {
+			rm = &routeMetrics{
+				duration: histogram{buckets: m.durationBuckets, counts: make([]uint64, len(m.durationBuckets))},
+				size:     histogram{buckets: m.sizeBuckets, counts: make([]uint64, len(m.sizeBuckets))},
+			}
+			m.routes[labels] = rm
+		}
+		rm.duration.observe(time.Since(start).Seconds())
+		rm.size.observe(float64(size))
+		m.mu.Unlock()
+	}
+}
+
+// Handler returns a handler exposing the metrics in the Prometheus text format.
+func (m *Metrics) Handler() HandlerFunc {
+	return func(c *Context) {
+		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		w := bufio.NewWriter(c.Writer)
+		m.write(w)
+		_ = w.Flush()
+	}
+}
+
+func (m *Metrics) write(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]metricLabels, 0, len(m.routes))
+	for labels := range m.routes {
+		keys = append(keys, labels)
+	}
+	sortMetricLabels(keys)
+
+	name := m.namespace + "_http_requests_total"
+	writeMetricHeader(w, name, "counter", "Total number of HTTP requests.")
+	for _, labels := range keys {
+		writeSample(w, name, labels, "", m.routes[labels].duration.count)
+	}
+
+	name = m.namespace + "_http_request_duration_seconds"
+	writeMetricHeader(w, name, "histogram", "Duration of the HTTP requests in seconds.")
+	for _, labels := range keys {
+		writeHistogram(w, name, labels, &m.routes[labels].duration)
+	}
+
+	name = m.namespace + "_http_response_size_bytes"
+	writeMetricHeader(w, name, "histogram", "Size of the HTTP response bodies in bytes.")
+	for _, labels := range keys {
+		writeHistogram(w, name, labels, &m.routes[labels].size)
+	}
+
+	keys = keys[:0]
+	for labels := range m.inFlight {
+		keys = append(keys, labels)
+	}
+	sortMetricLabels(keys)
+	name = m.namespace + "_http_requests_in_flight"
+	writeMetricHeader(w, name, "gauge", "Number of HTTP requests being served.")
+	for _, labels := range keys {
+		w.WriteString(name)
+		writeLabels(w, labels, "")
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatInt(m.inFlight[labels], 10))
+		w.WriteByte('\n')
+	}
+}
+
+func sortMetricLabels(keys []metricLabels) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+}
+
+func writeMetricHeader(w *bufio.Writer, name, typ, help string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func writeHistogram(w *bufio.Writer, name string, labels metricLabels, h *histogram) {
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		writeSample(w, name+"_bucket", labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	writeSample(w, name+"_bucket", labels, "+Inf", h.count)
+	w.WriteString(name + "_sum")
+	writeLabels(w, labels, "")
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatFloat(h.sum, 'g', -1, 64))
+	w.WriteByte('\n')
+	writeSample(w, name+"_count", labels, "", h.count)
+}
+
+func writeSample(w *bufio.Writer, name string, labels metricLabels, le string, value uint64) {
+	w.WriteString(name)
+	writeLabels(w, labels, le)
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatUint(value, 10))
+	w.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeLabels(w *bufio.Writer, labels metricLabels, le string) {
+	w.WriteString(`{method="` + labelEscaper.Replace(labels.method) + `",route="` + labelEscaper.Replace(labels.route) + `"`)
+	if labels.status != "" {
+		w.WriteString(`,status="` + labels.status + `"`)
+	}
+	if le != "" {
+		w.WriteString(`,le="` + le + `"`)
+	}
+	w.WriteByte('}')
+}
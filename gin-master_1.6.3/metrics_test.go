@@ -0,0 +1,61 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics(MetricsConfig{Namespace: "app", DurationBuckets: []float64{60}, SizeBuckets: []float64{2, 10}})
+	router := New()
+	router.Use(metrics.Middleware())
+	router.GET("/users/:id", func(c *Context) { c.String(http.StatusOK, "user") })
+	router.GET("/metrics", metrics.Handler())
+
+	performRequest(router, "GET", "/users/1")
+	performRequest(router, "GET", "/users/2")
+	performRequest(router, "GET", "/missing")
+
+	w := performRequest(router, "GET", "/metrics")
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	expected := []string{
+		"# TYPE app_http_requests_total counter",
+		`app_http_requests_total{method="GET",route="",status="4xx"} 1`,
+		`app_http_requests_total{method="GET",route="/users/:id",status="2xx"} 2`,
+		"# TYPE app_http_request_duration_seconds histogram",
+		`app_http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="2xx",le="60"} 2`,
+		`app_http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="2xx",le="+Inf"} 2`,
+		`app_http_request_duration_seconds_count{method="GET",route="/users/:id",status="2xx"} 2`,
+		`app_http_response_size_bytes_bucket{method="GET",route="/users/:id",status="2xx",le="2"} 0`,
+		`app_http_response_size_bytes_bucket{method="GET",route="/users/:id",status="2xx",le="10"} 2`,
+		`app_http_response_size_bytes_sum{method="GET",route="/users/:id",status="2xx"} 8`,
+		`app_http_response_size_bytes_bucket{method="GET",route="",status="4xx",le="+Inf"} 1`,
+		"# TYPE app_http_requests_in_flight gauge",
+		`app_http_requests_in_flight{method="GET",route="/metrics"} 1`,
+		`app_http_requests_in_flight{method="GET",route="/users/:id"} 0`,
+	}
+	for _, line := range expected {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.True(t, strings.Index(body, `route="",status="4xx"} 1`) < strings.Index(body, `route="/users/:id",status="2xx"} 2`))
+}
+
+func TestMetricsDefaults(t *testing.T) {
+	metrics := NewMetrics(MetricsConfig{})
+	router := New()
+	router.Use(metrics.Middleware())
+	router.GET("/metrics", metrics.Handler())
+
+	performRequest(router, "GET", "/metrics")
+	w := performRequest(router, "GET", "/metrics")
+	assert.Contains(t, w.Body.String(), `gin_http_request_duration_seconds_bucket{method="GET",route="/metrics",status="2xx",le="0.005"} `)
+	assert.Contains(t, w.Body.String(), `gin_http_response_size_bytes_bucket{method="GET",route="/metrics",status="2xx",le="1e+07"} 1`)
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"path"
+	"strings"
+)
+
+// RequestMatcher reports whether a request matches, e.g. to select the requests a
+// middleware runs for with UseIf.
+type RequestMatcher func(c *Context) bool
+
+// MatchPath matches the requests whose path matches one of the patterns. The patterns
+// are matched with path.Match, except for a trailing "/*" which matches every path below:
+//     gin.MatchPath("/healthz", "/assets/*", "/users/*/avatar")
+func MatchPath(patterns ...string) RequestMatcher {
+	p := newPathPatterns(patterns)
+	return func(c *Context) bool {
+		return p.match(c.Request.URL.Path)
+	}
+}
+
+// MatchMethod matches the requests of one of the methods.
+func MatchMethod(methods ...string) RequestMatcher {
+	return func(c *Context) bool {
+		for _, method := range methods {
+			if c.Request.Method == method {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchRoute matches the requests of one of the route patterns, e.g. "/users/:id".
+func MatchRoute(routes ...string) RequestMatcher {
+	return func(c *Context) bool {
+		for _, route := range routes {
+			if c.FullPath() == route {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchHeader matches the requests whose header has the value, or which have the
+// header when the value is empty.
+func MatchHeader(name, value string) RequestMatcher {
+	return func(c *Context) bool {
+		got := c.requestHeader(name)
+		if value == "" {
+			return got != ""
+		}
+		return got == value
+	}
+}
+
+// MatchAll matches the requests which match all the matchers.
+func MatchAll(matchers ...RequestMatcher) RequestMatcher {
+	return func(c *Context) bool {
+		for _, match := range matchers {
+			if !match(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchAny matches the requests which match one of the matchers.
+func MatchAny(matchers ...RequestMatcher) RequestMatcher {
+	return func(c *Context) bool {
+		for _, match := range matchers {
+			if match(c) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchNot matches the requests which don't match the matcher.
+func MatchNot(match RequestMatcher) RequestMatcher {
+	return func(c *Context) bool {
+		return !match(c)
+	}
+}
+
+// When returns a middleware running the middleware only for the requests matching
+// the predicate, the other requests go on to the next handlers. The preflight requests
+// are still answered by a CORS middleware run by it.
+func When(predicate func(c *Context) bool, middleware HandlerFunc) HandlerFunc {
+	if isCORS(middleware) {
+		return whenCORS(predicate, middleware)
+	}
+	return func(c *Context) {
+		if predicate(c) {
+			middleware(c)
+		}
+	}
+}
+
+// UseIf adds middleware to the group which run only for the requests matching the
+// predicate, e.g. to skip the expensive middleware for health checks and assets:
+//     router.UseIf(gin.MatchNot(gin.MatchPath("/healthz", "/assets/*")), gin.BodyLogger(conf))
+// The predicate is called once per middleware.
+func (group *RouterGroup) UseIf(predicate func(c *Context) bool, middleware ...HandlerFunc) IRoutes {
+	assert1(predicate != nil, "predicate can not be nil")
+	handlers := make(HandlersChain, len(middleware))
+	for i, h := range middleware {
+		handlers[i] = When(predicate, h)
+	}
+	return group.Use(handlers...)
+}
+
+// UseIf attaches global middleware running for the requests matching the predicate,
+// see RouterGroup.UseIf.
+func (engine *Engine) UseIf(predicate func(c *Context) bool, middleware ...HandlerFunc) IRoutes {
+	engine.RouterGroup.UseIf(predicate, middleware...)
+	engine.rebuildGlobalHandlers()
+	return engine
+}
+
+// pathPatterns matches paths with path.Match patterns, or by prefix for the patterns
+// ending with "/*".
+type pathPatterns struct {
+	patterns []string
+	prefixes []string
+}
+
+func newPathPatterns(patterns []string) pathPatterns {
+	var p pathPatterns
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/*") {
+			p.prefixes = append(p.prefixes, pattern[:len(pattern)-1])
+		} else {
+			p.patterns = append(p.patterns, pattern)
+		}
+	}
+	return p
+}
+
+func (p pathPatterns) match(s string) bool {
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	for _, pattern := range p.patterns {
+		if matched, _ := path.Match(pattern, s); matched {
+			return true
+		}
+	}
+	return false
+}
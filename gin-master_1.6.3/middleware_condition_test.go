@@ -0,0 +1,53 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseIf(t *testing.T) {
+	var trace []string
+	router := New()
+	router.UseIf(MatchNot(MatchPath("/healthz", "/assets/*")), tracer(&trace, "logger"), tracer(&trace, "auth"))
+	api := router.Group("/api")
+	api.UseIf(MatchMethod(http.MethodPost, http.MethodPut), tracer(&trace, "csrf"))
+	api.UseIf(MatchAll(MatchMethod(http.MethodGet), MatchPath("/api/users/*/avatar")), tracer(&trace, "cache"))
+	api.Any("/users/:id/avatar", tracer(&trace, "handler"))
+	router.GET("/healthz", tracer(&trace, "handler"))
+	router.Static("/assets", "./testdata")
+
+	for _, tt := range []struct {
+		method, path, trace string
+	}{
+		{"GET", "/healthz", "handler"},
+		{"GET", "/assets/template/hello.tmpl", ""},
+		{"GET", "/api/users/1/avatar", "logger auth cache handler"},
+		{"POST", "/api/users/1/avatar", "logger auth csrf handler"},
+		{"GET", "/missing", "logger auth"},
+	} {
+		trace = nil
+		performRequest(router, tt.method, tt.path)
+		assert.Equal(t, tt.trace, strings.Join(trace, " "), tt.method, tt.path)
+	}
+}
+
+func TestRequestMatchers(t *testing.T) {
+	c, _ := CreateTestContext(nil)
+	c.Request, _ = http.NewRequest("DELETE", "/users/1", nil)
+
+	assert.True(t, MatchPath("/users/*")(c))
+	assert.True(t, MatchPath("/users/?")(c))
+	assert.False(t, MatchPath("/users")(c))
+	assert.True(t, MatchMethod("GET", "DELETE")(c))
+	assert.True(t, MatchAny(MatchMethod("GET"), MatchPath("/users/1"))(c))
+	assert.False(t, MatchAll(MatchMethod("GET"), MatchPath("/users/1"))(c))
+	assert.True(t, MatchAll()(c))
+	assert.False(t, MatchAny()(c))
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+// The names of the middleware used by Default.
+const (
+	MiddlewareLogger   = "logger"
+	MiddlewareRecovery = "recovery"
+)
+
+// UseNamed adds a middleware to the group under a name, so that other middleware can
+// be inserted relative to it with UseBefore and UseAfter, or replace it with Replace.
+// A name is used at most once in a group.
+func (group *RouterGroup) UseNamed(name string, middleware HandlerFunc) IRoutes {
+	assert1(name != "", "middleware name can not be empty")
+	assert1(group.middlewareIndex(name) < 0, "middleware '"+name+"' is already used")
+	defaultHandlerName(name, middleware)
+	group.insertMiddleware(len(group.Handlers), name, middleware)
+	return group.returnObj()
+}
+
+// UseBefore inserts middleware right before the middleware of the name, e.g. for a
+// library to run its middleware before the logger whichever order Use is called in:
+//     router := gin.Default()
+//     router.UseBefore(gin.MiddlewareLogger, gin.RequestID())
+// It panics if the group has no middleware of the name.
+func (group *RouterGroup) UseBefore(name string, middleware ...HandlerFunc) IRoutes {
+	i := group.middlewareIndex(name)
+	assert1(i >= 0, "middleware '"+name+"' is not used")
+	for j, h := range middleware {
+		group.insertMiddleware(i+j, "", h)
+	}
+	return group.returnObj()
+}
+
+// UseAfter inserts middleware right after the middleware of the name.
+// It panics if the group has no middleware of the name.
+func (group *RouterGroup) UseAfter(name string, middleware ...HandlerFunc) IRoutes {
+	i := group.middlewareIndex(name)
+	assert1(i >= 0, "middleware '"+name+"' is not used")
+	for j, h := range middleware {
+		group.insertMiddleware(i+1+j, "", h)
+	}
+	return group.returnObj()
+}
+
+// Replace replaces the middleware of the name, keeping its position and name, e.g.
+// to configure the logger of Default.
+// It panics if the group has no middleware of the name.
+func (group *RouterGroup) Replace(name string, middleware HandlerFunc) IRoutes {
+	i := group.middlewareIndex(name)
+	assert1(i >= 0, "middleware '"+name+"' is not used")
+	handlers := make(HandlersChain, len(group.Handlers))
+	copy(handlers, group.Handlers)
+	handlers[i] = middleware
+	group.Handlers = handlers
+	return group.returnObj()
+}
+
+// MiddlewareNames returns the names of the middleware of the group, in order, with
+// empty strings for the middleware added without a name.
+func (group *RouterGroup) MiddlewareNames() []string {
+	names := make([]string, len(group.Handlers))
+	copy(names, group.names)
+	return names
+}
+
+func (group *RouterGroup) middlewareIndex(name string) int {
+	for i, n := range group.names {
+		if n == name && i < len(group.Handlers) {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertMiddleware inserts the middleware at position i. The slices may be shared with
+// the parent group, so they're copied.
+func (group *RouterGroup) insertMiddleware(i int, name string, middleware HandlerFunc) {
+	if len(group.Handlers)+1 >= int(abortIndex) {
+		panic("too many handlers")
+	}
+	handlers := make(HandlersChain, 0, len(group.Handlers)+1)
+	handlers = append(handlers, group.Handlers[:i]...)
+	handlers = append(handlers, middleware)
+	group.Handlers = append(handlers, group.Handlers[i:]...)
+
+	names := group.MiddlewareNames()[:len(group.Handlers)-1]
+	names = append(names[:i], append([]string{name}, names[i:]...)...)
+	group.names = names
+
+	if group.bodyLimitIndex > i {
+		group.bodyLimitIndex++
+	}
+}
+
+// UseNamed attaches a global named middleware to the router, see RouterGroup.UseNamed.
+func (engine *Engine) UseNamed(name string, middleware HandlerFunc) IRoutes {
+	engine.RouterGroup.UseNamed(name, middleware)
+	engine.rebuildGlobalHandlers()
+	return engine
+}
+
+// UseBefore inserts global middleware before the one of the name, see RouterGroup.UseBefore.
+func (engine *Engine) UseBefore(name string, middleware ...HandlerFunc) IRoutes {
+	engine.RouterGroup.UseBefore(name, middleware...)
+	engine.rebuildGlobalHandlers()
+	return engine
+}
+
+// UseAfter inserts global middleware after the one of the name, see RouterGroup.UseAfter.
+func (engine *Engine) UseAfter(name string, middleware ...HandlerFunc) IRoutes {
+	engine.RouterGroup.UseAfter(name, middleware...)
+	engine.rebuildGlobalHandlers()
+	return engine
+}
+
+// Replace replaces the global middleware of the name, see RouterGroup.Replace.
+func (engine *Engine) Replace(name string, middleware HandlerFunc) IRoutes {
+	engine.RouterGroup.Replace(name, middleware)
+	engine.rebuildGlobalHandlers()
+	return engine
+}
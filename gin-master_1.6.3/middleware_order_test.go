@@ -0,0 +1,74 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tracer(trace *[]string, name string) HandlerFunc {
+	return func(c *Context) { *trace = append(*trace, name) }
+}
+
+func TestMiddlewareOrderedInsertion(t *testing.T) {
+	var trace []string
+	router := New()
+	router.UseNamed(MiddlewareLogger, tracer(&trace, "logger"))
+	router.UseNamed(MiddlewareRecovery, tracer(&trace, "recovery"))
+	router.Use(tracer(&trace, "app"))
+	router.UseBefore(MiddlewareLogger, tracer(&trace, "request-id"), tracer(&trace, "tracing"))
+	router.UseAfter(MiddlewareRecovery, tracer(&trace, "metrics"))
+	router.Replace(MiddlewareLogger, tracer(&trace, "json-logger"))
+
+	api := router.Group("/api", tracer(&trace, "group"))
+	api.UseAfter(MiddlewareLogger, tracer(&trace, "sampling"))
+	api.GET("/", tracer(&trace, "handler"))
+	router.GET("/", tracer(&trace, "handler"))
+
+	assert.Equal(t, []string{"", "", "logger", "", "recovery", "", "", ""}, api.MiddlewareNames())
+	assert.Equal(t, []string{"", "", "logger", "recovery", "", ""}, router.MiddlewareNames())
+
+	performRequest(router, "GET", "/api/")
+	assert.Equal(t, "request-id tracing json-logger sampling recovery metrics app group handler", strings.Join(trace, " "))
+
+	// the parent group is not changed by its children
+	trace = nil
+	performRequest(router, "GET", "/")
+	assert.Equal(t, "request-id tracing json-logger recovery metrics app handler", strings.Join(trace, " "))
+
+	// nor the global middleware of the errors
+	trace = nil
+	w := performRequest(router, "GET", "/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "request-id tracing json-logger recovery metrics app", strings.Join(trace, " "))
+}
+
+func TestMiddlewareOrderedInsertionPanics(t *testing.T) {
+	router := Default()
+	assert.Equal(t, []string{MiddlewareLogger, MiddlewareRecovery}, router.MiddlewareNames())
+	assert.Panics(t, func() { router.UseBefore("auth", func(c *Context) {}) })
+	assert.Panics(t, func() { router.Replace("auth", func(c *Context) {}) })
+	assert.Panics(t, func() { router.UseNamed(MiddlewareLogger, func(c *Context) {}) })
+	assert.Panics(t, func() { router.UseNamed("", func(c *Context) {}) })
+}
+
+func TestMiddlewareInsertionKeepsBodyLimit(t *testing.T) {
+	var trace []string
+	router := New()
+	router.UseNamed("auth", tracer(&trace, "auth"))
+	group := router.Group("/").MaxBodyBytes(10)
+	group.UseBefore("auth", tracer(&trace, "first"))
+	group.MaxBodyBytes(5)
+	group.POST("/", func(c *Context) {})
+
+	w := performBodyRequest(router, "/", "12345678", false)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "first auth", strings.Join(trace, " "))
+	assert.Len(t, group.Handlers, 3)
+}
@@ -54,19 +54,21 @@ func SetMode(value string) {
 	if value == "" {
 		value = DebugMode
 	}
+	ginMode = modeCode(value)
+	modeName = value
+}
 
+func modeCode(value string) int {
 	switch value {
 	case DebugMode:
-		ginMode = debugCode
+		return debugCode
 	case ReleaseMode:
-		ginMode = releaseCode
+		return releaseCode
 	case TestMode:
-		ginMode = testCode
+		return testCode
 	default:
 		panic("gin mode unknown: " + value + " (available mode: debug release test)")
 	}
-
-	modeName = value
 }
 
 // DisableBindValidation closes the default validator.
@@ -0,0 +1,103 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+// ParamTypeFunc reports whether the value of a path segment is of a param type.
+type ParamTypeFunc func(value string) bool
+
+// paramTypes are the types of the params declared as :name|type, by name.
+var paramTypes = map[string]ParamTypeFunc{
+	"int":  isIntParam,
+	"uuid": isUUIDParam,
+	"date": isDateParam,
+}
+
+// RegisterParamType registers a type of the params declared as :name|type, whose values
+// the type check must accept for the route to match:
+//     gin.RegisterParamType("slug", func(value string) bool {
+//         return slugPattern.MatchString(value)
+//     })
+//     router.GET("/posts/:title|slug", getPost)
+// The built-in types are int, a base 10 int64 with an optional '-' sign, uuid, in the
+// canonical 8-4-4-4-12 hexadecimal form, and date, a valid YYYY-MM-DD date.
+// Registering one of them replaces it for the routes added after. Not
+// concurrency-safe, the types must be registered before the routes using them.
+func RegisterParamType(name string, match ParamTypeFunc) {
+	assert1(name != "", "param type name can not be empty")
+	assert1(match != nil, "param type func can not be nil")
+	paramTypes[name] = match
+}
+
+func isIntParam(value string) bool {
+	digits := value
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 || len(digits) > 19 {
+		return false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return false
+		}
+	}
+	// only 19 digit values may overflow an int64
+	if len(digits) == 19 {
+		limit := "9223372036854775807"
+		if value[0] == '-' {
+			limit = "9223372036854775808"
+		}
+		return digits <= limit
+	}
+	return true
+}
+
+func isUUIDParam(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isDateParam(value string) bool {
+	if len(value) != 10 || value[4] != '-' || value[7] != '-' {
+		return false
+	}
+	year, ok1 := parseDigits(value[:4])
+	month, ok2 := parseDigits(value[5:7])
+	day, ok3 := parseDigits(value[8:])
+	if !ok1 || !ok2 || !ok3 || month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	days := [...]int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}[month-1]
+	if month == 2 && year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+		days = 29
+	}
+	return day <= days
+}
+
+// parseDigits parses the decimal digits of s, it's not ok when s has another character.
+func parseDigits(s string) (n int, ok bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		n = n*10 + int(s[i]-'0')
+	}
+	return n, true
+}
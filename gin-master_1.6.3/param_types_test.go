@@ -0,0 +1,63 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamTypes(t *testing.T) {
+	for _, value := range []string{"0", "42", "-7", "9223372036854775807", "-9223372036854775808", "0012"} {
+		assert.True(t, isIntParam(value), value)
+	}
+	for _, value := range []string{"", "-", "+1", "4a", "1.5", "9223372036854775808", "-9223372036854775809", "12345678901234567890"} {
+		assert.False(t, isIntParam(value), value)
+	}
+
+	for _, value := range []string{"0b6f1c7e-3e1a-4c8e-9d1b-2f8a7c9e4d10", "0B6F1C7E-3E1A-4C8E-9D1B-2F8A7C9E4D10"} {
+		assert.True(t, isUUIDParam(value), value)
+	}
+	for _, value := range []string{"", "0b6f1c7e3e1a4c8e9d1b2f8a7c9e4d10", "0b6f1c7e-3e1a-4c8e-9d1b-2f8a7c9e4d1g", "0b6f1c7e-3e1a-4c8e-9d1b_2f8a7c9e4d10"} {
+		assert.False(t, isUUIDParam(value), value)
+	}
+
+	for _, value := range []string{"2021-01-31", "2020-02-29", "2000-02-29", "0001-12-01"} {
+		assert.True(t, isDateParam(value), value)
+	}
+	for _, value := range []string{"", "2021-1-31", "2021-02-29", "1900-02-29", "2021-13-01", "2021-04-31", "2021-00-10", "2021-01-00", "2021/01/31", "20a1-01-31"} {
+		assert.False(t, isDateParam(value), value)
+	}
+}
+
+func TestRegisterParamType(t *testing.T) {
+	RegisterParamType("lower", func(value string) bool {
+		return value == strings.ToLower(value)
+	})
+	defer delete(paramTypes, "lower")
+	assert.Panics(t, func() { RegisterParamType("", isIntParam) })
+	assert.Panics(t, func() { RegisterParamType("nil", nil) })
+
+	router := New()
+	router.GET("/tags/:tag|lower", func(c *Context) {
+		c.String(http.StatusOK, "tag "+c.Param("tag"))
+	})
+	router.GET("/tags/Go", func(c *Context) {
+		c.String(http.StatusOK, "static")
+	})
+	assert.PanicsWithValue(t, "unknown type 'upper' of the wildcard ':tag|upper' in path '/labels/:tag|upper'", func() {
+		router.GET("/labels/:tag|upper", func(c *Context) {})
+	})
+
+	w := performRequest(router, http.MethodGet, "/tags/gopher")
+	assert.Equal(t, "tag gopher", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/tags/Go")
+	assert.Equal(t, "static", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/tags/Gopher")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
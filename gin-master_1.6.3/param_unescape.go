@@ -0,0 +1,62 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/url"
+	"strings"
+)
+
+// markEscapedParams records the params which are still escaped, after a lookup which
+// didn't unescape them, see Engine.LazyUnescapePathValues. The params past the first 64
+// are unescaped at once.
+func (c *Context) markEscapedParams() {
+	for i := range c.Params {
+		if !strings.ContainsAny(c.Params[i].Value, "%+") {
+			continue
+		}
+		if i < 64 {
+			c.escapedParams |= 1 << uint(i)
+		} else {
+			c.Params[i].Value = unescapeParam(c.Params[i].Value)
+		}
+	}
+}
+
+// unescapeParam unescapes the value of a path param as the tree lookups do, leaving it
+// alone when it's not a valid escape.
+func unescapeParam(value string) string {
+	if v, err := url.QueryUnescape(value); err == nil {
+		return v
+	}
+	return value
+}
+
+// unescapedParam returns the value of the first param of the key, unescaping it if
+// needed.
+func (c *Context) unescapedParam(key string) string {
+	for i := range c.Params {
+		if c.Params[i].Key != key {
+			continue
+		}
+		if i < 64 && c.escapedParams&(1<<uint(i)) != 0 {
+			c.Params[i].Value = unescapeParam(c.Params[i].Value)
+			c.escapedParams &^= 1 << uint(i)
+		}
+		return c.Params[i].Value
+	}
+	return ""
+}
+
+// unescapeParams unescapes the params which are still escaped, before c.Params is read
+// as a whole.
+func (c *Context) unescapeParams() {
+	for i := 0; c.escapedParams != 0 && i < len(c.Params) && i < 64; i++ {
+		if c.escapedParams&(1<<uint(i)) != 0 {
+			c.Params[i].Value = unescapeParam(c.Params[i].Value)
+			c.escapedParams &^= 1 << uint(i)
+		}
+	}
+}
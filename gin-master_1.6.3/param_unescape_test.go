@@ -0,0 +1,91 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newUnescapeRouter(lazy bool, handler HandlerFunc) *Engine {
+	router := New()
+	router.UseRawPath = true
+	router.UnescapePathValues = true
+	router.LazyUnescapePathValues = lazy
+	router.GET("/info/:user/project/:project", handler)
+	return router
+}
+
+func TestLazyUnescapePathValues(t *testing.T) {
+	var escaped, user, project, rawProject string
+	router := newUnescapeRouter(true, func(c *Context) {
+		escaped = c.Params[0].Value
+		user = c.Param("user")
+		rawProject = c.Params[1].Value
+		project = c.Param("project")
+	})
+
+	performRequest(router, http.MethodGet, "/info/slash%2Fgordon/project/Project%20%231")
+	assert.Equal(t, "slash%2Fgordon", escaped, "the params are unescaped on first access")
+	assert.Equal(t, "slash/gordon", user)
+	assert.Equal(t, "Project%20%231", rawProject)
+	assert.Equal(t, "Project #1", project)
+}
+
+func TestLazyUnescapePathValuesLikeEager(t *testing.T) {
+	paths := []string{
+		"/info/gordon/project/go",
+		"/info/slash%2Fgordon/project/Project%20%231",
+		"/info/a+b/project/c%2Bd",
+		"/info/%41b/project/100%25",
+	}
+	for _, path := range paths {
+		var results [2]string
+		for i, lazy := range []bool{false, true} {
+			i := i
+			router := newUnescapeRouter(lazy, func(c *Context) {
+				var uri struct {
+					User    string `uri:"user"`
+					Project string `uri:"project"`
+				}
+				assert.NoError(t, c.ShouldBindUri(&uri))
+				cp := c.Copy()
+				results[i] = fmt.Sprintf("%s %s | %s %s | %v",
+					c.Param("user"), c.Param("project"), uri.User, uri.Project, cp.Params)
+			})
+			performRequest(router, http.MethodGet, path)
+		}
+		assert.Equal(t, results[0], results[1], path)
+	}
+}
+
+func TestLazyUnescapePathValuesReset(t *testing.T) {
+	var user string
+	router := newUnescapeRouter(true, func(c *Context) {
+		user = c.Param("user")
+	})
+	performRequest(router, http.MethodGet, "/info/a%20b/project/go")
+	assert.Equal(t, "a b", user)
+	performRequest(router, http.MethodGet, "/info/a%2520b/project/go")
+	assert.Equal(t, "a%20b", user)
+}
+
+func BenchmarkLazyUnescapePathValues(b *testing.B) {
+	for _, lazy := range []bool{false, true} {
+		router := newUnescapeRouter(lazy, func(c *Context) {})
+		req, _ := http.NewRequest(http.MethodGet, "/info/slash%2Fgordon/project/Project%20%231", nil)
+		w := newMockWriter()
+
+		b.Run(fmt.Sprintf("Lazy=%v", lazy), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				router.ServeHTTP(w, req)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@
 
 package gin
 
+import "sync"
+
 // cleanPath is the URL version of path.Clean, it returns a canonical URL path
 // for p, eliminating . and .. elements.
 //
@@ -24,13 +26,25 @@ func cleanPath(p string) string {
 	if p == "" {
 		return "/"
 	}
+	// Most paths are already clean, a single scan tells.
+	if isCleanPath(p) {
+		return p
+	}
 
 	// Reasonably sized buffer on stack to avoid allocations in the common case.
-	// If a larger buffer is required, it gets allocated dynamically.
+	// If a larger buffer is required, it's taken from a pool.
 	// TODO: 了解下这种sized buffer, stack分配啥的
 	buf := make([]byte, 0, stackBufSize)
 
 	n := len(p)
+	var pooled *[]byte
+	if n+1 > stackBufSize {
+		pooled = cleanPathBufPool.Get().(*[]byte)
+		if cap(*pooled) < n+1 {
+			*pooled = make([]byte, 0, n+1)
+		}
+		buf = (*pooled)[:0]
+	}
 
 	// Invariants:
 	//      reading from path; r is index of next byte to process.
@@ -44,11 +58,7 @@ func cleanPath(p string) string {
 	if p[0] != '/' {
 		r = 0
 
-		if n+1 > stackBufSize {
-			buf = make([]byte, n+1)
-		} else {
-			buf = buf[:n+1]
-		}
+		buf = buf[:n+1]
 		buf[0] = '/'
 	}
 	// 最终路径是否有尾 /
@@ -130,10 +140,53 @@ func cleanPath(p string) string {
 	// return the respective substring of the original string.
 	// Otherwise return a new string from the buffer.
 	// 路径不需要清理，原封不动的情况下，buf是空的
+	var result string
 	if len(buf) == 0 {
-		return p[:w]
+		result = p[:w]
+	} else {
+		result = string(buf[:w])
+	}
+	if pooled != nil && cap(*pooled) <= maxPooledCleanPathBuf {
+		cleanPathBufPool.Put(pooled)
+	}
+	return result
+}
+
+// maxPooledCleanPathBuf is the capacity of the largest buffer kept in cleanPathBufPool,
+// so that a few huge paths don't pin memory.
+const maxPooledCleanPathBuf = 16 << 10
+
+// cleanPathBufPool holds the buffers of cleanPath for the paths which don't fit in its
+// stack buffer.
+var cleanPathBufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+// isCleanPath reports whether cleanPath would return p unchanged: p starts with a slash
+// and has no empty, . or .. element.
+func isCleanPath(p string) bool {
+	n := len(p)
+	if n == 0 || p[0] != '/' {
+		return false
+	}
+	for i := 0; i < n-1; i++ {
+		if p[i] != '/' {
+			continue
+		}
+		// check the element after the slash
+		switch p[i+1] {
+		case '/':
+			return false
+		case '.':
+			if i+2 == n || p[i+2] == '/' || (p[i+2] == '.' && (i+3 == n || p[i+3] == '/')) {
+				return false
+			}
+		}
 	}
-	return string(buf[:w])
+	return true
 }
 
 // Internal helper to lazily create a buffer if necessary.
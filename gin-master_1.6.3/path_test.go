@@ -129,6 +129,31 @@ func TestPathCleanLong(t *testing.T) {
 	}
 }
 
+func TestIsCleanPath(t *testing.T) {
+	tests := append(genLongPaths(), cleanTests...)
+	for _, p := range []string{"/.a", "/..a", "/a.", "/a..", "/a/.b/..c/", "/...", "/.../a"} {
+		tests = append(tests, cleanPathTest{path: p, result: p})
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.result == test.path, isCleanPath(test.path), test.path)
+		assert.True(t, isCleanPath(test.result), test.result)
+		assert.Equal(t, test.result, cleanPath(test.path))
+	}
+}
+
+func TestPathCleanLongMallocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping malloc count in short mode")
+	}
+
+	long := "/" + strings.Repeat("a", 300)
+	cleanPath("/" + long) // fills the pool
+	for _, p := range []string{"/" + long, long + "/b/../c", long[1:]} {
+		allocs := testing.AllocsPerRun(100, func() { cleanPath(p) })
+		assert.EqualValues(t, 1, allocs, "only the result is allocated for %q", p)
+	}
+}
+
 func BenchmarkPathCleanLong(b *testing.B) {
 	cleanTests := genLongPaths()
 	b.ResetTimer()
@@ -140,3 +165,14 @@ func BenchmarkPathCleanLong(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkPathCleanAlreadyClean(b *testing.B) {
+	paths := []string{"/", "/abc", "/a/b/c", "/users/42/repos/gin/issues", "/assets/css/site/main.css"}
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		for _, p := range paths {
+			cleanPath(p)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// precompressedEncodings are the content-codings of the sidecar files, by extension,
+// in order of preference when the client accepts several of them equally.
+var precompressedEncodings = []namedEncoder{{name: "br"}, {name: "zstd"}, {name: "gzip"}}
+
+var precompressedExtensions = map[string]string{"br": ".br", "zstd": ".zst", "gzip": ".gz"}
+
+// servePrecompressed serves the sidecar file of name compressed with the best coding
+// accepted by the client, e.g. "app.js.br" for "app.js", and reports whether it did.
+func servePrecompressed(c *Context, fs http.FileSystem, name string) bool {
+	if !c.engine.ServePrecompressed || strings.HasSuffix(name, "/") || strings.HasSuffix(name, "/index.html") {
+		return false
+	}
+	accept := c.requestHeader("Accept-Encoding")
+	if accept == "" {
+		return false
+	}
+	header := c.Writer.Header()
+	candidates := precompressedEncodings
+	for {
+		encoding, ok := negotiateEncoding(candidates, accept)
+		if !ok {
+			return false
+		}
+		if f, stat, ok := openRegularFile(fs, name+precompressedExtensions[encoding.name]); ok {
+			defer f.Close()
+			contentType, ok := detectContentType(fs, name)
+			if !ok {
+				return false
+			}
+			header.Set("Content-Type", contentType)
+			header.Set("Content-Encoding", encoding.name)
+			header.Add("Vary", "Accept-Encoding")
+			c.noCompression = true
+			http.ServeContent(c.Writer, c.Request, name, stat.ModTime(), f)
+			return true
+		}
+		remaining := make([]namedEncoder, 0, len(candidates)-1)
+		for _, e := range candidates {
+			if e.name != encoding.name {
+				remaining = append(remaining, e)
+			}
+		}
+		candidates = remaining
+	}
+}
+
+func openRegularFile(fs http.FileSystem, name string) (http.File, os.FileInfo, bool) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, nil, false
+	}
+	stat, err := f.Stat()
+	if err != nil || !stat.Mode().IsRegular() {
+		f.Close()
+		return nil, nil, false
+	}
+	return f, stat, true
+}
+
+// detectContentType returns the type of the uncompressed file, from its extension or
+// else from its first bytes, as http.ServeContent would.
+func detectContentType(fs http.FileSystem, name string) (string, bool) {
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		return contentType, true
+	}
+	f, err := fs.Open(name)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	var buf [512]byte
+	n, _ := io.ReadFull(f, buf[:])
+	return http.DetectContentType(buf[:n]), true
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServePrecompressed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gin-precompressed")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{"app.js": "plain", "app.js.gz": "gzipped", "app.js.br": "brotli", "style.css": "css", "style.css.gz": "gzipped css"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	router := New()
+	router.ServePrecompressed = true
+	router.Use(Compress(CompressConfig{MinSize: 1}))
+	router.Static("/assets", dir)
+	router.StaticFile("/app.js", filepath.Join(dir, "app.js"))
+
+	for _, p := range []string{"/assets/app.js", "/app.js"} {
+		w := performRequest(router, "GET", p, header{"Accept-Encoding", "gzip, br"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "brotli", w.Body.String())
+		assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Header().Get("Content-Type"), "javascript")
+		assert.Contains(t, w.Header().Get("Vary"), "Accept-Encoding")
+
+		w = performRequest(router, "GET", p, header{"Accept-Encoding", "gzip"})
+		assert.Equal(t, "gzipped", w.Body.String())
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+		w = performRequest(router, "GET", p)
+		assert.Equal(t, "plain", w.Body.String())
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+	}
+
+	// falls back to the sidecars which exist
+	w := performRequest(router, "GET", "/assets/style.css", header{"Accept-Encoding", "br;q=1, gzip;q=0.5"})
+	assert.Equal(t, "gzipped css", w.Body.String())
+	assert.Equal(t, "text/css; charset=utf-8", w.Header().Get("Content-Type"))
+
+	router.ServePrecompressed = false
+	w = performRequest(router, "GET", "/assets/app.js", header{"Accept-Encoding", "identity"})
+	assert.Equal(t, "plain", w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
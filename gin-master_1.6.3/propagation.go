@@ -0,0 +1,137 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// BaggageKey is the key the baggage set with Context.SetBaggage is stored under.
+const BaggageKey = "_gin-gonic/gin/baggagekey"
+
+// The limits of the baggage header, see https://www.w3.org/TR/baggage/#limits.
+const (
+	maxBaggageMembers = 180
+	maxBaggageLength  = 8192
+)
+
+// Baggage holds the key-value pairs propagated by the W3C baggage header, see
+// https://www.w3.org/TR/baggage/.
+type Baggage map[string]string
+
+// ParseBaggage parses a baggage header, skipping the invalid members. The properties
+// of the members are dropped.
+func ParseBaggage(header string) Baggage {
+	baggage := Baggage{}
+	if len(header) > maxBaggageLength {
+		return baggage
+	}
+	for _, member := range strings.Split(header, ",") {
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+		i := strings.IndexByte(member, '=')
+		if i < 0 {
+			continue
+		}
+		key := strings.TrimSpace(member[:i])
+		value, err := url.PathUnescape(strings.TrimSpace(member[i+1:]))
+		if key == "" || strings.ContainsAny(key, " \t\"(),/:;<=>?@[\\]{}") || err != nil {
+			continue
+		}
+		baggage[key] = value
+		if len(baggage) == maxBaggageMembers {
+			break
+		}
+	}
+	return baggage
+}
+
+// String returns the baggage header, the members sorted by key.
+func (b Baggage) String() string {
+	keys := make([]string, 0, len(b))
+	for key := range b {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, key := range keys {
+		member := key + "=" + url.PathEscape(b[key])
+		if sb.Len()+len(member)+1 > maxBaggageLength {
+			break
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(member)
+	}
+	return sb.String()
+}
+
+// TraceContext returns the trace context of the request: the one of the span started
+// by the Tracing middleware, or else the one of the traceparent and tracestate headers.
+func (c *Context) TraceContext() (TraceContext, bool) {
+	if span := SpanFromContext(c.Request.Context()); span != nil {
+		tc := span.TraceContext()
+		return tc, tc.IsValid()
+	}
+	tc, ok := ParseTraceparent(c.requestHeader("traceparent"))
+	if ok {
+		tc.State = c.requestHeader("tracestate")
+	}
+	return tc, ok
+}
+
+// Baggage returns the baggage of the request header along with the one set with
+// SetBaggage.
+func (c *Context) Baggage() Baggage {
+	baggage := ParseBaggage(strings.Join(c.Request.Header["Baggage"], ","))
+	if set, ok := c.Get(BaggageKey); ok {
+		for key, value := range set.(Baggage) {
+			baggage[key] = value
+		}
+	}
+	return baggage
+}
+
+// SetBaggage adds a member to the baggage propagated by PropagateHeaders, e.g. the
+// tenant of the request.
+func (c *Context) SetBaggage(key, value string) {
+	set, _ := c.Get(BaggageKey)
+	baggage, _ := set.(Baggage)
+	if baggage == nil {
+		baggage = Baggage{}
+		c.Set(BaggageKey, baggage)
+	}
+	baggage[key] = value
+}
+
+// PropagateHeaders sets the correlation headers of the request on an outgoing request,
+// so that the services which don't use a tracing library still forward them: the
+// traceparent and tracestate headers of TraceContext, the baggage header of Baggage and
+// the X-Request-ID header of RequestID.
+//     req, _ := http.NewRequestWithContext(c, http.MethodGet, "http://billing/invoices", nil)
+//     c.PropagateHeaders(req)
+//     resp, err := http.DefaultClient.Do(req)
+// Without the Tracing middleware, the trace context is forwarded as received.
+func (c *Context) PropagateHeaders(req *http.Request) {
+	if tc, ok := c.TraceContext(); ok {
+		req.Header.Set("traceparent", tc.Traceparent())
+		if tc.State != "" {
+			req.Header.Set("tracestate", tc.State)
+		} else {
+			req.Header.Del("tracestate")
+		}
+	}
+	if baggage := c.Baggage(); len(baggage) > 0 {
+		req.Header.Set("baggage", baggage.String())
+	}
+	if id := c.RequestID(); id != "" {
+		req.Header.Set(defaultRequestIDHeader, id)
+	}
+}
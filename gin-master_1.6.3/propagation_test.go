@@ -0,0 +1,90 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBaggage(t *testing.T) {
+	assert.Equal(t, Baggage{"userId": "alice", "serverNode": "DF 28", "isProduction": "false"},
+		ParseBaggage("userId=alice, serverNode=DF%2028 ; prop=1,isProduction=false"))
+	assert.Equal(t, Baggage{"ok": "1"}, ParseBaggage("ok=1,novalue,bad key=2,=3,pct=%zz"))
+	assert.Equal(t, Baggage{}, ParseBaggage(""))
+	assert.Equal(t, Baggage{}, ParseBaggage("k="+strings.Repeat("v", maxBaggageLength)))
+
+	members := make([]string, maxBaggageMembers+10)
+	for i := range members {
+		members[i] = "k" + strconv.Itoa(i) + "=v"
+	}
+	assert.Len(t, ParseBaggage(strings.Join(members, ",")), maxBaggageMembers)
+}
+
+func TestBaggageString(t *testing.T) {
+	assert.Equal(t, "a=1,b=x%2Cy%3Bz,c=two%20words", Baggage{"c": "two words", "a": "1", "b": "x,y;z"}.String())
+	assert.Equal(t, "", Baggage{}.String())
+
+	b := Baggage{"c": "two words", "a": "1", "b": "x,y;z"}
+	assert.Equal(t, b, ParseBaggage(b.String()))
+}
+
+func TestPropagateHeaders(t *testing.T) {
+	router := New()
+	router.Use(RequestID())
+	var outgoing *http.Request
+	router.GET("/orders", func(c *Context) {
+		c.SetBaggage("tenant", "acme")
+		outgoing = httptest.NewRequest(http.MethodGet, "http://billing/invoices", nil)
+		outgoing.Header.Set("tracestate", "stale=1")
+		c.PropagateHeaders(outgoing)
+	})
+
+	performRequest(router, http.MethodGet, "/orders",
+		header{"traceparent", testTraceparent},
+		header{"baggage", "userId=alice"},
+		header{"baggage", "tenant=other"},
+		header{"X-Request-ID", "req-1"})
+	assert.Equal(t, testTraceparent, outgoing.Header.Get("traceparent"))
+	assert.Empty(t, outgoing.Header.Get("tracestate"))
+	assert.Equal(t, "tenant=acme,userId=alice", outgoing.Header.Get("baggage"))
+	assert.Equal(t, "req-1", outgoing.Header.Get("X-Request-ID"))
+}
+
+func TestPropagateHeadersWithTracing(t *testing.T) {
+	router := New()
+	router.Use(Tracing(TracingConfig{Tracer: &testTracer{}}))
+	var outgoing *http.Request
+	router.GET("/orders", func(c *Context) {
+		tc, ok := c.TraceContext()
+		assert.True(t, ok)
+		assert.Equal(t, "vendor=1", tc.State)
+		outgoing = httptest.NewRequest(http.MethodGet, "http://billing/invoices", nil)
+		c.PropagateHeaders(outgoing)
+	})
+
+	performRequest(router, http.MethodGet, "/orders", header{"traceparent", testTraceparent}, header{"tracestate", "vendor=1"})
+	assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-0102030405060708-01", outgoing.Header.Get("traceparent"))
+	assert.Equal(t, "vendor=1", outgoing.Header.Get("tracestate"))
+	assert.Empty(t, outgoing.Header.Get("baggage"))
+	assert.Empty(t, outgoing.Header.Get("X-Request-ID"))
+}
+
+func TestPropagateHeadersWithoutTraceContext(t *testing.T) {
+	c, _ := CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("traceparent", "garbage")
+	_, ok := c.TraceContext()
+	assert.False(t, ok)
+
+	outgoing := httptest.NewRequest(http.MethodGet, "http://billing/invoices", nil)
+	c.PropagateHeaders(outgoing)
+	assert.Empty(t, outgoing.Header)
+}
@@ -0,0 +1,364 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitResult is the outcome of taking one request from a rate limit.
+type RateLimitResult struct {
+	// Allowed reports whether the request is within the limit.
+	Allowed bool
+	// Limit is the number of requests allowed by period.
+	Limit int
+	// Remaining is the number of requests still allowed.
+	Remaining int
+	// Reset is the time until the limit is available again, or until the
+	// next request is allowed when it is not.
+	Reset time.Duration
+}
+
+// RateLimitStore counts the requests made under a key.
+type RateLimitStore interface {
+	// Take counts a request under key, allowing limit requests by period.
+	Take(ctx context.Context, key string, limit int, period time.Duration) (RateLimitResult, error)
+}
+
+// RateLimitKeyFunc returns the key the requests are counted under.
+type RateLimitKeyFunc func(c *Context) string
+
+// RateLimitByIP counts the requests by client IP, see Context.ClientIP.
+func RateLimitByIP() RateLimitKeyFunc {
+	return func(c *Context) string {
+		return c.ClientIP()
+	}
+}
+
+// RateLimitByRoute counts the requests by route, i.e. method and full path,
+// so that all clients share the limit of the route.
+func RateLimitByRoute() RateLimitKeyFunc {
+	return func(c *Context) string {
+		return c.Request.Method + " " + c.FullPath()
+	}
+}
+
+// RateLimitByParam counts the requests by value of the given path parameter,
+// e.g. a tenant with "/tenants/:tenant/*path".
+func RateLimitByParam(name string) RateLimitKeyFunc {
+	return func(c *Context) string {
+		return c.Param(name)
+	}
+}
+
+// RateLimitKeys counts the requests by combination of the given keys, e.g. by
+// route and client IP.
+func RateLimitKeys(keys ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(c *Context) string {
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key(c)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// RateLimitConfig configures the RateLimit middleware.
+type RateLimitConfig struct {
+	// Limit is the number of requests allowed by Period for a key.
+	Limit  int
+	Period time.Duration
+	// Key returns the key the requests are counted under, RateLimitByIP when nil.
+	// An empty key is not limited.
+	Key RateLimitKeyFunc
+	// Store counts the requests, an in-memory NewTokenBucketStore telling the time with
+	// the Clock of the engine when nil.
+	Store RateLimitStore
+	// OnLimited handles the requests over the limit, after the headers are set.
+	// The chain is aborted with 429 Too Many Requests when nil.
+	OnLimited HandlerFunc
+}
+
+// RateLimit returns a middleware limiting the rate of the requests. The responses
+// carry the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, plus
+// Retry-After when the limit is exceeded:
+//     router.POST("/login", gin.RateLimit(gin.RateLimitConfig{Limit: 5, Period: time.Minute}), login)
+// When the store fails the request is let through and the error added to the context.
+func RateLimit(config RateLimitConfig) HandlerFunc {
+	assert1(config.Limit > 0, "rate limit must be positive")
+	assert1(config.Period > 0, "rate limit period must be positive")
+	key := config.Key
+	if key == nil {
+		key = RateLimitByIP()
+	}
+	store := config.Store
+	var buckets *TokenBucketStore
+	if store == nil {
+		buckets = NewTokenBucketStore(0)
+		store = buckets
+	}
+	limit := strconv.Itoa(config.Limit)
+
+	return func(c *Context) {
+		k := key(c)
+		if k == "" {
+			return
+		}
+		var result RateLimitResult
+		var err error
+		if buckets != nil {
+			result = buckets.take(k, config.Limit, config.Period, c.engine.clock().Now())
+		} else {
+			result, err = store.Take(c.Request.Context(), k, config.Limit, config.Period)
+		}
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		header := c.Writer.Header()
+		reset := strconv.FormatInt(int64(math.Ceil(result.Reset.Seconds())), 10)
+		header.Set("RateLimit-Limit", limit)
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", reset)
+		if result.Allowed {
+			return
+		}
+		header.Set("Retry-After", reset)
+		if config.OnLimited != nil {
+			config.OnLimited(c)
+			c.Abort()
+			return
+		}
+		c.AbortWithStatus(http.StatusTooManyRequests)
+	}
+}
+
+const defaultRateLimitShards = 32
+
+// rateLimitShards spreads the entries of the in-memory stores over several
+// locks, entries idle for longer than their period are swept from time to time.
+type rateLimitShards struct {
+	shards []rateLimitShard
+	now    func() time.Time
+}
+
+type rateLimitShard struct {
+	mu        sync.Mutex
+	entries   map[string]rateLimitEntry
+	nextSweep time.Time
+}
+
+type rateLimitEntry interface {
+	expired(now time.Time) bool
+}
+
+func newRateLimitShards(shards int) rateLimitShards {
+	if shards <= 0 {
+		shards = defaultRateLimitShards
+	}
+	s := rateLimitShards{shards: make([]rateLimitShard, shards), now: time.Now}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]rateLimitEntry)
+	}
+	return s
+}
+
+// SetClock sets the clock the store tells the time with, SystemClock by default, e.g.
+// a FakeClock in tests. It must be called before the store is used.
+func (s *rateLimitShards) SetClock(clock Clock) {
+	s.now = clock.Now
+}
+
+// update calls fn with the entry of key under the lock of its shard, at the time now.
+func (s *rateLimitShards) update(key string, period time.Duration, now time.Time, fn func(entry rateLimitEntry) rateLimitEntry) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%uint32(len(s.shards))]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if now.After(shard.nextSweep) {
+		for k, entry := range shard.entries {
+			if entry.expired(now) {
+				delete(shard.entries, k)
+			}
+		}
+		shard.nextSweep = now.Add(period)
+	}
+	shard.entries[key] = fn(shard.entries[key])
+}
+
+// TokenBucketStore is an in-memory RateLimitStore implementing token buckets:
+// a key holds up to limit tokens, refilled at the rate of limit by period, and
+// each request takes one. Bursts are thus allowed up to the limit.
+type TokenBucketStore struct {
+	rateLimitShards
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+func (b *tokenBucket) expired(now time.Time) bool {
+	return now.After(b.full)
+}
+
+// NewTokenBucketStore returns a TokenBucketStore spreading the keys over the given
+// number of shards, a default number when shards is 0.
+func NewTokenBucketStore(shards int) *TokenBucketStore {
+	return &TokenBucketStore{newRateLimitShards(shards)}
+}
+
+// Take implements RateLimitStore.
+func (s *TokenBucketStore) Take(_ context.Context, key string, limit int, period time.Duration) (RateLimitResult, error) {
+	return s.take(key, limit, period, s.now()), nil
+}
+
+// take takes one request of key at the time now.
+func (s *TokenBucketStore) take(key string, limit int, period time.Duration, now time.Time) (result RateLimitResult) {
+	rate := float64(limit) / float64(period)
+	until := func(tokens float64) time.Duration {
+		return time.Duration(math.Ceil(tokens / rate))
+	}
+	s.update(key, period, now, func(entry rateLimitEntry) rateLimitEntry {
+		b, ok := entry.(*tokenBucket)
+		if !ok {
+			b = &tokenBucket{tokens: float64(limit), last: now}
+		}
+		b.tokens = math.Min(float64(limit), b.tokens+float64(now.Sub(b.last))*rate)
+		b.last = now
+		result = RateLimitResult{Limit: limit}
+		if b.tokens >= 1 {
+			b.tokens--
+			result.Allowed = true
+			result.Reset = until(float64(limit) - b.tokens)
+		} else {
+			result.Reset = until(1 - b.tokens)
+		}
+		result.Remaining = int(b.tokens)
+		b.full = now.Add(until(float64(limit) - b.tokens))
+		return b
+	})
+	return result
+}
+
+// SlidingWindowStore is an in-memory RateLimitStore counting the requests of a
+// sliding window of one period, approximated from the counts of the current and
+// the previous fixed windows. Unlike TokenBucketStore it doesn't allow bursts
+// at the limit of two windows.
+type SlidingWindowStore struct {
+	rateLimitShards
+}
+
+type slidingWindow struct {
+	start    time.Time
+	previous int
+	current  int
+	period   time.Duration
+}
+
+func (w *slidingWindow) expired(now time.Time) bool {
+	return now.Sub(w.start) >= 2*w.period
+}
+
+// NewSlidingWindowStore returns a SlidingWindowStore spreading the keys over the
+// given number of shards, a default number when shards is 0.
+func NewSlidingWindowStore(shards int) *SlidingWindowStore {
+	return &SlidingWindowStore{newRateLimitShards(shards)}
+}
+
+// Take implements RateLimitStore.
+func (s *SlidingWindowStore) Take(_ context.Context, key string, limit int, period time.Duration) (result RateLimitResult, err error) {
+	now := s.now()
+	s.update(key, period, now, func(entry rateLimitEntry) rateLimitEntry {
+		w, ok := entry.(*slidingWindow)
+		if !ok {
+			w = &slidingWindow{start: now.Truncate(period), period: period}
+		}
+		if elapsed := now.Sub(w.start); elapsed >= 2*period {
+			w.start, w.previous, w.current = now.Truncate(period), 0, 0
+		} else if elapsed >= period {
+			w.start, w.previous, w.current = w.start.Add(period), w.current, 0
+		}
+		elapsed := now.Sub(w.start)
+		weight := 1 - float64(elapsed)/float64(period)
+		count := int(float64(w.previous)*weight) + w.current
+		result = RateLimitResult{Limit: limit, Reset: period - elapsed}
+		if count < limit {
+			w.current++
+			count++
+			result.Allowed = true
+		}
+		result.Remaining = limit - count
+		return w
+	})
+	return result, nil
+}
+
+// RedisScripter runs Lua scripts on a Redis server. Clients such as go-redis need
+// a small adapter:
+//     func (a adapter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//         return a.client.Eval(ctx, script, keys, args...).Result()
+//     }
+type RedisScripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// redisRateLimitScript counts the requests of a fixed window expiring with the key,
+// it returns the count and the milliseconds until the window ends.
+const redisRateLimitScript = `
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}
+`
+
+type redisRateLimitStore struct {
+	client RedisScripter
+	prefix string
+}
+
+// NewRedisRateLimitStore returns a RateLimitStore counting the requests in Redis,
+// so that several instances share the limits. The requests are counted by fixed
+// windows of one period, under keys starting with prefix.
+func NewRedisRateLimitStore(client RedisScripter, prefix string) RateLimitStore {
+	return &redisRateLimitStore{client: client, prefix: prefix}
+}
+
+func (s *redisRateLimitStore) Take(ctx context.Context, key string, limit int, period time.Duration) (RateLimitResult, error) {
+	reply, err := s.client.Eval(ctx, redisRateLimitScript, []string{s.prefix + key}, period.Milliseconds())
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return RateLimitResult{}, fmt.Errorf("rate limit: unexpected redis reply %v", reply)
+	}
+	count, ok1 := values[0].(int64)
+	ttl, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return RateLimitResult{}, fmt.Errorf("rate limit: unexpected redis reply %v", reply)
+	}
+	result := RateLimitResult{
+		Allowed: count <= int64(limit),
+		Limit:   limit,
+		Reset:   time.Duration(ttl) * time.Millisecond,
+	}
+	if remaining := int64(limit) - count; remaining > 0 {
+		result.Remaining = int(remaining)
+	}
+	return result, nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestTokenBucketStore(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	store := NewTokenBucketStore(1)
+	store.now = clock.now
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(ctx, "k", 3, 3*time.Second)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+	result, _ := store.Take(ctx, "k", 3, 3*time.Second)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.Reset)
+
+	result, _ = store.Take(ctx, "other", 3, 3*time.Second)
+	assert.True(t, result.Allowed)
+
+	clock.t = clock.t.Add(time.Second)
+	result, _ = store.Take(ctx, "k", 3, 3*time.Second)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// idle buckets are swept once full again
+	clock.t = clock.t.Add(time.Minute)
+	_, _ = store.Take(ctx, "new", 3, 3*time.Second)
+	count := 0
+	for i := range store.shards {
+		count += len(store.shards[i].entries)
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestSlidingWindowStore(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	store := NewSlidingWindowStore(0)
+	store.now = clock.now
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		result, _ := store.Take(ctx, "k", 4, 10*time.Second)
+		assert.True(t, result.Allowed)
+	}
+	result, _ := store.Take(ctx, "k", 4, 10*time.Second)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 10*time.Second, result.Reset)
+
+	// half of the previous window still counts
+	clock.t = clock.t.Add(15 * time.Second)
+	result, _ = store.Take(ctx, "k", 4, 10*time.Second)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+	result, _ = store.Take(ctx, "k", 4, 10*time.Second)
+	assert.True(t, result.Allowed)
+	result, _ = store.Take(ctx, "k", 4, 10*time.Second)
+	assert.False(t, result.Allowed)
+
+	clock.t = clock.t.Add(20 * time.Second)
+	result, _ = store.Take(ctx, "k", 4, 10*time.Second)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 3, result.Remaining)
+}
+
+type fakeRedis struct {
+	counts map[string]int64
+	err    error
+}
+
+func (r *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.counts[keys[0]]++
+	return []interface{}{r.counts[keys[0]], args[0]}, nil
+}
+
+func TestRedisRateLimitStore(t *testing.T) {
+	redis := &fakeRedis{counts: make(map[string]int64)}
+	store := NewRedisRateLimitStore(redis, "rl:")
+
+	result, err := store.Take(context.Background(), "k", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, RateLimitResult{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Minute}, result)
+	assert.Equal(t, int64(1), redis.counts["rl:k"])
+
+	result, err = store.Take(context.Background(), "k", 1, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	redis.err = errors.New("down")
+	_, err = store.Take(context.Background(), "k", 1, time.Minute)
+	assert.Error(t, err)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	router := New()
+	router.GET("/tenants/:tenant", RateLimit(RateLimitConfig{
+		Limit:  1,
+		Period: time.Minute,
+		Key:    RateLimitKeys(RateLimitByRoute(), RateLimitByParam("tenant")),
+	}), func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	w := performRequest(router, "GET", "/tenants/a")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = performRequest(router, "GET", "/tenants/a")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	w = performRequest(router, "GET", "/tenants/b")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRateLimitMiddlewareOnLimited(t *testing.T) {
+	router := New()
+	var errs []string
+	router.Use(func(c *Context) {
+		c.Next()
+		errs = append(errs, c.Errors.Errors()...)
+	})
+	router.GET("/", RateLimit(RateLimitConfig{
+		Limit:     1,
+		Period:    time.Minute,
+		Store:     NewSlidingWindowStore(1),
+		OnLimited: func(c *Context) { c.String(http.StatusServiceUnavailable, "slow down") },
+	}), func(c *Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/down", RateLimit(RateLimitConfig{
+		Limit:  1,
+		Period: time.Minute,
+		Store:  NewRedisRateLimitStore(&fakeRedis{err: errors.New("down")}, ""),
+	}), func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	performRequest(router, "GET", "/")
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "slow down", w.Body.String())
+
+	w = performRequest(router, "GET", "/down")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, errs, 1)
+
+	assert.Panics(t, func() { RateLimit(RateLimitConfig{Period: time.Second}) })
+}
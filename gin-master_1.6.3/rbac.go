@@ -0,0 +1,120 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+)
+
+const (
+	// RouteMetaScopes is the route metadata key of the scopes required by RBAC, see
+	// RouterGroup.RequireScopes.
+	RouteMetaScopes = "scopes"
+
+	// RBACMissingScopesKey is the key the required scopes not granted to the principal
+	// are set under in the Context, before RBACConfig.Forbidden is called.
+	RBACMissingScopesKey = "rbacMissingScopes"
+)
+
+// RequireScopes returns a copy of the group whose routes require all the scopes, in
+// addition to the scopes required by the group. They're enforced by the RBAC middleware:
+//     router.Use(gin.JWT(jwtConfig), gin.RBAC(gin.RBACConfig{}))
+//     router.RequireScopes("invoices:read").GET("/invoices", listInvoices)
+//     admin := router.Group("/admin").RequireScopes("admin")
+func (group *RouterGroup) RequireScopes(scopes ...string) *RouterGroup {
+	required, _ := group.meta[RouteMetaScopes].([]string)
+	all := make([]string, 0, len(required)+len(scopes))
+	all = append(all, required...)
+	all = append(all, scopes...)
+	return group.WithMeta(RouteMetaScopes, all)
+}
+
+// RouteScopes returns the scopes required by the matched route, see RouterGroup.RequireScopes.
+func (c *Context) RouteScopes() []string {
+	scopes, _ := c.RouteMeta(RouteMetaScopes)
+	s, _ := scopes.([]string)
+	return s
+}
+
+// RBACConfig defines the config for RBAC middleware.
+type RBACConfig struct {
+	// Principal returns the scopes, or roles, granted to the authenticated principal of
+	// the request, and false when the request isn't authenticated.
+	// Optional. Default value is the scopes of the claims set by the JWT middleware, or
+	// no scope for the user set under AuthUserKey, e.g. by BasicAuth.
+	Principal func(c *Context) (grants []string, authenticated bool)
+
+	// Roles maps the roles to the scopes they grant, e.g. "admin" to "invoices:read" and
+	// "invoices:write".
+	// Optional. Default value is nil, the grants are scopes.
+	Roles map[string][]string
+
+	// Unauthorized handles the requests which aren't authenticated.
+	// Optional. Default aborts with 401 and {"error": "unauthorized"}.
+	Unauthorized HandlerFunc
+
+	// Forbidden handles the requests which lack some required scopes, see RBACMissingScopesKey.
+	// Optional. Default aborts with 403 and {"error": "forbidden", "missing_scopes": [...]}.
+	Forbidden HandlerFunc
+}
+
+// RBAC returns a middleware checking that the authenticated principal of the request
+// is granted the scopes required by the matched route. It must be used after the
+// authentication middleware. The routes which require no scope are not checked.
+func RBAC(conf RBACConfig) HandlerFunc {
+	principal := conf.Principal
+	if principal == nil {
+		principal = defaultPrincipal
+	}
+
+	return func(c *Context) {
+		required := c.RouteScopes()
+		if len(required) == 0 {
+			return
+		}
+		grants, ok := principal(c)
+		if !ok {
+			if conf.Unauthorized != nil {
+				conf.Unauthorized(c)
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, H{"error": "unauthorized"})
+			return
+		}
+
+		granted := make(map[string]bool, len(grants))
+		for _, grant := range grants {
+			granted[grant] = true
+			for _, scope := range conf.Roles[grant] {
+				granted[scope] = true
+			}
+		}
+		var missing []string
+		for _, scope := range required {
+			if !granted[scope] {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) == 0 {
+			return
+		}
+		c.Set(RBACMissingScopesKey, missing)
+		if conf.Forbidden != nil {
+			conf.Forbidden(c)
+			c.Abort()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, H{"error": "forbidden", "missing_scopes": missing})
+	}
+}
+
+func defaultPrincipal(c *Context) ([]string, bool) {
+	if claims := c.JWTClaims(); claims != nil {
+		return claims.Scopes(), true
+	}
+	_, ok := c.Get(AuthUserKey)
+	return nil, ok
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteMeta(t *testing.T) {
+	router := New()
+	billing := router.WithMeta("owner", "billing")
+	billing.GET("/invoices", func(c *Context) {
+		owner, ok := c.RouteMeta("owner")
+		assert.True(t, ok)
+		c.String(http.StatusOK, owner.(string))
+	})
+	billing.Group("/v2").WithMeta("version", 2).GET("/invoices", func(c *Context) {
+		version, _ := c.RouteMeta("version")
+		owner, _ := c.RouteMeta("owner")
+		assert.Equal(t, 2, version)
+		c.String(http.StatusOK, owner.(string))
+	})
+	router.GET("/health", func(c *Context) {
+		_, ok := c.RouteMeta("owner")
+		assert.False(t, ok)
+	})
+
+	w := performRequest(router, "GET", "/invoices")
+	assert.Equal(t, "billing", w.Body.String())
+	w = performRequest(router, "GET", "/v2/invoices")
+	assert.Equal(t, "billing", w.Body.String())
+	w = performRequest(router, "GET", "/health")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, route := range router.Routes() {
+		if route.Path == "/health" {
+			assert.Nil(t, route.Meta)
+		} else {
+			assert.Equal(t, "billing", route.Meta["owner"])
+		}
+	}
+}
+
+func TestRBAC(t *testing.T) {
+	router := New()
+	router.Use(func(c *Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set(AuthUserKey, user)
+			c.Set("grants", strings.Fields(c.GetHeader("X-Grants")))
+		}
+	})
+	router.Use(RBAC(RBACConfig{
+		Principal: func(c *Context) ([]string, bool) {
+			if _, ok := c.Get(AuthUserKey); !ok {
+				return nil, false
+			}
+			return c.GetStringSlice("grants"), true
+		},
+		Roles: map[string][]string{"accountant": {"invoices:read", "invoices:write"}},
+	}))
+	ok := func(c *Context) { c.String(http.StatusOK, "ok") }
+	router.GET("/public", ok)
+	router.RequireScopes("invoices:read").GET("/invoices", ok)
+	admin := router.Group("/admin").RequireScopes("admin")
+	admin.RequireScopes("invoices:write").POST("/invoices", ok)
+
+	for _, tt := range []struct {
+		method, path, user, grants string
+		code                       int
+		body                       string
+	}{
+		{"GET", "/public", "", "", http.StatusOK, "ok"},
+		{"GET", "/invoices", "", "", http.StatusUnauthorized, `{"error":"unauthorized"}`},
+		{"GET", "/invoices", "bob", "", http.StatusForbidden, `{"error":"forbidden","missing_scopes":["invoices:read"]}`},
+		{"GET", "/invoices", "bob", "invoices:read", http.StatusOK, "ok"},
+		{"GET", "/invoices", "bob", "accountant", http.StatusOK, "ok"},
+		{"POST", "/admin/invoices", "bob", "accountant", http.StatusForbidden, `{"error":"forbidden","missing_scopes":["admin"]}`},
+		{"POST", "/admin/invoices", "bob", "admin accountant", http.StatusOK, "ok"},
+	} {
+		w := performRequest(router, tt.method, tt.path, header{"X-User", tt.user}, header{"X-Grants", tt.grants})
+		assert.Equal(t, tt.code, w.Code, tt.path, tt.grants)
+		assert.Equal(t, tt.body, w.Body.String(), tt.path, tt.grants)
+	}
+}
+
+func TestRBACWithJWTClaims(t *testing.T) {
+	router := New()
+	router.Use(func(c *Context) {
+		c.Set(JWTClaimsKey, JWTClaims{"sub": "bob", "scope": "invoices:read profile"})
+	}, RBAC(RBACConfig{
+		Forbidden: func(c *Context) {
+			c.String(http.StatusForbidden, "missing %v", c.GetStringSlice(RBACMissingScopesKey))
+		},
+	}))
+	router.RequireScopes("profile").GET("/me", func(c *Context) { c.String(http.StatusOK, "ok") })
+	router.RequireScopes("invoices:write").POST("/invoices", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	w := performRequest(router, "GET", "/me")
+	assert.Equal(t, "ok", w.Body.String())
+	w = performRequest(router, "POST", "/invoices")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "missing [invoices:write]", w.Body.String())
+
+	assert.Equal(t, []string{"a", "b"}, JWTClaims{"scp": []interface{}{"a", "b"}}.Scopes())
+}
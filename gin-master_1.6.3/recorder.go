@@ -0,0 +1,226 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+const defaultRecordedRequests = 1000
+
+// RecordedRequest is a request captured by the Recorder middleware, sanitized with the
+// redaction of its config.
+type RecordedRequest struct {
+	Time      time.Time   `json:"time"`
+	RequestID string      `json:"request_id,omitempty"`
+	Method    string      `json:"method"`
+	URL       string      `json:"url"` // the path and the query
+	Route     string      `json:"route,omitempty"`
+	Header    http.Header `json:"header,omitempty"`
+	Body      []byte      `json:"body,omitempty"`
+	// BodyTruncated is set when the body was larger than RecorderConfig.MaxBodySize,
+	// in which case the replayed body is truncated too.
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+	// Status is the status code of the recorded response.
+	Status int `json:"status"`
+}
+
+// HTTPRequest returns a new request made of the recorded one, to be replayed.
+func (r *RecordedRequest) HTTPRequest() *http.Request {
+	req := httptest.NewRequest(r.Method, r.URL, bytes.NewReader(r.Body))
+	for name, values := range r.Header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	return req
+}
+
+// RequestStore stores the recorded requests, e.g. in memory or in a database.
+type RequestStore interface {
+	// Save stores the request, it's called at the end of each recorded request.
+	Save(ctx context.Context, req *RecordedRequest) error
+	// Load returns the stored requests, from the oldest.
+	Load(ctx context.Context) ([]*RecordedRequest, error)
+}
+
+// MemoryRequestStore is a RequestStore keeping the last requests in memory.
+type MemoryRequestStore struct {
+	mu       sync.Mutex
+	requests []*RecordedRequest
+	next     int
+	full     bool
+}
+
+// NewMemoryRequestStore returns a store keeping the last capacity requests, 1000 by default.
+func NewMemoryRequestStore(capacity int) *MemoryRequestStore {
+	if capacity <= 0 {
+		capacity = defaultRecordedRequests
+	}
+	return &MemoryRequestStore{requests: make([]*RecordedRequest, capacity)}
+}
+
+// Save implements RequestStore.
+func (s *MemoryRequestStore) Save(_ context.Context, req *RecordedRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[s.next] = req
+	s.next = (s.next + 1) % len(s.requests)
+	if s.next == 0 {
+		s.full = true
+	}
+	return nil
+}
+
+// Load implements RequestStore.
+func (s *MemoryRequestStore) Load(context.Context) ([]*RecordedRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.full {
+		return append([]*RecordedRequest(nil), s.requests[:s.next]...), nil
+	}
+	return append(append([]*RecordedRequest(nil), s.requests[s.next:]...), s.requests[:s.next]...), nil
+}
+
+// RecorderConfig defines the config for Recorder middleware.
+type RecorderConfig struct {
+	// Store receives the recorded requests.
+	// Required.
+	Store RequestStore
+
+	// Record reports whether the request is recorded, e.g. to sample the requests or
+	// record the requests of a route only.
+	// Optional. All the requests are recorded by default.
+	Record func(c *Context) bool
+
+	// MaxBodySize is the number of bytes of the bodies recorded, the rest is dropped.
+	// Optional. Default value is 64 KB.
+	MaxBodySize int
+
+	// Redaction declares the values which must not be recorded, as for BodyLogger.
+	// Optional.
+	Redaction BodyRedaction
+}
+
+// Recorder returns a middleware recording the requests, sanitized, to the store, so that
+// they can be replayed with Replay, e.g. to debug an issue which only happens in
+// production or to check the changes of the routes against real traffic:
+//     store := gin.NewMemoryRequestStore(1000)
+//     router.Use(gin.Recorder(gin.RecorderConfig{
+//         Store:     store,
+//         Redaction: gin.BodyRedaction{JSONPaths: []string{"password"}},
+//     }))
+// The redacted headers, e.g. Authorization, are recorded as RedactedValue, the JSON and
+// form bodies are redacted, and the other bodies are recorded as is.
+func Recorder(conf RecorderConfig) HandlerFunc {
+	assert1(conf.Store != nil, "request store can not be nil")
+	maxSize := conf.MaxBodySize
+	if maxSize <= 0 {
+		maxSize = defaultMaxLoggedBody
+	}
+	redactor := newBodyRedactor(conf.Redaction)
+
+	return func(c *Context) {
+		if conf.Record != nil && !conf.Record(c) {
+			return
+		}
+		req := &RecordedRequest{
+			Time:   time.Now(),
+			Method: c.Request.Method,
+			URL:    c.Request.URL.Path,
+			Header: make(http.Header, len(c.Request.Header)),
+		}
+		if raw := redactor.query(c.Request.URL.RawQuery); raw != "" {
+			req.URL += "?" + raw
+		}
+		for name, values := range c.Request.Header {
+			if redactor.headers[http.CanonicalHeaderKey(name)] {
+				req.Header[name] = []string{RedactedValue}
+			} else {
+				req.Header[name] = append([]string(nil), values...)
+			}
+		}
+		body := &boundedBuffer{max: maxSize}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			c.Request.Body = &teeReadCloser{Reader: io.TeeReader(c.Request.Body, body), Closer: c.Request.Body}
+		}
+
+		c.Next()
+
+		req.RequestID = c.RequestID()
+		req.Route = c.FullPath()
+		req.Status = c.Writer.Status()
+		req.Body = redactor.recordedBody(c.requestHeader("Content-Type"), body)
+		req.BodyTruncated = body.truncated
+		if err := conf.Store.Save(c.Request.Context(), req); err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
+
+// recordedBody returns the body to replay: the JSON and form bodies redacted, the
+// others as is.
+func (r *bodyRedactor) recordedBody(contentType string, body *boundedBuffer) []byte {
+	if body.size == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == MIMEJSON || strings.HasSuffix(mediaType, "+json") || mediaType == MIMEPOSTForm {
+		switch v := r.body(contentType, body).(type) {
+		case string:
+			return []byte(v)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return []byte(RedactedValue)
+			}
+			return data
+		}
+	}
+	return append([]byte(nil), body.Bytes()...)
+}
+
+// ReplayResult is the outcome of a replayed request.
+type ReplayResult struct {
+	Request  *RecordedRequest
+	Response *httptest.ResponseRecorder
+}
+
+// StatusChanged reports whether the status of the response differs from the recorded one.
+func (r ReplayResult) StatusChanged() bool {
+	return r.Response.Code != r.Request.Status
+}
+
+// Replay serves the recorded requests with the handler, usually an Engine, through
+// ServeHTTP, in order. The header, e.g. an Authorization header, is set on all the
+// requests, to replace the redacted credentials:
+//     requests, _ := store.Load(ctx)
+//     for _, result := range gin.Replay(router, requests, http.Header{"Authorization": {token}}) {
+//         if result.StatusChanged() {
+//             log.Printf("%s %s: %d -> %d", result.Request.Method, result.Request.URL,
+//                 result.Request.Status, result.Response.Code)
+//         }
+//     }
+func Replay(handler http.Handler, requests []*RecordedRequest, header http.Header) []ReplayResult {
+	results := make([]ReplayResult, 0, len(requests))
+	for _, recorded := range requests {
+		req := recorded.HTTPRequest()
+		for name, values := range header {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		results = append(results, ReplayResult{Request: recorded, Response: w})
+	}
+	return results
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func performRequestWithBody(r http.Handler, method, path, body string, headers ...header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for _, h := range headers {
+		req.Header.Add(h.Key, h.Value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRecorderAndReplay(t *testing.T) {
+	store := NewMemoryRequestStore(10)
+	router := New()
+	router.Use(Recorder(RecorderConfig{
+		Store:     store,
+		Record:    func(c *Context) bool { return c.Request.URL.Path != "/healthz" },
+		Redaction: BodyRedaction{JSONPaths: []string{"password"}, FormKeys: []string{"token"}},
+	}))
+	router.POST("/login", func(c *Context) {
+		var body struct{ User, Password string }
+		assert.NoError(t, c.ShouldBindJSON(&body))
+		if c.GetHeader("Authorization") == "" {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.String(http.StatusOK, body.User+" "+body.Password)
+	})
+	router.GET("/healthz", func(c *Context) {})
+
+	w := performRequestWithBody(router, "POST", "/login?token=secret&page=1", `{"user":"bob","password":"hunter2"}`,
+		header{"Content-Type", MIMEJSON}, header{"Authorization", "Bearer abc"}, header{"X-Client", "cli"})
+	assert.Equal(t, "bob hunter2", w.Body.String())
+	performRequest(router, "GET", "/healthz")
+
+	requests, err := store.Load(context.Background())
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	req := requests[0]
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "/login?page=1&token=%5Bredacted%5D", req.URL)
+	assert.Equal(t, "/login", req.Route)
+	assert.Equal(t, []string{RedactedValue}, req.Header["Authorization"])
+	assert.Equal(t, []string{"cli"}, req.Header["X-Client"])
+	assert.JSONEq(t, `{"user":"bob","password":"[redacted]"}`, string(req.Body))
+	assert.Equal(t, http.StatusOK, req.Status)
+
+	// the replay changes the status without credentials
+	results := Replay(router, requests, nil)
+	require.Len(t, results, 1)
+	assert.Equal(t, "bob [redacted]", results[0].Response.Body.String())
+	assert.False(t, results[0].StatusChanged())
+	delete(req.Header, "Authorization")
+	results = Replay(router, requests, nil)
+	assert.True(t, results[0].StatusChanged())
+	results = Replay(router, requests, http.Header{"authorization": {"Bearer xyz"}})
+	assert.False(t, results[0].StatusChanged())
+
+	// the replayed requests are recorded too
+	requests, _ = store.Load(context.Background())
+	assert.Len(t, requests, 4)
+}
+
+func TestRecorderKeepsOtherBodies(t *testing.T) {
+	store := NewMemoryRequestStore(0)
+	router := New()
+	router.Use(Recorder(RecorderConfig{Store: store, MaxBodySize: 4}))
+	router.PUT("/blob", func(c *Context) {
+		data, _ := ioutil.ReadAll(c.Request.Body)
+		c.Data(http.StatusCreated, "application/octet-stream", data)
+	})
+
+	w := performRequestWithBody(router, "PUT", "/blob", "\x00\x01\x02\x03\x04", header{"Content-Type", "application/octet-stream"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	requests, _ := store.Load(context.Background())
+	require.Len(t, requests, 1)
+	assert.Equal(t, []byte("\x00\x01\x02\x03"), requests[0].Body)
+	assert.True(t, requests[0].BodyTruncated)
+}
+
+func TestMemoryRequestStoreRing(t *testing.T) {
+	store := NewMemoryRequestStore(3)
+	for _, url := range []string{"/1", "/2", "/3", "/4"} {
+		require.NoError(t, store.Save(context.Background(), &RecordedRequest{URL: url}))
+	}
+	requests, _ := store.Load(context.Background())
+	var urls []string
+	for _, req := range requests {
+		urls = append(urls, req.URL)
+	}
+	assert.Equal(t, "/2 /3 /4", strings.Join(urls, " "))
+}
+
+type failingRequestStore struct{ MemoryRequestStore }
+
+func (*failingRequestStore) Save(context.Context, *RecordedRequest) error {
+	return errors.New("store down")
+}
+
+func TestRecorderStoreError(t *testing.T) {
+	router := New()
+	router.Use(func(c *Context) {
+		c.Next()
+		assert.EqualError(t, c.Errors.Last().Err, "store down")
+	}, Recorder(RecorderConfig{Store: &failingRequestStore{}}))
+	router.GET("/", func(c *Context) {})
+	performRequest(router, "GET", "/")
+}
@@ -49,13 +49,25 @@ func RecoveryWithWriter(out io.Writer, recovery ...RecoveryFunc) HandlerFunc {
 
 // CustomRecoveryWithWriter returns a middleware for a given writer that recovers from any panics and calls the provided handle func to handle it.
 func CustomRecoveryWithWriter(out io.Writer, handle RecoveryFunc) HandlerFunc {
+	return RecoveryWithConfig(RecoveryConfig{Output: out, Handle: handle})
+}
+
+// RecoveryWithConfig returns a middleware that recovers from any panics with the given config.
+// The panics are reported to the reporters of the config and of the engine, see
+// Engine.AddPanicReporter, then logged to the output and handled.
+func RecoveryWithConfig(conf RecoveryConfig) HandlerFunc {
 	var logger *log.Logger
-	if out != nil {
-		logger = log.New(out, "\n\n\x1b[31m", log.LstdFlags)
+	if conf.Output != nil {
+		logger = log.New(conf.Output, "\n\n\x1b[31m", log.LstdFlags)
 	}
+	handle := conf.handler()
 	return func(c *Context) {
 		defer func() {
 			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					// the handler aborted the response on purpose, the server closes the connection
+					panic(err)
+				}
 				// Check for a broken connection, as it is not really a
 				// condition that warrants a panic stack trace.
 				var brokenPipe bool
@@ -66,8 +78,15 @@ func CustomRecoveryWithWriter(out io.Writer, handle RecoveryFunc) HandlerFunc {
 						}
 					}
 				}
+				var trace []byte
+				if !brokenPipe && (len(conf.Reporters) > 0 || len(c.engine.panicReporters) > 0) {
+					trace = stack(3)
+					reportPanic(c, conf.Reporters, err, trace)
+				}
 				if logger != nil {
-					stack := stack(3)
+					if trace == nil {
+						trace = stack(3)
+					}
 					httpRequest, _ := httputil.DumpRequest(c.Request, false)
 					headers := strings.Split(string(httpRequest), "\r\n")
 					for idx, header := range headers {
@@ -81,10 +100,10 @@ func CustomRecoveryWithWriter(out io.Writer, handle RecoveryFunc) HandlerFunc {
 						logger.Printf("%s\n%s%s", err, headersToStr, reset)
 					} else if IsDebugging() {
 						logger.Printf("[Recovery] %s panic recovered:\n%s\n%s\n%s%s",
-							timeFormat(time.Now()), headersToStr, err, stack, reset)
+							timeFormat(time.Now()), headersToStr, err, trace, reset)
 					} else {
 						logger.Printf("[Recovery] %s panic recovered:\n%s\n%s%s",
-							timeFormat(time.Now()), err, stack, reset)
+							timeFormat(time.Now()), err, trace, reset)
 					}
 				}
 				if brokenPipe {
@@ -0,0 +1,143 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// PanicReporter receives the panics recovered by the Recovery middlewares, e.g. to
+// send them to an error tracking service. It's called before the panic is handled
+// and must not write to the response.
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, report PanicReport)
+}
+
+// PanicReporterFunc is an adapter to use a function as a PanicReporter.
+type PanicReporterFunc func(ctx context.Context, report PanicReport)
+
+// ReportPanic calls f(ctx, report).
+func (f PanicReporterFunc) ReportPanic(ctx context.Context, report PanicReport) {
+	f(ctx, report)
+}
+
+// PanicReport describes a recovered panic.
+type PanicReport struct {
+	// Value is the value given to panic.
+	Value interface{}
+	// Stack is the stack trace of the panic.
+	Stack []byte
+	// Time is when the panic was recovered.
+	Time    time.Time
+	Request RequestSnapshot
+	// Handler is the name of the main handler of the route, see Context.HandlerName.
+	Handler string
+}
+
+// RequestSnapshot is a copy of the request which panicked, without its body and
+// with the credentials of its headers redacted.
+type RequestSnapshot struct {
+	Method string
+	URL    string
+	// FullPath is the pattern of the matched route.
+	FullPath  string
+	ClientIP  string
+	RequestID string
+	Header    http.Header
+}
+
+// redactedHeaders are the headers whose values are replaced by RedactedValue in the request snapshots.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Auth-Token"}
+
+// RedactedValue replaces the sensitive values of the request snapshots.
+const RedactedValue = "[redacted]"
+
+func newRequestSnapshot(c *Context) RequestSnapshot {
+	header := c.Request.Header.Clone()
+	for _, name := range redactedHeaders {
+		if _, ok := header[name]; ok {
+			header[name] = []string{RedactedValue}
+		}
+	}
+	return RequestSnapshot{
+		Method:    c.Request.Method,
+		URL:       c.Request.URL.String(),
+		FullPath:  c.FullPath(),
+		ClientIP:  c.ClientIP(),
+		RequestID: c.RequestID(),
+		Header:    header,
+	}
+}
+
+// AddPanicReporter registers reporters called by every Recovery middleware of the engine.
+func (engine *Engine) AddPanicReporter(reporters ...PanicReporter) {
+	engine.panicReporters = append(engine.panicReporters, reporters...)
+}
+
+func reportPanic(c *Context, reporters []PanicReporter, value interface{}, stack []byte) {
+	report := PanicReport{
+		Value:   value,
+		Stack:   stack,
+		Time:    time.Now(),
+		Request: newRequestSnapshot(c),
+		Handler: c.HandlerName(),
+	}
+	ctx := c.Request.Context()
+	for _, reporter := range c.engine.panicReporters {
+		reporter.ReportPanic(ctx, report)
+	}
+	for _, reporter := range reporters {
+		reporter.ReportPanic(ctx, report)
+	}
+}
+
+// RecoveryConfig defines the config for Recovery middleware.
+type RecoveryConfig struct {
+	// Output is a writer where the panics are logged.
+	// Optional. The panics are not logged when nil.
+	Output io.Writer
+
+	// Reporters receive the panics, after the reporters of the engine.
+	// Optional.
+	Reporters []PanicReporter
+
+	// Responses handles the panics by content type, negotiated with the Accept
+	// header of the request, e.g. to render a JSON error to API clients and an
+	// HTML page to browsers.
+	// Optional.
+	Responses map[string]RecoveryFunc
+
+	// Handle handles the panics when no content type of Responses is accepted.
+	// Optional. Default aborts with 500.
+	Handle RecoveryFunc
+}
+
+func (conf RecoveryConfig) handler() RecoveryFunc {
+	handle := conf.Handle
+	if handle == nil {
+		handle = defaultHandleRecovery
+	}
+	if len(conf.Responses) == 0 {
+		return handle
+	}
+	offered := make([]string, 0, len(conf.Responses))
+	for contentType := range conf.Responses {
+		offered = append(offered, contentType)
+	}
+	sort.Strings(offered)
+	return func(c *Context, err interface{}) {
+		if c.requestHeader("Accept") != "" {
+			if response, ok := conf.Responses[c.NegotiateFormat(offered...)]; ok {
+				response(c, err)
+				return
+			}
+		}
+		handle(c, err)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoveryReporters(t *testing.T) {
+	var reports []PanicReport
+	var order []string
+	router := New()
+	router.AddPanicReporter(PanicReporterFunc(func(_ context.Context, report PanicReport) {
+		order = append(order, "engine")
+		reports = append(reports, report)
+	}))
+	router.Use(RequestID(), RecoveryWithConfig(RecoveryConfig{
+		Reporters: []PanicReporter{PanicReporterFunc(func(context.Context, PanicReport) {
+			order = append(order, "config")
+		})},
+	}))
+	router.GET("/users/:id", func(c *Context) { panic("boom") })
+
+	w := performRequest(router, "GET", "/users/1?q=2",
+		header{"Authorization", "Bearer secret"},
+		header{"Cookie", "session=secret"},
+		header{"X-Request-ID", "abc"},
+		header{"User-Agent", "test"})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, []string{"engine", "config"}, order)
+	report := reports[0]
+	assert.Equal(t, "boom", report.Value)
+	assert.Contains(t, string(report.Stack), "recovery_report_test.go")
+	assert.False(t, report.Time.IsZero())
+	assert.Equal(t, RequestSnapshot{
+		Method:    "GET",
+		URL:       "/users/1?q=2",
+		FullPath:  "/users/:id",
+		ClientIP:  "192.0.2.1",
+		RequestID: "abc",
+		Header: http.Header{
+			"Authorization": {RedactedValue},
+			"Cookie":        {RedactedValue},
+			"X-Request-Id":  {"abc"},
+			"User-Agent":    {"test"},
+		},
+	}, report.Request)
+}
+
+func TestRecoveryReportersWithDefaultRecovery(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	reported := false
+	router := New()
+	router.AddPanicReporter(PanicReporterFunc(func(context.Context, PanicReport) { reported = true }))
+	router.Use(RecoveryWithWriter(buffer))
+	router.GET("/", func(c *Context) { panic("boom") })
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.True(t, reported)
+	assert.Contains(t, buffer.String(), "panic recovered")
+	assert.Contains(t, buffer.String(), "boom")
+}
+
+func TestRecoveryResponses(t *testing.T) {
+	router := New()
+	router.Use(RecoveryWithConfig(RecoveryConfig{
+		Responses: map[string]RecoveryFunc{
+			MIMEJSON: func(c *Context, err interface{}) {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, H{"error": err})
+			},
+			MIMEHTML: func(c *Context, err interface{}) {
+				c.Data(http.StatusInternalServerError, MIMEHTML, []byte("<h1>oops</h1>"))
+				c.Abort()
+			},
+		},
+		Handle: func(c *Context, err interface{}) {
+			c.String(http.StatusServiceUnavailable, "unavailable")
+			c.Abort()
+		},
+	}))
+	router.GET("/", func(c *Context) { panic("boom") })
+
+	w := performRequest(router, "GET", "/", header{"Accept", "application/json"})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, `{"error":"boom"}`, w.Body.String())
+
+	w = performRequest(router, "GET", "/", header{"Accept", "text/html,application/xhtml+xml"})
+	assert.Equal(t, "<h1>oops</h1>", w.Body.String())
+
+	w = performRequest(router, "GET", "/", header{"Accept", "image/png"})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = performRequest(router, "GET", "/")
+	assert.Equal(t, "unavailable", w.Body.String())
+}
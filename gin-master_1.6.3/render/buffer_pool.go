@@ -0,0 +1,184 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// minBufferClass is the capacity of the buffers of the smallest size class.
+	minBufferClass = 1 << 10
+	// bufferClasses is the number of size classes, the largest one holds 1 MB buffers.
+	bufferClasses = 11
+)
+
+// BufferPool pools the buffers the renders write the output to before sending it, e.g.
+// ETag and ContentLength, by size class: a buffer starts in the smallest class and
+// moves to the next class fitting the output when it grows, so that the large
+// responses reuse large buffers while the small ones never hold them. The output
+// larger than the largest class is written to buffers which aren't pooled.
+// A nil *BufferPool allocates the buffers without pooling them.
+type BufferPool struct {
+	classes   [bufferClasses]bufferClass
+	oversized uint64
+}
+
+type bufferClass struct {
+	gets uint64
+	hits uint64
+	pool sync.Pool
+}
+
+// BufferPoolStats are the counts of the buffers taken from a BufferPool.
+type BufferPoolStats struct {
+	// Classes are the counts by size class, from the smallest.
+	Classes []BufferClassStats
+	// Oversized is the number of buffers larger than the largest class.
+	Oversized uint64
+}
+
+// BufferClassStats are the counts of the buffers of a size class.
+type BufferClassStats struct {
+	// Size is the capacity of the buffers of the class.
+	Size int
+	// Gets is the number of buffers taken from the class.
+	Gets uint64
+	// Hits is the number of those which were reused instead of allocated.
+	Hits uint64
+}
+
+// HitRate returns the share of the buffers taken from the pool which were reused, 0
+// when none was taken.
+func (s BufferPoolStats) HitRate() float64 {
+	var gets, hits uint64
+	for _, class := range s.Classes {
+		gets += class.Gets
+		hits += class.Hits
+	}
+	if gets == 0 {
+		return 0
+	}
+	return float64(hits) / float64(gets)
+}
+
+// Stats returns the counts of the buffers taken from the pool so far.
+func (p *BufferPool) Stats() BufferPoolStats {
+	stats := BufferPoolStats{Classes: make([]BufferClassStats, bufferClasses)}
+	if p == nil {
+		for i := range stats.Classes {
+			stats.Classes[i].Size = minBufferClass << i
+		}
+		return stats
+	}
+	for i := range p.classes {
+		stats.Classes[i] = BufferClassStats{
+			Size: minBufferClass << i,
+			Gets: atomic.LoadUint64(&p.classes[i].gets),
+			Hits: atomic.LoadUint64(&p.classes[i].hits),
+		}
+	}
+	stats.Oversized = atomic.LoadUint64(&p.oversized)
+	return stats
+}
+
+// Get returns an empty buffer of the smallest class, to be released once its bytes
+// are no longer used.
+func (p *BufferPool) Get() *Buffer {
+	return &Buffer{pool: p, b: p.get(0)}
+}
+
+// get returns an empty buffer of the class.
+func (p *BufferPool) get(class int) *[]byte {
+	if p != nil {
+		c := &p.classes[class]
+		atomic.AddUint64(&c.gets, 1)
+		if b, _ := c.pool.Get().(*[]byte); b != nil {
+			atomic.AddUint64(&c.hits, 1)
+			*b = (*b)[:0]
+			return b
+		}
+	}
+	b := make([]byte, 0, minBufferClass<<class)
+	return &b
+}
+
+// put returns the buffer to its class, unless it's oversized.
+func (p *BufferPool) put(b *[]byte) {
+	if p == nil {
+		return
+	}
+	if class := bufferClassOf(cap(*b)); class >= 0 {
+		p.classes[class].pool.Put(b)
+	}
+}
+
+// bufferClassOf returns the class of the buffers of the capacity, -1 for the ones not
+// pooled.
+func bufferClassOf(capacity int) int {
+	for class := 0; class < bufferClasses; class++ {
+		if capacity == minBufferClass<<class {
+			return class
+		}
+	}
+	return -1
+}
+
+// Buffer is a buffer of a BufferPool.
+type Buffer struct {
+	pool *BufferPool
+	b    *[]byte
+}
+
+// Write appends data to the buffer, moving it to a larger class when it doesn't fit.
+func (b *Buffer) Write(data []byte) (int, error) {
+	if n := len(*b.b) + len(data); n > cap(*b.b) {
+		b.grow(n)
+	}
+	*b.b = append(*b.b, data...)
+	return len(data), nil
+}
+
+// grow moves the bytes to a buffer of the smallest class fitting n bytes, or to an
+// oversized one, which grows as usual.
+func (b *Buffer) grow(n int) {
+	current := bufferClassOf(cap(*b.b))
+	if current < 0 {
+		return
+	}
+	for class := current + 1; class < bufferClasses; class++ {
+		if minBufferClass<<class >= n {
+			grown := b.pool.get(class)
+			*grown = append(*grown, *b.b...)
+			b.pool.put(b.b)
+			b.b = grown
+			return
+		}
+	}
+	if b.pool != nil {
+		atomic.AddUint64(&b.pool.oversized, 1)
+	}
+	grown := make([]byte, len(*b.b), 2*n)
+	copy(grown, *b.b)
+	b.pool.put(b.b)
+	b.b = &grown
+}
+
+// Bytes returns the bytes written to the buffer, valid until Release.
+func (b *Buffer) Bytes() []byte {
+	return *b.b
+}
+
+// Len returns the number of bytes written to the buffer.
+func (b *Buffer) Len() int {
+	return len(*b.b)
+}
+
+// Release returns the buffer to its pool, it must not be used after.
+func (b *Buffer) Release() {
+	b.pool.put(b.b)
+	b.b = nil
+}
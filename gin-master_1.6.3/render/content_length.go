@@ -0,0 +1,63 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// ContentLength buffers the output of Body up to Limit bytes. When the whole
+// output fits, it's sent with a Content-Length header instead of the chunked
+// transfer encoding; otherwise it's passed through as soon as it exceeds Limit.
+type ContentLength struct {
+	Body  Render
+	Limit int
+	// Buffers holds the buffer of the output, nil to allocate it.
+	Buffers *BufferPool
+}
+
+// Render (ContentLength) renders Body and sets the Content-Length header when the output is small enough.
+func (r ContentLength) Render(w http.ResponseWriter) error {
+	sw := &sizingWriter{ResponseWriter: w, limit: r.Limit, buf: r.Buffers.Get()}
+	defer sw.buf.Release()
+	if err := r.Body.Render(sw); err != nil {
+		return err
+	}
+	if sw.overflow {
+		return nil
+	}
+	if w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(sw.buf.Len()))
+	}
+	_, err := w.Write(sw.buf.Bytes())
+	return err
+}
+
+// WriteContentType (ContentLength) writes the ContentType of the wrapped render.
+func (r ContentLength) WriteContentType(w http.ResponseWriter) {
+	r.Body.WriteContentType(w)
+}
+
+type sizingWriter struct {
+	http.ResponseWriter
+	limit    int
+	buf      *Buffer
+	overflow bool
+}
+
+func (w *sizingWriter) Write(data []byte) (int, error) {
+	if w.overflow {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.buf.Len()+len(data) <= w.limit {
+		return w.buf.Write(data)
+	}
+	w.overflow = true
+	if _, err := w.ResponseWriter.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return w.ResponseWriter.Write(data)
+}
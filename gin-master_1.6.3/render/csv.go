@@ -0,0 +1,111 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"context"
+	"encoding/csv"
+	"mime"
+	"net/http"
+)
+
+// CSV writes the rows produced by Rows as comma separated values, one row at a
+// time, so large exports never have to be held in memory at once.
+type CSV struct {
+	// Context stops the export when it is done, usually the request context.
+	Context context.Context
+	// Headers is written as the first record when not empty.
+	Headers []string
+	// Rows calls yield for each record, it must stop as soon as yield returns false.
+	Rows func(yield func(row []string) bool)
+	// BOM prepends the UTF-8 byte order mark, which Excel needs to detect the encoding.
+	BOM bool
+	// Comma is the field delimiter, ',' when zero.
+	Comma rune
+	// Filename, when not empty, makes the response an attachment with this name.
+	Filename string
+	// FlushEvery flushes the writer after that many rows, zero only flushes at the end.
+	FlushEvery int
+}
+
+var csvContentType = []string{"text/csv; charset=utf-8"}
+
+var csvBOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Render (CSV) writes the headers and the rows, quoting the fields when needed,
+// and flushes periodically. If the context is done the export stops early.
+func (r CSV) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	if r.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": r.Filename}))
+	}
+	if r.BOM {
+		if _, err = w.Write(csvBOM); err != nil {
+			return err
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if r.Comma != 0 {
+		writer.Comma = r.Comma
+	}
+	if len(r.Headers) > 0 {
+		if err = writer.Write(r.Headers); err != nil {
+			return err
+		}
+	}
+
+	flusher, _ := w.(http.Flusher)
+	count := 0
+	if r.Rows != nil {
+		r.Rows(func(row []string) bool {
+			if r.Context != nil && r.Context.Err() != nil {
+				return false
+			}
+			if err = writer.Write(row); err != nil {
+				return false
+			}
+			count++
+			if r.FlushEvery > 0 && count%r.FlushEvery == 0 {
+				writer.Flush()
+				if err = writer.Error(); err != nil {
+					return false
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return err
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// WriteContentType (CSV) writes CSV ContentType.
+func (r CSV) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, csvContentType)
+}
+
+// SliceRows adapts records already in memory to the Rows function of CSV.
+func SliceRows(rows [][]string) func(yield func(row []string) bool) {
+	return func(yield func(row []string) bool) {
+		for _, row := range rows {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
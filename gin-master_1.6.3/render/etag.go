@@ -0,0 +1,77 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// ETag buffers the output of Body, tags it with a strong ETag computed over the
+// bytes and answers 304 Not Modified, without a body, when IfNoneMatch matches.
+// An ETag header already set by the handler is used as is.
+type ETag struct {
+	Body Render
+	// IfNoneMatch is the If-None-Match header of the request.
+	IfNoneMatch string
+	// Buffers holds the buffer of the output, nil to allocate it.
+	Buffers *BufferPool
+}
+
+// Render (ETag) renders Body, sets the ETag header and writes the body unless it's not modified.
+func (r ETag) Render(w http.ResponseWriter) error {
+	bw := &bufferedWriter{ResponseWriter: w, buf: r.Buffers.Get()}
+	defer bw.buf.Release()
+	if err := r.Body.Render(bw); err != nil {
+		return err
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(bw.buf.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+	}
+	if ETagMatch(r.IfNoneMatch, etag) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	_, err := w.Write(bw.buf.Bytes())
+	return err
+}
+
+// WriteContentType (ETag) writes the ContentType of the wrapped render.
+func (r ETag) WriteContentType(w http.ResponseWriter) {
+	r.Body.WriteContentType(w)
+}
+
+// ETagMatch reports whether the If-None-Match header value matches etag, using
+// the weak comparison RFC 7232 requires for If-None-Match.
+func ETagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStreaming reports whether r writes its output progressively, in which case
+// it must not be buffered, e.g. to compute an ETag.
+func IsStreaming(r Render) bool {
+	switch r.(type) {
+	case JSONStream, NDJSON, CSV, XLSX, HTMLStream, Reader, Redirect:
+		return true
+	}
+	return false
+}
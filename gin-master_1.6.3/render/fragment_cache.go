@@ -0,0 +1,135 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sync"
+	"time"
+)
+
+// FragmentFuncName is the name of the template function caching a fragment:
+//     {{ cache "sidebar" "5m" "sidebar.tmpl" . }}
+// renders the template "sidebar.tmpl" with the given data once, then reuses its
+// output under the key "sidebar" for 5 minutes. Go templates can't define new
+// block actions, so the cached section is a named template of its own.
+const FragmentFuncName = "cache"
+
+// FragmentStore stores rendered template fragments.
+type FragmentStore interface {
+	// Get returns the fragment stored under key, if it didn't expire.
+	Get(key string) (template.HTML, bool)
+	// Set stores the fragment under key for ttl.
+	Set(key string, fragment template.HTML, ttl time.Duration)
+}
+
+// FragmentFuncs returns the template functions caching fragments in the store,
+// which render the fragments with the templates of the current HTMLRender. Both
+// are given as functions since the templates are parsed with the functions.
+// A nil store disables the cache.
+func FragmentFuncs(store func() FragmentStore, html func() HTMLRender) template.FuncMap {
+	return template.FuncMap{
+		FragmentFuncName: func(key string, ttl interface{}, name string, data interface{}) (template.HTML, error) {
+			duration, err := fragmentTTL(ttl)
+			if err != nil {
+				return "", err
+			}
+			s := store()
+			if s != nil {
+				if fragment, ok := s.Get(key); ok {
+					return fragment, nil
+				}
+			}
+
+			templ := templateSet(html())
+			if templ == nil {
+				return "", fmt.Errorf("html/template: no template to render fragment %q", key)
+			}
+			var buf bytes.Buffer
+			if err = templ.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			fragment := template.HTML(buf.String())
+			if s != nil {
+				s.Set(key, fragment, duration)
+			}
+			return fragment, nil
+		},
+	}
+}
+
+func fragmentTTL(ttl interface{}) (time.Duration, error) {
+	switch v := ttl.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return time.ParseDuration(v)
+	case int:
+		return time.Duration(v) * time.Second, nil
+	default:
+		return 0, fmt.Errorf("invalid fragment ttl %v, expected a duration, a string or seconds", ttl)
+	}
+}
+
+// templateSet returns the templates rendered by r.
+func templateSet(r HTMLRender) *template.Template {
+	switch r := r.(type) {
+	case HTMLProduction:
+		return r.Template
+	case HTMLDebug:
+		return r.loadTemplate()
+	case HTMLLocalized:
+		return templateSet(r.Renders[r.Fallback])
+	}
+	return nil
+}
+
+// MemoryFragmentStore is a FragmentStore keeping the fragments in memory.
+// Expired fragments are dropped when they are looked up.
+type MemoryFragmentStore struct {
+	mu        sync.RWMutex
+	fragments map[string]memoryFragment
+}
+
+type memoryFragment struct {
+	html    template.HTML
+	expires time.Time
+}
+
+// NewMemoryFragmentStore returns an empty MemoryFragmentStore.
+func NewMemoryFragmentStore() *MemoryFragmentStore {
+	return &MemoryFragmentStore{fragments: make(map[string]memoryFragment)}
+}
+
+// Get implements FragmentStore.
+func (s *MemoryFragmentStore) Get(key string) (template.HTML, bool) {
+	s.mu.RLock()
+	fragment, ok := s.fragments[key]
+	s.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(fragment.expires) {
+		s.Delete(key)
+		return "", false
+	}
+	return fragment.html, true
+}
+
+// Set implements FragmentStore.
+func (s *MemoryFragmentStore) Set(key string, fragment template.HTML, ttl time.Duration) {
+	s.mu.Lock()
+	s.fragments[key] = memoryFragment{html: fragment, expires: time.Now().Add(ttl)}
+	s.mu.Unlock()
+}
+
+// Delete removes the fragment stored under key, e.g. when its content changed.
+func (s *MemoryFragmentStore) Delete(key string) {
+	s.mu.Lock()
+	delete(s.fragments, key)
+	s.mu.Unlock()
+}
@@ -27,6 +27,8 @@ type HTMLRender interface {
 type HTMLProduction struct {
 	Template *template.Template
 	Delims   Delims
+
+	layouts *layoutCache
 }
 
 // HTMLDebug contains template delims and pattern and function with file list.
@@ -35,6 +37,7 @@ type HTMLDebug struct {
 	Glob    string
 	Delims  Delims
 	FuncMap template.FuncMap
+	reload  *templateReloader // set by WithReload, parses the templates on every instance when nil
 }
 
 // HTML contains template reference and its name with given interface object.
@@ -64,6 +67,13 @@ func (r HTMLDebug) Instance(name string, data interface{}) Render {
 	}
 }
 func (r HTMLDebug) loadTemplate() *template.Template {
+	if r.reload != nil {
+		return r.reload.get(r)
+	}
+	return r.parseTemplate()
+}
+
+func (r HTMLDebug) parseTemplate() *template.Template {
 	if r.FuncMap == nil {
 		r.FuncMap = template.FuncMap{}
 	}
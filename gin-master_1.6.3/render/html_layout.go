@@ -0,0 +1,143 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// LayoutContentBlock is the name of the block a layout renders the page into:
+//     <body>{{ block "content" . }}{{ end }}</body>
+const LayoutContentBlock = "content"
+
+// HTMLLayoutRender is implemented by the HTMLRenders which are able to compose
+// a page template into a layout template.
+//
+// The page template fills the LayoutContentBlock of the layout. Any other named
+// block of the layout can be overridden by the page defining a template called
+// "<page>:<block>", e.g. for the page "users.tmpl":
+//     {{ define "users.tmpl:title" }}Users{{ end }}
+// fills {{ block "title" . }}Default title{{ end }} of the layout. Since these are
+// regular templates, a single block can also be rendered alone, as a partial,
+// with c.HTML(code, "users.tmpl:title", data).
+type HTMLLayoutRender interface {
+	// InstanceWithLayout returns an HTML instance rendering name inside layout.
+	InstanceWithLayout(layout, name string, data interface{}) Render
+}
+
+// layoutCache caches the template sets composed by HTMLProduction.
+type layoutCache struct {
+	// base is a pristine clone of the production template. html/template refuses
+	// to clone a set once it has been executed, base never is.
+	base *template.Template
+	mu   sync.RWMutex
+	sets map[[2]string]*template.Template
+}
+
+// NewHTMLProduction returns an HTMLProduction which also supports layouts,
+// composing every (layout, page) pair once and caching the result.
+// It must be called before the template is executed.
+func NewHTMLProduction(templ *template.Template, delims Delims) HTMLProduction {
+	r := HTMLProduction{Template: templ, Delims: delims}
+	if base, err := templ.Clone(); err == nil {
+		r.layouts = &layoutCache{base: base, sets: make(map[[2]string]*template.Template)}
+	}
+	return r
+}
+
+func (c *layoutCache) get(layout, name string) (*template.Template, error) {
+	key := [2]string{layout, name}
+	c.mu.RLock()
+	set, ok := c.sets[key]
+	c.mu.RUnlock()
+	if ok {
+		return set, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if set, ok = c.sets[key]; ok {
+		return set, nil
+	}
+	base, err := c.base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if set, err = composeLayout(base, name); err != nil {
+		return nil, err
+	}
+	c.sets[key] = set
+	return set, nil
+}
+
+// composeLayout binds the page name and its "<name>:<block>" templates to the blocks
+// of the given set. The set must not have been executed yet.
+func composeLayout(set *template.Template, name string) (*template.Template, error) {
+	page := set.Lookup(name)
+	if page == nil || page.Tree == nil {
+		return nil, fmt.Errorf("html/template: %q is undefined", name)
+	}
+	if _, err := set.AddParseTree(LayoutContentBlock, page.Tree); err != nil {
+		return nil, err
+	}
+	prefix := name + ":"
+	for _, t := range set.Templates() {
+		if block := strings.TrimPrefix(t.Name(), prefix); block != t.Name() && t.Tree != nil {
+			if _, err := set.AddParseTree(block, t.Tree); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return set, nil
+}
+
+// HTMLLayout renders the page Name inside the template Layout.
+type HTMLLayout struct {
+	Template *template.Template
+	Layout   string
+	Name     string
+	Data     interface{}
+	// Err is returned by Render, it's set when the composition failed.
+	Err error
+}
+
+// InstanceWithLayout (HTMLProduction) returns an HTMLLayout instance which it realizes Render interface.
+func (r HTMLProduction) InstanceWithLayout(layout, name string, data interface{}) Render {
+	var (
+		set *template.Template
+		err error
+	)
+	if r.layouts != nil {
+		set, err = r.layouts.get(layout, name)
+	} else if set, err = r.Template.Clone(); err == nil {
+		set, err = composeLayout(set, name)
+	}
+	return HTMLLayout{Template: set, Layout: layout, Name: name, Data: data, Err: err}
+}
+
+// InstanceWithLayout (HTMLDebug) returns an HTMLLayout instance which it realizes Render interface.
+func (r HTMLDebug) InstanceWithLayout(layout, name string, data interface{}) Render {
+	// the composition modifies the set, it can't be the one shared by WithReload
+	set, err := composeLayout(r.parseTemplate(), name)
+	return HTMLLayout{Template: set, Layout: layout, Name: name, Data: data, Err: err}
+}
+
+// Render (HTMLLayout) executes the layout and writes its result with custom ContentType for response.
+func (r HTMLLayout) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	if r.Err != nil {
+		return r.Err
+	}
+	return r.Template.ExecuteTemplate(w, r.Layout, r.Data)
+}
+
+// WriteContentType (HTMLLayout) writes HTML ContentType.
+func (r HTMLLayout) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, htmlContentType)
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import "sort"
+
+// HTMLLocaleRender is implemented by the HTMLRenders holding one template tree per locale.
+type HTMLLocaleRender interface {
+	HTMLRender
+	// Locales returns the available locales, the fallback one first.
+	Locales() []string
+	// InstanceForLocale returns an HTML instance of the template name of the locale,
+	// or of the fallback locale when the locale doesn't have it.
+	InstanceForLocale(locale, name string, data interface{}) Render
+}
+
+// HTMLLocalized renders the templates of the locale negotiated for the request.
+type HTMLLocalized struct {
+	// Renders holds the HTMLRender of each locale, keyed by lowercase language tag.
+	Renders map[string]HTMLRender
+	// Fallback is the locale used when no other one matches.
+	Fallback string
+}
+
+// Instance (HTMLLocalized) returns an HTML instance of the fallback locale.
+func (r HTMLLocalized) Instance(name string, data interface{}) Render {
+	return r.InstanceForLocale(r.Fallback, name, data)
+}
+
+// Locales (HTMLLocalized) returns the locales in alphabetical order, after the fallback one.
+func (r HTMLLocalized) Locales() []string {
+	locales := make([]string, 0, len(r.Renders))
+	for locale := range r.Renders {
+		if locale != r.Fallback {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales)
+	return append([]string{r.Fallback}, locales...)
+}
+
+// InstanceForLocale (HTMLLocalized) returns an HTML instance of the locale, falling back
+// to the fallback locale when the locale or its template doesn't exist.
+func (r HTMLLocalized) InstanceForLocale(locale, name string, data interface{}) Render {
+	if htmlRender, ok := r.Renders[locale]; ok && locale != r.Fallback {
+		if templ := templateSet(htmlRender); templ != nil && templ.Lookup(name) != nil {
+			return htmlRender.Instance(name, data)
+		}
+	}
+	return r.Renders[r.Fallback].Instance(name, data)
+}
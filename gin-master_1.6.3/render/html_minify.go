@@ -0,0 +1,136 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// MinifiedHTML renders HTML into a buffer and writes it minified, see MinifyHTML.
+type MinifiedHTML struct {
+	HTML Render
+	// Buffers holds the buffer of the output, nil to allocate it.
+	Buffers *BufferPool
+}
+
+// Render (MinifiedHTML) executes the wrapped render and writes its minified result.
+func (r MinifiedHTML) Render(w http.ResponseWriter) error {
+	bw := &bufferedWriter{ResponseWriter: w, buf: r.Buffers.Get()}
+	defer bw.buf.Release()
+	if err := r.HTML.Render(bw); err != nil {
+		return err
+	}
+	_, err := w.Write(MinifyHTML(bw.buf.Bytes()))
+	return err
+}
+
+// WriteContentType (MinifiedHTML) writes the ContentType of the wrapped render.
+func (r MinifiedHTML) WriteContentType(w http.ResponseWriter) {
+	r.HTML.WriteContentType(w)
+}
+
+type bufferedWriter struct {
+	http.ResponseWriter
+	buf *Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+// rawTextTags are the elements whose content is kept untouched by MinifyHTML.
+var rawTextTags = [][]byte{[]byte("pre"), []byte("textarea"), []byte("script"), []byte("style")}
+
+var (
+	commentOpen  = []byte("<!--")
+	commentClose = []byte("-->")
+	// conditional comments are markup for old browsers, flush markers are used by HTMLStream.
+	keptComments = [][]byte{[]byte("<!--[if"), flushMarkerBytes}
+)
+
+// MinifyHTML collapses the runs of whitespace of src into a single space, or a
+// single newline when the run contains one, and strips the comments. The content
+// of pre, textarea, script and style elements is left as is.
+func MinifyHTML(src []byte) []byte {
+	dst := make([]byte, 0, len(src))
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '<' && bytes.HasPrefix(src[i:], commentOpen):
+			end := bytes.Index(src[i+len(commentOpen):], commentClose)
+			if end < 0 {
+				return append(dst, src[i:]...)
+			}
+			end += i + len(commentOpen) + len(commentClose)
+			if keepComment(src[i:end]) {
+				dst = append(dst, src[i:end]...)
+			}
+			i = end
+		case c == '<':
+			end := rawTextEnd(src, i)
+			dst = append(dst, src[i:end]...)
+			if end == i {
+				dst = append(dst, c)
+				end++
+			}
+			i = end
+		case isHTMLSpace(c):
+			space := byte(' ')
+			for ; i < len(src) && isHTMLSpace(src[i]); i++ {
+				if src[i] == '\n' {
+					space = '\n'
+				}
+			}
+			dst = append(dst, space)
+		default:
+			dst = append(dst, c)
+			i++
+		}
+	}
+	return dst
+}
+
+func keepComment(comment []byte) bool {
+	for _, prefix := range keptComments {
+		if bytes.HasPrefix(comment, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rawTextEnd returns the index of the closing tag of the raw text element
+// opening at src[i], or i when there is none.
+func rawTextEnd(src []byte, i int) int {
+	for _, tag := range rawTextTags {
+		if !hasTagName(src[i+1:], tag) {
+			continue
+		}
+		for j := i + 1 + len(tag); j+1 < len(src); j++ {
+			if src[j] == '<' && src[j+1] == '/' && hasTagName(src[j+2:], tag) {
+				return j
+			}
+		}
+		return len(src)
+	}
+	return i
+}
+
+// hasTagName reports whether b starts with the tag name, case insensitively.
+func hasTagName(b, name []byte) bool {
+	if len(b) < len(name) || !bytes.EqualFold(b[:len(name)], name) {
+		return false
+	}
+	if len(b) == len(name) {
+		return true
+	}
+	next := b[len(name)]
+	return next == '>' || next == '/' || isHTMLSpace(next)
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
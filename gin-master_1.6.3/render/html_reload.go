@@ -0,0 +1,78 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// templateReloader keeps the templates of an HTMLDebug parsed until one of
+// their files is modified, added or removed.
+type templateReloader struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	checked time.Time
+	stamps  map[string]time.Time
+	templ   *template.Template
+}
+
+// WithReload returns a copy of the HTMLDebug which parses its templates once and
+// parses them again when their files change, instead of on every instance. The
+// files are checked at most once per interval, when an instance is requested.
+func (r HTMLDebug) WithReload(interval time.Duration) HTMLDebug {
+	r.reload = &templateReloader{interval: interval}
+	return r
+}
+
+func (t *templateReloader) get(r HTMLDebug) *template.Template {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if t.templ != nil && now.Sub(t.checked) < t.interval {
+		return t.templ
+	}
+	t.checked = now
+	stamps := r.fileStamps()
+	if t.templ != nil && sameStamps(t.stamps, stamps) {
+		return t.templ
+	}
+	// parse before swapping, a template which doesn't parse panics and the previous set stays
+	templ := r.parseTemplate()
+	t.templ, t.stamps = templ, stamps
+	return templ
+}
+
+// fileStamps returns the modification time of the template files.
+func (r HTMLDebug) fileStamps() map[string]time.Time {
+	files := r.Files
+	if len(files) == 0 && r.Glob != "" {
+		files, _ = filepath.Glob(r.Glob)
+	}
+	stamps := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = info.ModTime()
+		}
+	}
+	return stamps
+}
+
+func sameStamps(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, modTime := range a {
+		if other, ok := b[file]; !ok || !other.Equal(modTime) {
+			return false
+		}
+	}
+	return true
+}
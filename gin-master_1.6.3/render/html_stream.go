@@ -0,0 +1,92 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+)
+
+// FlushFuncName is the name of the template function marking a flush point:
+//     <head>...</head>{{ flush }}<body>...
+// Everything rendered before a flush point is sent to the client right away
+// when the template is rendered by HTMLStream, e.g. to let the browser fetch
+// the stylesheets while the rest of the page is being computed.
+const FlushFuncName = "flush"
+
+// flushMarker is what the flush function outputs. HTMLStream cuts the output on
+// it, any other render leaves it in the page as an HTML comment.
+const flushMarker = "<!--gin:flush-->"
+
+var flushMarkerBytes = []byte(flushMarker)
+
+var errStreamAborted = errors.New("html stream aborted: client disconnected")
+
+// StreamFuncs returns the template functions needed by HTMLStream. They must be
+// registered before the templates are parsed; the engine does it by itself when
+// templates are loaded with LoadHTMLGlob or LoadHTMLFiles.
+func StreamFuncs() template.FuncMap {
+	return template.FuncMap{
+		FlushFuncName: func() template.HTML { return flushMarker },
+	}
+}
+
+// HTMLStream renders an HTML template and flushes the response at every flush
+// point. Rendering stops as soon as the context is done.
+type HTMLStream struct {
+	// Context is usually the request context.
+	Context context.Context
+	// HTML is the render to stream, e.g. the result of HTMLRender.Instance.
+	HTML Render
+}
+
+// Render (HTMLStream) executes the template and flushes its result at every flush point.
+// When the client goes away the rendering is aborted and nil is returned.
+func (r HTMLStream) Render(w http.ResponseWriter) error {
+	fw := &flushWriter{ResponseWriter: w, ctx: r.Context}
+	fw.flusher, _ = w.(http.Flusher)
+	err := r.HTML.Render(fw)
+	if errors.Is(err, errStreamAborted) {
+		return nil
+	}
+	return err
+}
+
+// WriteContentType (HTMLStream) writes the ContentType of the wrapped render.
+func (r HTMLStream) WriteContentType(w http.ResponseWriter) {
+	r.HTML.WriteContentType(w)
+}
+
+type flushWriter struct {
+	http.ResponseWriter
+	ctx     context.Context
+	flusher http.Flusher
+}
+
+func (w *flushWriter) Write(data []byte) (n int, err error) {
+	for {
+		i := bytes.Index(data, flushMarkerBytes)
+		if i < 0 {
+			m, err := w.ResponseWriter.Write(data)
+			return n + m, err
+		}
+		m, err := w.ResponseWriter.Write(data[:i])
+		n += m
+		if err != nil {
+			return n, err
+		}
+		if w.ctx != nil && w.ctx.Err() != nil {
+			return n, errStreamAborted
+		}
+		if w.flusher != nil {
+			w.flusher.Flush()
+		}
+		n += len(flushMarkerBytes)
+		data = data[i+len(flushMarkerBytes):]
+	}
+}
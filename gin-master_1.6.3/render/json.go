@@ -19,6 +19,13 @@ type JSON struct {
 	Data interface{}
 }
 
+// ScratchJSON is JSON encoding the given interface object in Scratch, whose grown buffer
+// is kept for the next render, instead of a new slice.
+type ScratchJSON struct {
+	Data    interface{}
+	Scratch *bytes.Buffer
+}
+
 // IndentedJSON contains the given interface object.
 type IndentedJSON struct {
 	Data interface{}
@@ -74,6 +81,24 @@ func WriteJSON(w http.ResponseWriter, obj interface{}) error {
 	return err
 }
 
+// Render (ScratchJSON) writes data with custom ContentType.
+func (r ScratchJSON) Render(w http.ResponseWriter) error {
+	writeContentType(w, jsonContentType)
+	r.Scratch.Reset()
+	defer r.Scratch.Reset()
+	if err := json.NewEncoder(r.Scratch).Encode(r.Data); err != nil {
+		panic(err)
+	}
+	// Encode terminates each value with a newline, Marshal does not
+	_, err := w.Write(bytes.TrimSuffix(r.Scratch.Bytes(), []byte("\n")))
+	return err
+}
+
+// WriteContentType (ScratchJSON) writes JSON ContentType.
+func (r ScratchJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
 // Render (IndentedJSON) marshals the given interface object and writes it with custom ContentType.
 func (r IndentedJSON) Render(w http.ResponseWriter) error {
 	r.WriteContentType(w)
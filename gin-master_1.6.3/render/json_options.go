@@ -0,0 +1,235 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// NonFiniteMode tells how NaN and ±Inf floats, which JSON can't represent, are encoded.
+type NonFiniteMode uint8
+
+const (
+	// NonFiniteError fails the render, like encoding/json does.
+	NonFiniteError NonFiniteMode = iota
+	// NonFiniteNull encodes them as null.
+	NonFiniteNull
+	// NonFiniteString encodes them as the strings "NaN", "+Inf" and "-Inf".
+	NonFiniteString
+)
+
+// JSONOptions controls how OptionsJSON encodes its data.
+type JSONOptions struct {
+	// EscapeHTML replaces <, > and & with their unicode escapes, like JSON does.
+	EscapeHTML bool
+	// Indent indents the output with the given string when not empty.
+	Indent string
+	// Prefix is written before the output when it is an array, like SecureJSON does.
+	Prefix string
+	// ASCII escapes every non ASCII character, like AsciiJSON does.
+	ASCII bool
+	// SortKeys sorts the keys of every object, struct fields included.
+	SortKeys bool
+	// NonFinite selects the encoding of NaN and ±Inf.
+	NonFinite NonFiniteMode
+	// ContentType overrides the Content-Type, e.g. to drop or change the charset.
+	ContentType string
+}
+
+// OptionsJSON contains the given interface object and the options used to encode it.
+type OptionsJSON struct {
+	Data    interface{}
+	Options JSONOptions
+}
+
+// Render (OptionsJSON) encodes the given interface object according to the options and writes it with custom ContentType.
+func (r OptionsJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := r.encode()
+	if err != nil {
+		return err
+	}
+	if r.Options.Prefix != "" && bytes.HasPrefix(jsonBytes, jsonArrayOpen) {
+		if _, err = w.Write([]byte(r.Options.Prefix)); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(jsonBytes)
+	return err
+}
+
+// WriteContentType (OptionsJSON) writes JSON ContentType.
+func (r OptionsJSON) WriteContentType(w http.ResponseWriter) {
+	if r.Options.ContentType != "" {
+		writeContentType(w, []string{r.Options.ContentType})
+		return
+	}
+	if r.Options.ASCII {
+		writeContentType(w, jsonAsciiContentType)
+		return
+	}
+	writeContentType(w, jsonContentType)
+}
+
+func (r OptionsJSON) encode() ([]byte, error) {
+	data := r.Data
+	jsonBytes, err := r.marshal(data)
+	if err != nil && r.Options.NonFinite != NonFiniteError {
+		// only pay for the conversion when encoding failed, which is what NaN and ±Inf do
+		data = replaceNonFinite(reflect.ValueOf(data), r.Options.NonFinite)
+		jsonBytes, err = r.marshal(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Options.SortKeys {
+		// decoding into interface{} turns structs into maps, which are encoded sorted
+		var generic interface{}
+		decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+		decoder.UseNumber()
+		if err = decoder.Decode(&generic); err != nil {
+			return nil, err
+		}
+		if jsonBytes, err = r.marshal(generic); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Options.ASCII {
+		var buffer bytes.Buffer
+		for _, r := range string(jsonBytes) {
+			if r >= 128 {
+				fmt.Fprintf(&buffer, "\\u%04x", int64(r))
+				continue
+			}
+			buffer.WriteRune(r)
+		}
+		jsonBytes = buffer.Bytes()
+	}
+	return jsonBytes, nil
+}
+
+func (r OptionsJSON) marshal(data interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(r.Options.EscapeHTML)
+	if r.Options.Indent != "" {
+		encoder.SetIndent("", r.Options.Indent)
+	}
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+	// Encode terminates each value with a newline, Marshal does not
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// replaceNonFinite returns a copy of v made of maps, slices and plain values,
+// where NaN and ±Inf are replaced according to mode. Struct fields are named
+// after their json tag, values implementing json.Marshaler are kept as is.
+func replaceNonFinite(v reflect.Value, mode NonFiniteMode) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return v.Interface()
+		}
+		if mode == NonFiniteNull {
+			return nil
+		}
+		switch {
+		case math.IsNaN(f):
+			return "NaN"
+		case f > 0:
+			return "+Inf"
+		default:
+			return "-Inf"
+		}
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return replaceNonFinite(v.Elem(), mode)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && (v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8) {
+			return v.Interface()
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = replaceNonFinite(v.Index(i), mode)
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v.Interface()
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = replaceNonFinite(iter.Value(), mode)
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		replaceNonFiniteFields(v, mode, out)
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+func replaceNonFiniteFields(v reflect.Value, mode NonFiniteMode, out map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.IndexByte(tag, ','); idx >= 0 {
+			name, opts = tag[:idx], tag[idx:]
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				replaceNonFiniteFields(fv, mode, out)
+				continue
+			}
+		}
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if strings.Contains(opts, "omitempty") && fv.IsZero() {
+			continue
+		}
+		out[name] = replaceNonFinite(fv, mode)
+	}
+}
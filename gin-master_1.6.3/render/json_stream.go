@@ -0,0 +1,95 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// JSONStream writes the values produced by Items as a JSON array, one element
+// at a time, so large result sets never have to be held in memory at once.
+type JSONStream struct {
+	// Context stops the stream when it is done, usually the request context.
+	Context context.Context
+	// Items calls yield for each element, it must stop as soon as yield returns false.
+	Items func(yield func(v interface{}) bool)
+	// FlushEvery flushes the writer after that many elements, zero only flushes at the end.
+	FlushEvery int
+}
+
+var (
+	jsonArrayOpen  = []byte("[")
+	jsonArrayComma = []byte(",")
+	jsonArrayClose = []byte("]")
+)
+
+// Render (JSONStream) writes the elements as a JSON array and flushes periodically.
+// If the context is done the stream stops early and the array is left open,
+// since nobody is listening anymore.
+func (r JSONStream) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	if _, err = w.Write(jsonArrayOpen); err != nil {
+		return err
+	}
+
+	flusher, _ := w.(http.Flusher)
+	done := false
+	count := 0
+	r.Items(func(v interface{}) bool {
+		if r.Context != nil && r.Context.Err() != nil {
+			done = true
+			return false
+		}
+		jsonBytes, e := json.Marshal(v)
+		if e != nil {
+			err = e
+			return false
+		}
+		if count > 0 {
+			if _, err = w.Write(jsonArrayComma); err != nil {
+				return false
+			}
+		}
+		if _, err = w.Write(jsonBytes); err != nil {
+			return false
+		}
+		count++
+		if flusher != nil && r.FlushEvery > 0 && count%r.FlushEvery == 0 {
+			flusher.Flush()
+		}
+		return true
+	})
+	if err != nil || done {
+		return err
+	}
+
+	if _, err = w.Write(jsonArrayClose); err != nil {
+		return err
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// WriteContentType (JSONStream) writes JSON ContentType.
+func (r JSONStream) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// ChannelItems adapts a channel to the Items function of JSONStream.
+// The channel is drained until it is closed or the consumer stops.
+func ChannelItems(ch <-chan interface{}) func(yield func(v interface{}) bool) {
+	return func(yield func(v interface{}) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
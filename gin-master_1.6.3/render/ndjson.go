@@ -0,0 +1,54 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// NDJSON writes the values produced by Items as newline delimited JSON and
+// flushes after every line, so the client can process them as they come.
+type NDJSON struct {
+	// Context stops the stream when it is done, usually the request context.
+	Context context.Context
+	// Items calls yield for each value, it must stop as soon as yield returns false.
+	Items func(yield func(v interface{}) bool)
+}
+
+var ndjsonContentType = []string{"application/x-ndjson"}
+
+var ndjsonNewline = []byte("\n")
+
+// Render (NDJSON) writes one JSON value per line and flushes after each of them.
+func (r NDJSON) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	flusher, _ := w.(http.Flusher)
+	r.Items(func(v interface{}) bool {
+		if r.Context != nil && r.Context.Err() != nil {
+			return false
+		}
+		jsonBytes, e := json.Marshal(v)
+		if e != nil {
+			err = e
+			return false
+		}
+		if _, err = w.Write(append(jsonBytes, ndjsonNewline...)); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	})
+	return err
+}
+
+// WriteContentType (NDJSON) writes NDJSON ContentType.
+func (r NDJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, ndjsonContentType)
+}
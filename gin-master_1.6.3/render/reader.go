@@ -5,19 +5,29 @@
 package render
 
 import (
+	"context"
+	"errors"
 	"io"
 	"net/http"
+	"os"
 	"strconv"
 )
 
 // Reader contains the IO reader and its length, and custom ContentType and other headers.
+// A negative ContentLength sends the body with the chunked transfer encoding.
 type Reader struct {
 	ContentType   string
 	ContentLength int64
 	Reader        io.Reader
 	Headers       map[string]string
+	// Context stops the copy when it is done, usually the request context.
+	// Files are not watched, they are sent with sendfile when possible and a
+	// client going away makes the copy fail anyway.
+	Context context.Context
 }
 
+var errReaderCanceled = errors.New("reader render canceled")
+
 // Render (Reader) writes data with custom ContentType and headers.
 func (r Reader) Render(w http.ResponseWriter) (err error) {
 	r.WriteContentType(w)
@@ -28,10 +38,31 @@ func (r Reader) Render(w http.ResponseWriter) (err error) {
 		r.Headers["Content-Length"] = strconv.FormatInt(r.ContentLength, 10)
 	}
 	r.writeHeaders(w, r.Headers)
-	_, err = io.Copy(w, r.Reader)
+	reader := r.Reader
+	if _, isFile := reader.(*os.File); r.Context != nil && !isFile {
+		reader = contextReader{ctx: r.Context, r: reader}
+	}
+	// io.Copy uses the io.ReaderFrom of w if any, avoiding a copy in user space
+	_, err = io.Copy(w, reader)
+	if err == errReaderCanceled {
+		return nil
+	}
 	return
 }
 
+// contextReader stops reading once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, errReaderCanceled
+	}
+	return r.r.Read(p)
+}
+
 // WriteContentType (Reader) writes custom ContentType.
 func (r Reader) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, []string{r.ContentType})
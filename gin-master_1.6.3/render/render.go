@@ -29,7 +29,22 @@ var (
 	_ Render     = YAML{}
 	_ Render     = Reader{}
 	_ Render     = AsciiJSON{}
+	_ Render     = ScratchJSON{}
 	_ Render     = ProtoBuf{}
+	_ Render     = JSONStream{}
+	_ Render     = HTMLLayout{}
+	_ Render     = HTMLStream{}
+	_ Render     = OptionsJSON{}
+	_ Render     = CSV{}
+	_ Render     = XLSX{}
+	_ Render     = NDJSON{}
+	_ Render     = MinifiedHTML{}
+	_ Render     = ETag{}
+	_ Render     = ContentLength{}
+
+	_ HTMLLayoutRender = HTMLDebug{}
+	_ HTMLLayoutRender = HTMLProduction{}
+	_ HTMLLocaleRender = HTMLLocalized{}
 )
 
 func writeContentType(w http.ResponseWriter, value []string) {
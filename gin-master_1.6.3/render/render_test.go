@@ -5,17 +5,28 @@
 package render
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"html/template"
+	"io"
+	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	testdata "github.com/gin-gonic/gin/testdata/protoexample"
 )
@@ -48,6 +59,36 @@ func TestRenderJSONPanics(t *testing.T) {
 	assert.Panics(t, func() { assert.NoError(t, (JSON{data}).Render(w)) })
 }
 
+func TestRenderScratchJSON(t *testing.T) {
+	var scratch bytes.Buffer
+	data := map[string]interface{}{
+		"foo":  "bar",
+		"html": "<b>",
+	}
+
+	w := httptest.NewRecorder()
+	(ScratchJSON{Data: data, Scratch: &scratch}).WriteContentType(w)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	err := (ScratchJSON{Data: data, Scratch: &scratch}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"foo\":\"bar\",\"html\":\"\\u003cb\\u003e\"}", w.Body.String())
+	assert.Zero(t, scratch.Len())
+	assert.NotZero(t, scratch.Cap())
+
+	// the scratch is reused
+	grown := scratch.Cap()
+	w = httptest.NewRecorder()
+	err = (ScratchJSON{Data: "small", Scratch: &scratch}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `"small"`, w.Body.String())
+	assert.Equal(t, grown, scratch.Cap())
+
+	assert.Panics(t, func() {
+		_ = (ScratchJSON{Data: make(chan int), Scratch: &scratch}).Render(httptest.NewRecorder())
+	})
+}
+
 func TestRenderIndentedJSON(t *testing.T) {
 	w := httptest.NewRecorder()
 	data := map[string]interface{}{
@@ -201,6 +242,526 @@ func TestRenderPureJSON(t *testing.T) {
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 }
 
+func TestRenderJSONStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	items := func(yield func(v interface{}) bool) {
+		for i := 0; i < 3; i++ {
+			if !yield(map[string]int{"id": i}) {
+				return
+			}
+		}
+	}
+
+	err := (JSONStream{Items: items, FlushEvery: 2}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":0},{"id":1},{"id":2}]`, w.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.True(t, w.Flushed)
+}
+
+func TestRenderJSONStreamEmptyAndChannel(t *testing.T) {
+	w := httptest.NewRecorder()
+	ch := make(chan interface{}, 2)
+	ch <- "a"
+	ch <- "b"
+	close(ch)
+	err := (JSONStream{Items: ChannelItems(ch)}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `["a","b"]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	err = (JSONStream{Items: func(yield func(v interface{}) bool) {}}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `[]`, w.Body.String())
+}
+
+func TestRenderJSONStreamCancelled(t *testing.T) {
+	w := httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	produced := 0
+	items := func(yield func(v interface{}) bool) {
+		for i := 0; i < 10; i++ {
+			if i == 2 {
+				cancel()
+			}
+			if !yield(i) {
+				return
+			}
+			produced++
+		}
+	}
+	err := (JSONStream{Context: ctx, Items: items}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, produced)
+	assert.Equal(t, `[0,1`, w.Body.String())
+}
+
+func TestRenderJSONStreamError(t *testing.T) {
+	w := httptest.NewRecorder()
+	items := func(yield func(v interface{}) bool) {
+		yield(1)
+		yield(make(chan int))
+	}
+	err := (JSONStream{Items: items}).Render(w)
+	assert.Error(t, err)
+	assert.Equal(t, `[1`, w.Body.String())
+}
+
+func TestRenderOptionsJSON(t *testing.T) {
+	type item struct {
+		Zeta  string  `json:"zeta"`
+		Alpha string  `json:"alpha"`
+		Score float64 `json:"score"`
+	}
+	data := []item{{Zeta: "<b>", Alpha: "ü", Score: 1.5}}
+
+	w := httptest.NewRecorder()
+	err := (OptionsJSON{Data: data, Options: JSONOptions{EscapeHTML: true}}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"zeta":"\u003cb\u003e","alpha":"ü","score":1.5}]`, w.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	err = (OptionsJSON{Data: data, Options: JSONOptions{SortKeys: true, Prefix: "while(1);", ASCII: true}}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `while(1);[{"alpha":"\u00fc","score":1.5,"zeta":"<b>"}]`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	err = (OptionsJSON{Data: map[string]int{"a": 1}, Options: JSONOptions{Indent: "  ", Prefix: "while(1);"}}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": 1\n}", w.Body.String())
+}
+
+func TestRenderOptionsJSONNonFinite(t *testing.T) {
+	type inner struct {
+		Value float64 `json:"value"`
+	}
+	type embedded struct {
+		Embedded float32
+	}
+	type outer struct {
+		embedded
+		Values  []float64          `json:"values"`
+		Inner   *inner             `json:"inner"`
+		Map     map[string]float64 `json:"map"`
+		Skipped float64            `json:"-"`
+		Empty   string             `json:"empty,omitempty"`
+		Raw     []byte             `json:"raw"`
+		private float64
+	}
+	data := outer{
+		embedded: embedded{Embedded: float32(math.Inf(-1))},
+		Values:   []float64{1, math.NaN()},
+		Inner:    &inner{Value: math.Inf(1)},
+		Map:      map[string]float64{"x": math.NaN()},
+		Skipped:  math.NaN(),
+		Raw:      []byte("hi"),
+		private:  math.NaN(),
+	}
+
+	err := (OptionsJSON{Data: data}).Render(httptest.NewRecorder())
+	assert.Error(t, err)
+
+	w := httptest.NewRecorder()
+	err = (OptionsJSON{Data: data, Options: JSONOptions{NonFinite: NonFiniteNull}}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Embedded":null,"inner":{"value":null},"map":{"x":null},"raw":"aGk=","values":[1,null]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	err = (OptionsJSON{Data: data, Options: JSONOptions{NonFinite: NonFiniteString}}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Embedded":"-Inf","inner":{"value":"+Inf"},"map":{"x":"NaN"},"raw":"aGk=","values":[1,"NaN"]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	err = (OptionsJSON{Data: make(chan int), Options: JSONOptions{NonFinite: NonFiniteNull}}).Render(w)
+	assert.Error(t, err)
+}
+
+func TestMinifyHTML(t *testing.T) {
+	src := "<html>\n  <head>  <!-- comment -->\n<!--[if IE]><p>old</p><![endif]-->" + flushMarker +
+		"<STYLE>\n a  { }\n</STYLE></head>\n\t<body>  <p>Hello \t world</p>\n" +
+		"<pre>\n  keep   this\n</pre><textarea>  a\n  b</textarea><script type=\"text/javascript\">// x\nvar a  = 1;</script>" +
+		"<presentation>  x  </presentation><!-- unterminated"
+
+	assert.Equal(t, "<html>\n<head> \n<!--[if IE]><p>old</p><![endif]-->"+flushMarker+
+		"<STYLE>\n a  { }\n</STYLE></head>\n<body> <p>Hello world</p>\n"+
+		"<pre>\n  keep   this\n</pre><textarea>  a\n  b</textarea><script type=\"text/javascript\">// x\nvar a  = 1;</script>"+
+		"<presentation> x </presentation><!-- unterminated", string(MinifyHTML([]byte(src))))
+
+	assert.Equal(t, "<pre> x", string(MinifyHTML([]byte("<pre> x"))))
+	assert.Equal(t, "a <", string(MinifyHTML([]byte("a  <"))))
+}
+
+func TestRenderMinifiedHTML(t *testing.T) {
+	w := httptest.NewRecorder()
+	templ := template.Must(template.New("t").Parse("<p>\n  Hello   {{.}}\n</p>"))
+	htmlRender := HTMLProduction{Template: templ}
+
+	err := (MinifiedHTML{HTML: htmlRender.Instance("t", "gin")}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>\nHello gin\n</p>", w.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	err = (MinifiedHTML{HTML: htmlRender.Instance("missing", nil)}).Render(w)
+	assert.Error(t, err)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestRenderETag(t *testing.T) {
+	w := httptest.NewRecorder()
+	body := JSON{Data: map[string]string{"foo": "bar"}}
+
+	err := (ETag{Body: body}).Render(w)
+	assert.NoError(t, err)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"7a38bf81f383f69433ad6e900d35b3e2"`, etag)
+	assert.Equal(t, `{"foo":"bar"}`, w.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	err = (ETag{Body: body, IfNoneMatch: `"other", W/` + etag}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = httptest.NewRecorder()
+	w.Header().Set("ETag", `"v1"`)
+	err = (ETag{Body: body, IfNoneMatch: `"v2"`}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	assert.Equal(t, `{"foo":"bar"}`, w.Body.String())
+
+	err = (ETag{Body: IndentedJSON{Data: make(chan int)}}).Render(httptest.NewRecorder())
+	assert.Error(t, err)
+}
+
+func TestRenderContentLength(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := (ContentLength{Body: String{Format: "hello"}, Limit: 5}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "5", w.Header().Get("Content-Length"))
+	assert.Equal(t, "hello", w.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	body := CSV{Rows: SliceRows([][]string{{"a"}, {"b"}, {"c"}}), FlushEvery: 1}
+	err = (ContentLength{Body: body, Limit: 3}).Render(w)
+	assert.NoError(t, err)
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Equal(t, "a\nb\nc\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	err = (ETag{Body: ContentLength{Body: String{Format: "hello"}, Limit: 10}, IfNoneMatch: "*"}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Length"))
+
+	err = (ContentLength{Body: IndentedJSON{Data: make(chan int)}, Limit: 10}).Render(httptest.NewRecorder())
+	assert.Error(t, err)
+}
+
+func TestBufferPool(t *testing.T) {
+	pool := new(BufferPool)
+	small := bytes.Repeat([]byte("a"), 100)
+	large := bytes.Repeat([]byte("b"), 5000)
+
+	buf := pool.Get()
+	_, err := buf.Write(small)
+	assert.NoError(t, err)
+	assert.Equal(t, small, buf.Bytes())
+	assert.Equal(t, minBufferClass, cap(buf.Bytes()))
+	buf.Release()
+
+	// the buffer moves to the smallest class fitting the output as it grows
+	buf = pool.Get()
+	buf.Write(small)
+	buf.Write(large)
+	assert.Equal(t, len(small)+len(large), buf.Len())
+	assert.Equal(t, append(append([]byte{}, small...), large...), buf.Bytes())
+	assert.Equal(t, 8<<10, cap(buf.Bytes()))
+	buf.Release()
+
+	// the output larger than the largest class isn't pooled
+	buf = pool.Get()
+	buf.Write(make([]byte, 2<<20))
+	buf.Write(make([]byte, 1<<20))
+	assert.Equal(t, 3<<20, buf.Len())
+	buf.Release()
+
+	stats := pool.Stats()
+	assert.Len(t, stats.Classes, bufferClasses)
+	assert.Equal(t, 1<<10, stats.Classes[0].Size)
+	assert.Equal(t, 1<<20, stats.Classes[bufferClasses-1].Size)
+	assert.Equal(t, uint64(3), stats.Classes[0].Gets)
+	assert.Equal(t, uint64(1), stats.Classes[3].Gets)
+	assert.Equal(t, uint64(1), stats.Oversized)
+	assert.True(t, stats.Classes[0].Hits <= 2)
+
+	for i := 0; i < 100; i++ {
+		buf := pool.Get()
+		buf.Write(large)
+		buf.Release()
+	}
+	stats = pool.Stats()
+	assert.True(t, stats.Classes[3].Hits > 0)
+	assert.True(t, stats.HitRate() > 0 && stats.HitRate() <= 1)
+}
+
+func TestNilBufferPool(t *testing.T) {
+	var pool *BufferPool
+	buf := pool.Get()
+	buf.Write([]byte("hello"))
+	buf.Write(make([]byte, 4<<10))
+	assert.Equal(t, "hello", string(buf.Bytes()[:5]))
+	buf.Release()
+	assert.Zero(t, pool.Stats().HitRate())
+	assert.Len(t, pool.Stats().Classes, bufferClasses)
+}
+
+func TestRenderBuffersPooled(t *testing.T) {
+	pool := new(BufferPool)
+	w := httptest.NewRecorder()
+	err := (ETag{Body: ContentLength{Body: String{Format: "hello"}, Limit: 10, Buffers: pool}, Buffers: pool}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", w.Body.String())
+	assert.Equal(t, "5", w.Header().Get("Content-Length"))
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+	assert.Equal(t, uint64(2), pool.Stats().Classes[0].Gets)
+}
+
+func TestETagMatch(t *testing.T) {
+	assert.True(t, ETagMatch(`"a"`, `"a"`))
+	assert.True(t, ETagMatch(`*`, `"a"`))
+	assert.True(t, ETagMatch(`"b" , W/"a"`, `W/"a"`))
+	assert.False(t, ETagMatch(`"b"`, `"a"`))
+	assert.False(t, ETagMatch(``, `"a"`))
+	assert.False(t, ETagMatch(`*`, ``))
+
+	assert.True(t, IsStreaming(NDJSON{}))
+	assert.False(t, IsStreaming(JSON{}))
+}
+
+func TestFragmentFuncs(t *testing.T) {
+	calls := 0
+	memory := NewMemoryFragmentStore()
+	var store FragmentStore = memory
+	var htmlRender HTMLRender
+	funcs := FragmentFuncs(func() FragmentStore { return store }, func() HTMLRender { return htmlRender })
+	funcs["count"] = func() int { calls++; return calls }
+	templ := template.Must(template.New("page").Funcs(funcs).Parse(
+		`{{ define "side" }}<i>{{ count }} {{ . }}</i>{{ end }}{{ cache "side" .ttl "side" .name }}`))
+	htmlRender = HTMLProduction{Template: templ}
+
+	render := func(data map[string]interface{}) (string, error) {
+		w := httptest.NewRecorder()
+		err := htmlRender.Instance("page", data).Render(w)
+		return w.Body.String(), err
+	}
+
+	out, err := render(map[string]interface{}{"ttl": "1m", "name": "<b>"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<i>1 &lt;b&gt;</i>", out)
+	out, err = render(map[string]interface{}{"ttl": time.Minute, "name": "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<i>1 &lt;b&gt;</i>", out)
+
+	memory.Delete("side")
+	out, err = render(map[string]interface{}{"ttl": 0, "name": "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<i>2 x</i>", out)
+	_, ok := memory.Get("side")
+	assert.False(t, ok)
+
+	_, err = render(map[string]interface{}{"ttl": 1.5, "name": "x"})
+	assert.Error(t, err)
+	_, err = render(map[string]interface{}{"ttl": "soon", "name": "x"})
+	assert.Error(t, err)
+
+	store = nil
+	out, err = render(map[string]interface{}{"ttl": "1m", "name": "y"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<i>3 y</i>", out)
+
+	htmlRender = nil
+	_, err = funcs[FragmentFuncName].(func(string, interface{}, string, interface{}) (template.HTML, error))("side", "1m", "side", nil)
+	assert.Error(t, err)
+}
+
+func TestRenderNDJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	items := func(yield func(v interface{}) bool) {
+		for i := 0; i < 2; i++ {
+			if !yield(map[string]int{"id": i}) {
+				return
+			}
+		}
+	}
+
+	err := (NDJSON{Items: items}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\":0}\n{\"id\":1}\n", w.Body.String())
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.True(t, w.Flushed)
+
+	w = httptest.NewRecorder()
+	err = (NDJSON{Items: func(yield func(v interface{}) bool) {
+		yield(1)
+		yield(make(chan int))
+	}}).Render(w)
+	assert.Error(t, err)
+	assert.Equal(t, "1\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = (NDJSON{Context: ctx, Items: items}).Render(w)
+	assert.NoError(t, err)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestRenderCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	rows := SliceRows([][]string{
+		{"1", "Doe, John", `say "hi"`},
+		{"2", "multi\nline", ""},
+	})
+
+	err := (CSV{Headers: []string{"id", "name", "note"}, Rows: rows, FlushEvery: 1}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,name,note\n1,\"Doe, John\",\"say \"\"hi\"\"\"\n2,\"multi\nline\",\n", w.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.True(t, w.Flushed)
+}
+
+func TestRenderCSVOptions(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := (CSV{
+		Rows:     SliceRows([][]string{{"a", "b;c"}}),
+		BOM:      true,
+		Comma:    ';',
+		Filename: "rapport été.csv",
+	}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "\xef\xbb\xbfa;\"b;c\"\n", w.Body.String())
+	assert.Equal(t, "attachment; filename*=utf-8''rapport%20%C3%A9t%C3%A9.csv", w.Header().Get("Content-Disposition"))
+
+	w = httptest.NewRecorder()
+	err = (CSV{Headers: []string{"a"}, Comma: '"'}).Render(w)
+	assert.Error(t, err)
+}
+
+func TestRenderCSVCancelled(t *testing.T) {
+	w := httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	produced := 0
+	rows := func(yield func(row []string) bool) {
+		for i := 0; i < 10; i++ {
+			if i == 2 {
+				cancel()
+			}
+			if !yield([]string{"x"}) {
+				return
+			}
+			produced++
+		}
+	}
+	err := (CSV{Context: ctx, Rows: rows}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, produced)
+	assert.Equal(t, "x\nx\n", w.Body.String())
+}
+
+func readZipFile(t *testing.T, data []byte, name string) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		defer rc.Close()
+		content, err := ioutil.ReadAll(rc)
+		require.NoError(t, err)
+		return string(content)
+	}
+	t.Fatalf("%s not found in archive", name)
+	return ""
+}
+
+func TestRenderXLSX(t *testing.T) {
+	w := httptest.NewRecorder()
+	rows := func(yield func(row []interface{}) bool) {
+		yield([]interface{}{1, 2.5, true, nil, "a<b"})
+	}
+	err := (XLSX{
+		Sheets:   []Sheet{{Name: "Users", Headers: []string{"id"}, Rows: rows}, {}},
+		Filename: "report.xlsx",
+	}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=report.xlsx", w.Header().Get("Content-Disposition"))
+	assert.True(t, w.Flushed)
+
+	body := w.Body.Bytes()
+	sheet := readZipFile(t, body, "xl/worksheets/sheet1.xml")
+	assert.Contains(t, sheet, `<sheetData><row><c t="inlineStr"><is><t xml:space="preserve">id</t></is></c></row>`+
+		`<row><c><v>1</v></c><c><v>2.5</v></c><c t="b"><v>1</v></c><c/>`+
+		`<c t="inlineStr"><is><t xml:space="preserve">a&lt;b</t></is></c></row></sheetData>`)
+	assert.Contains(t, readZipFile(t, body, "xl/worksheets/sheet2.xml"), "<sheetData></sheetData>")
+	workbook := readZipFile(t, body, "xl/workbook.xml")
+	assert.Contains(t, workbook, `<sheet name="Users" sheetId="1" r:id="rId1"/><sheet name="Sheet2" sheetId="2" r:id="rId2"/>`)
+	assert.Contains(t, readZipFile(t, body, "[Content_Types].xml"), "/xl/worksheets/sheet2.xml")
+	assert.Contains(t, readZipFile(t, body, "xl/_rels/workbook.xml.rels"), `Target="worksheets/sheet2.xml"`)
+	assert.Contains(t, readZipFile(t, body, "_rels/.rels"), `Target="xl/workbook.xml"`)
+}
+
+type fakeSheetWriter struct {
+	w io.Writer
+}
+
+func (f fakeSheetWriter) NewSheet(name string) error {
+	_, err := fmt.Fprintf(f.w, "[%s]", name)
+	return err
+}
+
+func (f fakeSheetWriter) WriteRow(cells []interface{}) error {
+	_, err := fmt.Fprint(f.w, cells...)
+	return err
+}
+
+func (f fakeSheetWriter) Close() error {
+	_, err := fmt.Fprint(f.w, ".")
+	return err
+}
+
+func TestRenderXLSXCustomWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := func(yield func(row []interface{}) bool) {
+		yield([]interface{}{"a"})
+		cancel()
+		yield([]interface{}{"b"})
+	}
+	err := (XLSX{
+		Context:     ctx,
+		Sheets:      []Sheet{{Name: "one", Rows: rows}, {Name: "two"}},
+		Writer:      func(w io.Writer) SheetWriter { return fakeSheetWriter{w} },
+		ContentType: "text/plain",
+	}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "[one]a", w.Body.String())
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	err = (XLSX{}).Render(w)
+	assert.NoError(t, err)
+	assert.Contains(t, readZipFile(t, w.Body.Bytes(), "xl/workbook.xml"), `<sheet name="Sheet1"`)
+}
+
 type xmlmap map[string]interface{}
 
 // Allows type H to be used with xml.Marshal
@@ -418,6 +979,104 @@ func TestRenderHTMLTemplateEmptyName(t *testing.T) {
 	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
 }
 
+func TestRenderHTMLLayout(t *testing.T) {
+	templ := template.Must(template.ParseGlob("../testdata/layout/*"))
+	htmlRender := NewHTMLProduction(templ, Delims{})
+
+	w := httptest.NewRecorder()
+	instance := htmlRender.InstanceWithLayout("layout.tmpl", "users.tmpl", map[string]interface{}{
+		"users": []string{"a", "b"},
+	})
+	assert.NoError(t, instance.Render(w))
+	assert.Equal(t, "<title>Users</title><main><ul><li>a</li><li>b</li></ul></main>", w.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	// the plain template set keeps working next to the layouts
+	w = httptest.NewRecorder()
+	assert.NoError(t, htmlRender.Instance("users.tmpl:title", nil).Render(w))
+	assert.Equal(t, "Users", w.Body.String())
+
+	// compositions are cached and don't leak into each other
+	w = httptest.NewRecorder()
+	instance = htmlRender.InstanceWithLayout("layout.tmpl", "about.tmpl", map[string]interface{}{"name": "gin"})
+	assert.NoError(t, instance.Render(w))
+	assert.Equal(t, "<title>Default</title><main><p>About gin</p></main>", w.Body.String())
+	assert.Len(t, htmlRender.layouts.sets, 2)
+
+	w = httptest.NewRecorder()
+	instance = htmlRender.InstanceWithLayout("layout.tmpl", "missing.tmpl", nil)
+	assert.Error(t, instance.Render(w))
+}
+
+func TestRenderHTMLLayoutWithoutCache(t *testing.T) {
+	templ := template.Must(template.ParseGlob("../testdata/layout/*"))
+	htmlRender := HTMLProduction{Template: templ}
+
+	w := httptest.NewRecorder()
+	instance := htmlRender.InstanceWithLayout("layout.tmpl", "about.tmpl", map[string]interface{}{"name": "gin"})
+	assert.NoError(t, instance.Render(w))
+	assert.Equal(t, "<title>Default</title><main><p>About gin</p></main>", w.Body.String())
+
+	// once executed, a template set can't be composed anymore
+	assert.NoError(t, templ.ExecuteTemplate(httptest.NewRecorder(), "about.tmpl", nil))
+	instance = htmlRender.InstanceWithLayout("layout.tmpl", "about.tmpl", nil)
+	assert.Error(t, instance.Render(httptest.NewRecorder()))
+}
+
+func TestRenderHTMLDebugLayout(t *testing.T) {
+	htmlRender := HTMLDebug{Glob: "../testdata/layout/*"}
+	w := httptest.NewRecorder()
+	instance := htmlRender.InstanceWithLayout("layout.tmpl", "users.tmpl", map[string]interface{}{
+		"users": []string{"a"},
+	})
+	assert.NoError(t, instance.Render(w))
+	assert.Equal(t, "<title>Users</title><main><ul><li>a</li></ul></main>", w.Body.String())
+}
+
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	chunks []string
+}
+
+func (w *flushRecorder) Flush() {
+	w.chunks = append(w.chunks, w.Body.String())
+	w.ResponseRecorder.Flush()
+}
+
+func TestRenderHTMLStream(t *testing.T) {
+	templ := template.Must(template.New("t").Funcs(StreamFuncs()).Parse(`<head></head>{{ flush }}<p>{{ .name }}</p>{{ flush }}<footer></footer>`))
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r := HTMLStream{
+		Context: context.Background(),
+		HTML:    HTML{Template: templ, Name: "t", Data: map[string]string{"name": "<gin>"}},
+	}
+	r.WriteContentType(w)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	assert.NoError(t, r.Render(w))
+	assert.Equal(t, "<head></head><p>&lt;gin&gt;</p><footer></footer>", w.Body.String())
+	assert.Equal(t, []string{"<head></head>", "<head></head><p>&lt;gin&gt;</p>"}, w.chunks)
+
+	// without HTMLStream the flush point is a plain comment
+	w2 := httptest.NewRecorder()
+	assert.NoError(t, (HTML{Template: templ, Name: "t"}).Render(w2))
+	assert.Equal(t, "<head></head><!--gin:flush--><p></p><!--gin:flush--><footer></footer>", w2.Body.String())
+}
+
+func TestRenderHTMLStreamAborted(t *testing.T) {
+	templ := template.Must(template.New("t").Funcs(StreamFuncs()).Parse(`<head></head>{{ flush }}<body></body>`))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	r := HTMLStream{Context: ctx, HTML: HTML{Template: templ, Name: "t"}}
+	assert.NoError(t, r.Render(w))
+	assert.Equal(t, "<head></head>", w.Body.String())
+
+	broken := template.Must(template.New("t").Funcs(StreamFuncs()).Parse(`{{ .Missing.Field }}`))
+	r = HTMLStream{Context: context.Background(), HTML: HTML{Template: broken, Name: "t", Data: 1}}
+	assert.Error(t, r.Render(httptest.NewRecorder()))
+}
+
 func TestRenderHTMLDebugFiles(t *testing.T) {
 	w := httptest.NewRecorder()
 	htmlRender := HTMLDebug{Files: []string{"../testdata/template/hello.tmpl"},
@@ -454,6 +1113,36 @@ func TestRenderHTMLDebugGlob(t *testing.T) {
 	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
 }
 
+func TestRenderHTMLDebugWithReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gin-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	page := filepath.Join(dir, "page.tmpl")
+	require.NoError(t, ioutil.WriteFile(page, []byte("v1"), 0600))
+
+	htmlRender := HTMLDebug{Glob: filepath.Join(dir, "*")}.WithReload(0)
+	render := func(name string) string {
+		w := httptest.NewRecorder()
+		require.NoError(t, htmlRender.Instance(name, nil).Render(w))
+		return w.Body.String()
+	}
+	assert.Equal(t, "v1", render("page.tmpl"))
+	assert.Same(t, htmlRender.loadTemplate(), htmlRender.loadTemplate())
+
+	require.NoError(t, ioutil.WriteFile(page, []byte("v2"), 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(page, later, later))
+	assert.Equal(t, "v2", render("page.tmpl"))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other.tmpl"), []byte("other"), 0600))
+	assert.Equal(t, "other", render("other.tmpl"))
+
+	throttled := HTMLDebug{Files: []string{page}}.WithReload(time.Hour)
+	templ := throttled.loadTemplate()
+	require.NoError(t, os.Chtimes(page, time.Now(), time.Now()))
+	assert.Same(t, templ, throttled.loadTemplate())
+}
+
 func TestRenderHTMLDebugPanics(t *testing.T) {
 	htmlRender := HTMLDebug{Files: nil,
 		Glob:    "",
@@ -486,6 +1175,26 @@ func TestRenderReader(t *testing.T) {
 	assert.Equal(t, headers["x-request-id"], w.Header().Get("x-request-id"))
 }
 
+func TestRenderReaderCanceled(t *testing.T) {
+	w := httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := io.MultiReader(strings.NewReader("first"), readerFunc(func(p []byte) (int, error) {
+		cancel()
+		return copy(p, "second"), nil
+	}), strings.NewReader("third"))
+
+	err := (Reader{ContentLength: -1, Reader: reader, Context: ctx}).Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "firstsecond", w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Length"))
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
 func TestRenderReaderNoContentLength(t *testing.T) {
 	w := httptest.NewRecorder()
 
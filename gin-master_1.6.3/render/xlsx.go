@@ -0,0 +1,268 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SheetWriter writes a spreadsheet document, sheet after sheet and row after row,
+// so that it never has to be held in memory at once.
+type SheetWriter interface {
+	// NewSheet starts a new sheet, the rows written next belong to it.
+	NewSheet(name string) error
+	// WriteRow appends a row to the current sheet.
+	WriteRow(cells []interface{}) error
+	// Close finishes the document, it doesn't close the underlying writer.
+	Close() error
+}
+
+// SheetWriterFactory returns a SheetWriter encoding to w. It's the integration
+// point of spreadsheet libraries, NewXLSXWriter is used when none is set.
+type SheetWriterFactory func(w io.Writer) SheetWriter
+
+// Sheet is a sheet of a spreadsheet document.
+type Sheet struct {
+	Name string
+	// Headers is written as the first row when not empty.
+	Headers []string
+	// Rows calls yield for each row, it must stop as soon as yield returns false.
+	Rows func(yield func(row []interface{}) bool)
+}
+
+// XLSX streams its sheets as a spreadsheet document, an Office Open XML workbook by default.
+type XLSX struct {
+	// Context stops the export when it is done, usually the request context.
+	Context context.Context
+	Sheets  []Sheet
+	// Writer creates the SheetWriter encoding the document, NewXLSXWriter when nil.
+	Writer SheetWriterFactory
+	// ContentType overrides the xlsx ContentType, for writers producing another format.
+	ContentType string
+	// Filename, when not empty, makes the response an attachment with this name.
+	Filename string
+}
+
+var xlsxContentType = []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}
+
+// Render (XLSX) encodes the sheets one row at a time and flushes after every sheet.
+// If the context is done the export stops early and the document is left unfinished.
+func (r XLSX) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	if r.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": r.Filename}))
+	}
+
+	newWriter := r.Writer
+	if newWriter == nil {
+		newWriter = NewXLSXWriter
+	}
+	sw := newWriter(w)
+	flusher, _ := w.(http.Flusher)
+	for _, sheet := range r.Sheets {
+		if err := writeSheet(r.Context, sw, sheet); err != nil {
+			return err
+		}
+		if r.Context != nil && r.Context.Err() != nil {
+			return nil
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if err := sw.Close(); err != nil {
+		return err
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func writeSheet(ctx context.Context, sw SheetWriter, sheet Sheet) (err error) {
+	if err = sw.NewSheet(sheet.Name); err != nil {
+		return err
+	}
+	if len(sheet.Headers) > 0 {
+		headers := make([]interface{}, len(sheet.Headers))
+		for i, header := range sheet.Headers {
+			headers[i] = header
+		}
+		if err = sw.WriteRow(headers); err != nil {
+			return err
+		}
+	}
+	if sheet.Rows == nil {
+		return nil
+	}
+	sheet.Rows(func(row []interface{}) bool {
+		if ctx != nil && ctx.Err() != nil {
+			return false
+		}
+		err = sw.WriteRow(row)
+		return err == nil
+	})
+	return err
+}
+
+// WriteContentType (XLSX) writes the xlsx ContentType, or the overridden one.
+func (r XLSX) WriteContentType(w http.ResponseWriter) {
+	if r.ContentType != "" {
+		writeContentType(w, []string{r.ContentType})
+		return
+	}
+	writeContentType(w, xlsxContentType)
+}
+
+// xlsxWriter is a minimal xlsx encoder: cells are inline strings, numbers and
+// booleans, without styles, formulas nor shared strings.
+type xlsxWriter struct {
+	zip    *zip.Writer
+	sheet  io.Writer
+	sheets []string
+}
+
+// NewXLSXWriter returns a SheetWriter encoding an xlsx workbook to w.
+// Numbers and booleans are written as such, any other value as a string,
+// times are formatted with time.RFC3339.
+func NewXLSXWriter(w io.Writer) SheetWriter {
+	return &xlsxWriter{zip: zip.NewWriter(w)}
+}
+
+const xlsxSheetHeader = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const xlsxSheetFooter = `</sheetData></worksheet>`
+
+func (x *xlsxWriter) NewSheet(name string) (err error) {
+	if err = x.endSheet(); err != nil {
+		return err
+	}
+	if name == "" {
+		name = "Sheet" + strconv.Itoa(len(x.sheets)+1)
+	}
+	x.sheets = append(x.sheets, name)
+	if x.sheet, err = x.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(x.sheets))); err != nil {
+		return err
+	}
+	_, err = io.WriteString(x.sheet, xlsxSheetHeader)
+	return err
+}
+
+func (x *xlsxWriter) endSheet() error {
+	if x.sheet == nil {
+		return nil
+	}
+	_, err := io.WriteString(x.sheet, xlsxSheetFooter)
+	x.sheet = nil
+	return err
+}
+
+func (x *xlsxWriter) WriteRow(cells []interface{}) error {
+	if x.sheet == nil {
+		if err := x.NewSheet(""); err != nil {
+			return err
+		}
+	}
+	buf := []byte("<row>")
+	for _, cell := range cells {
+		buf = appendXLSXCell(buf, cell)
+	}
+	buf = append(buf, "</row>"...)
+	_, err := x.sheet.Write(buf)
+	return err
+}
+
+func appendXLSXCell(buf []byte, cell interface{}) []byte {
+	switch v := cell.(type) {
+	case nil:
+		return append(buf, "<c/>"...)
+	case bool:
+		if v {
+			return append(buf, `<c t="b"><v>1</v></c>`...)
+		}
+		return append(buf, `<c t="b"><v>0</v></c>`...)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return append(append(append(buf, "<c><v>"...), fmt.Sprint(v)...), "</v></c>"...)
+	case float32:
+		return append(strconv.AppendFloat(append(buf, "<c><v>"...), float64(v), 'g', -1, 32), "</v></c>"...)
+	case float64:
+		return append(strconv.AppendFloat(append(buf, "<c><v>"...), v, 'g', -1, 64), "</v></c>"...)
+	case time.Time:
+		return appendXLSXString(buf, v.Format(time.RFC3339))
+	case string:
+		return appendXLSXString(buf, v)
+	default:
+		return appendXLSXString(buf, fmt.Sprint(v))
+	}
+}
+
+func appendXLSXString(buf []byte, s string) []byte {
+	buf = append(buf, `<c t="inlineStr"><is><t xml:space="preserve">`...)
+	buf = append(buf, xmlEscape(s)...)
+	return append(buf, "</t></is></c>"...)
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+func (x *xlsxWriter) Close() error {
+	if len(x.sheets) == 0 {
+		// a workbook needs at least one sheet
+		if err := x.NewSheet(""); err != nil {
+			return err
+		}
+	}
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+
+	var types, sheets, rels []byte
+	for i, name := range x.sheets {
+		n := i + 1
+		types = append(types, fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)...)
+		sheets = append(sheets, fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(name), n, n)...)
+		rels = append(rels, fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)...)
+	}
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xml.Header +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			string(types) + `</Types>`},
+		{"_rels/.rels", xml.Header +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header +
+			`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + string(sheets) + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			string(rels) + `</Relationships>`},
+	}
+	for _, part := range parts {
+		f, err := x.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+	return x.zip.Close()
+}
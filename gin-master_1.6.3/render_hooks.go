@@ -0,0 +1,29 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import "github.com/gin-gonic/gin/render"
+
+// BeforeRenderFunc is called right before a render writes the response body.
+// It returns the render to use instead, r itself or e.g. a render wrapping it
+// to add an envelope or to sign the body.
+type BeforeRenderFunc func(c *Context, r render.Render) render.Render
+
+// AfterRenderFunc is called right after a render wrote the response body, with
+// the render returned by the BeforeRenderFunc hooks and its error, if any.
+type AfterRenderFunc func(c *Context, r render.Render, err error)
+
+// OnBeforeRender registers hooks called, in order, by every Context.Render writing
+// a body, whatever the output format. Responses without body, such as 204 or 304,
+// don't call the render hooks.
+func (engine *Engine) OnBeforeRender(hooks ...BeforeRenderFunc) {
+	engine.beforeRender = append(engine.beforeRender, hooks...)
+}
+
+// OnAfterRender registers hooks called, in order, once a Context.Render wrote the body.
+// They are called before the render error, if any, panics.
+func (engine *Engine) OnAfterRender(hooks ...AfterRenderFunc) {
+	engine.afterRender = append(engine.afterRender, hooks...)
+}
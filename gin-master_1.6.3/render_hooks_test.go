@@ -0,0 +1,88 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin/render"
+	"github.com/stretchr/testify/assert"
+)
+
+type envelopeRender struct {
+	data render.Render
+}
+
+func (r envelopeRender) Render(w http.ResponseWriter) error {
+	if _, err := w.Write([]byte(`{"data":`)); err != nil {
+		return err
+	}
+	if err := r.data.Render(w); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte(`}`))
+	return err
+}
+
+func (r envelopeRender) WriteContentType(w http.ResponseWriter) {
+	r.data.WriteContentType(w)
+}
+
+func TestRenderHooks(t *testing.T) {
+	var calls []string
+	router := New()
+	router.OnBeforeRender(func(c *Context, r render.Render) render.Render {
+		calls = append(calls, "before:"+c.FullPath())
+		if _, ok := r.(render.JSON); ok {
+			return envelopeRender{r}
+		}
+		return r
+	})
+	router.OnAfterRender(func(c *Context, r render.Render, err error) {
+		_, wrapped := r.(envelopeRender)
+		calls = append(calls, "after:"+c.FullPath())
+		assert.NoError(t, err)
+		assert.Equal(t, c.FullPath() == "/json", wrapped)
+	})
+	router.GET("/json", func(c *Context) {
+		c.JSON(http.StatusOK, H{"foo": "bar"})
+	})
+	router.GET("/text", func(c *Context) {
+		c.String(http.StatusOK, "foo")
+	})
+	router.GET("/empty", func(c *Context) {
+		c.String(http.StatusNoContent, "foo")
+	})
+
+	w := performRequest(router, "GET", "/json")
+	assert.Equal(t, `{"data":{"foo":"bar"}}`, w.Body.String())
+	w = performRequest(router, "GET", "/text")
+	assert.Equal(t, "foo", w.Body.String())
+	performRequest(router, "GET", "/empty")
+
+	assert.Equal(t, []string{"before:/json", "after:/json", "before:/text", "after:/text"}, calls)
+}
+
+type failingRender struct{}
+
+func (failingRender) Render(http.ResponseWriter) error {
+	return errors.New("failed")
+}
+
+func (failingRender) WriteContentType(http.ResponseWriter) {}
+
+func TestRenderHooksError(t *testing.T) {
+	var renderErr error
+	c, router := CreateTestContext(httptest.NewRecorder())
+	router.OnAfterRender(func(c *Context, r render.Render, err error) {
+		renderErr = err
+	})
+
+	assert.Panics(t, func() { c.Render(http.StatusOK, failingRender{}) })
+	assert.EqualError(t, renderErr, "failed")
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+// RenderFactory returns the render writing obj in a given format.
+type RenderFactory func(obj interface{}) render.Render
+
+// RegisterRender registers the render factory of a content type, which
+// c.Negotiate and c.Respond then select when the client accepts that type:
+//     router.RegisterRender("application/hal+json", func(obj interface{}) render.Render {
+//         return render.JSON{Data: toHAL(obj)}
+//     })
+// The response gets the registered content type, with the parameters the render
+// writes for it if any, such as a charset. Registering a built-in type such as
+// "application/json" replaces its render.
+func (engine *Engine) RegisterRender(contentType string, factory RenderFactory) {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	assert1(contentType != "", "content type can not be empty")
+	assert1(factory != nil, "render factory can not be nil")
+
+	if engine.renders == nil {
+		engine.renders = make(map[string]RenderFactory)
+	}
+	if _, ok := engine.renders[contentType]; !ok && !isBuiltinRenderType(contentType) {
+		engine.renderTypes = append(engine.renderTypes, contentType)
+	}
+	engine.renders[contentType] = factory
+}
+
+// isBuiltinRenderType reports whether c.Respond already offers contentType.
+func isBuiltinRenderType(contentType string) bool {
+	switch contentType {
+	case MIMEJSON, MIMEXML, MIMEYAML:
+		return true
+	}
+	return false
+}
+
+// negotiatedContentType returns the Content-Type r writes if it's of the given
+// media type, the media type itself otherwise.
+func negotiatedContentType(r render.Render, mediaType string) string {
+	probe := headerWriter{header: make(http.Header)}
+	r.WriteContentType(probe)
+	contentType := probe.header.Get("Content-Type")
+	if strings.EqualFold(filterFlags(contentType), mediaType) {
+		return contentType
+	}
+	return mediaType
+}
+
+// headerWriter is an http.ResponseWriter only recording headers.
+type headerWriter struct {
+	header http.Header
+}
+
+func (w headerWriter) Header() http.Header         { return w.header }
+func (w headerWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w headerWriter) WriteHeader(int)             {}
@@ -0,0 +1,89 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin/render"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterRender(t *testing.T) {
+	router := New()
+	router.RegisterRender(" Text/Calendar ", func(obj interface{}) render.Render {
+		return render.String{Format: "BEGIN:VCALENDAR\n%v\nEND:VCALENDAR", Data: []interface{}{obj}}
+	})
+	router.RegisterRender("application/hal+json", func(obj interface{}) render.Render {
+		return render.JSON{Data: H{"_embedded": obj}}
+	})
+	router.RegisterRender(MIMEXML, func(obj interface{}) render.Render {
+		return render.Data{ContentType: "application/xml", Data: []byte(fmt.Sprintf("<v>%v</v>", obj))}
+	})
+	router.RegisterRender("text/calendar", func(obj interface{}) render.Render {
+		return render.String{Format: "%v", Data: []interface{}{obj}}
+	})
+	assert.Equal(t, []string{"text/calendar", "application/hal+json"}, router.renderTypes)
+
+	assert.Panics(t, func() { router.RegisterRender("", nil) })
+	assert.Panics(t, func() { router.RegisterRender("text/x", nil) })
+}
+
+func TestContextRespond(t *testing.T) {
+	router := New()
+	router.RegisterRender("application/hal+json", func(obj interface{}) render.Render {
+		return render.JSON{Data: H{"_embedded": obj}}
+	})
+	router.RegisterRender("text/calendar", func(obj interface{}) render.Render {
+		return render.Data{ContentType: "text/calendar; charset=utf-8", Data: []byte(fmt.Sprint(obj))}
+	})
+
+	for accept, expected := range map[string][2]string{
+		"":                     {"application/json; charset=utf-8", `{"foo":"bar"}`},
+		"application/hal+json": {"application/hal+json", `{"_embedded":{"foo":"bar"}}`},
+		"text/calendar":        {"text/calendar; charset=utf-8", "map[foo:bar]"},
+		"application/x-yaml":   {"application/x-yaml; charset=utf-8", "foo: bar\n"},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := CreateTestContext(w)
+		c.engine = router
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.Header.Set("Accept", accept)
+
+		c.Respond(http.StatusOK, H{"foo": "bar"})
+
+		assert.Equal(t, expected[0], w.Header().Get("Content-Type"), accept)
+		assert.Equal(t, expected[1], w.Body.String(), accept)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request.Header.Set("Accept", "image/png")
+	c.Respond(http.StatusOK, H{"foo": "bar"})
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
+
+func TestContextNegotiationWithRegisteredRender(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, router := CreateTestContext(w)
+	router.RegisterRender("application/hal+json", func(obj interface{}) render.Render {
+		return render.JSON{Data: obj}
+	})
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request.Header.Add("Accept", "application/hal+json")
+
+	c.Negotiate(http.StatusOK, Negotiate{
+		Offered:  []string{MIMEJSON, "application/hal+json"},
+		JSONData: "json",
+		Data:     "hal",
+	})
+
+	assert.Equal(t, `"hal"`, w.Body.String())
+	assert.Equal(t, "application/hal+json", w.Header().Get("Content-Type"))
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// RequestIDKey is the key the request ID is set under in the Context, see Context.RequestID.
+const RequestIDKey = "requestID"
+
+// defaultRequestIDHeader is the header the request ID is read from and written to.
+const defaultRequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of the request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+// RequestIDConfig defines the config for RequestID middleware.
+type RequestIDConfig struct {
+	// Header is read for the ID given by the client or a proxy, and set on the response.
+	// Optional. Default value is "X-Request-ID".
+	Header string
+
+	// Generator returns the ID of the requests without one.
+	// Optional. Default value is gin.NewUUIDv7.
+	Generator func() string
+
+	// IgnoreIncoming always generates the ID, e.g. when the clients are not trusted.
+	// Optional.
+	IgnoreIncoming bool
+}
+
+// RequestID returns a RequestID middleware with the default config.
+func RequestID() HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+// RequestIDWithConfig returns a middleware identifying each request. The ID given by
+// the request header is kept when it's made of at most 128 printable ASCII characters,
+// a new one is generated otherwise. The ID is set in the response header, in the
+// Context under RequestIDKey, in the context of the request so that it can be
+// propagated downstream (see RequestIDFromContext), and it's written by the Logger
+// and in the default 404 and 405 bodies.
+func RequestIDWithConfig(conf RequestIDConfig) HandlerFunc {
+	header := conf.Header
+	if header == "" {
+		header = defaultRequestIDHeader
+	}
+	generator := conf.Generator
+	if generator == nil {
+		generator = NewUUIDv7
+	}
+
+	return func(c *Context) {
+		var id string
+		if !conf.IgnoreIncoming {
+			id = c.requestHeader(header)
+		}
+		if !validRequestID(id) {
+			id = generator()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(header, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDContextKey{}, id))
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID returns the ID set by the RequestID middleware, or an empty string.
+func (c *Context) RequestID() string {
+	return c.GetString(RequestIDKey)
+}
+
+// RequestIDFromContext returns the request ID set by the RequestID middleware in
+// the context of the request, e.g. to set it on the requests made to other services.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// NewUUIDv7 returns a random UUID of version 7, which starts with the current Unix
+// time in milliseconds so that the IDs sort by creation time.
+func NewUUIDv7() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[6:]); err != nil {
+		panic(err)
+	}
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	copy(uuid[:6], ms[2:])
+	uuid[6] = uuid[6]&0x0f | 0x70 // version 7
+	uuid[8] = uuid[8]&0x3f | 0x80 // variant 10
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf[:])
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var uuidv7Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUUIDv7(t *testing.T) {
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+	id := NewUUIDv7()
+	assert.Regexp(t, uuidv7Pattern, id)
+	assert.NotEqual(t, id, NewUUIDv7())
+
+	var ms int64
+	for _, c := range strings.Replace(id[:13], "-", "", 1) {
+		ms = ms<<4 | int64(strings.IndexRune("0123456789abcdef", c))
+	}
+	assert.True(t, ms >= before && ms <= before+1000)
+}
+
+func TestRequestID(t *testing.T) {
+	router := New()
+	router.Use(RequestID())
+	var fromContext string
+	router.GET("/", func(c *Context) {
+		fromContext = RequestIDFromContext(c.Request.Context())
+		c.String(http.StatusOK, c.RequestID())
+	})
+
+	w := performRequest(router, "GET", "/")
+	assert.Regexp(t, uuidv7Pattern, w.Body.String())
+	assert.Equal(t, w.Body.String(), w.Header().Get("X-Request-ID"))
+	assert.Equal(t, w.Body.String(), fromContext)
+
+	w = performRequest(router, "GET", "/", header{"X-Request-ID", "abc-123"})
+	assert.Equal(t, "abc-123", w.Body.String())
+	assert.Equal(t, "abc-123", w.Header().Get("X-Request-ID"))
+
+	for _, invalid := range []string{"with space", "new\nline", strings.Repeat("a", 129)} {
+		w = performRequest(router, "GET", "/", header{"X-Request-ID", invalid})
+		assert.Regexp(t, uuidv7Pattern, w.Body.String())
+	}
+
+	w = performRequest(router, "GET", "/missing", header{"X-Request-ID", "abc-123"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "404 page not found\nrequest id: abc-123", w.Body.String())
+	w = performRequest(router, "GET", "/missing")
+	assert.Equal(t, "404 page not found\nrequest id: "+w.Header().Get("X-Request-ID"), w.Body.String())
+}
+
+func TestRequestIDWithConfig(t *testing.T) {
+	router := New()
+	router.Use(RequestIDWithConfig(RequestIDConfig{
+		Header:         "X-Trace",
+		Generator:      func() string { return "generated" },
+		IgnoreIncoming: true,
+	}))
+	router.GET("/", func(c *Context) { c.String(http.StatusOK, c.RequestID()) })
+
+	w := performRequest(router, "GET", "/", header{"X-Trace", "given"})
+	assert.Equal(t, "generated", w.Body.String())
+	assert.Equal(t, "generated", w.Header().Get("X-Trace"))
+	assert.Empty(t, w.Header().Get("X-Request-ID"))
+}
+
+func TestRequestIDLogger(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(LoggerWithWriter(buffer), RequestID())
+	router.GET("/", func(c *Context) {})
+
+	performRequest(router, "GET", "/", header{"X-Request-ID", "abc-123"})
+	assert.Contains(t, buffer.String(), `"/" | abc-123`)
+
+	buffer.Reset()
+	router = New()
+	router.Use(LoggerWithWriter(buffer))
+	router.GET("/", func(c *Context) {})
+	performRequest(router, "GET", "/", header{"X-Request-ID", "abc-123"})
+	assert.NotContains(t, buffer.String(), "abc-123")
+}
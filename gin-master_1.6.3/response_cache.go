@@ -0,0 +1,315 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response stored by the Cache middleware.
+type CachedResponse struct {
+	// Path is the path of the request, which CacheInvalidate patterns are matched against.
+	Path   string
+	Status int
+	Header http.Header
+	Body   []byte
+	// Stored is when the response was stored.
+	Stored time.Time
+}
+
+// ResponseCacheStore stores the responses of the Cache middleware.
+type ResponseCacheStore interface {
+	// Get returns the response stored under key, if it didn't expire.
+	Get(key string) (*CachedResponse, bool)
+	// Set stores the response under key for ttl.
+	Set(key string, response *CachedResponse, ttl time.Duration)
+	// DeleteFunc deletes the responses for which match returns true and
+	// returns how many were deleted.
+	DeleteFunc(match func(response *CachedResponse) bool) int
+}
+
+// CacheInvalidate deletes the cached responses whose path matches pattern, e.g. after
+// an update, and returns how many were deleted. The pattern is matched with path.Match,
+// except for a trailing "/*" which matches every path below:
+//     router.CacheInvalidate("/users/42")
+//     router.CacheInvalidate("/users/*")
+func (engine *Engine) CacheInvalidate(pattern string) int {
+	if engine.ResponseCache == nil {
+		return 0
+	}
+	prefix := ""
+	if strings.HasSuffix(pattern, "/*") {
+		prefix = pattern[:len(pattern)-1]
+	}
+	return engine.ResponseCache.DeleteFunc(func(response *CachedResponse) bool {
+		if prefix != "" {
+			return strings.HasPrefix(response.Path, prefix)
+		}
+		matched, _ := path.Match(pattern, response.Path)
+		return matched
+	})
+}
+
+// CacheConfig defines the config for Cache middleware.
+type CacheConfig struct {
+	// TTL is how long the responses are cached, unless their Cache-Control header says otherwise.
+	TTL time.Duration
+
+	// VaryHeaders are the request headers the responses depend on, e.g. Accept-Language.
+	// Accept-Encoding is always one of them.
+	// Optional.
+	VaryHeaders []string
+
+	// MaxSize is the size of the largest body cached.
+	// Optional. Default value is 1 MB.
+	MaxSize int
+}
+
+const defaultCacheMaxSize = 1 << 20
+
+// Cache returns a middleware caching the successful responses of the GET requests in
+// the ResponseCache of the engine, keyed by path, query and the VaryHeaders of the
+// request. HEAD requests are answered from the cached GET responses. The responses of
+// the handlers are not cached when their Cache-Control header contains no-store, private
+// or no-cache, nor when they are flushed, and their max-age or s-maxage overrides the TTL.
+// The requests with Cache-Control: no-cache are served by the handlers and cached again.
+// The cached responses are sent with the X-Cache and Age headers.
+func Cache(conf CacheConfig) HandlerFunc {
+	assert1(conf.TTL > 0, "cache TTL must be positive")
+	maxSize := conf.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultCacheMaxSize
+	}
+	varySet := map[string]bool{"Accept-Encoding": true}
+	for _, name := range conf.VaryHeaders {
+		varySet[http.CanonicalHeaderKey(name)] = true
+	}
+	vary := make([]string, 0, len(varySet))
+	for name := range varySet {
+		vary = append(vary, name)
+	}
+	sort.Strings(vary)
+
+	return func(c *Context) {
+		store := c.engine.ResponseCache
+		method := c.Request.Method
+		if store == nil || (method != http.MethodGet && method != http.MethodHead) {
+			return
+		}
+		requestCC := c.requestHeader("Cache-Control")
+		if strings.Contains(requestCC, "no-store") {
+			return
+		}
+		key := cacheKey(c.Request, vary)
+		if !strings.Contains(requestCC, "no-cache") {
+			if response, ok := store.Get(key); ok {
+				serveCached(c, response)
+				return
+			}
+		}
+		if method != http.MethodGet {
+			return
+		}
+
+		w := &cacheWriter{ResponseWriter: c.Writer, maxSize: maxSize}
+		c.Writer = w
+		c.Header("X-Cache", "MISS")
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.cacheable || w.Status() != http.StatusOK {
+			return
+		}
+		ttl, ok := cacheTTL(w.Header(), conf.TTL, varySet)
+		if !ok {
+			return
+		}
+		header := w.Header().Clone()
+		header.Del("X-Cache")
+		header.Del("Set-Cookie")
+		store.Set(key, &CachedResponse{
+			Path:   c.Request.URL.Path,
+			Status: w.Status(),
+			Header: header,
+			Body:   w.body,
+			Stored: c.engine.clock().Now(),
+		}, ttl)
+	}
+}
+
+func cacheKey(req *http.Request, vary []string) string {
+	var sb strings.Builder
+	sb.WriteString(req.URL.Path)
+	sb.WriteByte('?')
+	sb.WriteString(req.URL.RawQuery)
+	for _, name := range vary {
+		sb.WriteByte('\n')
+		sb.WriteString(name)
+		sb.WriteByte(':')
+		sb.WriteString(strings.Join(req.Header[name], ","))
+	}
+	return sb.String()
+}
+
+// cacheTTL returns how long the response can be cached according to its headers,
+// it can't when it varies with request headers which are not part of the key.
+func cacheTTL(header http.Header, ttl time.Duration, vary map[string]bool) (time.Duration, bool) {
+	for _, value := range header["Vary"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" && !vary[http.CanonicalHeaderKey(name)] {
+				return 0, false
+			}
+		}
+	}
+	maxAge, sharedMaxAge := -1, -1
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache" || directive == "private":
+			return 0, false
+		case strings.HasPrefix(directive, "max-age="):
+			maxAge, _ = strconv.Atoi(directive[len("max-age="):])
+		case strings.HasPrefix(directive, "s-maxage="):
+			sharedMaxAge, _ = strconv.Atoi(directive[len("s-maxage="):])
+		}
+	}
+	if sharedMaxAge >= 0 {
+		maxAge = sharedMaxAge
+	}
+	if maxAge == 0 {
+		return 0, false
+	}
+	if maxAge > 0 {
+		ttl = time.Duration(maxAge) * time.Second
+	}
+	return ttl, true
+}
+
+func serveCached(c *Context, response *CachedResponse) {
+	header := c.Writer.Header()
+	for k, v := range response.Header {
+		header[k] = v
+	}
+	header.Set("X-Cache", "HIT")
+	header.Set("Age", strconv.Itoa(int(c.engine.clock().Now().Sub(response.Stored)/time.Second)))
+	c.Status(response.Status)
+	if c.Request.Method == http.MethodHead {
+		c.Writer.WriteHeaderNow()
+	} else {
+		_, _ = c.Writer.Write(response.Body)
+	}
+	c.Abort()
+}
+
+// cacheWriter copies the body written by the handlers, while it fits in the cache.
+type cacheWriter struct {
+	ResponseWriter
+	maxSize   int
+	body      []byte
+	cacheable bool
+	streamed  bool
+}
+
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	w.copy(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	w.copy([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *cacheWriter) copy(data []byte) {
+	if w.streamed {
+		return
+	}
+	if len(w.body)+len(data) > w.maxSize {
+		w.streamed, w.cacheable, w.body = true, false, nil
+		return
+	}
+	w.cacheable = true
+	w.body = append(w.body, data...)
+}
+
+func (w *cacheWriter) Flush() {
+	w.streamed, w.cacheable, w.body = true, false, nil
+	w.ResponseWriter.Flush()
+}
+
+func (w *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.streamed, w.cacheable, w.body = true, false, nil
+	return w.ResponseWriter.Hijack()
+}
+
+// MemoryResponseCache is a ResponseCacheStore keeping the responses in memory.
+// Expired responses are dropped when they are looked up.
+type MemoryResponseCache struct {
+	mu        sync.RWMutex
+	responses map[string]memoryCachedResponse
+	now       func() time.Time
+}
+
+type memoryCachedResponse struct {
+	response *CachedResponse
+	expires  time.Time
+}
+
+// NewMemoryResponseCache returns an empty MemoryResponseCache.
+func NewMemoryResponseCache() *MemoryResponseCache {
+	return &MemoryResponseCache{responses: make(map[string]memoryCachedResponse), now: time.Now}
+}
+
+// SetClock sets the clock the cache tells the time with, SystemClock by default, e.g.
+// a FakeClock in tests. It must be called before the cache is used.
+func (s *MemoryResponseCache) SetClock(clock Clock) {
+	s.now = clock.Now
+}
+
+// Get implements ResponseCacheStore.
+func (s *MemoryResponseCache) Get(key string) (*CachedResponse, bool) {
+	s.mu.RLock()
+	cached, ok := s.responses[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if s.now().After(cached.expires) {
+		s.mu.Lock()
+		delete(s.responses, key)
+		s.mu.Unlock()
+		return nil, false
+	}
+	return cached.response, true
+}
+
+// Set implements ResponseCacheStore.
+func (s *MemoryResponseCache) Set(key string, response *CachedResponse, ttl time.Duration) {
+	s.mu.Lock()
+	s.responses[key] = memoryCachedResponse{response: response, expires: s.now().Add(ttl)}
+	s.mu.Unlock()
+}
+
+// DeleteFunc implements ResponseCacheStore.
+func (s *MemoryResponseCache) DeleteFunc(match func(response *CachedResponse) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	for key, cached := range s.responses {
+		if match(cached.response) {
+			delete(s.responses, key)
+			deleted++
+		}
+	}
+	return deleted
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	router := New()
+	router.Use(Cache(CacheConfig{TTL: time.Minute, VaryHeaders: []string{"accept-language"}}))
+	router.GET("/users/:id", func(c *Context) {
+		calls++
+		c.Header("Vary", "Accept-Language")
+		c.SetCookie("session", "secret", 0, "/", "", false, true)
+		c.String(http.StatusOK, "user %s #%d %s", c.Param("id"), calls, c.GetHeader("Accept-Language"))
+	})
+
+	w := performRequest(router, "GET", "/users/1")
+	assert.Equal(t, "user 1 #1 ", w.Body.String())
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+
+	w = performRequest(router, "GET", "/users/1")
+	assert.Equal(t, "user 1 #1 ", w.Body.String())
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.Equal(t, "0", w.Header().Get("Age"))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+
+	w = performRequest(router, "HEAD", "/users/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.Empty(t, w.Body.String())
+
+	w = performRequest(router, "GET", "/users/1", header{"Accept-Language", "de"})
+	assert.Equal(t, "user 1 #2 de", w.Body.String())
+	w = performRequest(router, "GET", "/users/1?page=2")
+	assert.Equal(t, "user 1 #3 ", w.Body.String())
+
+	w = performRequest(router, "GET", "/users/1", header{"Cache-Control", "no-cache"})
+	assert.Equal(t, "user 1 #4 ", w.Body.String())
+	w = performRequest(router, "GET", "/users/1")
+	assert.Equal(t, "user 1 #4 ", w.Body.String())
+
+	performRequest(router, "GET", "/users/2")
+	assert.Equal(t, 3, router.CacheInvalidate("/users/1"))
+	assert.Equal(t, 1, router.CacheInvalidate("/users/*"))
+	w = performRequest(router, "GET", "/users/1")
+	assert.Equal(t, "user 1 #6 ", w.Body.String())
+	assert.Equal(t, 1, router.CacheInvalidate("/users/?"))
+}
+
+func TestCacheControl(t *testing.T) {
+	calls := 0
+	router := New()
+	router.Use(Cache(CacheConfig{TTL: time.Minute, MaxSize: 10}))
+	handler := func(cacheControl string) HandlerFunc {
+		return func(c *Context) {
+			calls++
+			if cacheControl != "" {
+				c.Header("Cache-Control", cacheControl)
+			}
+			c.String(http.StatusOK, strconv.Itoa(calls))
+		}
+	}
+	router.GET("/private", handler("private, max-age=60"))
+	router.GET("/no-store", handler("no-store"))
+	router.GET("/expired", handler("max-age=0"))
+	router.GET("/shared", handler("max-age=0, s-maxage=60"))
+	router.GET("/vary", func(c *Context) {
+		calls++
+		c.Header("Vary", "Cookie")
+		c.String(http.StatusOK, strconv.Itoa(calls))
+	})
+	router.GET("/large", func(c *Context) {
+		calls++
+		c.String(http.StatusOK, "%011d", calls)
+	})
+	router.GET("/stream", func(c *Context) {
+		calls++
+		c.String(http.StatusOK, strconv.Itoa(calls))
+		c.Writer.Flush()
+	})
+	router.GET("/missing", func(c *Context) {
+		calls++
+		c.String(http.StatusNotFound, strconv.Itoa(calls))
+	})
+
+	for _, path := range []string{"/private", "/no-store", "/expired", "/vary", "/large", "/stream", "/missing"} {
+		first := performRequest(router, "GET", path).Body.String()
+		assert.NotEqual(t, first, performRequest(router, "GET", path).Body.String(), path)
+	}
+	first := performRequest(router, "GET", "/shared").Body.String()
+	assert.Equal(t, first, performRequest(router, "GET", "/shared").Body.String())
+
+	router.ResponseCache = nil
+	assert.NotEqual(t, first, performRequest(router, "GET", "/shared").Body.String())
+	assert.Equal(t, 0, router.CacheInvalidate("/*"))
+	assert.Panics(t, func() { Cache(CacheConfig{}) })
+}
+
+func TestMemoryResponseCache(t *testing.T) {
+	store := NewMemoryResponseCache()
+	store.Set("a", &CachedResponse{Path: "/a"}, time.Minute)
+	store.Set("b", &CachedResponse{Path: "/b"}, -time.Second)
+
+	response, ok := store.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "/a", response.Path)
+	_, ok = store.Get("b")
+	assert.False(t, ok)
+	assert.Len(t, store.responses, 1)
+}
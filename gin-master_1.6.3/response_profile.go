@@ -0,0 +1,75 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import "github.com/gin-gonic/gin/render"
+
+// Names of the response profiles every engine knows.
+const (
+	// ProfileBrowserSafe prefixes JSON arrays with "while(1);", escapes HTML and
+	// every non ASCII character, like SecureJSON and AsciiJSON together.
+	ProfileBrowserSafe = "browser-safe"
+	// ProfileInternal keeps HTML characters as is, like PureJSON.
+	ProfileInternal = "internal"
+)
+
+// ResponseProfile defines how the JSON renders of the Context behave for the routes
+// using it, so handlers can call c.JSON whatever the audience of the route is.
+type ResponseProfile struct {
+	JSONConfig
+
+	// Prefix is written before JSON arrays, like SecureJSON does.
+	Prefix string
+
+	// ASCII escapes every non ASCII character, like AsciiJSON does.
+	ASCII bool
+
+	// ContentType overrides the Content-Type of the JSON responses, e.g. to change the charset.
+	// Optional.
+	ContentType string
+}
+
+var defaultResponseProfiles = map[string]*ResponseProfile{
+	ProfileBrowserSafe: {Prefix: "while(1);", ASCII: true},
+	ProfileInternal:    {JSONConfig: JSONConfig{DisableHTMLEscaping: true}},
+}
+
+func (p *ResponseProfile) jsonOptions() render.JSONOptions {
+	opts := p.JSONConfig.jsonOptions()
+	opts.Prefix = p.Prefix
+	opts.ASCII = p.ASCII
+	opts.ContentType = p.ContentType
+	return opts
+}
+
+// RegisterResponseProfile registers a response profile under the given name, replacing
+// the profile of that name if any, built-in ones included.
+func (engine *Engine) RegisterResponseProfile(name string, profile ResponseProfile) {
+	if engine.profiles == nil {
+		engine.profiles = make(map[string]*ResponseProfile)
+	}
+	engine.profiles[name] = &profile
+}
+
+func (engine *Engine) responseProfile(name string) *ResponseProfile {
+	if profile, ok := engine.profiles[name]; ok {
+		return profile
+	}
+	return defaultResponseProfiles[name]
+}
+
+// UseResponseProfile returns a middleware applying the named response profile to the
+// JSON renders of the routes it's used by, e.g. a group serving browsers:
+//     api := router.Group("/api", gin.UseResponseProfile(gin.ProfileBrowserSafe))
+// It panics when the profile is not registered.
+func UseResponseProfile(name string) HandlerFunc {
+	return func(c *Context) {
+		profile := c.engine.responseProfile(name)
+		if profile == nil {
+			panic("gin: unknown response profile " + name)
+		}
+		c.profile = profile
+	}
+}
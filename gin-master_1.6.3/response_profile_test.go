@@ -0,0 +1,57 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseProfiles(t *testing.T) {
+	router := New()
+	data := []string{"<b>", "é"}
+	handler := func(c *Context) { c.JSON(http.StatusOK, data) }
+	router.GET("/plain", handler)
+	router.GET("/browser", UseResponseProfile(ProfileBrowserSafe), handler)
+	router.GET("/internal", UseResponseProfile(ProfileInternal), handler)
+	router.RegisterResponseProfile("legacy", ResponseProfile{Prefix: ")]}',\n", ContentType: "application/json; charset=iso-8859-1"})
+	router.GET("/legacy", UseResponseProfile("legacy"), handler)
+
+	w := performRequest(router, "GET", "/plain")
+	assert.Equal(t, `["\u003cb\u003e","é"]`, w.Body.String())
+
+	w = performRequest(router, "GET", "/browser")
+	assert.Equal(t, `while(1);["\u003cb\u003e","\u00e9"]`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	w = performRequest(router, "GET", "/internal")
+	assert.Equal(t, `["<b>","é"]`, w.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = performRequest(router, "GET", "/legacy")
+	assert.Equal(t, ")]}',\n[\"\\u003cb\\u003e\",\"é\"]", w.Body.String())
+	assert.Equal(t, "application/json; charset=iso-8859-1", w.Header().Get("Content-Type"))
+}
+
+func TestResponseProfileSecureJSON(t *testing.T) {
+	router := New()
+	router.RegisterResponseProfile("prefixed", ResponseProfile{Prefix: "for(;;);"})
+	router.GET("/default", UseResponseProfile(ProfileInternal), func(c *Context) { c.SecureJSON(http.StatusOK, []int{1}) })
+	router.GET("/prefixed", UseResponseProfile("prefixed"), func(c *Context) { c.SecureJSON(http.StatusOK, []int{1}) })
+
+	w := performRequest(router, "GET", "/default")
+	assert.Equal(t, "while(1);[1]", w.Body.String())
+
+	w = performRequest(router, "GET", "/prefixed")
+	assert.Equal(t, "for(;;);[1]", w.Body.String())
+}
+
+func TestResponseProfileUnknown(t *testing.T) {
+	router := New()
+	router.GET("/", UseResponseProfile("missing"), func(c *Context) {})
+	assert.Panics(t, func() { performRequest(router, "GET", "/") })
+}
@@ -87,6 +87,19 @@ func (w *responseWriter) WriteString(s string) (n int, err error) {
 	return
 }
 
+// ReadFrom implements the io.ReaderFrom interface, so that io.Copy hands the reader
+// to the underlying writer, which sends *os.File contents with sendfile.
+func (w *responseWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	w.WriteHeaderNow()
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.size += int(n)
+	return
+}
+
 func (w *responseWriter) Status() int {
 	return w.status
 }
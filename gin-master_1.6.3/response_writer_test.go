@@ -5,11 +5,16 @@
 package gin
 
 import (
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TODO
@@ -23,6 +28,7 @@ var _ http.ResponseWriter = ResponseWriter(&responseWriter{})
 var _ http.Hijacker = ResponseWriter(&responseWriter{})
 var _ http.Flusher = ResponseWriter(&responseWriter{})
 var _ http.CloseNotifier = ResponseWriter(&responseWriter{})
+var _ io.ReaderFrom = &responseWriter{}
 
 func init() {
 	SetMode(TestMode)
@@ -130,3 +136,43 @@ func TestResponseWriterFlush(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 }
+
+func TestResponseWriterReadFrom(t *testing.T) {
+	testWriter := httptest.NewRecorder()
+	writer := &responseWriter{}
+	writer.reset(testWriter)
+	w := ResponseWriter(writer)
+
+	w.WriteHeader(http.StatusCreated)
+	n, err := io.Copy(w, strings.NewReader("hola"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.Equal(t, 4, w.Size())
+	assert.Equal(t, http.StatusCreated, testWriter.Code)
+	assert.Equal(t, "hola", testWriter.Body.String())
+}
+
+func TestResponseWriterReadFromFile(t *testing.T) {
+	file, err := os.Open("./testdata/template/hello.tmpl")
+	require.NoError(t, err)
+	defer file.Close()
+	expected, err := ioutil.ReadFile("./testdata/template/hello.tmpl")
+	require.NoError(t, err)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := &responseWriter{}
+		writer.reset(w)
+		n, err := io.Copy(writer, file)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(expected)), n)
+		assert.Equal(t, len(expected), writer.Size())
+	}))
+	defer testServer.Close()
+
+	resp, err := http.Get(testServer.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, body)
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+// RouteMeta is the metadata attached to a route when it's registered, e.g. the scopes
+// required by RBAC. It's read by the middleware with Context.RouteMeta and listed by
+// Engine.Routes.
+type RouteMeta map[string]interface{}
+
+// WithMeta returns a copy of the group which attaches the metadata key to the routes
+// registered with it, in addition to the metadata of the group:
+//     router.WithMeta("owner", "billing").GET("/invoices", listInvoices)
+// The metadata is inherited by the groups created from it.
+func (group *RouterGroup) WithMeta(key string, value interface{}) *RouterGroup {
+	meta := make(RouteMeta, len(group.meta)+1)
+	for k, v := range group.meta {
+		meta[k] = v
+	}
+	meta[key] = value
+
+	g := *group
+	g.root = false
+	g.meta = meta
+	return &g
+}
+
+// withMeta returns a copy of the snapshot where the metadata of the route is meta, or
+// where the route has no metadata when meta is empty. The maps are copied rather than
+// changed, since the requests being served may read them.
+func (rt *routeTrees) withMeta(method, path string, meta RouteMeta) *routeTrees {
+	if _, ok := rt.meta[method][path]; !ok && len(meta) == 0 {
+		return rt
+	}
+	next := *rt
+	next.meta = make(map[string]map[string]RouteMeta, len(rt.meta)+1)
+	for m, routes := range rt.meta {
+		next.meta[m] = routes
+	}
+	routes := make(map[string]RouteMeta, len(rt.meta[method])+1)
+	for p, meta := range rt.meta[method] {
+		if p != path {
+			routes[p] = meta
+		}
+	}
+	if len(meta) > 0 {
+		routes[path] = meta
+	}
+	next.meta[method] = routes
+	return &next
+}
+
+// RouteMeta returns the metadata key of the matched route, see RouterGroup.WithMeta.
+func (c *Context) RouteMeta(key string) (value interface{}, exists bool) {
+	if c.engine == nil || c.fullPath == "" {
+		return nil, false
+	}
+	value, exists = c.routeTrees().meta[c.Request.Method][c.fullPath][key]
+	return
+}
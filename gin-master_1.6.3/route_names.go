@@ -0,0 +1,323 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+)
+
+// Named returns a copy of the group which names the routes registered with it, so that
+// their URLs can be built with Engine.URLFor instead of hard-coding their paths:
+//     router.Named("user-detail").GET("/users/:id", getUser)
+// A name can only be given to one path of one host, the routes of several methods can
+// share it. The groups created from it don't inherit the name.
+func (group *RouterGroup) Named(name string) *RouterGroup {
+	assert1(name != "", "route name can not be empty")
+	g := *group
+	g.root = false
+	g.routeName = name
+	g.lastPath, g.lastMethods = "", nil
+	return &g
+}
+
+// Name names the route registered last with the group, with all the methods it was
+// registered with through the group, as Named does:
+//     router.GET("/users/:id", getUser).Name("user-detail")
+// It panics when the name is used by another path or the route already has another
+// name.
+func (group *RouterGroup) Name(name string) IRoutes {
+	assert1(name != "", "route name can not be empty")
+	engine := group.engine
+	engine.treesMu.Lock()
+	defer engine.treesMu.Unlock()
+	assert1(group.lastPath != "", "no route to name, the route must be registered first")
+	all := engine.routeTrees()
+	trees := all
+	if group.host != "" {
+		trees = all.hostTrees(group.host)
+	}
+	for _, method := range group.lastMethods {
+		var err error
+		if trees, err = trees.withHostName(all, name, method, group.lastPath); err != nil {
+			panic(err.Error())
+		}
+	}
+	if group.host != "" {
+		trees = all.withHost(trees)
+	}
+	engine.trees.Store(trees)
+	return group.returnObj()
+}
+
+// RoutesNamed returns the routes of the name, one by method, nil when there's none.
+func (engine *Engine) RoutesNamed(name string) (routes RoutesInfo) {
+	for _, route := range engine.Routes() {
+		if route.Name == name {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// RouteName returns the name of the matched route, empty when it has none, e.g. to label
+// metrics or to look up an authorization policy, see RouterGroup.Named.
+func (c *Context) RouteName() string {
+	if c.engine == nil || c.fullPath == "" {
+		return ""
+	}
+	return c.routeTrees().routeNames[c.Request.Method][c.fullPath]
+}
+
+// namedRoute is the path of a named route, split into the parts URLFor substitutes.
+type namedRoute struct {
+	path    string
+	methods []string
+	parts   []urlPart
+}
+
+// urlPart is a literal part of the path of a named route, or one of its wildcards.
+type urlPart struct {
+	literal    string
+	param      string // the name of the param, empty for a literal
+	catchAll   bool
+	optional   bool
+	constraint func(string) bool
+}
+
+// newNamedRoute splits the path of a route, as registered, into its parts.
+func newNamedRoute(path string) *namedRoute {
+	route := &namedRoute{path: path}
+	optional := strings.HasSuffix(path, "?")
+	rest := strings.TrimSuffix(path, "?")
+	for {
+		wildcard, i, _ := findWildcard(rest)
+		if i < 0 {
+			route.parts = append(route.parts, urlPart{literal: rest})
+			break
+		}
+		if i > 0 {
+			route.parts = append(route.parts, urlPart{literal: rest[:i]})
+		}
+		rest = rest[i+len(wildcard):]
+		part := urlPart{param: wildcard[1:], catchAll: wildcard[0] == '*', optional: optional && rest == ""}
+		if !part.catchAll {
+			name, pattern, typ := splitParamConstraint(wildcard)
+			part.param = name[1:]
+			if pattern != "" || len(name) < len(wildcard) && wildcard[len(name)] == '|' {
+				part.constraint = paramConstraint(wildcard, pattern, typ, path)
+			}
+		}
+		route.parts = append(route.parts, part)
+	}
+	return route
+}
+
+// withName returns a copy of the snapshot where the route of the method and path has the
+// name, or an error when another path has it or the route has another name.
+func (rt *routeTrees) withName(name, method, path string) (*routeTrees, error) {
+	current := rt.names[name]
+	if current != nil && current.path != path {
+		return nil, errors.New("route name '" + name + "' is already used by path '" + current.path + "'")
+	}
+	paths := optionalParamPaths(path)
+	for _, p := range paths {
+		if other := rt.routeNames[method][p]; other != "" && other != name {
+			return nil, errors.New("route " + method + " " + p + " is already named '" + other + "'")
+		}
+	}
+	route := newNamedRoute(path)
+	if current != nil {
+		route.methods = append(route.methods, current.methods...)
+	}
+	if !route.hasMethod(method) {
+		route.methods = append(route.methods, method)
+	}
+
+	next := *rt
+	next.names = make(map[string]*namedRoute, len(rt.names)+1)
+	for n, r := range rt.names {
+		next.names[n] = r
+	}
+	next.names[name] = route
+	next.routeNames = rt.copyRouteNames(method)
+	for _, p := range paths {
+		next.routeNames[method][p] = name
+	}
+	return &next, nil
+}
+
+// withHostName returns withName for the snapshot of the routes of its host pattern, or an
+// error when the name is used by the routes of another host, or of no host.
+func (rt *routeTrees) withHostName(all *routeTrees, name, method, path string) (*routeTrees, error) {
+	if route, host := all.namedRoute(name); route != nil && host != rt.host {
+		if host == "" {
+			return nil, errors.New("route name '" + name + "' is already used by the routes without host")
+		}
+		return nil, errors.New("route name '" + name + "' is already used by host '" + host + "'")
+	}
+	return rt.withName(name, method, path)
+}
+
+// namedRoute returns the route of the name, among the routes without host then the ones
+// of each host pattern, with its host pattern.
+func (rt *routeTrees) namedRoute(name string) (*namedRoute, string) {
+	if route := rt.names[name]; route != nil {
+		return route, ""
+	}
+	for _, h := range rt.hosts {
+		if route := h.trees.names[name]; route != nil {
+			return route, h.trees.host
+		}
+	}
+	return nil, ""
+}
+
+// copyRouteNames returns a copy of the names of the routes where the ones of the method
+// can be changed.
+func (rt *routeTrees) copyRouteNames(method string) map[string]map[string]string {
+	routeNames := make(map[string]map[string]string, len(rt.routeNames)+1)
+	for m, names := range rt.routeNames {
+		routeNames[m] = names
+	}
+	names := make(map[string]string, len(rt.routeNames[method])+2)
+	for p, n := range rt.routeNames[method] {
+		names[p] = n
+	}
+	routeNames[method] = names
+	return routeNames
+}
+
+// withoutName returns a copy of the snapshot where the removed route of the method and
+// path is no longer named. A name is removed with the last method of its path.
+func (rt *routeTrees) withoutName(method, path string) *routeTrees {
+	for name, route := range rt.names {
+		if !route.hasRoute(method, path) {
+			continue
+		}
+		next := *rt
+		next.names = make(map[string]*namedRoute, len(rt.names))
+		for n, r := range rt.names {
+			if n != name {
+				next.names[n] = r
+			}
+		}
+		if len(route.methods) > 1 {
+			r := *route
+			r.methods = nil
+			for _, m := range route.methods {
+				if m != method {
+					r.methods = append(r.methods, m)
+				}
+			}
+			next.names[name] = &r
+		}
+		next.routeNames = rt.copyRouteNames(method)
+		for _, p := range optionalParamPaths(route.path) {
+			delete(next.routeNames[method], p)
+		}
+		return &next
+	}
+	return rt
+}
+
+// hasRoute reports whether the route of the method and path is one of the named routes.
+func (route *namedRoute) hasRoute(method, path string) bool {
+	if !route.hasMethod(method) {
+		return false
+	}
+	for _, p := range optionalParamPaths(route.path) {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMethod reports whether the route of the method is named.
+func (route *namedRoute) hasMethod(method string) bool {
+	for _, m := range route.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// URLFor returns the path of the route of the name, see RouterGroup.Named, where the
+// params are replaced by their values, escaped:
+//     router.URLFor("user-detail", gin.H{"id": 42}) // "/users/42"
+// The values are formatted with fmt.Sprint, a catch-all param takes a path whose
+// segments are escaped separately, and the optional param may be omitted. It returns an
+// error when a param is missing or doesn't match its constraint. The values which
+// aren't params of the route are ignored. The URL of a route of a host pattern, see
+// RouterGroup.Host, is its path too, without the host.
+func (engine *Engine) URLFor(name string, params H) (string, error) {
+	route, _ := engine.routeTrees().namedRoute(name)
+	if route == nil {
+		return "", fmt.Errorf("gin: no route named %q", name)
+	}
+	b := make([]byte, 0, len(route.path)+16)
+	for _, part := range route.parts {
+		if part.param == "" {
+			b = append(b, part.literal...)
+			continue
+		}
+		v, ok := params[part.param]
+		if !ok && part.optional {
+			// the route without the optional param, as added by addRoute
+			b = b[:len(b)-1]
+			if len(b) == 0 {
+				b = append(b, '/')
+			}
+			continue
+		}
+		if !ok {
+			return "", fmt.Errorf("gin: missing param %q of the route %q", part.param, name)
+		}
+		value := fmt.Sprint(v)
+		if part.catchAll {
+			b = appendCatchAll(b, value)
+			continue
+		}
+		if value == "" || part.constraint != nil && !part.constraint(value) {
+			return "", fmt.Errorf("gin: invalid value %q of the param %q of the route %q", value, part.param, name)
+		}
+		b = append(b, url.PathEscape(value)...)
+	}
+	return string(b), nil
+}
+
+// appendCatchAll appends the value of a catch-all param, whose leading '/' is part of
+// the path already.
+func appendCatchAll(b []byte, value string) []byte {
+	for i, segment := range strings.Split(strings.TrimPrefix(value, "/"), "/") {
+		if i > 0 {
+			b = append(b, '/')
+		}
+		b = append(b, url.PathEscape(segment)...)
+	}
+	return b
+}
+
+// URLFuncs returns the template functions building the URLs of the named routes of the
+// engine, to be registered before loading the templates:
+//     router.AddTemplateFuncs(router.URLFuncs())
+// The set contains:
+//     urlFor {{ urlFor "user-detail" "id" .User.ID }}
+func (engine *Engine) URLFuncs() template.FuncMap {
+	return template.FuncMap{
+		"urlFor": func(name string, pairs ...interface{}) (string, error) {
+			params, err := templateDict(pairs...)
+			if err != nil {
+				return "", err
+			}
+			return engine.URLFor(name, params)
+		},
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLFor(t *testing.T) {
+	router := New()
+	router.Named("user-detail").GET("/users/:id", func(c *Context) {})
+	router.Named("user-detail").PUT("/users/:id", func(c *Context) {})
+	api := router.Group("/api")
+	api.Named("order").GET("/orders/:id|int", func(c *Context) {})
+	api.Named("file").GET("/files/:owner/*path", func(c *Context) {})
+	api.Named("article").GET("/articles/:slug/:rev([0-9]+)?", func(c *Context) {})
+	router.Group("/docs").Named("lang").GET("/:lang?", func(c *Context) {})
+	api.GET("/unnamed", func(c *Context) {})
+
+	tests := []struct {
+		name   string
+		params H
+		url    string
+	}{
+		{"user-detail", H{"id": 42}, "/users/42"},
+		{"user-detail", H{"id": "a b/c?", "extra": 1}, "/users/a%20b%2Fc%3F"},
+		{"order", H{"id": -7}, "/api/orders/-7"},
+		{"file", H{"owner": "me", "path": "/docs/a b.txt"}, "/api/files/me/docs/a%20b.txt"},
+		{"file", H{"owner": "me", "path": "docs/"}, "/api/files/me/docs/"},
+		{"article", H{"slug": "hello", "rev": 3}, "/api/articles/hello/3"},
+		{"article", H{"slug": "hello"}, "/api/articles/hello"},
+		{"lang", H{}, "/docs"},
+		{"lang", H{"lang": "fr"}, "/docs/fr"},
+	}
+	for _, test := range tests {
+		url, err := router.URLFor(test.name, test.params)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.url, url, test.name)
+	}
+
+	_, err := router.URLFor("missing", nil)
+	assert.EqualError(t, err, `gin: no route named "missing"`)
+	_, err = router.URLFor("user-detail", nil)
+	assert.EqualError(t, err, `gin: missing param "id" of the route "user-detail"`)
+	_, err = router.URLFor("user-detail", H{"id": ""})
+	assert.Error(t, err)
+	_, err = router.URLFor("order", H{"id": "latest"})
+	assert.EqualError(t, err, `gin: invalid value "latest" of the param "id" of the route "order"`)
+	_, err = router.URLFor("article", H{"slug": "hello", "rev": "x"})
+	assert.Error(t, err)
+
+	// the generated URLs match their routes, the raw path keeps the escaped '/' of a value
+	router.UseRawPath = true
+	url, _ := router.URLFor("user-detail", H{"id": "a b/c?"})
+	match, ok := router.Match(http.MethodGet, url)
+	if assert.True(t, ok) {
+		assert.Equal(t, "a b/c?", match.Params.ByName("id"))
+	}
+}
+
+func TestURLForNameConflicts(t *testing.T) {
+	router := New()
+	router.Named("user").GET("/users/:id", func(c *Context) {})
+	assert.PanicsWithValue(t, "route name 'user' is already used by path '/users/:id'", func() {
+		router.Named("user").GET("/members/:id", func(c *Context) {})
+	})
+	err := router.Named("user").TryHandle(http.MethodPost, "/members/:id", func(c *Context) {})
+	assert.EqualError(t, err, "gin: can not register POST /members/:id: route name 'user' is already used by path '/users/:id'")
+	assert.Len(t, router.Routes(), 1)
+	assert.Panics(t, func() { router.Named("") })
+
+	// a name is removed with the last route of its path
+	router.Named("user").DELETE("/users/:id", func(c *Context) {})
+	router.RemoveRoute(http.MethodGet, "/users/:id")
+	url, err := router.URLFor("user", H{"id": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/1", url)
+	router.RemoveRoute(http.MethodDelete, "/users/:id")
+	_, err = router.URLFor("user", H{"id": 1})
+	assert.Error(t, err)
+	router.Named("user").GET("/members/:id", func(c *Context) {})
+	url, _ = router.URLFor("user", H{"id": 1})
+	assert.Equal(t, "/members/1", url)
+}
+
+func TestURLFuncs(t *testing.T) {
+	router := New()
+	router.Named("user-detail").GET("/users/:id", func(c *Context) {})
+
+	tmpl := template.Must(template.New("link").Funcs(router.URLFuncs()).Parse(
+		`<a href="{{ urlFor "user-detail" "id" .ID }}">{{ .Name }}</a>`))
+	var out bytes.Buffer
+	assert.NoError(t, tmpl.Execute(&out, H{"ID": 42, "Name": "gopher"}))
+	assert.Equal(t, `<a href="/users/42">gopher</a>`, out.String())
+
+	tmpl = template.Must(template.New("link").Funcs(router.URLFuncs()).Parse(`{{ urlFor "user-detail" }}`))
+	assert.Error(t, tmpl.Execute(&out, nil))
+}
+
+func TestRouteName(t *testing.T) {
+	router := New()
+	var name string
+	handler := func(c *Context) { name = c.RouteName() }
+	router.GET("/users/:id", handler).Name("user-detail")
+	router.POST("/users", handler)
+	router.Any("/ping", handler).Name("ping")
+	router.Static("/assets", "/tmp").Name("assets")
+	router.GET("/docs/:lang?", handler).Name("docs")
+
+	performRequest(router, http.MethodGet, "/users/42")
+	assert.Equal(t, "user-detail", name)
+	performRequest(router, http.MethodPost, "/users")
+	assert.Empty(t, name)
+	performRequest(router, http.MethodPut, "/ping")
+	assert.Equal(t, "ping", name)
+	performRequest(router, http.MethodGet, "/docs")
+	assert.Equal(t, "docs", name)
+
+	url, err := router.URLFor("user-detail", H{"id": 42})
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/42", url)
+	assert.Len(t, router.RoutesNamed("ping"), 9)
+	assert.Len(t, router.RoutesNamed("assets"), 2)
+	assert.Len(t, router.RoutesNamed("docs"), 2)
+	assert.Nil(t, router.RoutesNamed("none"))
+	match, ok := router.Match(http.MethodGet, "/assets/app.js")
+	if assert.True(t, ok) {
+		assert.Equal(t, "assets", match.Name)
+	}
+
+	// the routes of a group are named with the group
+	v1 := router.Group("/v1")
+	v1.GET("/posts/:id", handler).Name("post")
+	url, _ = router.URLFor("post", H{"id": 1})
+	assert.Equal(t, "/v1/posts/1", url)
+
+	assert.Empty(t, (&Context{}).RouteName())
+}
+
+func TestRouteNameConflicts(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", func(c *Context) {}).Name("user")
+	assert.PanicsWithValue(t, "route name 'user' is already used by path '/users/:id'", func() {
+		router.GET("/members/:id", func(c *Context) {}).Name("user")
+	})
+	// the route stays unnamed when it can't be named
+	assert.Equal(t, "/users/:id", router.RoutesNamed("user")[0].Path)
+	router.Name("member")
+	assert.Len(t, router.RoutesNamed("member"), 1)
+	assert.PanicsWithValue(t, "route GET /posts is already named 'post'", func() {
+		router.Named("post").GET("/posts", func(c *Context) {}).Name("other")
+	})
+
+	// naming the route again keeps its name
+	router.GET("/admins/:id", func(c *Context) {}).Name("admin").Name("admin")
+	assert.Len(t, router.RoutesNamed("admin"), 1)
+
+	assert.Panics(t, func() { New().Name("nothing") })
+	assert.Panics(t, func() { router.Group("/v2").Name("nothing") })
+	assert.Panics(t, func() { router.GET("/empty", func(c *Context) {}).Name("") })
+
+	// a removed route loses its name
+	router.RemoveRoute(http.MethodGet, "/users/:id")
+	assert.Nil(t, router.RoutesNamed("user"))
+	router.GET("/users/:id", func(c *Context) {}).Name("member2")
+	assert.Len(t, router.RoutesNamed("member2"), 1)
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// RouteRecord is the structured record of a registered route, see Engine.RouteSink.
+type RouteRecord struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Handler is the name of the main handler, see HandlerFuncName.
+	Handler string `json:"handler"`
+	// Handlers are the names of the handlers of the route, the middleware then the main
+	// handler.
+	Handlers []string `json:"handlers"`
+	// Middleware is the number of middleware of the route.
+	Middleware int `json:"middleware"`
+}
+
+// RouteSink receives the records of the routes.
+type RouteSink func(RouteRecord)
+
+func newRouteRecord(method, path string, handlers HandlersChain) RouteRecord {
+	record := RouteRecord{
+		Method:     method,
+		Path:       path,
+		Handler:    nameOfFunction(handlers.Last()),
+		Handlers:   make([]string, len(handlers)),
+		Middleware: len(handlers) - 1,
+	}
+	for i, h := range handlers {
+		record.Handlers[i] = nameOfFunction(h)
+	}
+	return record
+}
+
+// JSONRouteSink returns a RouteSink writing the records to w as JSON, one per line,
+// e.g. for a startup script to validate the routes:
+//     router := gin.New()
+//     router.RouteSink = gin.JSONRouteSink(os.Stderr)
+// The write errors are ignored.
+func JSONRouteSink(w io.Writer) RouteSink {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(record RouteRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(record)
+	}
+}
+
+// EmitRoutes sends the records of the registered routes to the sink, sorted by path and
+// method, so that the output doesn't depend on the order of registration.
+func (engine *Engine) EmitRoutes(sink RouteSink) {
+	var records []RouteRecord
+	for _, tree := range engine.routeTrees().trees {
+		records = appendRouteRecords(records, tree.method, "", tree.root)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Path != records[j].Path {
+			return records[i].Path < records[j].Path
+		}
+		return records[i].Method < records[j].Method
+	})
+	for _, record := range records {
+		sink(record)
+	}
+}
+
+func appendRouteRecords(records []RouteRecord, method, path string, n *node) []RouteRecord {
+	path += n.path
+	if len(n.handlers) > 0 {
+		records = append(records, newRouteRecord(method, path, n.handlers))
+	}
+	for i := range n.children {
+		records = appendRouteRecords(records, method, path, &n.children[i])
+	}
+	return records
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteSink(t *testing.T) {
+	var records []RouteRecord
+	re := captureOutput(t, func() {
+		router := New()
+		router.SetMode(DebugMode)
+		router.RouteSink = func(r RouteRecord) { records = append(records, r) }
+		router.Use(NameHandler("auth", namedTestMiddleware("X-Auth")))
+		router.POST("/users", createUser)
+		router.GET("/users/:id", NameHandler("get-user", func(c *Context) {}))
+	})
+	assert.NotContains(t, re, "/users")
+	assert.Equal(t, []RouteRecord{
+		{
+			Method:     http.MethodPost,
+			Path:       "/users",
+			Handler:    "github.com/gin-gonic/gin.createUser",
+			Handlers:   []string{"auth", "github.com/gin-gonic/gin.createUser"},
+			Middleware: 1,
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/users/:id",
+			Handler:    "get-user",
+			Handlers:   []string{"auth", "get-user"},
+			Middleware: 1,
+		},
+	}, records)
+}
+
+func TestJSONRouteSinkAndEmitRoutes(t *testing.T) {
+	registered := new(bytes.Buffer)
+	router := New()
+	router.RouteSink = JSONRouteSink(registered)
+	router.DELETE("/users/:id", NameHandler("delete-user", func(c *Context) {}))
+	router.GET("/users", NameHandler("list-users", func(c *Context) {}))
+	router.GET("/users/:id", NameHandler("get-user", func(c *Context) {}))
+
+	assert.Equal(t, `{"method":"DELETE","path":"/users/:id","handler":"delete-user","handlers":["delete-user"],"middleware":0}
+{"method":"GET","path":"/users","handler":"list-users","handlers":["list-users"],"middleware":0}
+{"method":"GET","path":"/users/:id","handler":"get-user","handlers":["get-user"],"middleware":0}
+`, registered.String())
+
+	sorted := new(bytes.Buffer)
+	router.EmitRoutes(JSONRouteSink(sorted))
+	assert.Equal(t, `{"method":"GET","path":"/users","handler":"list-users","handlers":["list-users"],"middleware":0}
+{"method":"DELETE","path":"/users/:id","handler":"delete-user","handlers":["delete-user"],"middleware":0}
+{"method":"GET","path":"/users/:id","handler":"get-user","handlers":["get-user"],"middleware":0}
+`, sorted.String())
+}
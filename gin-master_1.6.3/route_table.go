@@ -0,0 +1,171 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// RouteEntry is a route of an exported RouteTable.
+type RouteEntry struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+	// Middleware are the function names of the middleware of the route, in order.
+	Middleware []string `json:"middleware,omitempty"`
+}
+
+// RouteTable is the exported table of the routes of an engine, sorted by path and method.
+// It's written and read as JSON, e.g. to compare the routes of two releases with
+// DiffRouteTables or the gin-routediff command.
+type RouteTable []RouteEntry
+
+// RouteTable returns the table of the registered routes.
+func (engine *Engine) RouteTable() RouteTable {
+	var table RouteTable
+	for _, tree := range engine.routeTrees().trees {
+		table = appendRouteEntries(table, tree.method, "", tree.root)
+	}
+	table.sort()
+	return table
+}
+
+func appendRouteEntries(table RouteTable, method, path string, n *node) RouteTable {
+	path += n.path
+	if len(n.handlers) > 0 {
+		entry := RouteEntry{
+			Method:  method,
+			Path:    path,
+			Handler: nameOfFunction(n.handlers.Last()),
+		}
+		for _, h := range n.handlers[:len(n.handlers)-1] {
+			entry.Middleware = append(entry.Middleware, nameOfFunction(h))
+		}
+		table = append(table, entry)
+	}
+	for i := range n.children {
+		table = appendRouteEntries(table, method, path, &n.children[i])
+	}
+	return table
+}
+
+func (table RouteTable) sort() {
+	sort.Slice(table, func(i, j int) bool {
+		if table[i].Path != table[j].Path {
+			return table[i].Path < table[j].Path
+		}
+		return table[i].Method < table[j].Method
+	})
+}
+
+// WriteJSON writes the table as JSON.
+func (table RouteTable) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(table, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadRouteTable reads a table written by RouteTable.WriteJSON.
+func ReadRouteTable(r io.Reader) (RouteTable, error) {
+	var table RouteTable
+	if err := json.NewDecoder(r).Decode(&table); err != nil {
+		return nil, fmt.Errorf("invalid route table: %w", err)
+	}
+	table.sort()
+	return table, nil
+}
+
+// RouteChange is a route whose handler or middleware changed.
+type RouteChange struct {
+	Old RouteEntry
+	New RouteEntry
+}
+
+// RouteDiff lists the differences between two route tables.
+type RouteDiff struct {
+	// Added are the routes which are only in the new table, i.e. newly exposed endpoints.
+	Added []RouteEntry
+	// Removed are the routes which are only in the old table.
+	Removed []RouteEntry
+	// Changed are the routes whose handler or middleware changed.
+	Changed []RouteChange
+}
+
+// DiffRouteTables compares the routes of two tables by method and path.
+func DiffRouteTables(oldTable, newTable RouteTable) RouteDiff {
+	key := func(e RouteEntry) string { return e.Method + " " + e.Path }
+	olds := make(map[string]RouteEntry, len(oldTable))
+	for _, e := range oldTable {
+		olds[key(e)] = e
+	}
+
+	var diff RouteDiff
+	news := make(map[string]bool, len(newTable))
+	for _, e := range newTable {
+		news[key(e)] = true
+		old, ok := olds[key(e)]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, e)
+		case old.Handler != e.Handler || !equalStrings(old.Middleware, e.Middleware):
+			diff.Changed = append(diff.Changed, RouteChange{Old: old, New: e})
+		}
+	}
+	for _, e := range oldTable {
+		if !news[key(e)] {
+			diff.Removed = append(diff.Removed, e)
+		}
+	}
+	return diff
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty reports whether the tables have the same routes.
+func (diff RouteDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+// String returns the differences, a line per route prefixed by "+" when added, "-"
+// when removed and "~" when changed.
+func (diff RouteDiff) String() string {
+	var b strings.Builder
+	for _, e := range diff.Added {
+		fmt.Fprintf(&b, "+ %-6s %s --> %s\n", e.Method, e.Path, e.Handler)
+	}
+	for _, e := range diff.Removed {
+		fmt.Fprintf(&b, "- %-6s %s --> %s\n", e.Method, e.Path, e.Handler)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(&b, "~ %-6s %s", change.New.Method, change.New.Path)
+		if change.Old.Handler != change.New.Handler {
+			fmt.Fprintf(&b, " handler: %s -> %s", change.Old.Handler, change.New.Handler)
+		}
+		if !equalStrings(change.Old.Middleware, change.New.Middleware) {
+			fmt.Fprintf(&b, " middleware: [%s] -> [%s]",
+				strings.Join(change.Old.Middleware, ", "), strings.Join(change.New.Middleware, ", "))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listUsers(c *Context)  {}
+func createUser(c *Context) {}
+func deleteUser(c *Context) {}
+
+func TestRouteTableDiff(t *testing.T) {
+	v1 := New()
+	v1.GET("/users", listUsers)
+	v1.POST("/users", createUser)
+	v1.DELETE("/users/:id", deleteUser)
+
+	v2 := New()
+	v2.Use(Recovery())
+	v2.GET("/users", listUsers)
+	v2.POST("/users", listUsers)
+	v2.GET("/admin/users", listUsers)
+
+	table := v1.RouteTable()
+	require.Len(t, table, 3)
+	assert.Equal(t, RouteEntry{Method: "GET", Path: "/users", Handler: "github.com/gin-gonic/gin.listUsers"}, table[0])
+	assert.Equal(t, "/users/:id", table[2].Path)
+
+	var buf bytes.Buffer
+	require.NoError(t, table.WriteJSON(&buf))
+	read, err := ReadRouteTable(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, table, read)
+	assert.True(t, DiffRouteTables(table, read).Empty())
+
+	diff := DiffRouteTables(table, v2.RouteTable())
+	assert.False(t, diff.Empty())
+	assert.Equal(t, []RouteEntry{v2.RouteTable()[0]}, diff.Added)
+	require.Len(t, diff.Removed, 1)
+	assert.Equal(t, "/users/:id", diff.Removed[0].Path)
+	require.Len(t, diff.Changed, 2)
+	assert.Equal(t, strings.Join([]string{
+		"+ GET    /admin/users --> github.com/gin-gonic/gin.listUsers",
+		"- DELETE /users/:id --> github.com/gin-gonic/gin.deleteUser",
+		"~ GET    /users middleware: [] -> [github.com/gin-gonic/gin.RecoveryWithConfig.func1]",
+		"~ POST   /users handler: github.com/gin-gonic/gin.createUser -> github.com/gin-gonic/gin.listUsers middleware: [] -> [github.com/gin-gonic/gin.RecoveryWithConfig.func1]",
+		"",
+	}, "\n"), diff.String())
+
+	_, err = ReadRouteTable(strings.NewReader("{"))
+	assert.Error(t, err)
+}
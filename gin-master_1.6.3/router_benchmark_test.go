@@ -0,0 +1,236 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// The benchmarks of the router by route set, to compare the changes of tree.go, e.g.:
+//     make bench > old.txt
+//     (apply the change)
+//     make bench > new.txt
+//     benchstat old.txt new.txt
+// Each op is the lookup of one path, the paths of all the routes of a set are looked up
+// in turn.
+
+// https://developers.google.com/+/api/latest/
+var googleAPI = []route{
+	// People
+	{http.MethodGet, "/people/:userId"},
+	{http.MethodGet, "/people"},
+	{http.MethodGet, "/activities/:activityId/people/:collection"},
+	{http.MethodGet, "/people/:userId/people/:collection"},
+	{http.MethodGet, "/people/:userId/openIdConnect"},
+	// Activities
+	{http.MethodGet, "/people/:userId/activities/:collection"},
+	{http.MethodGet, "/activities/:activityId"},
+	{http.MethodGet, "/activities"},
+	// Comments
+	{http.MethodGet, "/activities/:activityId/comments"},
+	{http.MethodGet, "/comments/:commentId"},
+	// Moments
+	{http.MethodPost, "/people/:userId/moments/:collection"},
+	{http.MethodGet, "/people/:userId/moments/:collection"},
+	{http.MethodDelete, "/moments/:id"},
+}
+
+// staticHeavyAPI returns static routes sharing long prefixes, as for documentation pages.
+func staticHeavyAPI() []route {
+	var routes []route
+	for _, section := range []string{"guide", "reference", "tutorials", "blog"} {
+		for i := 0; i < 50; i++ {
+			routes = append(routes, route{http.MethodGet, fmt.Sprintf("/docs/%s/page-%02d", section, i)})
+		}
+	}
+	return routes
+}
+
+// paramHeavyAPI returns routes with several params each, as for a nested REST API.
+func paramHeavyAPI() []route {
+	var routes []route
+	for _, resource := range []string{"orgs", "teams", "projects", "boards", "cards"} {
+		routes = append(routes,
+			route{http.MethodGet, "/" + resource + "/:id"},
+			route{http.MethodGet, "/" + resource + "/:id/members/:member"},
+			route{http.MethodGet, "/" + resource + "/:id/members/:member/roles/:role"},
+			route{http.MethodPut, "/" + resource + "/:id/members/:member/roles/:role"},
+		)
+	}
+	return routes
+}
+
+// catchAllHeavyAPI returns routes ending with catch-all params, as for file servers.
+func catchAllHeavyAPI() []route {
+	var routes []route
+	for i := 0; i < 20; i++ {
+		routes = append(routes, route{http.MethodGet, fmt.Sprintf("/assets-%02d/*filepath", i)})
+	}
+	return routes
+}
+
+var benchRouteSets = []struct {
+	name   string
+	routes []route
+}{
+	{"GitHub", githubAPI},
+	{"Google", googleAPI},
+	{"Static", staticHeavyAPI()},
+	{"Params", paramHeavyAPI()},
+	{"CatchAll", catchAllHeavyAPI()},
+}
+
+// benchPath returns the path matching the route, with fixed values for the params.
+func benchPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			segments[i] = "value"
+		case strings.HasPrefix(segment, "*"):
+			segments[i] = "css/site/main.css"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// BenchmarkTreeLookup measures the lookups in the tree, without the Engine.
+func BenchmarkTreeLookup(b *testing.B) {
+	for _, set := range benchRouteSets {
+		trees := make(map[string]*node)
+		var maxParams uint16
+		for _, r := range set.routes {
+			if trees[r.method] == nil {
+				trees[r.method] = &node{fullPath: "/"}
+			}
+			trees[r.method].addRoute(r.path, fakeHandler(r.path))
+			if n := countParams(r.path); n > maxParams {
+				maxParams = n
+			}
+		}
+		roots := make([]*node, len(set.routes))
+		paths := make([]string, len(set.routes))
+		for i, r := range set.routes {
+			roots[i] = trees[r.method]
+			paths[i] = benchPath(r.path)
+		}
+		params := make(Params, 0, maxParams)
+
+		b.Run(set.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				j := i % len(paths)
+				params = params[:0]
+				if value := roots[j].getValue(paths[j], &params, false); value.handlers == nil {
+					b.Fatalf("%s is not found", paths[j])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRouterLookup measures the requests served by an Engine, whose handlers do nothing.
+func BenchmarkRouterLookup(b *testing.B) {
+	for _, set := range benchRouteSets {
+		router := New()
+		requests := make([]*http.Request, len(set.routes))
+		for i, r := range set.routes {
+			router.Handle(r.method, r.path, func(c *Context) {})
+			requests[i], _ = http.NewRequest(r.method, benchPath(r.path), nil)
+		}
+		w := newMockWriter()
+
+		b.Run(set.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				router.ServeHTTP(w, requests[i%len(requests)])
+			}
+		})
+	}
+}
+
+// BenchmarkOptimizedRouterLookup is BenchmarkRouterLookup once Optimize collected the
+// static routes.
+func BenchmarkOptimizedRouterLookup(b *testing.B) {
+	for _, set := range benchRouteSets {
+		router := New()
+		requests := make([]*http.Request, len(set.routes))
+		for i, r := range set.routes {
+			router.Handle(r.method, r.path, func(c *Context) {})
+			requests[i], _ = http.NewRequest(r.method, benchPath(r.path), nil)
+		}
+		router.Optimize()
+		w := newMockWriter()
+
+		b.Run(set.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				router.ServeHTTP(w, requests[i%len(requests)])
+			}
+		})
+	}
+}
+
+// BenchmarkCaseInsensitiveLookup measures the lookups of the paths in upper case, as
+// done by RedirectFixedPath.
+func BenchmarkCaseInsensitiveLookup(b *testing.B) {
+	for _, set := range benchRouteSets {
+		trees := make(map[string]*node)
+		for _, r := range set.routes {
+			if trees[r.method] == nil {
+				trees[r.method] = &node{fullPath: "/"}
+			}
+			trees[r.method].addRoute(r.path, fakeHandler(r.path))
+		}
+		roots := make([]*node, len(set.routes))
+		paths := make([]string, len(set.routes))
+		for i, r := range set.routes {
+			roots[i] = trees[r.method]
+			paths[i] = strings.ToUpper(benchPath(r.path))
+		}
+
+		b.Run(set.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				j := i % len(paths)
+				if _, found := roots[j].findCaseInsensitivePathString(paths[j], true); !found {
+					b.Fatalf("%s is not found", paths[j])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRedirectFixedPath measures the requests redirected to the path in the case of
+// the route, and the ones which no route matches in any case.
+func BenchmarkRedirectFixedPath(b *testing.B) {
+	router := New()
+	router.RedirectFixedPath = true
+	for _, r := range githubAPI {
+		router.Handle(r.method, r.path, func(c *Context) {})
+	}
+	w := newMockWriter()
+	for _, test := range []struct{ name, path string }{
+		{"Redirected", "/REPOS/GIN-GONIC/GIN/ISSUES"},
+		{"NotFound", "/NOWHERE/AT/ALL"},
+	} {
+		path := test.path
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		b.Run(test.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				req.URL.Path = path
+				router.ServeHTTP(w, req)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@
 package gin
 
 import (
+	"errors"
 	"net/http"
 	"path"
 	"regexp"
@@ -36,6 +37,8 @@ type IRoutes interface {
 	StaticFile(string, string) IRoutes
 	Static(string, string) IRoutes
 	StaticFS(string, http.FileSystem) IRoutes
+
+	Name(string) IRoutes
 }
 
 // RouterGroup is used internally to configure router, a RouterGroup is associated with
@@ -45,6 +48,23 @@ type RouterGroup struct {
 	basePath string // 路由组的基准路径
 	engine   *Engine // 保有engine的指针
 	root     bool // 是否根routerGroup对象
+
+	// bodyLimitIndex is the position in Handlers, plus one, of the limit set by MaxBodyBytes.
+	bodyLimitIndex int
+	// meta is attached to the routes of the group, see WithMeta.
+	meta RouteMeta
+	// names are the names of Handlers, see UseNamed. It may be shorter than Handlers,
+	// whose last middleware are then unnamed.
+	names []string
+	// routeName is the name of the routes registered with the group, see Named.
+	routeName string
+	// host is the pattern of the host of the routes of the group, see Host.
+	host string
+	// trailingSlash is the policy of the routes of the group, see TrailingSlash.
+	trailingSlash TrailingSlashPolicy
+	// the path and methods of the route registered last with the group, see Name
+	lastPath    string
+	lastMethods []string
 }
 
 // RouterGroup实现了IRouter接口
@@ -63,6 +83,12 @@ func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *R
 		Handlers: group.combineHandlers(handlers), // handlers包含了当前路由组的中间件和所有祖先routerGroup的中间件
 		basePath: group.calculateAbsolutePath(relativePath),
 		engine:   group.engine,
+
+		bodyLimitIndex: group.bodyLimitIndex,
+		meta:           group.meta,
+		names:          group.names,
+		host:           group.host,
+		trailingSlash:  group.trailingSlash,
 	}
 }
 
@@ -75,10 +101,40 @@ func (group *RouterGroup) BasePath() string {
 func (group *RouterGroup) handle(httpMethod, relativePath string, handlers HandlersChain) IRoutes {
 	absolutePath := group.calculateAbsolutePath(relativePath)
 	handlers = group.combineHandlers(handlers)
-	group.engine.addRoute(httpMethod, absolutePath, handlers)
+	if err := group.engine.addRouteMeta(httpMethod, absolutePath, handlers, group.routeOptions()); err != nil {
+		panic(err.Error())
+	}
+	group.registered(httpMethod, absolutePath)
 	return group.returnObj()
 }
 
+// routeOptions are the settings of the routes taken from the group registering them.
+type routeOptions struct {
+	host          string
+	meta          RouteMeta
+	name          string
+	trailingSlash TrailingSlashPolicy
+}
+
+func (group *RouterGroup) routeOptions() routeOptions {
+	return routeOptions{
+		host:          group.host,
+		meta:          group.meta,
+		name:          group.routeName,
+		trailingSlash: group.trailingSlash,
+	}
+}
+
+// registered records the route registered last with the group, see Name.
+func (group *RouterGroup) registered(httpMethod, absolutePath string) {
+	group.engine.treesMu.Lock()
+	defer group.engine.treesMu.Unlock()
+	if group.lastPath != absolutePath {
+		group.lastPath, group.lastMethods = absolutePath, nil
+	}
+	group.lastMethods = append(group.lastMethods, httpMethod)
+}
+
 // Handle registers a new request handle and middleware with the given path and method.
 // The last handler should be the real handler, the other ones should be middleware that can and should be shared among different routes.
 // See the example code in GitHub.
@@ -89,6 +145,34 @@ func (group *RouterGroup) handle(httpMethod, relativePath string, handlers Handl
 // This function is intended for bulk loading and to allow the usage of less
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
+//
+// A param may be constrained with a regular expression its whole value must match,
+// so that params of different constraints can share a path segment:
+//     router.GET("/users/:id([0-9]+)", getUserByID)
+//     router.GET("/users/:login", getUserByLogin)
+// The params with a constraint are tried in the order they're registered, then the
+// one without a constraint, if any.
+//
+// A param may also be constrained with a type registered with RegisterParamType, e.g.
+// int, uuid or date. The static paths of a segment are matched before its params, so
+// they can share it with params which all have a constraint:
+//     router.GET("/orders/latest", getLatestOrder)
+//     router.GET("/orders/:id|int", getOrder)
+//
+// A param making the last segment of the path is optional when it ends with '?', the
+// handlers are then registered for the path with and without it:
+//     router.GET("/articles/:id/:rev?", getArticle) // /articles/1 and /articles/1/3
+//
+// A catch-all may be followed by the rest of the path, then its value is the longest run
+// of segments after which the rest matches, and the routes after it are tried before the
+// one ending with it, if any:
+//     router.GET("/repos/*path/blame/:line", blame) // /repos/gin-gonic/gin/blame/42
+//     router.GET("/repos/*path", browse)
+//
+// The routes may be registered, or removed with Engine.RemoveRoute, while the engine
+// serves requests: each change is made to a copy of the tree of the method, which then
+// replaces it atomically with the metadata of the routes, so the lookups never lock
+// nor see a route partially added. The changes themselves are serialized.
 func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...HandlerFunc) IRoutes {
 	if matches, err := regexp.MatchString("^[A-Z]+$", httpMethod); !matches || err != nil {
 		panic("http method " + httpMethod + " is not valid")
@@ -96,6 +180,50 @@ func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...Ha
 	return group.handle(httpMethod, relativePath, handlers)
 }
 
+// RouteError is the error of a route which can't be registered, see TryHandle.
+type RouteError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+func (e *RouteError) Error() string {
+	return "gin: can not register " + e.Method + " " + e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the route.
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// TryHandle registers the route as Handle does, but returns a *RouteError instead of
+// panicking when the route can't be registered, e.g. when it conflicts with another one
+// or its path is invalid, so that the routes loaded from a configuration at runtime
+// can be rejected:
+//     if err := router.TryHandle(route.Method, route.Path, proxy(route)); err != nil {
+//         log.Printf("skipped route: %v", err)
+//     }
+// The routes already registered are left unchanged by a failed registration.
+func (group *RouterGroup) TryHandle(httpMethod, relativePath string, handlers ...HandlerFunc) (err error) {
+	absolutePath := group.calculateAbsolutePath(relativePath)
+	defer func() {
+		if err != nil {
+			err = &RouteError{Method: httpMethod, Path: absolutePath, Err: err}
+		}
+	}()
+	defer recoverRouteError(&err)
+
+	if matches, err := regexp.MatchString("^[A-Z]+$", httpMethod); !matches || err != nil {
+		return errors.New("http method " + httpMethod + " is not valid")
+	}
+	err = group.engine.addRouteMeta(httpMethod, absolutePath, group.combineHandlers(handlers), group.routeOptions())
+	if err != nil {
+		return err
+	}
+	group.registered(httpMethod, absolutePath)
+	return nil
+}
+
 // POST is a shortcut for router.Handle("POST", path, handle).
 func (group *RouterGroup) POST(relativePath string, handlers ...HandlerFunc) IRoutes {
 	return group.handle(http.MethodPost, relativePath, handlers)
@@ -153,6 +281,9 @@ func (group *RouterGroup) StaticFile(relativePath, filepath string) IRoutes {
 		panic("URL parameters can not be used when serving a static file")
 	}
 	handler := func(c *Context) {
+		if servePrecompressed(c, http.Dir(path.Dir(filepath)), "/"+path.Base(filepath)) {
+			return
+		}
 		c.File(filepath)
 	}
 	group.GET(relativePath, handler)
@@ -206,6 +337,9 @@ func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileS
 		}
 		f.Close()
 
+		if servePrecompressed(c, fs, file) {
+			return
+		}
 		fileServer.ServeHTTP(c.Writer, c.Request)
 	}
 }
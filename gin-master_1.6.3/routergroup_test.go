@@ -5,6 +5,7 @@
 package gin
 
 import (
+	"errors"
 	"net/http"
 	"testing"
 
@@ -125,6 +126,40 @@ func TestRouterGroupTooManyHandlers(t *testing.T) {
 	})
 }
 
+func TestRouterGroupTryHandle(t *testing.T) {
+	router := New()
+	api := router.Group("/api")
+	assert.NoError(t, api.TryHandle(http.MethodGet, "/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "user "+c.Param("id"))
+	}))
+
+	err := api.TryHandle(http.MethodGet, "/users/:id", func(c *Context) {})
+	var routeErr *RouteError
+	if assert.True(t, errors.As(err, &routeErr)) {
+		assert.Equal(t, http.MethodGet, routeErr.Method)
+		assert.Equal(t, "/api/users/:id", routeErr.Path)
+		assert.EqualError(t, routeErr.Unwrap(), "handlers are already registered for path '/api/users/:id'")
+	}
+	assert.EqualError(t, err, "gin: can not register GET /api/users/:id: handlers are already registered for path '/api/users/:id'")
+
+	assert.Error(t, api.TryHandle(http.MethodGet, "/users/:name/posts", func(c *Context) {}))
+	assert.Error(t, api.TryHandle(http.MethodGet, "/users/new", func(c *Context) {}))
+	assert.Error(t, api.TryHandle(http.MethodGet, "/files/*", func(c *Context) {}))
+	assert.Error(t, api.TryHandle(http.MethodGet, "/posts?", func(c *Context) {}))
+	assert.Error(t, api.TryHandle("get", "/status", func(c *Context) {}))
+	assert.Error(t, api.TryHandle(http.MethodGet, "/status"))
+	assert.Error(t, api.TryHandle(http.MethodGet, "/status", make([]HandlerFunc, 64)...))
+
+	// a route ending with an optional param is added with and without it, or not at all
+	assert.Error(t, api.TryHandle(http.MethodGet, "/users/:id/:tab?", func(c *Context) {}))
+	w := performRequest(router, http.MethodGet, "/api/users/1/posts")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(router, http.MethodGet, "/api/users/1")
+	assert.Equal(t, "user 1", w.Body.String())
+	assert.Len(t, router.Routes(), 1)
+}
+
 func TestRouterGroupBadMethod(t *testing.T) {
 	router := New()
 	assert.Panics(t, func() {
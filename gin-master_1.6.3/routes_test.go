@@ -11,6 +11,8 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -224,6 +226,64 @@ func TestRouteRedirectFixedPath(t *testing.T) {
 	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
 }
 
+func TestRouteMatchCaseInsensitive(t *testing.T) {
+	router := New()
+	router.MatchCaseInsensitive = true
+	router.RedirectFixedPath = true
+
+	handler := func(c *Context) {
+		c.String(http.StatusOK, "%s %s %s", c.FullPath(), c.Request.URL.Path, c.Param("name"))
+	}
+	router.GET("/users/:name/Profile", handler)
+	router.GET("/docs/go1.html", handler)
+	router.GET("/DOCS/go1.html", func(c *Context) { c.String(http.StatusOK, "upper") })
+
+	w := performRequest(router, http.MethodGet, "/USERS/Gopher/profile")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/users/:name/Profile /USERS/Gopher/profile Gopher", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/Docs/GO1.html")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the exact matches come first
+	w = performRequest(router, http.MethodGet, "/DOCS/go1.html")
+	assert.Equal(t, "upper", w.Body.String())
+
+	// the paths to clean are still redirected
+	w = performRequest(router, http.MethodGet, "/docs/../Docs/GO1.html")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	w = performRequest(router, http.MethodGet, "/users/gopher")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	match, ok := router.Match(http.MethodGet, "/Users/Gopher/PROFILE")
+	if assert.True(t, ok) {
+		assert.Equal(t, "/users/:name/Profile", match.Path)
+		assert.Equal(t, "Gopher", match.Params.ByName("name"))
+	}
+}
+
+func TestRouteNormalizePath(t *testing.T) {
+	router := New()
+	// composes the decomposed é, as norm.NFC.String does
+	router.NormalizePath = strings.NewReplacer("e\u0301", "\u00e9").Replace
+	router.GET("/caf\u00e9/:item", func(c *Context) {
+		c.String(http.StatusOK, "%s %s", c.Param("item"), c.Request.URL.Path)
+	})
+
+	w := performRequest(router, http.MethodGet, "/caf\u00e9/cr\u00e8me")
+	assert.Equal(t, "cr\u00e8me /caf\u00e9/cr\u00e8me", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/cafe\u0301/the\u0301")
+	assert.Equal(t, "th\u00e9 /cafe\u0301/the\u0301", w.Body.String())
+
+	match, ok := router.Match(http.MethodGet, "/cafe%CC%81/latte")
+	if assert.True(t, ok) {
+		assert.Equal(t, "/caf\u00e9/:item", match.Path)
+	}
+
+	router.NormalizePath = nil
+	w = performRequest(router, http.MethodGet, "/cafe\u0301/the\u0301")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 // TestContextParamsGet tests that a parameter can be parsed from the URL.
 func TestRouteParamsByName(t *testing.T) {
 	name := ""
@@ -258,6 +318,264 @@ func TestRouteParamsByName(t *testing.T) {
 }
 
 // TestContextParamsGet tests that a parameter can be parsed from the URL even with extra slashes.
+func TestRouteParamConstraints(t *testing.T) {
+	router := New()
+	router.GET("/users/:id([0-9]+)", func(c *Context) {
+		c.String(http.StatusOK, "id "+c.Param("id"))
+	})
+	router.GET("/users/:slug", func(c *Context) {
+		c.String(http.StatusOK, "slug "+c.Param("slug")+" "+c.FullPath())
+	})
+	assert.PanicsWithValue(t, "handlers are already registered for path '/users/:id([0-9]+)'", func() {
+		router.GET("/users/:id([0-9]+)", func(c *Context) {})
+	})
+	assert.Panics(t, func() {
+		router.GET("/users/:name", func(c *Context) {})
+	})
+
+	w := performRequest(router, http.MethodGet, "/users/42")
+	assert.Equal(t, "id 42", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/users/gopher")
+	assert.Equal(t, "slug gopher /users/:slug", w.Body.String())
+
+	var paths []string
+	for _, route := range router.Routes() {
+		paths = append(paths, route.Path)
+	}
+	assert.ElementsMatch(t, []string{"/users/:id([0-9]+)", "/users/:slug"}, paths)
+
+	router.Optimize()
+	w = performRequest(router, http.MethodGet, "/users/7")
+	assert.Equal(t, "id 7", w.Body.String())
+}
+
+func TestRouteParamConstraintsFallThrough(t *testing.T) {
+	router := New()
+	router.RedirectFixedPath = true
+	router.GET("/users/:id([0-9]+)/posts", func(c *Context) {
+		c.String(http.StatusOK, "posts "+c.Param("id"))
+	})
+	router.GET("/users/:name/profile", func(c *Context) {
+		c.String(http.StatusOK, "profile %s %v", c.Param("name"), c.Params)
+	})
+
+	// the param without constraint is tried once the rest of the path doesn't match
+	// below the one whose constraint matches the value
+	w := performRequest(router, http.MethodGet, "/users/123/profile")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "profile 123 [{name 123}]", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/users/123/posts")
+	assert.Equal(t, "posts 123", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/users/gopher/posts")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(router, http.MethodGet, "/users/123/PROFILE")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/users/123/profile", w.Header().Get("Location"))
+}
+
+func TestRouteTypedParams(t *testing.T) {
+	router := New()
+	router.RedirectTrailingSlash = true
+	router.GET("/orders/:id|int", func(c *Context) {
+		c.String(http.StatusOK, "order "+c.Param("id")+" "+c.FullPath())
+	})
+	router.GET("/orders/latest", func(c *Context) {
+		c.String(http.StatusOK, "latest")
+	})
+	router.GET("/events/:day|date", func(c *Context) {
+		c.String(http.StatusOK, "day "+c.Param("day"))
+	})
+	assert.Panics(t, func() {
+		router.GET("/orders/:name", func(c *Context) {})
+	})
+
+	w := performRequest(router, http.MethodGet, "/orders/42")
+	assert.Equal(t, "order 42 /orders/:id|int", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/orders/latest")
+	assert.Equal(t, "latest", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/orders/oldest")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(router, http.MethodGet, "/orders/latest/")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/orders/latest", w.Header().Get("Location"))
+	w = performRequest(router, http.MethodGet, "/events/2021-03-01")
+	assert.Equal(t, "day 2021-03-01", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/events/2021-02-30")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	router.Optimize()
+	w = performRequest(router, http.MethodGet, "/orders/latest")
+	assert.Equal(t, "latest", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/orders/7")
+	assert.Equal(t, "order 7 /orders/:id|int", w.Body.String())
+}
+
+func TestRouteRemove(t *testing.T) {
+	router := New()
+	router.WithMeta("plugin", "reports").GET("/plugins/reports/export", func(c *Context) {
+		c.String(http.StatusOK, "export")
+	})
+	router.GET("/plugins/reports/import", func(c *Context) {
+		c.String(http.StatusOK, "import")
+	})
+	router.GET("/status/:name/:page?", func(c *Context) {
+		c.String(http.StatusOK, "status %s %s", c.Param("name"), c.Param("page"))
+	})
+	router.POST("/plugins/reports/export", func(c *Context) {})
+	router.Optimize()
+
+	assert.True(t, router.RemoveRoute(http.MethodGet, "/plugins/reports/export"))
+	assert.False(t, router.RemoveRoute(http.MethodGet, "/plugins/reports/export"))
+	assert.False(t, router.RemoveRoute(http.MethodGet, "/status/:other"))
+	assert.False(t, router.RemoveRoute(http.MethodPut, "/plugins/reports/export"))
+
+	w := performRequest(router, http.MethodGet, "/plugins/reports/export")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(router, http.MethodGet, "/plugins/reports/import")
+	assert.Equal(t, "import", w.Body.String())
+	w = performRequest(router, http.MethodPost, "/plugins/reports/export")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the route can be added again, without the metadata of the removed one
+	router.GET("/plugins/reports/export", func(c *Context) {
+		_, exists := c.RouteMeta("plugin")
+		c.String(http.StatusOK, "export again %v", exists)
+	})
+	w = performRequest(router, http.MethodGet, "/plugins/reports/export")
+	assert.Equal(t, "export again false", w.Body.String())
+
+	w = performRequest(router, http.MethodGet, "/status/reports/2")
+	assert.Equal(t, "status reports 2", w.Body.String())
+	assert.True(t, router.RemoveRoute(http.MethodGet, "/status/:name/:page?"))
+	w = performRequest(router, http.MethodGet, "/status/reports")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(router, http.MethodGet, "/status/reports/2")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var paths []string
+	for _, route := range router.Routes() {
+		paths = append(paths, route.Method+" "+route.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		"GET /plugins/reports/export",
+		"GET /plugins/reports/import",
+		"POST /plugins/reports/export",
+	}, paths)
+}
+
+func TestRouteRemoveCompiled(t *testing.T) {
+	router := New()
+	router.Matcher = MatcherDoubleArray
+	router.GET("/a", func(c *Context) { c.String(http.StatusOK, "a") })
+	router.GET("/b", func(c *Context) { c.String(http.StatusOK, "b") })
+	router.CompileRoutes()
+
+	assert.True(t, router.RemoveRoute(http.MethodGet, "/a"))
+	assert.NotNil(t, router.compiledRoutes())
+	w := performRequest(router, http.MethodGet, "/a")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(router, http.MethodGet, "/b")
+	assert.Equal(t, "b", w.Body.String())
+
+	assert.True(t, router.RemoveRoute(http.MethodGet, "/b"))
+	w = performRequest(router, http.MethodGet, "/b")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRouteConcurrentRegistration(t *testing.T) {
+	router := New()
+	router.GET("/ping", func(c *Context) { c.String(http.StatusOK, "pong") })
+
+	const workers, routes = 4, 50
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			group := router.Group(fmt.Sprintf("/w%d", w)).WithMeta("worker", w)
+			for i := 0; i < routes; i++ {
+				group.GET(fmt.Sprintf("/r%d/:id", i), func(c *Context) {
+					worker, _ := c.RouteMeta("worker")
+					c.String(http.StatusOK, "%v %s", worker, c.Param("id"))
+				})
+				if i%2 == 1 {
+					router.RemoveRoute(http.MethodGet, fmt.Sprintf("/w%d/r%d/:id", w, i-1))
+				}
+			}
+		}(w)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < routes; i++ {
+				r := performRequest(router, http.MethodGet, "/ping")
+				assert.Equal(t, "pong", r.Body.String())
+				performRequest(router, http.MethodGet, fmt.Sprintf("/w%d/r%d/7", w, i))
+				router.Routes()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.Len(t, router.Routes(), 1+workers*routes/2)
+	w := performRequest(router, http.MethodGet, "/w2/r3/7")
+	assert.Equal(t, "2 7", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/w2/r2/7")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRouteOptionalParam(t *testing.T) {
+	router := New()
+	router.WithMeta("scope", "articles").GET("/articles/:id/:rev?", func(c *Context) {
+		scope, _ := c.RouteMeta("scope")
+		c.String(http.StatusOK, "%s %s %s %v", c.Param("id"), c.Param("rev"), c.FullPath(), scope)
+	})
+	assert.Panics(t, func() {
+		router.GET("/articles/:id", func(c *Context) {})
+	})
+	assert.Panics(t, func() {
+		router.GET("/drafts/v:rev?", func(c *Context) {})
+	})
+
+	w := performRequest(router, http.MethodGet, "/articles/1")
+	assert.Equal(t, "1  /articles/:id articles", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/articles/1/3")
+	assert.Equal(t, "1 3 /articles/:id/:rev articles", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/articles")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	routes := router.Routes()
+	if assert.Len(t, routes, 2) {
+		assert.ElementsMatch(t, []string{"/articles/:id", "/articles/:id/:rev"}, []string{routes[0].Path, routes[1].Path})
+	}
+}
+
+func TestRouteMidPathCatchAll(t *testing.T) {
+	router := New()
+	router.RedirectFixedPath = true
+	router.GET("/repos/*path/blame/:line", func(c *Context) {
+		c.String(http.StatusOK, "%s %s %s", c.Param("path"), c.Param("line"), c.FullPath())
+	}).Name("blame")
+	router.GET("/repos/*path", func(c *Context) {
+		c.String(http.StatusOK, "%s %s", c.Param("path"), c.FullPath())
+	})
+
+	w := performRequest(router, http.MethodGet, "/repos/gin-gonic/gin/blame/42")
+	assert.Equal(t, "/gin-gonic/gin 42 /repos/*path/blame/:line", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/repos/gin-gonic/gin/blob")
+	assert.Equal(t, "/gin-gonic/gin/blob /repos/*path", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/REPOS/gin/blame/42")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/repos/gin/blame/42", w.Header().Get("Location"))
+
+	url, err := router.URLFor("blame", H{"path": "gin-gonic/gin", "line": 42})
+	assert.NoError(t, err)
+	assert.Equal(t, "/repos/gin-gonic/gin/blame/42", url)
+
+	assert.True(t, router.RemoveRoute(http.MethodGet, "/repos/*path/blame/:line"))
+	w = performRequest(router, http.MethodGet, "/repos/gin-gonic/gin/blame/42")
+	assert.Equal(t, "/gin-gonic/gin/blame/42 /repos/*path", w.Body.String())
+}
+
 func TestRouteParamsByNameWithExtraSlash(t *testing.T) {
 	name := ""
 	lastName := ""
@@ -0,0 +1,531 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// SessionKey is the key the session is set under in the Context, see Context.Session.
+const SessionKey = "session"
+
+// ErrSessionTooLarge is returned when the session doesn't fit in a cookie.
+var ErrSessionTooLarge = errors.New("gin: session too large for a cookie")
+
+// maxCookieSize is the size of the cookies the browsers are required to keep.
+const maxCookieSize = 4096
+
+// SessionStore keeps the data of the sessions by ID. Get returns nil data for unknown or
+// expired sessions.
+type SessionStore interface {
+	Get(ctx context.Context, id string) ([]byte, error)
+	Set(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+	Touch(ctx context.Context, id string, ttl time.Duration) error
+}
+
+// SessionEncoder is implemented by the stores keeping the data in the cookie itself,
+// such as the one of NewCookieSessionStore: the cookie holds the encoded data instead
+// of an ID, and it's given back to Get.
+type SessionEncoder interface {
+	Encode(data []byte, ttl time.Duration) (string, error)
+}
+
+// SessionConfig defines the config for Sessions middleware.
+type SessionConfig struct {
+	// Store keeps the data of the sessions.
+	// Required.
+	Store SessionStore
+
+	// Name is the name of the cookie.
+	// Optional. Default value is "session".
+	Name string
+
+	// TTL is how long the sessions are kept after they were last saved.
+	// Optional. Default value is 24 hours.
+	TTL time.Duration
+
+	// Rolling extends the sessions on every request, not only when they are modified.
+	// Optional.
+	Rolling bool
+
+	// Path, Domain, Secure and SameSite are the attributes of the cookie, which is
+	// always HttpOnly.
+	// Optional. Default value of Path is "/", and of SameSite is http.SameSiteLaxMode.
+	Path     string
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// Session holds the values of a client across requests. Its values are encoded in JSON,
+// so numbers are read back as float64.
+type Session struct {
+	id         string
+	values     map[string]interface{}
+	changed    bool
+	regenerate bool
+	destroyed  bool
+	saved      bool
+}
+
+// ID returns the ID of the session, empty for new sessions and the sessions kept in cookies.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Get returns the value of key.
+func (s *Session) Get(key string) (interface{}, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// GetString returns the value of key as a string.
+func (s *Session) GetString(key string) string {
+	v, _ := s.values[key].(string)
+	return v
+}
+
+// Set sets the value of key.
+func (s *Session) Set(key string, value interface{}) {
+	if s.values == nil {
+		s.values = make(map[string]interface{})
+	}
+	s.values[key] = value
+	s.changed = true
+}
+
+// Delete deletes the value of key.
+func (s *Session) Delete(key string) {
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.changed = true
+	}
+}
+
+// Clear deletes all the values.
+func (s *Session) Clear() {
+	if len(s.values) > 0 {
+		s.values = nil
+		s.changed = true
+	}
+}
+
+// Regenerate gives the session a new ID, which should be done when the user logs in
+// to prevent session fixation.
+func (s *Session) Regenerate() {
+	s.regenerate = true
+	s.changed = true
+}
+
+// Destroy deletes the session from the store and the client.
+func (s *Session) Destroy() {
+	s.values = nil
+	s.destroyed = true
+	s.changed = true
+}
+
+// Session returns the session loaded by the Sessions middleware, or nil.
+func (c *Context) Session() *Session {
+	s, _ := c.Get(SessionKey)
+	session, _ := s.(*Session)
+	return session
+}
+
+// Sessions returns a middleware loading the session of the client, from the ID in its
+// cookie or from the cookie itself depending on the store:
+//     router.Use(gin.Sessions(gin.SessionConfig{Store: gin.NewMemorySessionStore(), Secure: true}))
+//
+//     router.POST("/login", func(c *gin.Context) {
+//         session := c.Session()
+//         session.Regenerate()
+//         session.Set("user", user)
+//     })
+// The session is saved when it's modified, before the response headers are written.
+// The errors of the store are added to the context.
+func Sessions(conf SessionConfig) HandlerFunc {
+	assert1(conf.Store != nil, "session store can not be nil")
+	if conf.Name == "" {
+		conf.Name = "session"
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = 24 * time.Hour
+	}
+	if conf.Path == "" {
+		conf.Path = "/"
+	}
+	if conf.SameSite == 0 {
+		conf.SameSite = http.SameSiteLaxMode
+	}
+
+	return func(c *Context) {
+		session := &Session{}
+		if cookie, err := c.Request.Cookie(conf.Name); err == nil && cookie.Value != "" {
+			data, err := conf.Store.Get(c.Request.Context(), cookie.Value)
+			if err != nil {
+				_ = c.Error(err)
+			} else if data != nil {
+				if err = json.Unmarshal(data, &session.values); err != nil {
+					_ = c.Error(fmt.Errorf("session: %w", err))
+				} else if _, ok := conf.Store.(SessionEncoder); !ok {
+					session.id = cookie.Value
+				}
+			}
+		}
+		c.Set(SessionKey, session)
+
+		w := &sessionWriter{ResponseWriter: c.Writer, save: func() {
+			if err := saveSession(c, conf, session); err != nil {
+				_ = c.Error(err)
+			}
+		}}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if !w.Written() {
+			w.beforeWrite()
+		}
+	}
+}
+
+func saveSession(c *Context, conf SessionConfig, session *Session) error {
+	if session.saved {
+		return nil
+	}
+	session.saved = true
+	ctx := c.Request.Context()
+	encoder, inCookie := conf.Store.(SessionEncoder)
+
+	if session.destroyed || (session.changed && len(session.values) == 0) {
+		if session.id != "" {
+			if err := conf.Store.Delete(ctx, session.id); err != nil {
+				return err
+			}
+		}
+		if session.id != "" || (inCookie && session.changed) {
+			setSessionCookie(c, conf, "", -1)
+		}
+		return nil
+	}
+	if !session.changed {
+		if !conf.Rolling || (session.id == "" && !inCookie) || len(session.values) == 0 {
+			return nil
+		}
+		if session.id != "" {
+			if err := conf.Store.Touch(ctx, session.id, conf.TTL); err != nil {
+				return err
+			}
+			setSessionCookie(c, conf, session.id, conf.TTL)
+			return nil
+		}
+	}
+
+	data, err := json.Marshal(session.values)
+	if err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	if inCookie {
+		value, err := encoder.Encode(data, conf.TTL)
+		if err != nil {
+			return err
+		}
+		setSessionCookie(c, conf, value, conf.TTL)
+		return nil
+	}
+	if session.regenerate && session.id != "" {
+		if err = conf.Store.Delete(ctx, session.id); err != nil {
+			return err
+		}
+		session.id = ""
+	}
+	if session.id == "" {
+		session.id = newSessionID()
+	}
+	if err = conf.Store.Set(ctx, session.id, data, conf.TTL); err != nil {
+		return err
+	}
+	setSessionCookie(c, conf, session.id, conf.TTL)
+	return nil
+}
+
+func setSessionCookie(c *Context, conf SessionConfig, value string, ttl time.Duration) {
+	maxAge := -1
+	if ttl > 0 {
+		maxAge = int(ttl / time.Second)
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     conf.Name,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     conf.Path,
+		Domain:   conf.Domain,
+		Secure:   conf.Secure,
+		HttpOnly: true,
+		SameSite: conf.SameSite,
+	})
+}
+
+func newSessionID() string {
+	var id [32]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+// sessionWriter saves the session before the headers are written, so that its cookie
+// is sent.
+type sessionWriter struct {
+	ResponseWriter
+	save func()
+	done bool
+}
+
+func (w *sessionWriter) beforeWrite() {
+	if !w.done {
+		w.done = true
+		w.save()
+	}
+}
+
+func (w *sessionWriter) WriteHeaderNow() {
+	w.beforeWrite()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *sessionWriter) Write(data []byte) (int, error) {
+	w.beforeWrite()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *sessionWriter) WriteString(s string) (int, error) {
+	w.beforeWrite()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *sessionWriter) Flush() {
+	w.beforeWrite()
+	w.ResponseWriter.Flush()
+}
+
+func (w *sessionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.done = true
+	return w.ResponseWriter.Hijack()
+}
+
+// MemorySessionStore is a SessionStore keeping the sessions in memory, for a single
+// instance or for tests.
+type MemorySessionStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemorySessionStore returns an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession), now: time.Now}
+}
+
+// Get implements SessionStore.
+func (s *MemorySessionStore) Get(_ context.Context, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok || !s.now().Before(session.expires) {
+		return nil, nil
+	}
+	return session.data, nil
+}
+
+// Set implements SessionStore.
+func (s *MemorySessionStore) Set(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sessions[id] = memorySession{data: data, expires: now.Add(ttl)}
+	// the expired sessions are swept once per minute at most
+	if now.Sub(s.lastSweep) >= time.Minute {
+		s.lastSweep = now
+		for id, session := range s.sessions {
+			if !now.Before(session.expires) {
+				delete(s.sessions, id)
+			}
+		}
+	}
+	return nil
+}
+
+// Delete implements SessionStore.
+func (s *MemorySessionStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Touch implements SessionStore.
+func (s *MemorySessionStore) Touch(_ context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[id]; ok {
+		session.expires = s.now().Add(ttl)
+		s.sessions[id] = session
+	}
+	return nil
+}
+
+// redisSessionGetScript returns the session in a table, so that clients don't report
+// missing sessions as errors.
+const redisSessionGetScript = `
+local data = redis.call("GET", KEYS[1])
+if data then
+	return {data}
+end
+return {}
+`
+
+type redisSessionStore struct {
+	client RedisScripter
+	prefix string
+}
+
+// NewRedisSessionStore returns a SessionStore keeping the sessions in Redis, under keys
+// starting with prefix, so that several instances share them.
+func NewRedisSessionStore(client RedisScripter, prefix string) SessionStore {
+	return &redisSessionStore{client: client, prefix: prefix}
+}
+
+func (s *redisSessionStore) Get(ctx context.Context, id string) ([]byte, error) {
+	reply, err := s.client.Eval(ctx, redisSessionGetScript, []string{s.prefix + id})
+	if err != nil {
+		return nil, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) > 1 {
+		return nil, fmt.Errorf("session: unexpected redis reply %v", reply)
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	switch data := values[0].(type) {
+	case string:
+		return []byte(data), nil
+	case []byte:
+		return data, nil
+	}
+	return nil, fmt.Errorf("session: unexpected redis reply %v", reply)
+}
+
+func (s *redisSessionStore) Set(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	_, err := s.client.Eval(ctx, `return redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])`,
+		[]string{s.prefix + id}, string(data), ttl.Milliseconds())
+	return err
+}
+
+func (s *redisSessionStore) Delete(ctx context.Context, id string) error {
+	_, err := s.client.Eval(ctx, `return redis.call("DEL", KEYS[1])`, []string{s.prefix + id})
+	return err
+}
+
+func (s *redisSessionStore) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	_, err := s.client.Eval(ctx, `return redis.call("PEXPIRE", KEYS[1], ARGV[1])`,
+		[]string{s.prefix + id}, ttl.Milliseconds())
+	return err
+}
+
+type cookieSessionStore struct {
+	aeads []cipher.AEAD
+	now   func() time.Time
+}
+
+// NewCookieSessionStore returns a SessionStore keeping the sessions in the cookies,
+// encrypted and authenticated with AES-GCM, so that no state is kept on the server.
+// The keys must be 16, 24 or 32 bytes long. The first key encrypts the sessions, and
+// all of them decrypt, so that keys can be rotated. The sessions can't be revoked
+// before they expire, and must fit in a 4KB cookie.
+func NewCookieSessionStore(keys ...[]byte) SessionStore {
+	assert1(len(keys) > 0, "cookie session store needs a key")
+	s := &cookieSessionStore{now: time.Now}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			panic(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+		s.aeads = append(s.aeads, aead)
+	}
+	return s
+}
+
+// Encode implements SessionEncoder. The expiry time is sealed with the data.
+func (s *cookieSessionStore) Encode(data []byte, ttl time.Duration) (string, error) {
+	aead := s.aeads[0]
+	plain := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(plain, uint64(s.now().Add(ttl).Unix()))
+	plain = append(plain, data...)
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, nil))
+	if len(value) > maxCookieSize {
+		return "", ErrSessionTooLarge
+	}
+	return value, nil
+}
+
+// Get returns the data sealed in the cookie value, or nil when it can't be decrypted or
+// it expired.
+func (s *cookieSessionStore) Get(_ context.Context, value string) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, nil
+	}
+	for _, aead := range s.aeads {
+		if len(sealed) < aead.NonceSize() {
+			continue
+		}
+		plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+		if err != nil || len(plain) < 8 {
+			continue
+		}
+		if s.now().Unix() >= int64(binary.BigEndian.Uint64(plain)) {
+			return nil, nil
+		}
+		return plain[8:], nil
+	}
+	return nil, nil
+}
+
+// Set, Delete and Touch do nothing, the cookie holds the session.
+func (s *cookieSessionStore) Set(context.Context, string, []byte, time.Duration) error { return nil }
+
+func (s *cookieSessionStore) Delete(context.Context, string) error { return nil }
+
+func (s *cookieSessionStore) Touch(context.Context, string, time.Duration) error { return nil }
@@ -0,0 +1,196 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sessionRouter(conf SessionConfig) *Engine {
+	router := New()
+	router.Use(Sessions(conf))
+	router.GET("/get", func(c *Context) {
+		c.String(http.StatusOK, c.Session().GetString("user"))
+	})
+	router.GET("/login", func(c *Context) {
+		session := c.Session()
+		session.Regenerate()
+		session.Set("user", c.Query("user"))
+		c.String(http.StatusOK, "ok")
+	})
+	router.GET("/logout", func(c *Context) {
+		c.Session().Destroy()
+	})
+	return router
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	cookies := (&http.Response{Header: w.Header()}).Cookies()
+	require.Len(t, cookies, 1)
+	return cookies[0]
+}
+
+func TestSessionsMemoryStore(t *testing.T) {
+	store := NewMemorySessionStore()
+	now := time.Unix(1000, 0)
+	store.now = func() time.Time { return now }
+	router := sessionRouter(SessionConfig{Store: store, TTL: time.Hour, Secure: true})
+
+	w := performRequest(router, "GET", "/get")
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+
+	w = performRequest(router, "GET", "/login?user=alice")
+	cookie := sessionCookie(t, w)
+	assert.Equal(t, "session", cookie.Name)
+	assert.Equal(t, 3600, cookie.MaxAge)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	w = performRequest(router, "GET", "/get", header{"Cookie", "session=" + cookie.Value})
+	assert.Equal(t, "alice", w.Body.String())
+	// unchanged sessions are not saved again
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+
+	// logging in again gives a new ID
+	w = performRequest(router, "GET", "/login?user=bob", header{"Cookie", "session=" + cookie.Value})
+	renewed := sessionCookie(t, w)
+	assert.NotEqual(t, cookie.Value, renewed.Value)
+	w = performRequest(router, "GET", "/get", header{"Cookie", "session=" + cookie.Value})
+	assert.Empty(t, w.Body.String())
+
+	w = performRequest(router, "GET", "/logout", header{"Cookie", "session=" + renewed.Value})
+	assert.Equal(t, -1, sessionCookie(t, w).MaxAge)
+	data, _ := store.Get(context.Background(), renewed.Value)
+	assert.Nil(t, data)
+
+	w = performRequest(router, "GET", "/login?user=carol")
+	cookie = sessionCookie(t, w)
+	now = now.Add(time.Hour)
+	w = performRequest(router, "GET", "/get", header{"Cookie", "session=" + cookie.Value})
+	assert.Empty(t, w.Body.String())
+}
+
+func TestSessionsRolling(t *testing.T) {
+	store := NewMemorySessionStore()
+	now := time.Unix(1000, 0)
+	store.now = func() time.Time { return now }
+	router := sessionRouter(SessionConfig{Store: store, TTL: time.Hour, Rolling: true})
+
+	cookie := sessionCookie(t, performRequest(router, "GET", "/login?user=alice"))
+	for i := 0; i < 3; i++ {
+		now = now.Add(45 * time.Minute)
+		w := performRequest(router, "GET", "/get", header{"Cookie", "session=" + cookie.Value})
+		assert.Equal(t, "alice", w.Body.String())
+		assert.Equal(t, cookie.Value, sessionCookie(t, w).Value)
+	}
+}
+
+func TestSessionsCookieStore(t *testing.T) {
+	oldKey := []byte("0123456789abcdef")
+	newKey := []byte("fedcba9876543210")
+	router := sessionRouter(SessionConfig{Store: NewCookieSessionStore(oldKey), Name: "s"})
+
+	cookie := sessionCookie(t, performRequest(router, "GET", "/login?user=alice"))
+	assert.Equal(t, "s", cookie.Name)
+	assert.NotContains(t, cookie.Value, "alice")
+
+	// the sessions sealed by the old key are still read after a rotation
+	router = sessionRouter(SessionConfig{Store: NewCookieSessionStore(newKey, oldKey), Name: "s"})
+	w := performRequest(router, "GET", "/get", header{"Cookie", "s=" + cookie.Value})
+	assert.Equal(t, "alice", w.Body.String())
+
+	router = sessionRouter(SessionConfig{Store: NewCookieSessionStore(newKey), Name: "s"})
+	w = performRequest(router, "GET", "/get", header{"Cookie", "s=" + cookie.Value})
+	assert.Empty(t, w.Body.String())
+
+	tampered := cookie.Value[:len(cookie.Value)-2] + "AA"
+	router = sessionRouter(SessionConfig{Store: NewCookieSessionStore(oldKey), Name: "s"})
+	w = performRequest(router, "GET", "/get", header{"Cookie", "s=" + tampered})
+	assert.Empty(t, w.Body.String())
+
+	w = performRequest(router, "GET", "/logout", header{"Cookie", "s=" + cookie.Value})
+	assert.Equal(t, -1, sessionCookie(t, w).MaxAge)
+
+	router.GET("/large", func(c *Context) {
+		c.Session().Set("data", strings.Repeat("x", maxCookieSize))
+		c.String(http.StatusOK, "ok")
+		assert.Equal(t, ErrSessionTooLarge, c.Errors.Last().Err)
+	})
+	w = performRequest(router, "GET", "/large")
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+}
+
+func TestCookieSessionStoreExpiry(t *testing.T) {
+	store := NewCookieSessionStore([]byte("0123456789abcdef")).(*cookieSessionStore)
+	now := time.Unix(1000, 0)
+	store.now = func() time.Time { return now }
+
+	value, err := store.Encode([]byte(`{"a":1}`), time.Minute)
+	require.NoError(t, err)
+	data, err := store.Get(context.Background(), value)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(data))
+
+	now = now.Add(time.Minute)
+	data, err = store.Get(context.Background(), value)
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+type fakeRedisScripter struct {
+	data map[string]string
+	ttls map[string]int64
+}
+
+func (r *fakeRedisScripter) Eval(_ context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	switch {
+	case strings.Contains(script, `"GET"`):
+		if data, ok := r.data[keys[0]]; ok {
+			return []interface{}{data}, nil
+		}
+		return []interface{}{}, nil
+	case strings.Contains(script, `"SET"`):
+		r.data[keys[0]] = args[0].(string)
+		r.ttls[keys[0]] = args[1].(int64)
+	case strings.Contains(script, `"DEL"`):
+		delete(r.data, keys[0])
+	case strings.Contains(script, `"PEXPIRE"`):
+		r.ttls[keys[0]] = args[0].(int64)
+	}
+	return int64(1), nil
+}
+
+func TestRedisSessionStore(t *testing.T) {
+	redis := &fakeRedisScripter{data: map[string]string{}, ttls: map[string]int64{}}
+	store := NewRedisSessionStore(redis, "session:")
+	ctx := context.Background()
+
+	data, err := store.Get(ctx, "id")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	require.NoError(t, store.Set(ctx, "id", []byte("data"), time.Minute))
+	assert.Equal(t, int64(60000), redis.ttls["session:id"])
+	data, err = store.Get(ctx, "id")
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	require.NoError(t, store.Touch(ctx, "id", time.Hour))
+	assert.Equal(t, int64(3600000), redis.ttls["session:id"])
+
+	require.NoError(t, store.Delete(ctx, "id"))
+	data, err = store.Get(ctx, "id")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TemplateHelpers returns the standard set of template functions shipped with gin.
+// Register it with Engine.AddTemplateFuncs before loading the templates:
+//     router.AddTemplateFuncs(gin.TemplateHelpers())
+//     router.LoadHTMLGlob("templates/*")
+// The set contains:
+//     lower, upper, trim, join, split, contains, hasPrefix, hasSuffix, replace
+//     default  {{ .Name | default "anonymous" }}
+//     date     {{ .CreatedAt | date "2006-01-02" }}
+//     safeHTML {{ .Body | safeHTML }} (only for trusted content)
+//     add, sub {{ add .Index 1 }}
+//     dict     {{ template "item" dict "Item" . "Index" $i }}
+func TemplateHelpers() template.FuncMap {
+	return template.FuncMap{
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"trim":      strings.TrimSpace,
+		"join":      func(sep string, elems []string) string { return strings.Join(elems, sep) },
+		"split":     func(sep, s string) []string { return strings.Split(s, sep) },
+		"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"replace":   func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+		"default":   templateDefault,
+		"date":      func(layout string, t time.Time) string { return t.Format(layout) },
+		"safeHTML":  func(s string) template.HTML { return template.HTML(s) }, // nolint: gosec
+		"add":       func(a, b int) int { return a + b },
+		"sub":       func(a, b int) int { return a - b },
+		"dict":      templateDict,
+	}
+}
+
+// templateDefault returns def when value is the zero value of its type.
+func templateDefault(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		if v.Len() == 0 {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+	return value
+}
+
+// templateDict builds a map from a list of key/value pairs, it's mostly useful
+// to pass several values to a nested template.
+func templateDict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects an even number of arguments, got %d", len(pairs))
+	}
+	dict := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", pairs[i])
+		}
+		dict[key] = pairs[i+1]
+	}
+	return dict, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func executeHelper(t *testing.T, text string, data interface{}) string {
+	tmpl := template.Must(template.New("").Funcs(TemplateHelpers()).Parse(text))
+	var buf bytes.Buffer
+	assert.NoError(t, tmpl.Execute(&buf, data))
+	return buf.String()
+}
+
+func TestTemplateHelpers(t *testing.T) {
+	data := H{
+		"name":  "Gin",
+		"empty": "",
+		"tags":  []string{"a", "b"},
+		"at":    time.Date(2021, 3, 19, 0, 0, 0, 0, time.UTC),
+		"html":  "<b>x</b>",
+		"zero":  0,
+	}
+	assert.Equal(t, "gin GIN", executeHelper(t, `{{ .name | lower }} {{ .name | upper }}`, data))
+	assert.Equal(t, "a,b", executeHelper(t, `{{ .tags | join "," }}`, data))
+	assert.Equal(t, "[x y]", executeHelper(t, `{{ "x-y" | split "-" }}`, data))
+	assert.Equal(t, "true false", executeHelper(t, `{{ .name | contains "i" }} {{ .name | hasPrefix "x" }}`, data))
+	assert.Equal(t, "true G_n", executeHelper(t, `{{ .name | hasSuffix "n" }} {{ .name | replace "i" "_" }}`, data))
+	assert.Equal(t, "x", executeHelper(t, `{{ "  x " | trim }}`, data))
+	assert.Equal(t, "anon Gin 7 anon", executeHelper(t, `{{ .empty | default "anon" }} {{ .name | default "anon" }} {{ .zero | default 7 }} {{ .missing | default "anon" }}`, data))
+	assert.Equal(t, "anon", executeHelper(t, `{{ .none | default "anon" }}`, H{"none": []string{}}))
+	assert.Equal(t, "2021-03-19", executeHelper(t, `{{ .at | date "2006-01-02" }}`, data))
+	assert.Equal(t, "<b>x</b> &lt;b&gt;x&lt;/b&gt;", executeHelper(t, `{{ .html | safeHTML }} {{ .html }}`, data))
+	assert.Equal(t, "3 1", executeHelper(t, `{{ add 1 2 }} {{ sub 3 2 }}`, data))
+	assert.Equal(t, "Gin-1", executeHelper(t, `{{ with dict "n" .name "i" 1 }}{{ .n }}-{{ .i }}{{ end }}`, data))
+}
+
+func TestTemplateHelpersDictErrors(t *testing.T) {
+	_, err := templateDict("a")
+	assert.Error(t, err)
+	_, err = templateDict(1, 2)
+	assert.Error(t, err)
+}
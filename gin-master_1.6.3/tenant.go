@@ -0,0 +1,153 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TenantKey is the key the tenant is set under in the Context, see Context.Tenant.
+const TenantKey = "tenant"
+
+// ErrUnknownTenant is returned by TenantConfig.Lookup for the tenants which don't exist.
+var ErrUnknownTenant = errors.New("gin: unknown tenant")
+
+type tenantContextKey struct{}
+
+// Tenant is the tenant a request is made for.
+type Tenant struct {
+	// ID is the identifier given by the TenantResolver.
+	ID string
+	// Data is the value returned by TenantConfig.Lookup, e.g. the settings, the quotas or
+	// the database of the tenant.
+	Data interface{}
+}
+
+// TenantResolver returns the ID of the tenant of a request, or an empty string.
+type TenantResolver func(c *Context) string
+
+// TenantFromSubdomain resolves the tenant from the subdomain of domain the request is
+// made to, e.g. "acme" for "acme.example.com" with domain "example.com".
+func TenantFromSubdomain(domain string) TenantResolver {
+	suffix := "." + strings.ToLower(strings.Trim(domain, "."))
+	return func(c *Context) string {
+		host := strings.ToLower(c.Request.Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !strings.HasSuffix(host, suffix) {
+			return ""
+		}
+		sub := host[:len(host)-len(suffix)]
+		if strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// TenantFromParam resolves the tenant from a path parameter, e.g. with a group of
+// path "/tenants/:tenant".
+func TenantFromParam(name string) TenantResolver {
+	return func(c *Context) string {
+		return c.Param(name)
+	}
+}
+
+// TenantFromHeader resolves the tenant from a request header, e.g. set by a gateway.
+func TenantFromHeader(name string) TenantResolver {
+	return func(c *Context) string {
+		return c.requestHeader(name)
+	}
+}
+
+// TenantConfig defines the config for Tenants middleware.
+type TenantConfig struct {
+	// Resolver returns the ID of the tenant of the request.
+	// Required.
+	Resolver TenantResolver
+
+	// Lookup returns the data of the tenant, or ErrUnknownTenant. Other errors abort
+	// the request with 500.
+	// Optional. All the IDs are accepted by default.
+	Lookup func(ctx context.Context, id string) (interface{}, error)
+
+	// Unknown handles the requests without tenant or of an unknown tenant.
+	// Optional. Default aborts with 404.
+	Unknown HandlerFunc
+}
+
+// Tenants returns a middleware resolving the tenant of the requests, which is set in
+// the Context under TenantKey and in the context of the request, see TenantFromContext.
+// The requests without a known tenant are rejected.
+func Tenants(conf TenantConfig) HandlerFunc {
+	assert1(conf.Resolver != nil, "tenant resolver can not be nil")
+	return func(c *Context) {
+		tenant := &Tenant{ID: conf.Resolver(c)}
+		var err error
+		if tenant.ID == "" {
+			err = ErrUnknownTenant
+		} else if conf.Lookup != nil {
+			tenant.Data, err = conf.Lookup(c.Request.Context(), tenant.ID)
+		}
+		if err != nil {
+			if err != ErrUnknownTenant {
+				c.AbortWithError(http.StatusInternalServerError, err) // nolint: errcheck
+				return
+			}
+			if conf.Unknown != nil {
+				conf.Unknown(c)
+				c.Abort()
+				return
+			}
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Set(TenantKey, tenant)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), tenantContextKey{}, tenant))
+	}
+}
+
+// TenantScoped makes the routes added to the group afterwards tenant-scoped, i.e. served
+// only for a known tenant, resolved with the config:
+//     tenants := router.Group("/tenants/:tenant").TenantScoped(gin.TenantConfig{
+//         Resolver: gin.TenantFromParam("tenant"),
+//         Lookup:   loadTenant,
+//     })
+//     tenants.Use(gin.RateLimit(gin.RateLimitConfig{Limit: 100, Period: time.Second, Key: gin.RateLimitByTenant()}))
+//     tenants.GET("/invoices", listInvoices)
+func (group *RouterGroup) TenantScoped(conf TenantConfig) *RouterGroup {
+	group.Use(Tenants(conf))
+	return group
+}
+
+// Tenant returns the tenant resolved by the Tenants middleware, or nil.
+func (c *Context) Tenant() *Tenant {
+	v, _ := c.Get(TenantKey)
+	tenant, _ := v.(*Tenant)
+	return tenant
+}
+
+// TenantFromContext returns the tenant resolved by the Tenants middleware from the
+// context of the request, e.g. to select the database of the tenant, or nil.
+func TenantFromContext(ctx context.Context) *Tenant {
+	tenant, _ := ctx.Value(tenantContextKey{}).(*Tenant)
+	return tenant
+}
+
+// RateLimitByTenant counts the requests by tenant, so that all the clients of a tenant
+// share its quota.
+func RateLimitByTenant() RateLimitKeyFunc {
+	return func(c *Context) string {
+		if tenant := c.Tenant(); tenant != nil {
+			return tenant.ID
+		}
+		return ""
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantScopedGroup(t *testing.T) {
+	databases := map[string]string{"acme": "db-acme", "globex": "db-globex"}
+	router := New()
+	tenants := router.Group("/tenants/:tenant").TenantScoped(TenantConfig{
+		Resolver: TenantFromParam("tenant"),
+		Lookup: func(_ context.Context, id string) (interface{}, error) {
+			if id == "broken" {
+				return nil, errors.New("database down")
+			}
+			db, ok := databases[id]
+			if !ok {
+				return nil, ErrUnknownTenant
+			}
+			return db, nil
+		},
+	})
+	tenants.Use(RateLimit(RateLimitConfig{Limit: 1, Period: time.Minute, Key: RateLimitByTenant()}))
+	tenants.GET("/invoices", func(c *Context) {
+		assert.Equal(t, c.Tenant(), TenantFromContext(c.Request.Context()))
+		c.String(http.StatusOK, c.Tenant().ID+" "+c.Tenant().Data.(string))
+	})
+	router.GET("/health", func(c *Context) {
+		assert.Nil(t, c.Tenant())
+	})
+
+	w := performRequest(router, "GET", "/tenants/acme/invoices")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme db-acme", w.Body.String())
+
+	// each tenant has its own quota
+	w = performRequest(router, "GET", "/tenants/acme/invoices")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	w = performRequest(router, "GET", "/tenants/globex/invoices")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequest(router, "GET", "/tenants/initech/invoices")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(router, "GET", "/tenants/broken/invoices")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = performRequest(router, "GET", "/health")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestTenantResolvers(t *testing.T) {
+	router := New()
+	router.Use(Tenants(TenantConfig{
+		Resolver: TenantFromSubdomain("example.com"),
+		Unknown:  func(c *Context) { c.String(http.StatusBadRequest, "unknown tenant") },
+	}))
+	router.GET("/", func(c *Context) { c.String(http.StatusOK, c.Tenant().ID) })
+
+	for host, expected := range map[string]string{
+		"acme.example.com":      "acme",
+		"ACME.example.com:8080": "acme",
+		"example.com":           "unknown tenant",
+		"a.b.example.com":       "unknown tenant",
+		"acme.example.org":      "unknown tenant",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, expected, w.Body.String(), host)
+	}
+
+	router = New()
+	router.Use(Tenants(TenantConfig{Resolver: TenantFromHeader("X-Tenant")}))
+	router.GET("/", func(c *Context) { c.String(http.StatusOK, c.Tenant().ID) })
+	w := performRequest(router, "GET", "/", header{"X-Tenant", "acme"})
+	assert.Equal(t, "acme", w.Body.String())
+	w = performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
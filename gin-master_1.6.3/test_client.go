@@ -0,0 +1,286 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/internal/json"
+)
+
+// TestingT is the part of testing.TB used by the TestClient, so that the package
+// doesn't depend on the testing package.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+// TestClient sends requests to a handler in-process, through ServeHTTP, to test it:
+//     client := gin.NewTestClient(router)
+//     client.POST("/users").JSON(gin.H{"name": "bob"}).Do(t).
+//         AssertStatus(http.StatusCreated).
+//         AssertJSONPath("user.name", "bob")
+type TestClient struct {
+	handler http.Handler
+
+	// Header is sent with every request, e.g. an Authorization header.
+	Header http.Header
+}
+
+// NewTestClient returns a TestClient of the handler, usually an Engine.
+func NewTestClient(handler http.Handler) *TestClient {
+	return &TestClient{handler: handler, Header: http.Header{}}
+}
+
+// Request starts a request of the method to the path. The path may contain route
+// parameters, e.g. "/users/:id", set with TestRequest.Param.
+func (client *TestClient) Request(method, path string) *TestRequest {
+	return &TestRequest{
+		client: client,
+		method: method,
+		path:   path,
+		query:  url.Values{},
+		header: client.Header.Clone(),
+	}
+}
+
+// GET is a shortcut for client.Request("GET", path).
+func (client *TestClient) GET(path string) *TestRequest {
+	return client.Request(http.MethodGet, path)
+}
+
+// POST is a shortcut for client.Request("POST", path).
+func (client *TestClient) POST(path string) *TestRequest {
+	return client.Request(http.MethodPost, path)
+}
+
+// PUT is a shortcut for client.Request("PUT", path).
+func (client *TestClient) PUT(path string) *TestRequest {
+	return client.Request(http.MethodPut, path)
+}
+
+// PATCH is a shortcut for client.Request("PATCH", path).
+func (client *TestClient) PATCH(path string) *TestRequest {
+	return client.Request(http.MethodPatch, path)
+}
+
+// DELETE is a shortcut for client.Request("DELETE", path).
+func (client *TestClient) DELETE(path string) *TestRequest {
+	return client.Request(http.MethodDelete, path)
+}
+
+// TestFile is a file of a multipart request, see TestRequest.Multipart.
+type TestFile struct {
+	Field    string
+	Filename string
+	Content  []byte
+}
+
+// TestRequest builds a request of a TestClient.
+type TestRequest struct {
+	client *TestClient
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   io.Reader
+	err    error
+}
+
+// Param replaces the route parameter of the path, e.g. ":id" in "/users/:id".
+func (r *TestRequest) Param(name, value string) *TestRequest {
+	segments := strings.Split(r.path, "/")
+	for i, segment := range segments {
+		if segment == ":"+name || segment == "*"+name {
+			segments[i] = url.PathEscape(value)
+		}
+	}
+	r.path = strings.Join(segments, "/")
+	return r
+}
+
+// Query adds a query parameter.
+func (r *TestRequest) Query(name, value string) *TestRequest {
+	r.query.Add(name, value)
+	return r
+}
+
+// Header sets a header.
+func (r *TestRequest) Header(name, value string) *TestRequest {
+	r.header.Set(name, value)
+	return r
+}
+
+// Body sets the body and its content type.
+func (r *TestRequest) Body(contentType string, body io.Reader) *TestRequest {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// JSON sets the body to the value encoded as JSON.
+func (r *TestRequest) JSON(value interface{}) *TestRequest {
+	data, err := json.Marshal(value)
+	if err != nil {
+		r.err = err
+	}
+	return r.Body(MIMEJSON, bytes.NewReader(data))
+}
+
+// Form sets the body to the url-encoded form.
+func (r *TestRequest) Form(values url.Values) *TestRequest {
+	return r.Body(MIMEPOSTForm, strings.NewReader(values.Encode()))
+}
+
+// Multipart sets the body to a multipart form of the fields and files.
+func (r *TestRequest) Multipart(fields map[string]string, files ...TestFile) *TestRequest {
+	buf := new(bytes.Buffer)
+	mw := multipart.NewWriter(buf)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			r.err = err
+		}
+	}
+	for _, file := range files {
+		fw, err := mw.CreateFormFile(file.Field, file.Filename)
+		if err == nil {
+			_, err = fw.Write(file.Content)
+		}
+		if err != nil {
+			r.err = err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		r.err = err
+	}
+	return r.Body(mw.FormDataContentType(), buf)
+}
+
+// Do sends the request through ServeHTTP and returns the response, whose assertions
+// report their failures to t.
+func (r *TestRequest) Do(t TestingT) *TestResponse {
+	t.Helper()
+	if r.err != nil {
+		t.Errorf("cannot build request %s %s: %v", r.method, r.path, r.err)
+		t.FailNow()
+	}
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+	req := httptest.NewRequest(r.method, target, r.body)
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(w, req)
+	return &TestResponse{ResponseRecorder: w, t: t, request: r.method + " " + target}
+}
+
+// TestResponse is the response of a TestRequest, with fluent assertions which report
+// their failures without stopping the test.
+type TestResponse struct {
+	*httptest.ResponseRecorder
+	t       TestingT
+	request string
+}
+
+// AssertStatus asserts the status code.
+func (w *TestResponse) AssertStatus(code int) *TestResponse {
+	w.t.Helper()
+	if w.Code != code {
+		w.t.Errorf("%s: expected status %d, got %d, body: %s", w.request, code, w.Code, w.Body)
+	}
+	return w
+}
+
+// AssertHeader asserts the value of a header.
+func (w *TestResponse) AssertHeader(name, value string) *TestResponse {
+	w.t.Helper()
+	if got := w.Header().Get(name); got != value {
+		w.t.Errorf("%s: expected header %s %q, got %q", w.request, name, value, got)
+	}
+	return w
+}
+
+// AssertBody asserts the body.
+func (w *TestResponse) AssertBody(body string) *TestResponse {
+	w.t.Helper()
+	if got := w.Body.String(); got != body {
+		w.t.Errorf("%s: expected body %q, got %q", w.request, body, got)
+	}
+	return w
+}
+
+// AssertJSONPath asserts the value at the path of the JSON body, see JSONPath. The
+// values are compared by their JSON encoding, so that 1 equals 1.0.
+func (w *TestResponse) AssertJSONPath(path string, expected interface{}) *TestResponse {
+	w.t.Helper()
+	value, err := w.JSONPath(path)
+	if err != nil {
+		w.t.Errorf("%s: %v", w.request, err)
+		return w
+	}
+	want, err := json.Marshal(expected)
+	if err != nil {
+		w.t.Errorf("%s: cannot encode expected value: %v", w.request, err)
+		return w
+	}
+	got, _ := json.Marshal(value)
+	if !bytes.Equal(want, got) {
+		w.t.Errorf("%s: expected %s at %q, got %s", w.request, want, path, got)
+	}
+	return w
+}
+
+// DecodeJSON decodes the JSON body into v.
+func (w *TestResponse) DecodeJSON(v interface{}) error {
+	return json.Unmarshal(w.Body.Bytes(), v)
+}
+
+// JSONPath returns the value at the path of the JSON body, whose segments separated
+// by dots are object keys or array indexes, e.g. "users.0.name". The empty path is
+// the whole body.
+func (w *TestResponse) JSONPath(path string) (interface{}, error) {
+	var value interface{}
+	if err := w.DecodeJSON(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	if path == "" {
+		return value, nil
+	}
+	for _, segment := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			field, ok := v[segment]
+			if !ok {
+				return nil, fmt.Errorf("no %q at %q", segment, path)
+			}
+			value = field
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("no index %q at %q", segment, path)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("no %q at %q", segment, path)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingT struct {
+	errors []string
+	failed bool
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) FailNow() { t.failed = true }
+
+func TestTestClient(t *testing.T) {
+	router := New()
+	router.POST("/users/:id", func(c *Context) {
+		var user struct {
+			Name string `json:"name"`
+		}
+		assert.NoError(t, c.ShouldBindJSON(&user))
+		c.Header("X-Token", c.GetHeader("Authorization"))
+		c.JSON(http.StatusCreated, H{"user": H{"id": c.Param("id"), "name": user.Name, "tags": []string{c.Query("tag")}}})
+	})
+	router.PUT("/form", func(c *Context) {
+		c.String(http.StatusOK, c.PostForm("name"))
+	})
+	router.POST("/upload", func(c *Context) {
+		file, err := c.FormFile("file")
+		assert.NoError(t, err)
+		f, _ := file.Open()
+		content, _ := ioutil.ReadAll(f)
+		c.String(http.StatusOK, "%s %s %s", c.PostForm("title"), file.Filename, content)
+	})
+
+	client := NewTestClient(router)
+	client.Header.Set("Authorization", "Bearer token")
+	client.POST("/users/:id").Param("id", "42").Query("tag", "admin").JSON(H{"name": "bob"}).Do(t).
+		AssertStatus(http.StatusCreated).
+		AssertHeader("X-Token", "Bearer token").
+		AssertHeader("Content-Type", MIMEJSON+"; charset=utf-8").
+		AssertJSONPath("user.id", "42").
+		AssertJSONPath("user.name", "bob").
+		AssertJSONPath("user.tags.0", "admin").
+		AssertJSONPath("user", H{"id": "42", "name": "bob", "tags": []string{"admin"}})
+
+	client.PUT("/form").Form(url.Values{"name": {"alice"}}).Do(t).AssertBody("alice")
+	client.POST("/upload").Multipart(map[string]string{"title": "report"}, TestFile{"file", "a.txt", []byte("hello")}).Do(t).
+		AssertStatus(http.StatusOK).
+		AssertBody("report a.txt hello")
+}
+
+func TestTestClientFailures(t *testing.T) {
+	router := New()
+	router.GET("/", func(c *Context) {
+		c.JSON(http.StatusOK, H{"count": 1, "items": []int{1}})
+	})
+
+	rt := &recordingT{}
+	w := NewTestClient(router).GET("/").Do(rt).
+		AssertStatus(http.StatusCreated).
+		AssertHeader("X-Missing", "1").
+		AssertBody("{}").
+		AssertJSONPath("count", 1.0).
+		AssertJSONPath("count", 2).
+		AssertJSONPath("items.1", 1).
+		AssertJSONPath("count.value", 1)
+	assert.Equal(t, []string{
+		`GET /: expected status 201, got 200, body: {"count":1,"items":[1]}`,
+		`GET /: expected header X-Missing "1", got ""`,
+		`GET /: expected body "{}", got "{\"count\":1,\"items\":[1]}"`,
+		`GET /: expected 2 at "count", got 1`,
+		`GET /: no index "1" at "items.1"`,
+		`GET /: no "value" at "count.value"`,
+	}, rt.errors)
+
+	var body struct{ Count int }
+	assert.NoError(t, w.DecodeJSON(&body))
+	assert.Equal(t, 1, body.Count)
+
+	rt = &recordingT{}
+	NewTestClient(router).POST("/").JSON(make(chan int)).Do(rt)
+	assert.True(t, rt.failed)
+}
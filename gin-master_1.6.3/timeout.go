@@ -0,0 +1,210 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+// TimeoutConfig defines the config for Timeout middleware.
+type TimeoutConfig struct {
+	// Timeout is how long the handlers have to serve the request.
+	Timeout time.Duration
+
+	// Status is the status code of the response sent on timeout.
+	// Optional. Default value is 503.
+	Status int
+
+	// Response is rendered on timeout.
+	// Optional. Default value is the text of the status code.
+	Response render.Render
+}
+
+// Timeout returns a Timeout middleware with the given timeout.
+func Timeout(timeout time.Duration) HandlerFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: timeout})
+}
+
+// TimeoutWithConfig returns a middleware serving a response on timeout when the next
+// handlers take too long. The handlers run in their own goroutine with a request context
+// canceled on timeout, and their response is buffered until they return, so that exactly
+// one response is written: theirs, or the timeout response. The writes after the timeout
+// fail with http.ErrHandlerTimeout; streaming with Flush and hijacking are not supported.
+//
+// The middleware returns only once the handlers return, even after a timeout, so that
+// the Context is not reused while they run: handlers doing long operations must watch
+// the request context. A panic of the handlers is raised again by the middleware, to be
+// handled by the Recovery middleware used before it.
+func TimeoutWithConfig(conf TimeoutConfig) HandlerFunc {
+	assert1(conf.Timeout > 0, "timeout must be positive")
+	status := conf.Status
+	if status == 0 {
+		status = http.StatusServiceUnavailable
+	}
+	response := conf.Response
+	if response == nil {
+		response = render.String{Format: http.StatusText(status)}
+	}
+
+	return func(c *Context) {
+		// canceled once the writer is closed, so that no write can succeed after it
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+
+		original := c.Writer
+		tw := &timeoutWriter{
+			ResponseWriter: original,
+			header:         original.Header().Clone(),
+			size:           noWritten,
+			status:         defaultStatus,
+		}
+		c.Writer = tw
+		c.Request = c.Request.WithContext(ctx)
+
+		var (
+			done       = make(chan struct{})
+			panicked   bool
+			panicValue interface{}
+		)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked, panicValue = true, p
+				}
+				close(done)
+			}()
+			c.Next()
+		}()
+
+		timer := c.engine.clock().NewTimer(conf.Timeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+			c.Writer = original
+			if panicked {
+				panic(panicValue)
+			}
+			tw.commit()
+		case <-timer.C():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			cancel()
+			original.WriteHeader(status)
+			if err := response.Render(original); err != nil {
+				debugPrint("cannot write timeout response: %v", err)
+			}
+			original.WriteHeaderNow()
+			original.Flush()
+
+			<-done
+			c.Writer = original
+			if panicked {
+				panic(panicValue)
+			}
+		}
+	}
+}
+
+var errTimeoutHijack = errors.New("gin: the Timeout middleware doesn't support hijacking")
+
+// timeoutWriter buffers the response of the handlers run by the Timeout middleware.
+type timeoutWriter struct {
+	ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	size     int
+	status   int
+	timedOut bool
+}
+
+// commit writes the buffered response.
+func (w *timeoutWriter) commit() {
+	dst := w.ResponseWriter.Header()
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range w.header {
+		dst[k] = v
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.size != noWritten {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size == noWritten {
+		w.size = 0
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.size == noWritten {
+		w.size = 0
+	}
+	n, err := w.buf.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+func (w *timeoutWriter) Written() bool {
+	return w.Size() != noWritten
+}
+
+// Flush does nothing, the response is written when the handlers return.
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errTimeoutHijack
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin/render"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutInTime(t *testing.T) {
+	router := New()
+	router.Use(RequestID(), Timeout(time.Second))
+	router.GET("/", func(c *Context) {
+		c.Header("X-Handler", "1")
+		c.Writer.Header().Del("X-Request-ID")
+		c.String(http.StatusCreated, "done")
+	})
+	router.GET("/status", func(c *Context) { c.Status(http.StatusAccepted) })
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "done", w.Body.String())
+	assert.Equal(t, "1", w.Header().Get("X-Handler"))
+	assert.Empty(t, w.Header().Get("X-Request-ID"))
+
+	w = performRequest(router, "GET", "/status")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
+
+func TestTimeoutExceeded(t *testing.T) {
+	var writeErr error
+	var canceled bool
+	var status int
+	router := New()
+	router.Use(func(c *Context) {
+		c.Next()
+		status = c.Writer.Status()
+	})
+	router.Use(TimeoutWithConfig(TimeoutConfig{
+		Timeout:  10 * time.Millisecond,
+		Status:   http.StatusGatewayTimeout,
+		Response: render.JSON{Data: H{"error": "timeout"}},
+	}))
+	router.GET("/", func(c *Context) {
+		<-c.Request.Context().Done()
+		canceled = true
+		c.Header("X-Handler", "1")
+		_, writeErr = c.Writer.WriteString("late")
+	})
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, `{"error":"timeout"}`, w.Body.String())
+	assert.Empty(t, w.Header().Get("X-Handler"))
+	assert.True(t, canceled)
+	assert.Equal(t, http.ErrHandlerTimeout, writeErr)
+	assert.Equal(t, http.StatusGatewayTimeout, status)
+
+	router = New()
+	router.Use(Timeout(10 * time.Millisecond))
+	router.GET("/", func(c *Context) { time.Sleep(50 * time.Millisecond) })
+	w = performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "Service Unavailable", w.Body.String())
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(RecoveryWithWriter(buffer), Timeout(time.Second))
+	router.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	router.GET("/late", func(c *Context) {
+		<-c.Request.Context().Done()
+		panic("late boom")
+	})
+
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Contains(t, buffer.String(), "boom")
+
+	router = New()
+	router.Use(RecoveryWithWriter(buffer), Timeout(10*time.Millisecond))
+	router.GET("/", func(c *Context) {
+		<-c.Request.Context().Done()
+		panic("late boom")
+	})
+	w = performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, buffer.String(), "late boom")
+
+	assert.Panics(t, func() { Timeout(0) })
+}
+
+func TestTimeoutWriter(t *testing.T) {
+	router := New()
+	router.Use(Timeout(time.Second))
+	router.GET("/", func(c *Context) {
+		assert.False(t, c.Writer.Written())
+		c.Writer.WriteHeaderNow()
+		assert.True(t, c.Writer.Written())
+		c.Writer.Flush()
+		_, _, err := c.Writer.Hijack()
+		assert.Equal(t, errTimeoutHijack, err)
+		assert.Equal(t, 0, c.Writer.Size())
+	})
+	w := performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+)
+
+// TLSPreset is a policy of TLS versions and cipher suites for RunTLS, see Engine.TLSPreset.
+type TLSPreset uint8
+
+const (
+	// TLSDefault leaves the versions and cipher suites to the crypto/tls defaults.
+	TLSDefault TLSPreset = iota
+	// TLSModern only accepts TLS 1.3, for clients from 2019 on.
+	TLSModern
+	// TLSIntermediate accepts TLS 1.2 with the ECDHE AEAD cipher suites, and TLS 1.3.
+	TLSIntermediate
+)
+
+// intermediateCipherSuites are the TLS 1.2 cipher suites of TLSIntermediate, TLS 1.3
+// ones aren't configurable.
+var intermediateCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+// String returns the name of the preset.
+func (p TLSPreset) String() string {
+	switch p {
+	case TLSDefault:
+		return "default"
+	case TLSModern:
+		return "modern"
+	case TLSIntermediate:
+		return "intermediate"
+	}
+	return "unknown"
+}
+
+// Config returns a new tls.Config of the preset.
+func (p TLSPreset) Config() *tls.Config {
+	switch p {
+	case TLSModern:
+		return &tls.Config{
+			MinVersion:       tls.VersionTLS13,
+			CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+		}
+	case TLSIntermediate:
+		return &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			CipherSuites:     append([]uint16(nil), intermediateCipherSuites...),
+			CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+		}
+	}
+	return &tls.Config{}
+}
+
+// SessionTicketKeySource provides the keys encrypting the TLS session tickets, see
+// Engine.SessionTicketKeys. The first key encrypts the new tickets, all of them decrypt
+// the tickets of the resumed sessions. SessionTicketKeys is called once when RunTLS
+// starts, then every SessionTicketKeyRotation.
+type SessionTicketKeySource interface {
+	SessionTicketKeys() ([][32]byte, error)
+}
+
+// SessionTicketKeyFunc is a function used as a SessionTicketKeySource, e.g. to read the
+// keys shared by the instances of a deployment from a secret store.
+type SessionTicketKeyFunc func() ([][32]byte, error)
+
+// SessionTicketKeys calls f.
+func (f SessionTicketKeyFunc) SessionTicketKeys() ([][32]byte, error) {
+	return f()
+}
+
+// NewRandomSessionTicketKeys returns a SessionTicketKeySource generating a random key on
+// each call, which keeps the last keep keys so that the tickets encrypted with them
+// can still be resumed. Its keys are local to the process.
+func NewRandomSessionTicketKeys(keep int) SessionTicketKeySource {
+	assert1(keep > 0, "at least one session ticket key must be kept")
+	return &randomTicketKeys{keep: keep}
+}
+
+type randomTicketKeys struct {
+	mu   sync.Mutex
+	keep int
+	keys [][32]byte
+}
+
+func (s *randomTicketKeys) SessionTicketKeys() ([][32]byte, error) {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([][32]byte, 0, s.keep)
+	keys = append(keys, key)
+	for i := 0; i < len(s.keys) && len(keys) < s.keep; i++ {
+		keys = append(keys, s.keys[i])
+	}
+	s.keys = keys
+	return append([][32]byte(nil), keys...), nil
+}
+
+// defaultSessionTicketKeyRotation is the rotation period when SessionTicketKeyRotation
+// isn't set.
+const defaultSessionTicketKeyRotation = 24 * time.Hour
+
+// TLSConfig returns a new tls.Config of the TLSPreset of the engine, as RunTLS uses it.
+// The session ticket keys aren't set, see SessionTicketKeys.
+func (engine *Engine) TLSConfig() *tls.Config {
+	return engine.TLSPreset.Config()
+}
+
+// rotateSessionTicketKeys sets the keys of the SessionTicketKeys source to config.
+func (engine *Engine) rotateSessionTicketKeys(config *tls.Config) error {
+	keys, err := engine.SessionTicketKeys.SessionTicketKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("gin: the session ticket key source returned no key")
+	}
+	config.SetSessionTicketKeys(keys)
+	return nil
+}
+
+// startSessionTicketKeyRotation sets the first session ticket keys of config, then rotates
+// them every SessionTicketKeyRotation until stop is called. It does nothing when
+// SessionTicketKeys isn't set. The failed rotations keep the former keys.
+func (engine *Engine) startSessionTicketKeyRotation(config *tls.Config) (stop func(), err error) {
+	if engine.SessionTicketKeys == nil {
+		return func() {}, nil
+	}
+	if err := engine.rotateSessionTicketKeys(config); err != nil {
+		return nil, err
+	}
+	period := engine.SessionTicketKeyRotation
+	if period <= 0 {
+		period = defaultSessionTicketKeyRotation
+	}
+	done := make(chan struct{})
+	go func() {
+		for {
+			timer := engine.clock().NewTimer(period)
+			select {
+			case <-timer.C():
+				if err := engine.rotateSessionTicketKeys(config); err != nil {
+					debugPrintError(err)
+				}
+			case <-done:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"crypto/tls"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSPresetConfig(t *testing.T) {
+	assert.Equal(t, &tls.Config{}, TLSDefault.Config())
+
+	modern := TLSModern.Config()
+	assert.Equal(t, uint16(tls.VersionTLS13), modern.MinVersion)
+	assert.Empty(t, modern.CipherSuites)
+
+	intermediate := TLSIntermediate.Config()
+	assert.Equal(t, uint16(tls.VersionTLS12), intermediate.MinVersion)
+	assert.Equal(t, intermediateCipherSuites, intermediate.CipherSuites)
+	// each config has its own copy of the suites
+	intermediate.CipherSuites[0] = 0
+	assert.NotZero(t, TLSIntermediate.Config().CipherSuites[0])
+
+	assert.Equal(t, "default", TLSDefault.String())
+	assert.Equal(t, "modern", TLSModern.String())
+	assert.Equal(t, "intermediate", TLSIntermediate.String())
+	assert.Equal(t, "unknown", TLSPreset(42).String())
+
+	router := New()
+	router.TLSPreset = TLSModern
+	assert.Equal(t, modern, router.TLSConfig())
+}
+
+func TestRandomSessionTicketKeys(t *testing.T) {
+	assert.Panics(t, func() { NewRandomSessionTicketKeys(0) })
+
+	source := NewRandomSessionTicketKeys(2)
+	first, err := source.SessionTicketKeys()
+	assert.NoError(t, err)
+	assert.Len(t, first, 1)
+
+	second, err := source.SessionTicketKeys()
+	assert.NoError(t, err)
+	assert.Len(t, second, 2)
+	assert.NotEqual(t, first[0], second[0])
+	assert.Equal(t, first[0], second[1])
+
+	third, err := source.SessionTicketKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, [][32]byte{third[0], second[0]}, third)
+}
+
+func TestSessionTicketKeyRotation(t *testing.T) {
+	router := New()
+	stop, err := router.startSessionTicketKeyRotation(&tls.Config{})
+	assert.NoError(t, err)
+	stop()
+
+	var mu sync.Mutex
+	calls := 0
+	fail := false
+	router.SessionTicketKeys = SessionTicketKeyFunc(func() ([][32]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return nil, errors.New("secret store unavailable")
+		}
+		calls++
+		return [][32]byte{{byte(calls)}}, nil
+	})
+	router.SessionTicketKeyRotation = time.Hour
+	clock := NewFakeClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	router.Clock = clock
+	rotations := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+
+	stop, err = router.startSessionTicketKeyRotation(&tls.Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rotations())
+
+	waitFor(t, func() bool { return clock.Timers() == 1 })
+	clock.Advance(time.Hour)
+	waitFor(t, func() bool { return rotations() == 2 })
+
+	// a failed rotation keeps the keys and waits for the next one
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	waitFor(t, func() bool { return clock.Timers() == 1 })
+	clock.Advance(time.Hour)
+	waitFor(t, func() bool { return clock.Timers() == 1 })
+	assert.Equal(t, 2, rotations())
+
+	stop()
+	stop()
+	waitFor(t, func() bool { return clock.Timers() == 0 })
+
+	// the first keys are required
+	_, err = router.startSessionTicketKeyRotation(&tls.Config{})
+	assert.EqualError(t, err, "secret store unavailable")
+	router.SessionTicketKeys = SessionTicketKeyFunc(func() ([][32]byte, error) { return nil, nil })
+	_, err = router.startSessionTicketKeyRotation(&tls.Config{})
+	assert.Error(t, err)
+}
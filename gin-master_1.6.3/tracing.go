@@ -0,0 +1,171 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceContext identifies a span as propagated by the W3C Trace Context headers,
+// see https://www.w3.org/TR/trace-context/.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	// Flags are the trace flags, 1 when the trace is sampled.
+	Flags byte
+	// State is the vendor specific tracestate header.
+	State string
+}
+
+// IsValid reports whether both IDs are set.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// Traceparent returns the traceparent header identifying the span.
+func (tc TraceContext) Traceparent() string {
+	var buf [55]byte
+	copy(buf[:], "00-")
+	hex.Encode(buf[3:35], tc.TraceID[:])
+	buf[35] = '-'
+	hex.Encode(buf[36:52], tc.SpanID[:])
+	buf[52] = '-'
+	hex.Encode(buf[53:], []byte{tc.Flags})
+	return string(buf[:])
+}
+
+// ParseTraceparent parses a traceparent header, it returns false when it's invalid.
+// Future versions are accepted as long as they start like version 00.
+func ParseTraceparent(traceparent string) (TraceContext, bool) {
+	var tc TraceContext
+	traceparent = strings.TrimSpace(traceparent)
+	if len(traceparent) < 55 || traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' {
+		return tc, false
+	}
+	version := traceparent[:2]
+	if version == "ff" || (version == "00" && len(traceparent) != 55) || (len(traceparent) > 55 && traceparent[55] != '-') {
+		return tc, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(version)); err != nil {
+		return tc, false
+	}
+	if _, err := hex.Decode(tc.TraceID[:], []byte(traceparent[3:35])); err != nil {
+		return tc, false
+	}
+	if _, err := hex.Decode(tc.SpanID[:], []byte(traceparent[36:52])); err != nil {
+		return tc, false
+	}
+	if _, err := hex.Decode(flags[:], []byte(traceparent[53:55])); err != nil {
+		return tc, false
+	}
+	tc.Flags = flags[0]
+	return tc, tc.IsValid() && strings.ToLower(traceparent[:55]) == traceparent[:55]
+}
+
+// Span is a span started by a Tracer, e.g. an adapter of an OpenTelemetry span.
+type Span interface {
+	// SetAttribute records an attribute, such as "http.route".
+	SetAttribute(key string, value interface{})
+	// RecordError records an error which occurred during the span.
+	RecordError(err error)
+	// SetError marks the span as failed with the given description.
+	SetError(description string)
+	// TraceContext identifies the span, to propagate it.
+	TraceContext() TraceContext
+	// End ends the span.
+	End()
+}
+
+// Tracer starts server spans. It's the integration point of tracing libraries,
+// an OpenTelemetry adapter starts a span of kind server, with the remote parent
+// when it's valid, and returns the context carrying it.
+type Tracer interface {
+	Start(ctx context.Context, name string, parent TraceContext) (context.Context, Span)
+}
+
+type spanContextKey struct{}
+
+// TracingConfig defines the config for Tracing middleware.
+type TracingConfig struct {
+	// Tracer starts the spans.
+	Tracer Tracer
+
+	// Filter returns false for the requests not traced, e.g. health checks.
+	// Optional.
+	Filter func(c *Context) bool
+}
+
+// Tracing returns a middleware tracing the requests with a server span. The span
+// is named by the method and the route pattern, e.g. "GET /users/:id", rather than
+// by the path, to bound the number of span names. The parent span is read from the
+// traceparent and tracestate headers. The errors of the Context are recorded and the
+// 5xx responses mark the span as failed. Use InjectTraceContext to propagate the span
+// to other services.
+func Tracing(conf TracingConfig) HandlerFunc {
+	assert1(conf.Tracer != nil, "tracing needs a Tracer")
+	return func(c *Context) {
+		if conf.Filter != nil && !conf.Filter(c) {
+			return
+		}
+		parent, ok := ParseTraceparent(c.requestHeader("traceparent"))
+		if ok {
+			parent.State = c.requestHeader("tracestate")
+		}
+
+		method := c.Request.Method
+		route := c.FullPath()
+		name := method
+		if route != "" {
+			name += " " + route
+		}
+		ctx, span := conf.Tracer.Start(c.Request.Context(), name, parent)
+		c.Request = c.Request.WithContext(context.WithValue(ctx, spanContextKey{}, span))
+		span.SetAttribute("http.method", method)
+		if route != "" {
+			span.SetAttribute("http.route", route)
+			span.SetAttribute("gin.handler", c.HandlerName())
+		}
+		span.SetAttribute("http.client_ip", c.ClientIP())
+		defer span.End()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetError(http.StatusText(status))
+		}
+	}
+}
+
+// SpanFromContext returns the span started by the Tracing middleware, or nil.
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanContextKey{}).(Span)
+	return span
+}
+
+// InjectTraceContext sets the traceparent and tracestate headers of the span
+// started by the Tracing middleware, usually on the header of an outgoing request.
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	tc := span.TraceContext()
+	if !tc.IsValid() {
+		return
+	}
+	header.Set("traceparent", tc.Traceparent())
+	if tc.State != "" {
+		header.Set("tracestate", tc.State)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTraceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tc, ok := ParseTraceparent(testTraceparent)
+	assert.True(t, ok)
+	assert.Equal(t, byte(1), tc.Flags)
+	assert.Equal(t, testTraceparent, tc.Traceparent())
+
+	_, ok = ParseTraceparent("01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-future")
+	assert.True(t, ok)
+
+	for _, invalid := range []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"0x-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	} {
+		_, ok = ParseTraceparent(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+type testSpan struct {
+	name   string
+	parent TraceContext
+	attrs  map[string]interface{}
+	errs   []error
+	failed string
+	ended  bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *testSpan) RecordError(err error)                      { s.errs = append(s.errs, err) }
+func (s *testSpan) SetError(description string)                { s.failed = description }
+func (s *testSpan) End()                                       { s.ended = true }
+func (s *testSpan) TraceContext() TraceContext {
+	tc := s.parent
+	tc.SpanID = [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	return tc
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, parent TraceContext) (context.Context, Span) {
+	span := &testSpan{name: name, parent: parent, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestTracing(t *testing.T) {
+	tracer := &testTracer{}
+	router := New()
+	router.Use(Tracing(TracingConfig{
+		Tracer: tracer,
+		Filter: func(c *Context) bool { return c.FullPath() != "/health" },
+	}))
+	var outgoing http.Header
+	router.GET("/users/:id", func(c *Context) {
+		outgoing = http.Header{}
+		InjectTraceContext(c.Request.Context(), outgoing)
+		_ = c.Error(errors.New("cache miss"))
+		c.Status(http.StatusOK)
+	})
+	router.GET("/fail", func(c *Context) { c.Status(http.StatusBadGateway) })
+	router.GET("/health", func(c *Context) {})
+
+	performRequest(router, "GET", "/users/42", header{"traceparent", testTraceparent}, header{"tracestate", "vendor=1"})
+	assert.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, "GET /users/:id", span.name)
+	assert.True(t, span.ended)
+	assert.Equal(t, testTraceparent, span.parent.Traceparent())
+	assert.Equal(t, "/users/:id", span.attrs["http.route"])
+	assert.Equal(t, http.StatusOK, span.attrs["http.status_code"])
+	assert.Equal(t, []error{errors.New("cache miss")}, span.errs)
+	assert.Empty(t, span.failed)
+	assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-0102030405060708-01", outgoing.Get("traceparent"))
+	assert.Equal(t, "vendor=1", outgoing.Get("tracestate"))
+
+	performRequest(router, "GET", "/fail", header{"traceparent", "garbage"})
+	span = tracer.spans[1]
+	assert.Equal(t, "GET /fail", span.name)
+	assert.False(t, span.parent.IsValid())
+	assert.Equal(t, "Bad Gateway", span.failed)
+
+	performRequest(router, "GET", "/health")
+	assert.Len(t, tracer.spans, 2)
+
+	performRequest(router, "GET", "/missing")
+	assert.Equal(t, "GET", tracer.spans[2].name)
+	assert.NotContains(t, tracer.spans[2].attrs, "http.route")
+
+	outgoing = http.Header{}
+	InjectTraceContext(context.Background(), outgoing)
+	assert.Empty(t, outgoing)
+	assert.Panics(t, func() { Tracing(TracingConfig{}) })
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+// TrailingSlashPolicy is how a route handles the requests whose path only differs from
+// its path by a trailing slash, see RouterGroup.TrailingSlash.
+type TrailingSlashPolicy uint8
+
+const (
+	// TrailingSlashDefault redirects the requests when Engine.RedirectTrailingSlash is
+	// enabled, else it's TrailingSlashStrict.
+	TrailingSlashDefault TrailingSlashPolicy = iota
+	// TrailingSlashStrict doesn't match the requests, which are answered with 404.
+	TrailingSlashStrict
+	// TrailingSlashRedirect redirects the requests to the path of the route, with 301 for
+	// GET requests and 307 for the other methods.
+	TrailingSlashRedirect
+	// TrailingSlashMatch serves the requests with the route, without redirecting them.
+	TrailingSlashMatch
+)
+
+// TrailingSlash returns a copy of the group whose routes handle the requests whose path
+// only differs from theirs by a trailing slash with the policy, instead of following
+// Engine.RedirectTrailingSlash:
+//     api := router.Group("/v1").TrailingSlash(gin.TrailingSlashStrict)
+//     api.GET("/users", listUsers) // 404 for /v1/users/
+//     router.TrailingSlash(gin.TrailingSlashMatch).GET("/about/", about) // serves /about too
+// The policy is inherited by the groups created from it.
+func (group *RouterGroup) TrailingSlash(policy TrailingSlashPolicy) *RouterGroup {
+	assert1(policy <= TrailingSlashMatch, "invalid trailing slash policy")
+	g := *group
+	g.root = false
+	g.trailingSlash = policy
+	return &g
+}
+
+// withTrailingSlash returns a copy of the snapshot where the policy of the route is
+// policy. The maps are copied rather than changed, as withMeta does.
+func (rt *routeTrees) withTrailingSlash(method, path string, policy TrailingSlashPolicy) *routeTrees {
+	if rt.slashes[method][path] == policy {
+		return rt
+	}
+	next := *rt
+	next.slashes = make(map[string]map[string]TrailingSlashPolicy, len(rt.slashes)+1)
+	for m, routes := range rt.slashes {
+		next.slashes[m] = routes
+	}
+	routes := make(map[string]TrailingSlashPolicy, len(rt.slashes[method])+1)
+	for p, policy := range rt.slashes[method] {
+		if p != path {
+			routes[p] = policy
+		}
+	}
+	if policy != TrailingSlashDefault {
+		routes[path] = policy
+	}
+	next.slashes[method] = routes
+	return &next
+}
+
+// trailingSlashPolicy returns the policy of the route matching the path with (without)
+// the trailing slash it is without (with), as getValue recommended, and that path.
+func (engine *Engine) trailingSlashPolicy(trees *routeTrees, method string, root *node, path string) (TrailingSlashPolicy, string) {
+	if path[len(path)-1] == '/' {
+		path = path[:len(path)-1]
+	} else {
+		path += "/"
+	}
+	policy := TrailingSlashDefault
+	if len(trees.slashes[method]) > 0 {
+		policy = trees.slashes[method][root.getValue(path, nil, false).fullPath]
+	}
+	if policy == TrailingSlashDefault {
+		policy = TrailingSlashStrict
+		if engine.RedirectTrailingSlash {
+			policy = TrailingSlashRedirect
+		}
+	}
+	return policy, path
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterGroupTrailingSlash(t *testing.T) {
+	router := New()
+	handler := func(c *Context) { c.String(http.StatusOK, "%s %s", c.FullPath(), c.Param("id")) }
+	api := router.Group("/v1").TrailingSlash(TrailingSlashStrict)
+	api.GET("/users", handler)
+	api.Group("/admin").GET("/users/:id", handler)
+	router.TrailingSlash(TrailingSlashMatch).GET("/about/", handler)
+	router.TrailingSlash(TrailingSlashMatch).GET("/posts/:id", handler)
+	router.GET("/site/", handler)
+
+	w := performRequest(router, http.MethodGet, "/v1/users/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(router, http.MethodGet, "/v1/admin/users/1/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(router, http.MethodGet, "/about")
+	assert.Equal(t, "/about/ ", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/posts/42/")
+	assert.Equal(t, "/posts/:id 42", w.Body.String())
+	w = performRequest(router, http.MethodGet, "/site")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/site/", w.Header().Get("Location"))
+
+	match, ok := router.Match(http.MethodGet, "/posts/42/")
+	if assert.True(t, ok) {
+		assert.Equal(t, "/posts/:id", match.Path)
+		assert.Equal(t, Params{{Key: "id", Value: "42"}}, match.Params)
+	}
+	_, ok = router.Match(http.MethodGet, "/site")
+	assert.False(t, ok)
+
+	// the engine wide flag only applies to the routes without policy
+	router.RedirectTrailingSlash = false
+	w = performRequest(router, http.MethodGet, "/site")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	redirect := router.TrailingSlash(TrailingSlashRedirect)
+	redirect.GET("/docs", handler)
+	w = performRequest(router, http.MethodGet, "/docs/")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/docs", w.Header().Get("Location"))
+	w = performRequest(router, http.MethodGet, "/about")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// a removed route loses its policy
+	assert.True(t, router.RemoveRoute(http.MethodGet, "/about/"))
+	router.GET("/about/", handler)
+	w = performRequest(router, http.MethodGet, "/about")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.Panics(t, func() { router.TrailingSlash(TrailingSlashMatch + 1) })
+}
@@ -6,8 +6,15 @@ package gin
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 
@@ -64,6 +71,134 @@ func (trees methodTrees) get(method string) *node {
 	return nil
 }
 
+// methodTreeIndex finds the roots of the method trees without comparing the method
+// to the ones of every tree: the standard methods are indexed in an array, the
+// custom ones in a map.
+type methodTreeIndex struct {
+	standard [9]*node
+	custom   map[string]*node
+}
+
+// standardMethods are the methods indexed by standardMethodIndex.
+var standardMethods = [...]string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// standardMethodIndex returns the index of the method in standardMethods, or -1 when
+// it's not a standard method, with a single string comparison.
+func standardMethodIndex(method string) int {
+	if len(method) < 3 {
+		return -1
+	}
+	i := -1
+	switch method[0] {
+	case 'G':
+		i = 0
+	case 'H':
+		i = 1
+	case 'P':
+		switch len(method) {
+		case 4:
+			i = 2
+		case 3:
+			i = 3
+		case 5:
+			i = 4
+		}
+	case 'D':
+		i = 5
+	case 'C':
+		i = 6
+	case 'O':
+		i = 7
+	case 'T':
+		i = 8
+	}
+	if i < 0 || method != standardMethods[i] {
+		return -1
+	}
+	return i
+}
+
+func (idx *methodTreeIndex) get(method string) *node {
+	if i := standardMethodIndex(method); i >= 0 {
+		return idx.standard[i]
+	}
+	return idx.custom[method]
+}
+
+func (idx *methodTreeIndex) set(method string, root *node) {
+	if i := standardMethodIndex(method); i >= 0 {
+		idx.standard[i] = root
+		return
+	}
+	if idx.custom == nil {
+		idx.custom = make(map[string]*node)
+	}
+	idx.custom[method] = root
+}
+
+// routeTrees is a snapshot of the method trees of an Engine. It's never changed once
+// published: the routes are added to copies of the trees, see Engine.addRoute, so that
+// the lookups read it without locking while routes are added.
+type routeTrees struct {
+	trees methodTrees
+	index methodTreeIndex
+	// the nodes of the static routes by method and path, set by Engine.Optimize, which
+	// are found with a single map lookup instead of a walk of the tree
+	static map[string]map[string]*node
+	// the metadata of the routes by method and path, set with RouterGroup.WithMeta
+	meta map[string]map[string]RouteMeta
+	// the named routes by name, and their names by method and path, set with
+	// RouterGroup.Named and Name
+	names      map[string]*namedRoute
+	routeNames map[string]map[string]string
+	// the trailing slash policies of the routes by method and path, set with
+	// RouterGroup.TrailingSlash
+	slashes map[string]map[string]TrailingSlashPolicy
+	// the positions in the handlers of the routes, plus one, of their CORS middleware by
+	// method and path, see servePreflight
+	cors map[string]map[string]int
+	// the pattern of the host of the routes, empty for the routes of all the hosts, and
+	// the snapshots of the routes of the hosts, see RouterGroup.Host
+	host  string
+	hosts []*hostRoutes
+}
+
+// with returns a copy of the snapshot where the tree of the method is root.
+func (rt *routeTrees) with(method string, root *node) *routeTrees {
+	next := &routeTrees{trees: make(methodTrees, 0, len(rt.trees)+1), index: rt.index, meta: rt.meta,
+		names: rt.names, routeNames: rt.routeNames, slashes: rt.slashes, cors: rt.cors, host: rt.host, hosts: rt.hosts}
+	replaced := false
+	for _, tree := range rt.trees {
+		if tree.method == method {
+			tree.root, replaced = root, true
+		}
+		next.trees = append(next.trees, tree)
+	}
+	if !replaced {
+		next.trees = append(next.trees, methodTree{method: method, root: root})
+	}
+	if rt.index.custom != nil {
+		next.index.custom = make(map[string]*node, len(rt.index.custom)+1)
+		for m, n := range rt.index.custom {
+			next.index.custom[m] = n
+		}
+	}
+	next.index.set(method, root)
+	// once collected by Optimize, the static routes of the replaced tree are collected
+	// again, so that the routes added or removed later keep on being looked up first
+	if rt.static != nil {
+		next.static = make(map[string]map[string]*node, len(rt.static)+1)
+		for m, routes := range rt.static {
+			next.static[m] = routes
+		}
+		next.static[method] = staticRouteMap(root)
+	}
+	return next
+}
+
 func min(a, b int) int {
 	if a <= b {
 		return a
@@ -71,9 +206,19 @@ func min(a, b int) int {
 	return b
 }
 
+// longestCommonPrefix compares the strings 8 bytes at a time, then byte by byte.
 func longestCommonPrefix(a, b string) int {
 	i := 0
 	max := min(len(a), len(b))
+	if max >= 8 {
+		ab, bb := bytesconv.StringToBytes(a), bytesconv.StringToBytes(b)
+		for ; i+8 <= max; i += 8 {
+			// the first different byte is the lowest one of the little endian words
+			if x := binary.LittleEndian.Uint64(ab[i:]) ^ binary.LittleEndian.Uint64(bb[i:]); x != 0 {
+				return i + bits.TrailingZeros64(x)/8
+			}
+		}
+	}
 	for i < max && a[i] == b[i] {
 		i++
 	}
@@ -97,15 +242,26 @@ const (
 	catchAll
 )
 
+// node is a node of the radix tree. The fields read on each step of a lookup come first,
+// so that they share a cache line, and the children are stored by value in a contiguous
+// slice rather than behind pointers.
 type node struct {
 	path      string
 	indices   string
+	children  []node
 	wildChild bool
 	nType     nodeType
 	priority  uint32
-	children  []*node
 	handlers  HandlersChain
+	run       HandlerFunc // runs the handlers, set with Engine.CompileHandlers
 	fullPath  string
+	// constraint checks the values of a param, e.g. the regular expression [0-9]+ of
+	// :id([0-9]+) or the int type of :id|int, nil for the params without one
+	constraint func(value string) bool
+	// lowercase copies of path and indices for findCaseInsensitivePath, set by
+	// updateLowercase, lowerPath is empty when path isn't ASCII
+	lowerPath    string
+	lowerIndices string
 }
 
 // Increments priority of the given child and reorders if necessary
@@ -131,25 +287,89 @@ func (n *node) incrementChildPrio(pos int) int {
 	return newPos
 }
 
-// addRoute adds a node with the given handle to the path.
-// Not concurrency-safe!
+// sortChildren orders the children of the nodes of the tree by priority, keeping the
+// order of the children of the same priority, see Engine.FreezeRoutePriorities. The
+// nodes are copied before they are changed, as by addRoute.
+func (n *node) sortChildren() {
+	n.ownChildren()
+	// only the static children are sorted, the params are tried in order, see matchParam
+	if len(n.indices) > 1 {
+		order := make([]int, len(n.indices))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return n.children[order[i]].priority > n.children[order[j]].priority
+		})
+		children := make([]node, len(n.children))
+		indices := make([]byte, len(n.indices))
+		for i, j := range order {
+			children[i] = n.children[j]
+			indices[i] = n.indices[j]
+		}
+		copy(children[len(order):], n.children[len(order):])
+		n.children, n.indices = children, string(indices)
+	}
+	for i := range n.children {
+		n.children[i].sortChildren()
+	}
+}
+
+// addRoute adds a node with the given handle to the path and returns it.
+// Not concurrency-safe! The nodes below n are copied before they are changed, so that
+// n can be a copy of the root of a tree which is read concurrently.
 // 前缀树，基数树
-func (n *node) addRoute(path string, handlers HandlersChain) {
+func (n *node) addRoute(path string, handlers HandlersChain) *node {
+	return n.insertRoute(path, handlers, true)
+}
+
+// tryAddRoute is insertRoute returning the error of a route which can't be added, e.g.
+// a wildcard conflict or a duplicate route, instead of panicking. n is left unchanged
+// when the route isn't added.
+func (n *node) tryAddRoute(path string, handlers HandlersChain, reorder bool) (leaf *node, err error) {
+	defer recoverRouteError(&err)
+	root := *n
+	if leaf = root.insertRoute(path, handlers, reorder); leaf == &root {
+		leaf = n
+	}
+	*n = root
+	return leaf, nil
+}
+
+// recoverRouteError recovers the panic of a route which can't be added as an error, the
+// other panics are propagated.
+func recoverRouteError(err *error) {
+	if r := recover(); r != nil {
+		text, ok := r.(string)
+		if !ok {
+			panic(r)
+		}
+		*err = errors.New(text)
+	}
+}
+
+// insertRoute is addRoute, reordering the children by priority as the route is added
+// when reorder is set, or else keeping the children in the order they were added.
+func (n *node) insertRoute(path string, handlers HandlersChain, reorder bool) *node {
 	fullPath := path
 	n.priority++
+	defer n.updateLowercase()
 
 	// Empty tree
 	// 空树，根节点，直接插入。
 	if len(n.path) == 0 && len(n.children) == 0 {
-		n.insertChild(path, fullPath, handlers)
+		leaf := n.insertChild(path, fullPath, handlers)
 		n.nType = root
-		return
+		return leaf
 	}
 
 	parentFullPathIndex := 0
 
 walk:
 	for {
+		// the children may be shared with older snapshots of the tree
+		n.ownChildren()
+
 		// Find the longest common prefix.
 		// This also implies that the common prefix contains no ':' or '*'
 		// since the existing key can't contain those chars.
@@ -174,7 +394,7 @@ walk:
 				fullPath:  n.fullPath,
 			}
 
-			n.children = []*node{&child}
+			n.children = []node{child}
 			// []byte for proper unicode char conversion, see #65
 			// 所有子节点的路径首字符的字符串
 			n.indices = bytesconv.BytesToString([]byte{n.path[i]})
@@ -194,20 +414,22 @@ walk:
 
 			// 对子节点有通配符的特殊处理
 			// eg:  /a/:name 插入 /a/:name/cc
-			if n.wildChild {
+			// a static path is added next to params which all have a constraint, see addParamChild
+			if n.wildChild && (path[0] == ':' || path[0] == '*' || !n.paramsConstrained()) {
 				parentFullPathIndex += len(n.path)
+				// a param with another constraint is added next to the other ones
+				if leaf := n.addParamSibling(path, fullPath, handlers); leaf != nil {
+					return leaf
+				}
 				// n 由 /a/ 指向到 :name
 				// path 值为 :name/cc
-				n = n.children[0]
+				n = &n.children[n.wildcardChild(path)]
 				n.priority++
 
 				// eg: 已有 /a/:name 新增 /a/:name/xxx
 				// Check if the wildcard matches
-				// catch all (*)通配符不能有子节点
 				// 只有 /a/:name 插入 /a/:name/cc这种情况，不可插入 /a/:namesss, 不可插入 /a/xxx
 				if len(path) >= len(n.path) && n.path == path[:len(n.path)] &&
-					// Adding a child to a catchAll is not possible
-					n.nType != catchAll &&
 					// Check for longer wildcard, e.g. :name and :names
 					(len(n.path) >= len(path) || path[len(n.path)] == '/') {
 					// n已经指向了 :name节点， path 值为 :name/cc, 继续循环逻辑就可以了。
@@ -234,9 +456,10 @@ walk:
 			// param（:xxx）节点莫得indices?
 			//	还是说param如果有子节点，那么理论上只会有一个，一定是 /开头
 			//	不像普通路径 /abc， 插入/ad, 可以拆分成 /a -> bc,d
-			if n.nType == param && c == '/' && len(n.children) == 1 {
+			// the rest of the path after a catch-all is its single child too
+			if (n.nType == param || n.nType == catchAll) && c == '/' && len(n.children) == 1 {
 				parentFullPathIndex += len(n.path)
-				n = n.children[0]
+				n = &n.children[0]
 				n.priority++
 				continue walk
 			}
@@ -246,8 +469,12 @@ walk:
 			for i, max := 0, len(n.indices); i < max; i++ {
 				if c == n.indices[i] {
 					parentFullPathIndex += len(n.path)
-					i = n.incrementChildPrio(i)
-					n = n.children[i]
+					if reorder {
+						i = n.incrementChildPrio(i)
+					} else {
+						n.children[i].priority++
+					}
+					n = &n.children[i]
 					continue walk
 				}
 			}
@@ -260,19 +487,27 @@ walk:
 			if c != ':' && c != '*' {
 				// n 的 indices 添加新孩子节点的路径首字母
 				// []byte for proper unicode char conversion, see #65
+				// the static children come before the params, if any
+				pos := len(n.indices)
 				n.indices += bytesconv.BytesToString([]byte{c})
-				child := &node{
+				n.children = append(n.children, node{})
+				copy(n.children[pos+1:], n.children[pos:])
+				n.children[pos] = node{
 					fullPath: fullPath,
 				}
-				n.children = append(n.children, child)
 				// 子节点权重调整。 根据调整后权重更新n的indices顺序
-				n.incrementChildPrio(len(n.indices) - 1)
-				n = child
-			} else {
+				if reorder {
+					pos = n.incrementChildPrio(pos)
+				} else {
+					n.children[pos].priority++
+				}
+				n = &n.children[pos]
+			} else if leaf := n.addParamChild(path, fullPath, handlers); leaf != nil {
 				// eg: 已有 /search/ 插入 /search/:name, 此时 path值为 :name, c值为 : , n指向/search/
+				// a param with a constraint is added next to the static children
+				return leaf
 			}
-			n.insertChild(path, fullPath, handlers)
-			return
+			return n.insertChild(path, fullPath, handlers)
 		}
 
 		// eg: 添加重复路径的情况  已有 /a  添加 /a
@@ -282,12 +517,234 @@ walk:
 		}
 		n.handlers = handlers
 		n.fullPath = fullPath
+		return n
+	}
+}
+
+// ownChildren replaces the children by a copy, so that they can be changed without
+// changing the trees sharing them.
+func (n *node) ownChildren() {
+	if len(n.children) > 0 {
+		n.children = append(make([]node, 0, len(n.children)+1), n.children...)
+	}
+}
+
+// wildcardChild returns the index of the wildcard child of n matching the wildcard path
+// starts with, or of the last one when none does. The wildcard children come after the
+// static ones.
+func (n *node) wildcardChild(path string) int {
+	for i := len(n.indices); i < len(n.children); i++ {
+		child := &n.children[i]
+		if len(path) >= len(child.path) && child.path == path[:len(child.path)] &&
+			(len(child.path) == len(path) || path[len(child.path)] == '/') {
+			return i
+		}
+	}
+	return len(n.children) - 1
+}
+
+// addParamSibling adds the route to a new param child of n when path starts with a param
+// which isn't one of the children of n yet, and has a constraint or none of the
+// children of n is without one nor static. It returns the leaf of the route, nil when it's not
+// added. The params with a constraint are tried in the order they're added, before the
+// one without a constraint, see matchParam.
+func (n *node) addParamSibling(path, fullPath string, handlers HandlersChain) *node {
+	if path[0] != ':' || n.children[len(n.indices)].nType != param {
+		return nil
+	}
+	wildcard, _, valid := findWildcard(path)
+	if !valid {
+		return nil
+	}
+	unconstrained := -1
+	for i := len(n.indices); i < len(n.children); i++ {
+		if n.children[i].path == wildcard {
+			return nil
+		}
+		if n.children[i].constraint == nil {
+			unconstrained = i
+		}
+	}
+	// a param without a constraint would shadow the static children
+	if _, pattern, typ := splitParamConstraint(wildcard); pattern == "" && typ == "" &&
+		(unconstrained >= 0 || len(n.indices) > 0) {
+		return nil
+	}
+
+	var parent node
+	leaf := parent.insertChild(path, fullPath, handlers)
+	pos := len(n.children)
+	if unconstrained >= 0 {
+		pos = unconstrained
+	}
+	children := make([]node, 0, len(n.children)+1)
+	children = append(children, n.children[:pos]...)
+	children = append(children, parent.children[0])
+	n.children = append(children, n.children[pos:]...)
+	if leaf == &parent.children[0] {
+		leaf = &n.children[pos]
+	}
+	return leaf
+}
+
+// addParamChild adds the route to a new param child of n when n only has static children
+// and path starts with a param with a constraint, e.g. /orders/:id|int next to
+// /orders/latest. It returns the leaf of the route, nil when it's not added. The static
+// children are tried before the params, see getMixedValue.
+func (n *node) addParamChild(path, fullPath string, handlers HandlersChain) *node {
+	if path[0] != ':' || len(n.children) == 0 || n.wildChild {
+		return nil
+	}
+	wildcard, _, valid := findWildcard(path)
+	if !valid {
+		return nil
+	}
+	if _, pattern, typ := splitParamConstraint(wildcard); pattern == "" && typ == "" {
+		return nil
+	}
+
+	var parent node
+	leaf := parent.insertChild(path, fullPath, handlers)
+	pos := len(n.children)
+	n.children = append(n.children, parent.children[0])
+	n.wildChild = true
+	if leaf == &parent.children[0] {
+		leaf = &n.children[pos]
+	}
+	return leaf
+}
+
+// paramsConstrained reports whether the wildcard children of n are params which all
+// have a constraint, so that static children can be added next to them.
+func (n *node) paramsConstrained() bool {
+	for i := len(n.indices); i < len(n.children); i++ {
+		if n.children[i].nType != param || n.children[i].constraint == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// removeRoute removes the handlers of the route of path, as registered with addRoute, and
+// returns whether it was registered. The nodes left without route are removed and the
+// nodes left with a single static child are merged with it, so that the tree is the
+// one the remaining routes would build, except for the order of the children when
+// reorder isn't set. Like addRoute, the nodes below n are copied before they are
+// changed. Not concurrency-safe!
+func (n *node) removeRoute(path string, reorder bool) bool {
+	if !n.removeChildRoute(path, reorder) {
+		return false
+	}
+	if n.handlers == nil && len(n.children) == 0 {
+		*n = node{}
+		return true
+	}
+	n.mergeChild()
+	n.updateLowercase()
+	return true
+}
+
+// removeChildRoute removes the route of path, which starts with the path of n, from the
+// nodes below n.
+func (n *node) removeChildRoute(path string, reorder bool) bool {
+	if !strings.HasPrefix(path, n.path) {
+		return false
+	}
+	path = path[len(n.path):]
+	if path == "" {
+		if n.handlers == nil {
+			return false
+		}
+		n.handlers, n.run = nil, nil
+		n.priority--
+		return true
+	}
+
+	pos := n.routeChild(path)
+	if pos < 0 {
+		return false
+	}
+	n.ownChildren()
+	if !n.children[pos].removeChildRoute(path, reorder) {
+		return false
+	}
+	n.priority--
+
+	child := &n.children[pos]
+	switch {
+	case child.handlers == nil && len(child.children) == 0:
+		// the child has no route left
+		n.children = append(n.children[:pos], n.children[pos+1:]...)
+		if pos < len(n.indices) {
+			n.indices = n.indices[:pos] + n.indices[pos+1:]
+		} else if len(n.children) == len(n.indices) {
+			n.wildChild = false
+		}
+	case child.nType == static:
+		child.mergeChild()
+		if reorder && pos < len(n.indices) {
+			n.decrementChildPrio(pos)
+		}
+	}
+	return true
+}
+
+// routeChild returns the index of the child of n holding the rest of a registered path,
+// -1 when there's none.
+func (n *node) routeChild(path string) int {
+	// the rest of the path after a param or a catch-all is its single child
+	if n.nType == param || n.nType == catchAll {
+		if len(n.children) > 0 {
+			return 0
+		}
+		return -1
+	}
+	for i := 0; i < len(n.indices); i++ {
+		if path[0] == n.indices[i] {
+			return i
+		}
+	}
+	for i := len(n.indices); i < len(n.children); i++ {
+		child := &n.children[i]
+		if strings.HasPrefix(path, child.path) &&
+			(len(path) == len(child.path) || path[len(child.path)] == '/') {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeChild merges n with its child when n has no handlers and a single static child,
+// as the split edge of addRoute is undone.
+func (n *node) mergeChild() {
+	if n.handlers != nil || len(n.children) != 1 || n.wildChild || n.children[0].nType != static {
 		return
 	}
+	child := n.children[0]
+	child.path = n.path + child.path
+	child.nType = n.nType
+	*n = child
+}
+
+// decrementChildPrio moves the child at pos, whose priority was decremented, after the
+// static children of higher priority, as incrementChildPrio moves it before them.
+func (n *node) decrementChildPrio(pos int) {
+	cs := n.children
+	newPos := pos
+	for ; newPos+1 < len(n.indices) && cs[newPos+1].priority > cs[newPos].priority; newPos++ {
+		// Swap node positions
+		cs[newPos+1], cs[newPos] = cs[newPos], cs[newPos+1]
+	}
+	if newPos != pos {
+		n.indices = n.indices[:pos] + n.indices[pos+1:newPos+1] + // Rest without char at 'pos'
+			n.indices[pos:pos+1] + n.indices[newPos+1:] // The index char we move
+	}
 }
 
 // Search for a wildcard segment and check the name for invalid characters.
 // Returns -1 as index, if no wildcard was found.
+// A param may end with a constraint in parentheses, e.g. :id([0-9]+), in which any
+// character but '/' is allowed, or with a type, e.g. :id|int.
 func findWildcard(path string) (wildcard string, i int, valid bool) {
 	// Find start
 	for start, c := range []byte(path) {
@@ -298,20 +755,105 @@ func findWildcard(path string) (wildcard string, i int, valid bool) {
 
 		// Find end and check for invalid characters
 		valid = true
-		for end, c := range []byte(path[start+1:]) {
-			switch c {
-			case '/':
-				return path[start : start+1+end], start, valid
-			case ':', '*':
+		depth, constrained := 0, false
+		for end := start + 1; end < len(path); end++ {
+			c := path[end]
+			switch {
+			case c == '/':
+				return path[start:end], start, valid && depth == 0
+			case depth > 0:
+				switch c {
+				case '\\':
+					end++
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+			case constrained:
+				// nothing may follow the constraint
+				valid = false
+			case c == '(' && path[start] == ':':
+				depth, constrained = 1, true
+			case c == ':' || c == '*':
 				valid = false
 			}
 		}
-		return path[start:], start, valid
+		return path[start:], start, valid && depth == 0
 	}
 	return "", -1, false
 }
 
-func (n *node) insertChild(path string, fullPath string, handlers HandlersChain) {
+// splitParamConstraint splits a param wildcard into its name and its constraint, e.g.
+// ":id" and the pattern "[0-9]+" for ":id([0-9]+)", or ":id" and the type "int" for
+// ":id|int".
+func splitParamConstraint(wildcard string) (name, pattern, typ string) {
+	i := strings.IndexByte(wildcard, '(')
+	if j := strings.IndexByte(wildcard, '|'); j > 0 && (i < 0 || j < i) {
+		return wildcard[:j], "", wildcard[j+1:]
+	}
+	if i > 0 && wildcard[len(wildcard)-1] == ')' {
+		return wildcard[:i], wildcard[i+1 : len(wildcard)-1], ""
+	}
+	return wildcard, "", ""
+}
+
+// optionalParamPaths returns the paths of the routes of path, which are path without its
+// last segment and path without the '?' when its last segment is an optional param, e.g.
+// "/articles/:id" and "/articles/:id/:rev" for "/articles/:id/:rev?", or else path.
+func optionalParamPaths(path string) []string {
+	if !strings.HasSuffix(path, "?") {
+		return []string{path}
+	}
+	full := path[:len(path)-1]
+	slash := strings.LastIndexByte(full, '/')
+	if segment := full[slash+1:]; len(segment) < 2 || segment[0] != ':' {
+		panic("only a param making the whole last segment can be optional in path '" + path + "'")
+	}
+	short := full[:slash]
+	if short == "" {
+		short = "/"
+	}
+	return []string{short, full}
+}
+
+// paramConstraint returns the check of the values of a param with a constraint, split by
+// splitParamConstraint, and panics when it's invalid.
+func paramConstraint(wildcard, pattern, typ, fullPath string) func(string) bool {
+	if pattern != "" {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			panic("invalid constraint of the wildcard '" + wildcard + "' in path '" + fullPath + "': " + err.Error())
+		}
+		return re.MatchString
+	}
+	constraint := paramTypes[typ]
+	if constraint == nil {
+		panic("unknown type '" + typ + "' of the wildcard '" + wildcard + "' in path '" + fullPath + "'")
+	}
+	return constraint
+}
+
+// paramKey returns the name of the param of n, without its constraint.
+func (n *node) paramKey() string {
+	if n.constraint != nil {
+		return n.path[1:strings.IndexAny(n.path, "(|")]
+	}
+	return n.path[1:]
+}
+
+// matchParam returns the first of the param children whose constraint matches the value
+// of the path segment, nil when none does.
+func matchParam(children []node, value string) *node {
+	for i := range children {
+		if child := &children[i]; child.constraint == nil || child.constraint(value) {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *node) insertChild(path string, fullPath string, handlers HandlersChain) *node {
 	for {
 		// 循环处理通配符，可能会创建多个节点
 		// Find prefix until first wildcard
@@ -329,9 +871,17 @@ func (n *node) insertChild(path string, fullPath string, handlers HandlersChain)
 
 		// check if the wildcard has a name
 		// 通配符得有个名字吧,  :a ， 至少两个字符
-		if len(wildcard) < 2 {
+		name, pattern, typ := wildcard, "", ""
+		if wildcard[0] == ':' {
+			name, pattern, typ = splitParamConstraint(wildcard)
+		}
+		if len(name) < 2 {
 			panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
 		}
+		var constraint func(string) bool
+		if pattern != "" || len(name) < len(wildcard) && wildcard[len(name)] == '|' {
+			constraint = paramConstraint(wildcard, pattern, typ, fullPath)
+		}
 
 		// Check if this node has existing children which would be
 		// unreachable if we insert the wildcard here
@@ -353,13 +903,13 @@ func (n *node) insertChild(path string, fullPath string, handlers HandlersChain)
 
 			n.wildChild = true
 			// 创建 param子节点
-			child := &node{
-				nType:    param,
-				path:     wildcard,
-				fullPath: fullPath,
-			}
-			n.children = []*node{child}
-			n = child
+			n.children = []node{{
+				nType:      param,
+				path:       wildcard,
+				fullPath:   fullPath,
+				constraint: constraint,
+			}}
+			n = &n.children[0]
 			n.priority++
 
 			// if the path doesn't end with the wildcard, then there
@@ -370,27 +920,22 @@ func (n *node) insertChild(path string, fullPath string, handlers HandlersChain)
 			if len(wildcard) < len(path) {
 				path = path[len(wildcard):]
 
-				child := &node{
+				n.children = []node{{
 					priority: 1,
 					fullPath: fullPath,
-				}
-				n.children = []*node{child}
-				n = child
+				}}
+				n = &n.children[0]
 				continue
 			}
 
 			// Otherwise we're done. Insert the handle in the new leaf
 			// 否则到头了，handlers赋值给节点n
 			n.handlers = handlers
-			return
+			return n
 		}
 
 		// 处理 catchAll *
 		// catchAll
-		// catchAll必须是结尾，不能在path中间
-		if i+len(wildcard) != len(path) {
-			panic("catch-all routes are only allowed at the end of the path in path '" + fullPath + "'")
-		}
 		// eg: /src1/ 插入 /src1/*filepath 报错，  /src1/*filepath可以匹配（/src1/, /src1/xxx），包含了
 		if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
 			panic("catch-all conflicts with existing handle for the path segment root in path '" + fullPath + "'")
@@ -407,29 +952,39 @@ func (n *node) insertChild(path string, fullPath string, handlers HandlersChain)
 		n.path = path[:i]
 
 		// First node: catchAll node with empty path
-		child := &node{
+		n.children = []node{{
 			wildChild: true,
 			nType:     catchAll,
 			fullPath:  fullPath,
-		}
-
-		n.children = []*node{child}
+		}}
 		// 注意： catch all 两个节点的父节点的indices是 '/'
 		n.indices = string('/')
-		n = child
+		n = &n.children[0]
 		n.priority++
 
 		// second node: node holding the variable
-		child = &node{
-			path:     path[i:],
+		end := i + 1 + len(wildcard)
+		n.children = []node{{
+			path:     path[i:end],
 			nType:    catchAll,
-			handlers: handlers,
 			priority: 1,
 			fullPath: fullPath,
+		}}
+		n = &n.children[0]
+
+		// a catch-all in the middle of the path has a single child holding the rest of
+		// the path, which is tried after the longest values first, see getValue
+		if end < len(path) {
+			path = path[end:]
+			n.children = []node{{
+				priority: 1,
+				fullPath: fullPath,
+			}}
+			n = &n.children[0]
+			continue
 		}
-		n.children = []*node{child}
-
-		return
+		n.handlers = handlers
+		return n
 	}
 
 	// If no wildcard was found, simply insert the path and handle
@@ -437,16 +992,25 @@ func (n *node) insertChild(path string, fullPath string, handlers HandlersChain)
 	n.path = path
 	n.handlers = handlers
 	n.fullPath = fullPath
+	return n
 }
 
 // nodeValue holds return values of (*Node).getValue method
 type nodeValue struct {
 	handlers HandlersChain
+	run      HandlerFunc
 	params   *Params
 	tsr      bool
 	fullPath string
 }
 
+// add appends a param. The slice is preallocated with the capacity of the route with
+// the most params, see Engine.SetParamsCapacity, so that the lookups don't allocate; it only
+// grows when a route with more params was added after the slice was allocated.
+func (ps *Params) add(key, value string) {
+	*ps = append(*ps, Param{Key: key, Value: value})
+}
+
 // Returns the handle registered with the given path (key). The values of
 // wildcards are saved to a map.
 // If no handle can be found, a TSR (trailing slash redirect) recommendation is
@@ -468,7 +1032,7 @@ walk: // Outer loop for walking the tree
 					idxc := path[0]
 					for i, c := range []byte(n.indices) {
 						if c == idxc {
-							n = n.children[i]
+							n = &n.children[i]
 							continue walk
 						}
 					}
@@ -482,8 +1046,12 @@ walk: // Outer loop for walking the tree
 				}
 
 				// Handle wildcard child
-				// 节点如果有孩子节点是通配符节点，意味着节点只有一个孩子
-				n = n.children[0]
+				// 节点如果有孩子节点是通配符节点，意味着节点只有一个孩子（或者多个带约束的参数节点）
+				if len(n.indices) > 0 {
+					return n.getMixedValue(path, params, unescape, value)
+				}
+				children := n.children
+				n = &children[0]
 				switch n.nType {
 				case param:
 					// Find param end (either '/' or path end)
@@ -492,6 +1060,16 @@ walk: // Outer loop for walking the tree
 					for end < len(path) && path[end] != '/' {
 						end++
 					}
+					if n.constraint != nil {
+						// the siblings are tried in turn, when the rest of the path
+						// doesn't match below the first one matching the value
+						if len(children) > 1 {
+							return getParamValue(children, path, params, unescape, value)
+						}
+						if n = matchParam(children, path[:end]); n == nil {
+							return
+						}
+					}
 
 					// Save param value
 					// 保存参数。 value.params是一个数组
@@ -502,19 +1080,13 @@ walk: // Outer loop for walking the tree
 							value.params = params
 						}
 						// Expand slice within preallocated capacity
-						// 为何不用append? 避免容量的多余分配？
-						i := len(*value.params)
-						*value.params = (*value.params)[:i+1]
 						val := path[:end]
 						if unescape {
 							if v, err := url.QueryUnescape(val); err == nil {
 								val = v
 							}
 						}
-						(*value.params)[i] = Param{
-							Key:   n.path[1:],
-							Value: val,
-						}
+						value.params.add(n.paramKey(), val)
 					}
 
 					// we need to go deeper!
@@ -522,7 +1094,7 @@ walk: // Outer loop for walking the tree
 					if end < len(path) {
 						if len(n.children) > 0 {
 							path = path[end:]
-							n = n.children[0]
+							n = &n.children[0]
 							continue walk
 						}
 
@@ -533,13 +1105,14 @@ walk: // Outer loop for walking the tree
 					}
 
 					if value.handlers = n.handlers; value.handlers != nil {
+						value.run = n.run
 						value.fullPath = n.fullPath
 						return
 					}
 					if len(n.children) == 1 {
 						// No handle found. Check if a handle for this path + a
 						// trailing slash exists for TSR recommendation
-						n = n.children[0]
+						n = &n.children[0]
 						// 处理少尾斜杠的情况
 						value.tsr = (n.path == "/" && n.handlers != nil)
 					}
@@ -547,28 +1120,34 @@ walk: // Outer loop for walking the tree
 
 				case catchAll:
 					// catchAll的tree很有意思， /a/*name 会有 /a -> "" 空节点，wildChild为true -> /*name 三个节点。
-					// 走到这里时，都不用判断路径后续了，全部处理。 而且当前节点 /*name一定会有handlers
+					// 走到这里时，都不用判断路径后续了，全部处理。
+					// a catch-all in the middle of the path is tried with the longest values
+					// first, then as the end of the path
+					if len(n.children) > 0 {
+						if v, ok := n.getCatchAllValue(path, params, unescape); ok {
+							return v
+						} else if n.handlers == nil {
+							value.tsr = v.tsr
+							return
+						}
+					}
 					// Save param value
 					if params != nil {
 						if value.params == nil {
 							value.params = params
 						}
 						// Expand slice within preallocated capacity
-						i := len(*value.params)
-						*value.params = (*value.params)[:i+1]
 						val := path
 						if unescape {
 							if v, err := url.QueryUnescape(path); err == nil {
 								val = v
 							}
 						}
-						(*value.params)[i] = Param{
-							Key:   n.path[2:],
-							Value: val,
-						}
+						value.params.add(n.path[2:], val)
 					}
 
 					value.handlers = n.handlers
+					value.run = n.run
 					value.fullPath = n.fullPath
 					return
 
@@ -583,6 +1162,7 @@ walk: // Outer loop for walking the tree
 			// Check if this node has a handle registered.
 			// 有handlers
 			if value.handlers = n.handlers; value.handlers != nil {
+				value.run = n.run
 				value.fullPath = n.fullPath
 				return
 			}
@@ -603,7 +1183,7 @@ walk: // Outer loop for walking the tree
 			//     此时请求 /src
 			for i, c := range []byte(n.indices) {
 				if c == '/' {
-					n = n.children[i]
+					n = &n.children[i]
 					// (len(n.path) == 1 && n.handlers != nil)  处理 eg1 ，此时子节点必须是 / 路径并且有handlers，才有可能匹配tsr
 					// (n.nType == catchAll && n.children[0].handlers != nil) 处理eg2
 					value.tsr = (len(n.path) == 1 && n.handlers != nil) ||
@@ -625,6 +1205,107 @@ walk: // Outer loop for walking the tree
 	}
 }
 
+// getCatchAllValue looks up the rest of the path in the child of the catch-all n, after
+// each of the values of the catch-all, which ends before a '/', the longest first. It
+// reports whether a route matched, the tsr of the value is set when one matched the path
+// with an extra (without the) trailing slash.
+func (n *node) getCatchAllValue(path string, params *Params, unescape bool) (value nodeValue, ok bool) {
+	saved := 0
+	if params != nil {
+		saved = len(*params)
+	}
+	for end := len(path); end > 0; {
+		if end = strings.LastIndexByte(path[:end], '/'); end <= 0 {
+			break
+		}
+		if params != nil {
+			*params = (*params)[:saved]
+			val := path[:end]
+			if unescape {
+				if v, err := url.QueryUnescape(val); err == nil {
+					val = v
+				}
+			}
+			params.add(n.path[2:], val)
+		}
+		v := n.children[0].getValue(path[end:], params, unescape)
+		if v.handlers != nil {
+			if params != nil {
+				v.params = params
+			}
+			return v, true
+		}
+		value.tsr = value.tsr || v.tsr
+	}
+	if params != nil {
+		*params = (*params)[:saved]
+	}
+	return value, false
+}
+
+// getParamValue looks up the path, which starts with the value of a param, below each of
+// the param children whose constraint matches the value, in the order of matchParam,
+// until the rest of the path matches below one of them.
+func getParamValue(children []node, path string, params *Params, unescape bool, value nodeValue) nodeValue {
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	saved := 0
+	if params != nil {
+		saved = len(*params)
+	}
+	tsr := false
+	for i := range children {
+		if children[i].constraint != nil && !children[i].constraint(path[:end]) {
+			continue
+		}
+		if params != nil {
+			*params = (*params)[:saved]
+		}
+		wild := node{wildChild: true, children: children[i : i+1]}
+		v := wild.getValue(path, params, unescape)
+		if v.handlers != nil {
+			if v.params == nil {
+				v.params = value.params
+			}
+			return v
+		}
+		tsr = tsr || v.tsr
+	}
+	if params != nil {
+		*params = (*params)[:saved]
+	}
+	value.tsr = tsr
+	return value
+}
+
+// getMixedValue looks up the rest of the path below n, which has both static and param
+// children, in the static children first, then in the params, see addParamChild.
+func (n *node) getMixedValue(path string, params *Params, unescape bool, value nodeValue) nodeValue {
+	k := len(n.indices)
+	static := node{indices: n.indices, children: n.children[:k], handlers: n.handlers}
+	wild := node{wildChild: true, children: n.children[k:]}
+
+	saved := 0
+	if params != nil {
+		saved = len(*params)
+	}
+	v := static.getValue(path, params, unescape)
+	if v.handlers == nil {
+		if params != nil {
+			*params = (*params)[:saved]
+		}
+		tsr := v.tsr
+		v = wild.getValue(path, params, unescape)
+		v.tsr = v.tsr || (v.handlers == nil && tsr)
+	}
+	if v.params == nil {
+		v.params = value.params
+	}
+	return v
+}
+
 // Makes a case-insensitive lookup of the given path and tries to find a handler.
 // It can optionally also fix trailing slashes.
 // It returns the case-corrected path and a bool indicating whether the lookup
@@ -649,6 +1330,98 @@ func (n *node) findCaseInsensitivePath(path string, fixTrailingSlash bool) ([]by
 	return ciPath, ciPath != nil
 }
 
+// ciPathPool holds the buffers of findCaseInsensitivePathString.
+var ciPathPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// findCaseInsensitivePathString is findCaseInsensitivePath returning the corrected path
+// as a string. The lookup is done in a pooled buffer, large enough for the recursive
+// calls to never grow it, so that a miss doesn't allocate and a hit only allocates the
+// result.
+func (n *node) findCaseInsensitivePathString(path string, fixTrailingSlash bool) (string, bool) {
+	bp := ciPathPool.Get().(*[]byte)
+	if length := len(path) + 1; cap(*bp) < length {
+		*bp = make([]byte, 0, length)
+	}
+	ciPath := n.findCaseInsensitivePathRec(path, (*bp)[:0], [4]byte{}, fixTrailingSlash)
+	var fixed string
+	if ciPath != nil {
+		fixed = string(ciPath)
+	}
+	if cap(*bp) <= maxPooledCleanPathBuf {
+		ciPathPool.Put(bp)
+	}
+	return fixed, ciPath != nil
+}
+
+// updateLowercase sets the lowercase copies of the paths and indices of the nodes of
+// the tree, so that the case-insensitive lookups don't convert them on each request.
+// Only the changed nodes are written, the other ones may be shared with older
+// snapshots of the tree which are read concurrently.
+func (n *node) updateLowercase() {
+	lowerPath := ""
+	if isASCII(n.path) {
+		lowerPath = lowerASCII(n.path)
+	}
+	if n.lowerPath != lowerPath {
+		n.lowerPath = lowerPath
+	}
+	if lowerIndices := lowerASCII(n.indices); n.lowerIndices != lowerIndices {
+		n.lowerIndices = lowerIndices
+	}
+	for i := range n.children {
+		n.children[i].updateLowercase()
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerASCII lowercases the ASCII letters of s, leaving the other bytes alone.
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		b[i] = toLowerASCII(c)
+	}
+	return string(b)
+}
+
+func toLowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// prefixEqualFold reports whether the path starts with the path of the node, ignoring
+// the case and the first byte, which was matched by the index of the parent.
+func (n *node) prefixEqualFold(path string) bool {
+	if n.lowerPath == "" {
+		return strings.EqualFold(path[1:], n.path[1:])
+	}
+	for i := 1; i < len(path); i++ {
+		c := path[i]
+		if c >= utf8.RuneSelf {
+			// e.g. the Kelvin sign folds to "k"
+			return strings.EqualFold(path[1:], n.path[1:])
+		}
+		if toLowerASCII(c) != n.lowerPath[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // Shift bytes in array by n bytes left
 func shiftNRuneBytes(rb [4]byte, n int) [4]byte {
 	switch n {
@@ -670,7 +1443,19 @@ func (n *node) findCaseInsensitivePathRec(path string, ciPath []byte, rb [4]byte
 	npLen := len(n.path)
 
 walk: // Outer loop for walking the tree
-	for len(path) >= npLen && (npLen == 0 || strings.EqualFold(path[1:npLen], n.path[1:])) {
+	for len(path) >= npLen && (npLen == 0 || n.prefixEqualFold(path[:npLen])) {
+		// the static children are tried before the params, see getMixedValue
+		if n.wildChild && len(n.indices) > 0 {
+			k := len(n.indices)
+			static, wild := *n, *n
+			static.wildChild, static.children = false, n.children[:k]
+			wild.indices, wild.lowerIndices, wild.children = "", "", n.children[k:]
+			if out := static.findCaseInsensitivePathRec(path, ciPath, rb, fixTrailingSlash); out != nil {
+				return out
+			}
+			return wild.findCaseInsensitivePathRec(path, ciPath, rb, fixTrailingSlash)
+		}
+
 		// Add common prefix to result
 		oldPath := path
 		path = path[npLen:]
@@ -688,7 +1473,7 @@ walk: // Outer loop for walking the tree
 			if fixTrailingSlash {
 				for i, c := range []byte(n.indices) {
 					if c == '/' {
-						n = n.children[i]
+						n = &n.children[i]
 						if (len(n.path) == 1 && n.handlers != nil) ||
 							(n.nType == catchAll && n.children[0].handlers != nil) {
 							return append(ciPath, '/')
@@ -707,13 +1492,41 @@ walk: // Outer loop for walking the tree
 			// Skip rune bytes already processed
 			rb = shiftNRuneBytes(rb, npLen)
 
-			if rb[0] != 0 {
+			if rb[0] == 0 && path[0] < utf8.RuneSelf {
+				// ASCII byte, matched against the lowercase indices without
+				// decoding the rune
+				lo := toLowerASCII(path[0])
+				up := -1
+				for i, c := range []byte(n.lowerIndices) {
+					if c != lo {
+						continue
+					}
+					if n.indices[i] != lo {
+						up = i
+						continue
+					}
+					// must use a recursive approach since both the
+					// uppercase byte and the lowercase byte might exist
+					// as an index
+					if out := n.children[i].findCaseInsensitivePathRec(
+						path, ciPath, [4]byte{lo}, fixTrailingSlash,
+					); out != nil {
+						return out
+					}
+				}
+				if up >= 0 {
+					// Continue with the uppercase child node
+					n = &n.children[up]
+					npLen = len(n.path)
+					continue walk
+				}
+			} else if rb[0] != 0 {
 				// Old rune not finished
 				idxc := rb[0]
 				for i, c := range []byte(n.indices) {
 					if c == idxc {
 						// continue with child node
-						n = n.children[i]
+						n = &n.children[i]
 						npLen = len(n.path)
 						continue walk
 					}
@@ -768,7 +1581,7 @@ walk: // Outer loop for walking the tree
 						// Uppercase matches
 						if c == idxc {
 							// Continue with child node
-							n = n.children[i]
+							n = &n.children[i]
 							npLen = len(n.path)
 							continue walk
 						}
@@ -784,7 +1597,8 @@ walk: // Outer loop for walking the tree
 			return nil
 		}
 
-		n = n.children[0]
+		children := n.children
+		n = &children[0]
 		switch n.nType {
 		case param:
 			// Find param end (either '/' or path end)
@@ -792,6 +1606,24 @@ walk: // Outer loop for walking the tree
 			for end < len(path) && path[end] != '/' {
 				end++
 			}
+			if n.constraint != nil {
+				// the siblings are tried in turn, see getParamValue
+				if len(children) > 1 {
+					for i := range children {
+						if children[i].constraint != nil && !children[i].constraint(path[:end]) {
+							continue
+						}
+						wild := node{wildChild: true, children: children[i : i+1]}
+						if out := wild.findCaseInsensitivePathRec(path, ciPath, rb, fixTrailingSlash); out != nil {
+							return out
+						}
+					}
+					return nil
+				}
+				if n = matchParam(children, path[:end]); n == nil {
+					return nil
+				}
+			}
 
 			// Add param value to case insensitive path
 			ciPath = append(ciPath, path[:end]...)
@@ -800,7 +1632,7 @@ walk: // Outer loop for walking the tree
 			if end < len(path) {
 				if len(n.children) > 0 {
 					// Continue with child node
-					n = n.children[0]
+					n = &n.children[0]
 					npLen = len(n.path)
 					path = path[end:]
 					continue
@@ -820,7 +1652,7 @@ walk: // Outer loop for walking the tree
 			if fixTrailingSlash && len(n.children) == 1 {
 				// No handle found. Check if a handle for this path + a
 				// trailing slash exists
-				n = n.children[0]
+				n = &n.children[0]
 				if n.path == "/" && n.handlers != nil {
 					return append(ciPath, '/')
 				}
@@ -829,6 +1661,22 @@ walk: // Outer loop for walking the tree
 			return nil
 
 		case catchAll:
+			// a catch-all in the middle of the path is tried with the longest values
+			// first, see getCatchAllValue
+			if len(n.children) > 0 {
+				for end := len(path); end > 0; {
+					if end = strings.LastIndexByte(path[:end], '/'); end <= 0 {
+						break
+					}
+					out := append(ciPath, path[:end]...)
+					if out = n.children[0].findCaseInsensitivePathRec(path[end:], out, [4]byte{}, fixTrailingSlash); out != nil {
+						return out
+					}
+				}
+				if n.handlers == nil {
+					return nil
+				}
+			}
 			return append(ciPath, path...)
 
 		default:
@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"unsafe"
 )
 
 // Used as a workaround since we can't compare functions or their addresses
@@ -67,7 +68,7 @@ func checkRequests(t *testing.T, tree *node, requests testRequests, unescapes ..
 func checkPriorities(t *testing.T, n *node) uint32 {
 	var prio uint32
 	for i := range n.children {
-		prio += checkPriorities(t, n.children[i])
+		prio += checkPriorities(t, &n.children[i])
 	}
 
 	if n.handlers != nil {
@@ -93,6 +94,79 @@ func TestCountParams(t *testing.T) {
 	}
 }
 
+func TestLongestCommonPrefix(t *testing.T) {
+	base := "/repos/:owner/:repo/pulls/:number/comments"
+	for i := 0; i <= len(base); i++ {
+		for _, b := range []string{base[:i], base[:i] + "x", base[:i] + "x" + base[i:]} {
+			expected := 0
+			for expected < len(base) && expected < len(b) && base[expected] == b[expected] {
+				expected++
+			}
+			if got := longestCommonPrefix(base, b); got != expected {
+				t.Errorf("longestCommonPrefix(%q, %q) = %d, expected %d", base, b, got, expected)
+			}
+			if got := longestCommonPrefix(b, base); got != expected {
+				t.Errorf("longestCommonPrefix(%q, %q) = %d, expected %d", b, base, got, expected)
+			}
+		}
+	}
+	if got := longestCommonPrefix("", ""); got != 0 {
+		t.Errorf("longestCommonPrefix of empty strings = %d", got)
+	}
+}
+
+func BenchmarkLongestCommonPrefix(b *testing.B) {
+	a := "/repos/:owner/:repo/pulls/:number/comments"
+	c := "/repos/:owner/:repo/pulls/:number/commits"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		longestCommonPrefix(a, c)
+	}
+}
+
+func TestStandardMethodIndex(t *testing.T) {
+	for i, method := range standardMethods {
+		if got := standardMethodIndex(method); got != i {
+			t.Errorf("standardMethodIndex(%q) = %d, expected %d", method, got, i)
+		}
+	}
+	for _, method := range []string{"", "G", "GE", "GETS", "get", "PUTS", "PATCHES", "POSTS", "PROPFIND", "LINK", "DELETED", "HEAT"} {
+		if got := standardMethodIndex(method); got != -1 {
+			t.Errorf("standardMethodIndex(%q) = %d, expected -1", method, got)
+		}
+	}
+}
+
+func TestMethodTreeIndex(t *testing.T) {
+	var idx methodTreeIndex
+	get, propfind := &node{}, &node{}
+	idx.set("GET", get)
+	idx.set("PROPFIND", propfind)
+
+	if idx.get("GET") != get {
+		t.Error("GET tree is not found")
+	}
+	if idx.get("PROPFIND") != propfind {
+		t.Error("PROPFIND tree is not found")
+	}
+	for _, method := range []string{"POST", "get", "LINK", ""} {
+		if idx.get(method) != nil {
+			t.Errorf("%q tree is found", method)
+		}
+	}
+}
+
+func TestNodeHotFieldsLayout(t *testing.T) {
+	var n node
+	// the fields read on each step of a lookup fit in the first cache line
+	if end := unsafe.Offsetof(n.priority) + unsafe.Sizeof(n.priority); end > 64 {
+		t.Errorf("the hot fields of node end at byte %d, expected at most 64", end)
+	}
+	if unsafe.Offsetof(n.handlers) < unsafe.Offsetof(n.nType) {
+		t.Error("handlers are laid out before nType")
+	}
+}
+
 func TestTreeAddAndGet(t *testing.T) {
 	tree := &node{}
 
@@ -338,15 +412,69 @@ func TestEmptyWildcardName(t *testing.T) {
 
 func TestTreeCatchAllConflict(t *testing.T) {
 	routes := []testRoute{
-		{"/src/*filepath/x", true},
+		{"/src/*filepath/x", false},
+		{"/src/*path/y", true},
 		{"/src2/", false},
 		{"/src2/*filepath/x", true},
 		{"/src3/*filepath", false},
-		{"/src3/*filepath/x", true},
+		{"/src3/*filepath/x", false},
+		{"/src3/*path/y", true},
 	}
 	testRoutes(t, routes)
 }
 
+func TestTreeMidPathCatchAll(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/repos/*path/blame/:line",
+		"/repos/*path/raw",
+		"/repos/*path",
+		"/proxy/*target/status/",
+		"/files/:dir/*path/meta",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/repos/gin/blame/12", false, "/repos/*path/blame/:line", Params{Param{"path", "/gin"}, Param{"line", "12"}}},
+		{"/repos/a/b/blame/12", false, "/repos/*path/blame/:line", Params{Param{"path", "/a/b"}, Param{"line", "12"}}},
+		{"/repos/a/blame/b/blame/1", false, "/repos/*path/blame/:line", Params{Param{"path", "/a/blame/b"}, Param{"line", "1"}}},
+		{"/repos/a/b/raw", false, "/repos/*path/raw", Params{Param{"path", "/a/b"}}},
+		{"/repos/a/b/blame", false, "/repos/*path", Params{Param{"path", "/a/b/blame"}}},
+		{"/repos/raw", false, "/repos/*path", Params{Param{"path", "/raw"}}},
+		{"/proxy/a/b/status/", false, "/proxy/*target/status/", Params{Param{"target", "/a/b"}}},
+		{"/proxy/a/b/status", true, "", nil},
+		{"/proxy/a/b", true, "", nil},
+		{"/files/docs/a/b/meta", false, "/files/:dir/*path/meta", Params{Param{"dir", "docs"}, Param{"path", "/a/b"}}},
+		{"/files/docs/meta", true, "", Params{Param{"dir", "docs"}}},
+	})
+
+	checkPriorities(t, tree)
+
+	value := tree.getValue("/proxy/a/b/status", nil, false)
+	if !value.tsr {
+		t.Errorf("expected a trailing slash redirect for '/proxy/a/b/status'")
+	}
+
+	out, found := tree.findCaseInsensitivePath("/REPOS/Gin/BLAME/12", true)
+	if !found || string(out) != "/repos/Gin/blame/12" {
+		t.Errorf("wrong case-insensitive path, got '%s'", out)
+	}
+
+	for _, route := range routes[:2] {
+		if !tree.removeRoute(route, true) {
+			t.Errorf("route '%s' not removed", route)
+		}
+	}
+	checkRequests(t, tree, testRequests{
+		{"/repos/a/b/raw", false, "/repos/*path", Params{Param{"path", "/a/b/raw"}}},
+		{"/proxy/a/b/status/", false, "/proxy/*target/status/", Params{Param{"target", "/a/b"}}},
+	})
+	checkPriorities(t, tree)
+}
+
 func TestTreeCatchAllConflictRoot(t *testing.T) {
 	routes := []testRoute{
 		{"/", false},
@@ -491,6 +619,56 @@ func TestTreeRootTrailingSlashRedirect(t *testing.T) {
 	}
 }
 
+func checkLowercase(t *testing.T, n *node) {
+	lowerPath := ""
+	if isASCII(n.path) {
+		lowerPath = strings.ToLower(n.path)
+	}
+	if n.lowerPath != lowerPath {
+		t.Errorf("lowercase path of %q is %q, expected %q", n.path, n.lowerPath, lowerPath)
+	}
+	lowerIndices := []byte(n.indices)
+	for i, c := range lowerIndices {
+		if c < 0x80 {
+			lowerIndices[i] = strings.ToLower(string(c))[0]
+		}
+	}
+	if n.lowerIndices != string(lowerIndices) {
+		t.Errorf("lowercase indices of %q are %q, expected %q", n.path, n.lowerIndices, lowerIndices)
+	}
+	for i := range n.children {
+		checkLowercase(t, &n.children[i])
+	}
+}
+
+func TestTreeLowercaseCopies(t *testing.T) {
+	tree := &node{}
+	for _, route := range githubAPI {
+		if route.method == "GET" {
+			tree.addRoute(route.path, fakeHandler(route.path))
+			checkLowercase(t, tree)
+		}
+	}
+	for _, route := range [...]string{"/Users/:id", "/USERS", "/Π", "/u/Äpfêl/", "/U/x"} {
+		tree.addRoute(route, fakeHandler(route))
+		checkLowercase(t, tree)
+	}
+
+	for in, expected := range map[string]string{
+		"/USERS/42":    "/users/42", // the lowercase route is preferred
+		"/uSERS":       "/users",
+		"/u/X":         "/U/x",
+		"/U/äPFÊL":     "/u/Äpfêl/",
+		"/π":           "/Π",
+		"/USER/REPOS/": "/user/repos",
+	} {
+		out, found := tree.findCaseInsensitivePath(in, true)
+		if !found || string(out) != expected {
+			t.Errorf("wrong result for %q: got %q (found %v), expected %q", in, out, found, expected)
+		}
+	}
+}
+
 func TestTreeFindCaseInsensitivePath(t *testing.T) {
 	tree := &node{}
 
@@ -649,6 +827,46 @@ func TestTreeFindCaseInsensitivePath(t *testing.T) {
 			}
 		}
 	}
+	// The string variant matches
+	for _, test := range tests {
+		for _, fixTrailingSlash := range []bool{true, false} {
+			out, found := tree.findCaseInsensitivePath(test.in, fixTrailingSlash)
+			outString, foundString := tree.findCaseInsensitivePathString(test.in, fixTrailingSlash)
+			if foundString != found || outString != string(out) {
+				t.Errorf("Wrong string result for '%s': got %s, %t; want %s, %t",
+					test.in, outString, foundString, string(out), found)
+			}
+		}
+	}
+}
+
+func TestTreeFindCaseInsensitivePathAllocs(t *testing.T) {
+	tree := &node{}
+	for _, route := range githubAPI {
+		if route.method == "GET" {
+			tree.addRoute(route.path, fakeHandler(route.path))
+		}
+	}
+	tree.findCaseInsensitivePathString("/", true) // fills the pool
+
+	long := "/REPOS/" + strings.Repeat("o", 300) + "/GIN/ISSUES"
+	for _, test := range []struct {
+		path   string
+		allocs float64
+	}{
+		{"/REPOS/GIN-GONIC/GIN/ISSUES", 1},
+		{"/USER/REPOS/", 1},
+		{"/REPOS/GIN-GONIC/GIN/MISSING", 0},
+		{"/NOWHERE/AT/ALL", 0},
+		{long, 1},
+	} {
+		allocs := testing.AllocsPerRun(100, func() {
+			tree.findCaseInsensitivePathString(test.path, true)
+		})
+		if allocs != test.allocs {
+			t.Errorf("lookup of %s allocates %v times, expected %v", test.path, allocs, test.allocs)
+		}
+	}
 }
 
 func TestTreeInvalidNodeType(t *testing.T) {
@@ -716,3 +934,430 @@ func TestTreeWildcardConflictEx(t *testing.T) {
 		}
 	}
 }
+
+func TestTreeFrozenPriorities(t *testing.T) {
+	routes := [...]string{
+		"/a",
+		"/b/1",
+		"/b/2",
+		"/c/:id",
+		"/c/:id/x",
+		"/c/:id/y",
+		"/b/3",
+	}
+	tree := &node{}
+	for _, route := range routes {
+		tree.insertRoute(route, fakeHandler(route), false)
+	}
+
+	// the children keep the order they were added in
+	if tree.path != "/" || tree.indices != "abc" {
+		t.Errorf("unexpected layout before sorting: path %q, indices %q", tree.path, tree.indices)
+	}
+	checkPriorities(t, tree)
+
+	before := *tree
+	tree.sortChildren()
+	if tree.indices != "bca" {
+		t.Errorf("indices are %q after sorting, should be %q", tree.indices, "bca")
+	}
+	if before.indices != "abc" || before.children[0].path != "a" {
+		t.Error("sorting changed the former tree")
+	}
+	for i := range tree.children {
+		if tree.children[i].path[0] != tree.indices[i] {
+			t.Errorf("child %q doesn't match the index %q", tree.children[i].path, tree.indices[i])
+		}
+		if i > 0 && tree.children[i-1].priority < tree.children[i].priority {
+			t.Errorf("child %q comes before a child of higher priority", tree.children[i-1].path)
+		}
+	}
+	checkPriorities(t, tree)
+
+	checkRequests(t, tree, testRequests{
+		{"/a", false, "/a", nil},
+		{"/b/1", false, "/b/1", nil},
+		{"/b/3", false, "/b/3", nil},
+		{"/c/7/y", false, "/c/:id/y", Params{Param{"id", "7"}}},
+		{"/d", true, "", nil},
+	})
+}
+
+func TestTreeParamConstraints(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/users/:id([0-9]+)",
+		"/users/:slug",
+		"/users/:uuid([0-9a-f]{8}-[0-9a-f]{4})/posts",
+		"/users/:id([0-9]+)/posts/:post([0-9]+)",
+		"/files/:name([a-z]+\\.(?:png|jpg))",
+		"/files/:other([a-z]+)",
+		"/v:major([0-9])/status",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/users/42", false, "/users/:id([0-9]+)", Params{Param{"id", "42"}}},
+		{"/users/gopher", false, "/users/:slug", Params{Param{"slug", "gopher"}}},
+		{"/users/4a", false, "/users/:slug", Params{Param{"slug", "4a"}}},
+		{"/users/42/posts/7", false, "/users/:id([0-9]+)/posts/:post([0-9]+)", Params{Param{"id", "42"}, Param{"post", "7"}}},
+		{"/users/42/posts/x", true, "", Params{Param{"id", "42"}}},
+		{"/users/0123abcd-ef01/posts", false, "/users/:uuid([0-9a-f]{8}-[0-9a-f]{4})/posts", Params{Param{"uuid", "0123abcd-ef01"}}},
+		{"/files/logo.png", false, "/files/:name([a-z]+\\.(?:png|jpg))", Params{Param{"name", "logo.png"}}},
+		{"/files/logo", false, "/files/:other([a-z]+)", Params{Param{"other", "logo"}}},
+		{"/files/logo.gif", true, "", nil},
+		{"/files/LOGO", true, "", nil},
+		{"/v1/status", false, "/v:major([0-9])/status", Params{Param{"major", "1"}}},
+		{"/v12/status", true, "", nil},
+	})
+
+	checkPriorities(t, tree)
+
+	// the params with a constraint keep their order, before the one without a constraint
+	tree.sortChildren()
+	checkRequests(t, tree, testRequests{
+		{"/users/42", false, "/users/:id([0-9]+)", Params{Param{"id", "42"}}},
+		{"/users/gopher", false, "/users/:slug", Params{Param{"slug", "gopher"}}},
+	})
+
+	out, found := tree.findCaseInsensitivePathString("/USERS/42/POSTS/7", true)
+	if !found || out != "/users/42/posts/7" {
+		t.Errorf("wrong case-insensitive lookup: got %q, %v", out, found)
+	}
+	out, found = tree.findCaseInsensitivePathString("/FILES/logo.gif", true)
+	if found {
+		t.Errorf("case-insensitive lookup of a value matching no constraint found %q", out)
+	}
+}
+
+func TestTreeParamConstraintConflicts(t *testing.T) {
+	routes := []testRoute{
+		{"/users/:id([0-9]+)", false},
+		{"/users/:slug", false},
+		{"/users/:name", true},
+		{"/users/:login([a-z]+)", false},
+		{"/users/new", true},
+		{"/users/:id([0-9]+)/x", false},
+		{"/users/:idx([0-9]+", true},
+		{"/users/:idx([0-9]+)x", true},
+		{"/users/:idx([0-9]+/x)", true},
+		{"/users/:idx([0-9]+:x)/y", false},
+		{"/users/:([0-9]+)/z", true},
+		{"/users/:bad([0-9)", true},
+		{"/files/*path", false},
+		{"/files/:name([a-z]+)", true},
+	}
+	testRoutes(t, routes)
+}
+
+func TestTreeTypedParams(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/orders/latest",
+		"/orders/:id|int",
+		"/orders/:ref|uuid/items",
+		"/orders/archive/",
+		"/events/:day|date",
+		"/events/today",
+		"/events/:day|date/:slot([0-9]+)",
+		"/lookup/:key|int/latest",
+		"/lookup/:key|int/:sub|uuid",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	uuid := "0b6f1c7e-3e1a-4c8e-9d1b-2f8a7c9e4d10"
+	requests := testRequests{
+		{"/orders/latest", false, "/orders/latest", nil},
+		{"/orders/42", false, "/orders/:id|int", Params{Param{"id", "42"}}},
+		{"/orders/-7", false, "/orders/:id|int", Params{Param{"id", "-7"}}},
+		{"/orders/lat", true, "", nil},
+		{"/orders/l4", true, "", nil},
+		{"/orders/" + uuid + "/items", false, "/orders/:ref|uuid/items", Params{Param{"ref", uuid}}},
+		{"/orders/archive/", false, "/orders/archive/", nil},
+		{"/events/today", false, "/events/today", nil},
+		{"/events/2021-02-28", false, "/events/:day|date", Params{Param{"day", "2021-02-28"}}},
+		{"/events/2021-02-29", true, "", nil},
+		{"/events/2020-02-29/9", false, "/events/:day|date/:slot([0-9]+)", Params{Param{"day", "2020-02-29"}, Param{"slot", "9"}}},
+		{"/lookup/1/latest", false, "/lookup/:key|int/latest", Params{Param{"key", "1"}}},
+		{"/lookup/1/" + uuid, false, "/lookup/:key|int/:sub|uuid", Params{Param{"key", "1"}, Param{"sub", uuid}}},
+	}
+	checkRequests(t, tree, requests)
+	checkPriorities(t, tree)
+
+	// the static children are sorted, the params keep their order after them
+	tree.sortChildren()
+	checkRequests(t, tree, requests)
+
+	tsrRoutes := [...]string{
+		"/orders/archive",
+		"/orders/latest/",
+		"/orders/42/",
+	}
+	for _, route := range tsrRoutes {
+		value := tree.getValue(route, nil, false)
+		if value.handlers != nil {
+			t.Fatalf("non-nil handler for TSR route '%s", route)
+		} else if !value.tsr {
+			t.Errorf("expected TSR recommendation for route '%s'", route)
+		}
+	}
+
+	out, found := tree.findCaseInsensitivePathString("/ORDERS/LATEST", true)
+	if !found || out != "/orders/latest" {
+		t.Errorf("wrong case-insensitive lookup: got %q, %v", out, found)
+	}
+	out, found = tree.findCaseInsensitivePathString("/ORDERS/42", true)
+	if !found || out != "/orders/42" {
+		t.Errorf("wrong case-insensitive lookup: got %q, %v", out, found)
+	}
+	out, found = tree.findCaseInsensitivePathString("/ORDERS/LATE", true)
+	if found {
+		t.Errorf("case-insensitive lookup of a value matching no type found %q", out)
+	}
+}
+
+func TestTreeTypedParamConflicts(t *testing.T) {
+	routes := []testRoute{
+		{"/orders/latest", false},
+		{"/orders/:id|int", false},
+		{"/orders/:name", true},
+		{"/orders/*path", true},
+		{"/orders/:key|nope", true},
+		{"/orders/:|int", true},
+		{"/orders/new", false},
+		{"/users/:id", false},
+		{"/users/new", true},
+		{"/items/:id|int", false},
+		{"/items/:slug", false},
+		{"/items/new", true},
+	}
+	testRoutes(t, routes)
+}
+
+// dumpTree writes the structure of the tree, without the fullPath of the inner nodes,
+// which depends on the order the routes were added in.
+func dumpTree(n *node, depth int, out *strings.Builder) {
+	fmt.Fprintf(out, "%s%q %q wild=%v type=%d prio=%d handlers=%v",
+		strings.Repeat("  ", depth), n.path, n.indices, n.wildChild, n.nType, n.priority, n.handlers != nil)
+	if n.handlers != nil {
+		fmt.Fprintf(out, " %s", n.fullPath)
+	}
+	out.WriteByte('\n')
+	for i := range n.children {
+		dumpTree(&n.children[i], depth+1, out)
+	}
+}
+
+func TestTreeRemoveRoute(t *testing.T) {
+	routes := [...]string{
+		"/",
+		"/cmd/:tool/:sub",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/search/",
+		"/search/:query",
+		"/user_:name",
+		"/user_:name/about",
+		"/files/:dir/*filepath",
+		"/doc/",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/info/:user/public",
+		"/info/:user/project/:project",
+		"/orders/latest",
+		"/orders/:id|int",
+		"/orders/:ref|uuid",
+	}
+	removed := map[string]bool{
+		"/cmd/:tool/":        true,
+		"/src/*filepath":     true,
+		"/search/":           true,
+		"/doc/go_faq.html":   true,
+		"/info/:user/public": true,
+		"/orders/latest":     true,
+		"/orders/:id|int":    true,
+	}
+
+	tree := &node{}
+	for _, route := range routes {
+		tree.insertRoute(route, fakeHandler(route), false)
+	}
+	before := *tree
+	for _, route := range routes {
+		if removed[route] && !tree.removeRoute(route, false) {
+			t.Errorf("route '%s' not removed", route)
+		}
+	}
+	for route := range removed {
+		if tree.removeRoute(route, false) {
+			t.Errorf("route '%s' removed twice", route)
+		}
+	}
+	for _, route := range [...]string{"/cmd/:tool", "/doc", "/orders/:id", "/info/:user/project/:id", "/missing"} {
+		if tree.removeRoute(route, false) {
+			t.Errorf("unregistered route '%s' removed", route)
+		}
+	}
+
+	// the tree is the one of the remaining routes
+	want := &node{}
+	for _, route := range routes {
+		if !removed[route] {
+			want.insertRoute(route, fakeHandler(route), false)
+		}
+	}
+	var got, expected strings.Builder
+	dumpTree(tree, 0, &got)
+	dumpTree(want, 0, &expected)
+	if got.String() != expected.String() {
+		t.Errorf("tree after removal:\n%s\nwant:\n%s", got.String(), expected.String())
+	}
+	checkPriorities(t, tree)
+
+	checkRequests(t, tree, testRequests{
+		{"/", false, "/", nil},
+		{"/cmd/test/", true, "", Params{Param{"tool", "test"}}},
+		{"/cmd/test/3", false, "/cmd/:tool/:sub", Params{Param{"tool", "test"}, Param{"sub", "3"}}},
+		{"/src/some/file.png", true, "", nil},
+		{"/search/someth!ng+in+ünìcodé", false, "/search/:query", Params{Param{"query", "someth!ng+in+ünìcodé"}}},
+		{"/doc/go_faq.html", true, "", nil},
+		{"/doc/go1.html", false, "/doc/go1.html", nil},
+		{"/info/gordon/public", true, "", Params{Param{"user", "gordon"}}},
+		{"/orders/latest", true, "", nil},
+		{"/orders/42", true, "", nil},
+		{"/orders/0b6f1c7e-3e1a-4c8e-9d1b-2f8a7c9e4d10", false, "/orders/:ref|uuid", Params{Param{"ref", "0b6f1c7e-3e1a-4c8e-9d1b-2f8a7c9e4d10"}}},
+	})
+
+	// the former tree is unchanged
+	checkRequests(t, &before, testRequests{
+		{"/src/some/file.png", false, "/src/*filepath", Params{Param{"filepath", "/some/file.png"}}},
+		{"/orders/latest", false, "/orders/latest", nil},
+		{"/orders/42", false, "/orders/:id|int", Params{Param{"id", "42"}}},
+	})
+
+	// removing all the routes leaves an empty tree, to which routes can be added again
+	for _, route := range routes {
+		if !removed[route] && !tree.removeRoute(route, false) {
+			t.Errorf("route '%s' not removed", route)
+		}
+	}
+	if tree.path != "" || len(tree.children) != 0 || tree.priority != 0 {
+		t.Errorf("tree not empty after removing all the routes: %+v", tree)
+	}
+	tree.addRoute("/cmd/:tool/", fakeHandler("/cmd/:tool/"))
+	checkRequests(t, tree, testRequests{
+		{"/cmd/test/", false, "/cmd/:tool/", Params{Param{"tool", "test"}}},
+	})
+}
+
+func TestTreeRemoveRoutePriorities(t *testing.T) {
+	tree := &node{}
+	routes := [...]string{"/a", "/b", "/b/1", "/b/2", "/c", "/c/1"}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+	if tree.indices != "bca" {
+		t.Fatalf("wrong indices before removal: %q", tree.indices)
+	}
+	tree.removeRoute("/b/1", true)
+	tree.removeRoute("/b/2", true)
+	if tree.indices != "cba" {
+		t.Errorf("wrong indices after removal: %q", tree.indices)
+	}
+	checkPriorities(t, tree)
+}
+
+func TestTreeTryAddRoute(t *testing.T) {
+	tree := &node{}
+	leaf, err := tree.tryAddRoute("/", fakeHandler("/"), true)
+	if err != nil || leaf != tree {
+		t.Fatalf("wrong leaf of the root route: %p, %v", leaf, err)
+	}
+	for _, route := range [...]string{"/cmd/:tool/:sub", "/src/*filepath", "/search/"} {
+		if leaf, err = tree.tryAddRoute(route, fakeHandler(route), true); err != nil || leaf.fullPath != route {
+			t.Fatalf("route '%s' not added: %v", route, err)
+		}
+	}
+
+	var before strings.Builder
+	dumpTree(tree, 0, &before)
+	tests := []struct {
+		route string
+		err   string
+	}{
+		{"/search/", "handlers are already registered for path '/search/'"},
+		{"/cmd/:tool/:sub/x/:tool:x", "only one wildcard per path segment is allowed, has: ':tool:x' in path '/cmd/:tool/:sub/x/:tool:x'"},
+		{"/cmd/:cmd/x", "':cmd' in new path '/cmd/:cmd/x' conflicts with existing wildcard ':tool' in existing prefix '/cmd/:tool'"},
+		{"/src/x", "'/x' in new path '/src/x' conflicts with existing wildcard '/*filepath' in existing prefix '/src/*filepath'"},
+		{"/search/:query/:n|nope", "unknown type 'nope' of the wildcard ':n|nope' in path '/search/:query/:n|nope'"},
+	}
+	for _, test := range tests {
+		leaf, err := tree.tryAddRoute(test.route, fakeHandler(test.route), true)
+		if leaf != nil || err == nil || err.Error() != test.err {
+			t.Errorf("wrong error for route '%s': %v, want %s", test.route, err, test.err)
+		}
+	}
+
+	// the tree is unchanged by the routes not added
+	var after strings.Builder
+	dumpTree(tree, 0, &after)
+	if after.String() != before.String() {
+		t.Errorf("tree changed by failed routes:\n%s\nwas:\n%s", after.String(), before.String())
+	}
+	checkPriorities(t, tree)
+}
+
+func TestFindWildcardConstraints(t *testing.T) {
+	tests := []struct {
+		path     string
+		wildcard string
+		i        int
+		valid    bool
+	}{
+		{"/users/:id([0-9]+)/posts", ":id([0-9]+)", 7, true},
+		{"/users/:id((a|b)(c))", ":id((a|b)(c))", 7, true},
+		{"/users/:id(\\))", ":id(\\))", 7, true},
+		{"/users/:id([a-z]*:x)", ":id([a-z]*:x)", 7, true},
+		{"/users/:id([0-9]+", ":id([0-9]+", 7, false},
+		{"/users/:id([0-9]+)x", ":id([0-9]+)x", 7, false},
+		{"/users/:id(a/b)", ":id(a", 7, false},
+		{"/files/*path(x)", "*path(x)", 7, true},
+	}
+	for _, test := range tests {
+		wildcard, i, valid := findWildcard(test.path)
+		if wildcard != test.wildcard || i != test.i || valid != test.valid {
+			t.Errorf("findWildcard(%q) = %q, %d, %v, want %q, %d, %v",
+				test.path, wildcard, i, valid, test.wildcard, test.i, test.valid)
+		}
+	}
+}
+
+func TestOptionalParamPaths(t *testing.T) {
+	tests := []struct {
+		path  string
+		paths []string
+	}{
+		{"/articles/:id", []string{"/articles/:id"}},
+		{"/articles/:id/:rev?", []string{"/articles/:id", "/articles/:id/:rev"}},
+		{"/:lang?", []string{"/", "/:lang"}},
+		{"/v/:rev([0-9]+)?", []string{"/v", "/v/:rev([0-9]+)"}},
+		{"/v/:rev([0-9]+?)", []string{"/v/:rev([0-9]+?)"}},
+	}
+	for _, test := range tests {
+		if paths := optionalParamPaths(test.path); !reflect.DeepEqual(paths, test.paths) {
+			t.Errorf("optionalParamPaths(%q) = %q, want %q", test.path, paths, test.paths)
+		}
+	}
+
+	for _, path := range []string{"/articles?", "/articles/v:rev?", "/files/*path?", "/articles/:?"} {
+		if recv := catchPanic(func() { optionalParamPaths(path) }); recv == nil {
+			t.Errorf("no panic for the invalid optional param of '%s'", path)
+		}
+	}
+}
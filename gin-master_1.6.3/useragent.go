@@ -0,0 +1,266 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserAgentKey is the key the classification of the client is set under in the Context,
+// see Context.UserAgentInfo.
+const UserAgentKey = "userAgent"
+
+// UserAgentClass is the kind of client making a request.
+type UserAgentClass string
+
+// The classes of clients.
+const (
+	UserAgentBrowser     UserAgentClass = "browser"
+	UserAgentBot         UserAgentClass = "bot"
+	UserAgentVerifiedBot UserAgentClass = "verified-bot"
+	// UserAgentSpoofedBot claims to be a bot whose reverse DNS doesn't match.
+	UserAgentSpoofedBot UserAgentClass = "spoofed-bot"
+	UserAgentTool       UserAgentClass = "tool"
+	UserAgentUnknown    UserAgentClass = "unknown"
+)
+
+// UserAgentAction is what the UserAgentFilter does with the requests matching a rule.
+type UserAgentAction int
+
+const (
+	// UserAgentAllow lets the requests through.
+	UserAgentAllow UserAgentAction = iota
+	// UserAgentDeprioritize lets the requests through, flagged so that the following
+	// handlers can serve them last or with a lower rate limit.
+	UserAgentDeprioritize
+	// UserAgentBlock rejects the requests.
+	UserAgentBlock
+)
+
+// UserAgentRule classifies the clients whose User-Agent header matches Pattern.
+type UserAgentRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Class   UserAgentClass
+	Action  UserAgentAction
+
+	// VerifyDomains are the domains the reverse DNS of the clients must belong to, e.g.
+	// "googlebot.com". When set, the clients are classified as UserAgentVerifiedBot once
+	// the forward DNS confirms the address, and as UserAgentSpoofedBot otherwise.
+	VerifyDomains []string
+}
+
+// DefaultUserAgentRules are the rules of the UserAgentFilter when none is given: the
+// search engines publishing their crawler domains are verified, other bots and
+// command line tools are recognized by their names.
+var DefaultUserAgentRules = []UserAgentRule{
+	{Name: "googlebot", Pattern: regexp.MustCompile(`(?i)googlebot|google-inspectiontool`), Class: UserAgentBot, VerifyDomains: []string{"googlebot.com", "google.com"}},
+	{Name: "bingbot", Pattern: regexp.MustCompile(`(?i)bingbot`), Class: UserAgentBot, VerifyDomains: []string{"search.msn.com"}},
+	{Name: "applebot", Pattern: regexp.MustCompile(`(?i)applebot`), Class: UserAgentBot, VerifyDomains: []string{"applebot.apple.com"}},
+	{Name: "bot", Pattern: regexp.MustCompile(`(?i)bot\b|crawl|spider|slurp|facebookexternalhit|headless`), Class: UserAgentBot},
+	{Name: "tool", Pattern: regexp.MustCompile(`(?i)^(curl|wget|python-|go-http-client|java/|okhttp|libwww-perl|httpie|postmanruntime)`), Class: UserAgentTool},
+	{Name: "browser", Pattern: regexp.MustCompile(`^Mozilla/|^Opera/`), Class: UserAgentBrowser},
+}
+
+// UserAgentInfo is the classification of the client of a request.
+type UserAgentInfo struct {
+	Class  UserAgentClass
+	Action UserAgentAction
+	// Rule is the name of the rule matched, empty when none did.
+	Rule string
+}
+
+// Deprioritized reports whether the request should be served last or with a lower limit.
+func (info *UserAgentInfo) Deprioritized() bool {
+	return info != nil && info.Action == UserAgentDeprioritize
+}
+
+// DNSResolver resolves the addresses of the clients claiming to be verified bots,
+// *net.Resolver implements it.
+type DNSResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// UserAgentFilterConfig defines the config for UserAgentFilter middleware.
+type UserAgentFilterConfig struct {
+	// Rules classify the clients, the first one matching applies.
+	// Optional. Default value is gin.DefaultUserAgentRules.
+	Rules []UserAgentRule
+
+	// Unmatched is the action for the clients matching no rule, including the ones
+	// without User-Agent.
+	// Optional. Default value is UserAgentAllow.
+	Unmatched UserAgentAction
+
+	// Spoofed is the action for the clients classified as UserAgentSpoofedBot.
+	// Optional. Default value is UserAgentAllow.
+	Spoofed UserAgentAction
+
+	// Resolver verifies the bots.
+	// Optional. Default value is net.DefaultResolver.
+	Resolver DNSResolver
+
+	// Blocked handles the requests blocked.
+	// Optional. Default aborts with 403.
+	Blocked HandlerFunc
+}
+
+// UserAgentFilter returns a middleware classifying the clients by their User-Agent
+// header, and blocking or flagging them according to the rules. The classification
+// is set in the Context under UserAgentKey, to be logged with LogFieldUserAgentClass
+// or limited with RateLimitDeprioritized:
+//     rules := append([]gin.UserAgentRule{{
+//         Name:    "scrapers",
+//         Pattern: regexp.MustCompile(`(?i)scrapy|ahrefs|semrush`),
+//         Class:   gin.UserAgentBot,
+//         Action:  gin.UserAgentBlock,
+//     }}, gin.DefaultUserAgentRules...)
+//     router.Use(gin.UserAgentFilter(gin.UserAgentFilterConfig{Rules: rules, Spoofed: gin.UserAgentBlock}))
+// The reverse DNS verifications are cached for an hour by client address.
+func UserAgentFilter(conf UserAgentFilterConfig) HandlerFunc {
+	if conf.Rules == nil {
+		conf.Rules = DefaultUserAgentRules
+	}
+	resolver := conf.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	verifier := &botVerifier{resolver: resolver, cache: make(map[string]botVerification), now: time.Now}
+
+	return func(c *Context) {
+		info := &UserAgentInfo{Class: UserAgentUnknown, Action: conf.Unmatched}
+		if ua := c.requestHeader("User-Agent"); ua != "" {
+			for i := range conf.Rules {
+				rule := &conf.Rules[i]
+				if !rule.Pattern.MatchString(ua) {
+					continue
+				}
+				info.Class, info.Action, info.Rule = rule.Class, rule.Action, rule.Name
+				if len(rule.VerifyDomains) > 0 {
+					if verifier.verify(c.Request.Context(), c.ClientIP(), rule.VerifyDomains) {
+						info.Class = UserAgentVerifiedBot
+					} else {
+						info.Class, info.Action = UserAgentSpoofedBot, conf.Spoofed
+					}
+				}
+				break
+			}
+		}
+		c.Set(UserAgentKey, info)
+
+		if info.Action == UserAgentBlock {
+			if conf.Blocked != nil {
+				conf.Blocked(c)
+				c.Abort()
+				return
+			}
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+	}
+}
+
+// UserAgentInfo returns the classification set by the UserAgentFilter middleware, or nil.
+func (c *Context) UserAgentInfo() *UserAgentInfo {
+	v, _ := c.Get(UserAgentKey)
+	info, _ := v.(*UserAgentInfo)
+	return info
+}
+
+// LogFieldUserAgentClass is the class of the client set by the UserAgentFilter middleware.
+var LogFieldUserAgentClass = LogField{"user_agent_class", func(p *LogFormatterParams) interface{} {
+	if info, ok := p.Keys[UserAgentKey].(*UserAgentInfo); ok {
+		return string(info.Class)
+	}
+	return nil
+}}
+
+// RateLimitDeprioritized counts by client IP the requests deprioritized by the
+// UserAgentFilter middleware, the others are not limited, so that the bots can be
+// given a stricter limit than the browsers:
+//     router.Use(gin.RateLimit(gin.RateLimitConfig{Limit: 10, Period: time.Minute, Key: gin.RateLimitDeprioritized()}))
+func RateLimitDeprioritized() RateLimitKeyFunc {
+	return func(c *Context) string {
+		if !c.UserAgentInfo().Deprioritized() {
+			return ""
+		}
+		return c.ClientIP()
+	}
+}
+
+// maxBotVerifications bounds the size of the cache of the reverse DNS verifications.
+const maxBotVerifications = 10000
+
+type botVerification struct {
+	domains  string
+	verified bool
+	expires  time.Time
+}
+
+type botVerifier struct {
+	resolver DNSResolver
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]botVerification
+}
+
+// verify reports whether the reverse DNS of ip belongs to one of the domains, and the
+// forward DNS of the name gives ip back.
+func (v *botVerifier) verify(ctx context.Context, ip string, domains []string) bool {
+	key := strings.Join(domains, ",")
+	now := v.now()
+	v.mu.Lock()
+	cached, ok := v.cache[ip]
+	v.mu.Unlock()
+	if ok && cached.domains == key && now.Before(cached.expires) {
+		return cached.verified
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	verified := false
+	names, _ := v.resolver.LookupAddr(ctx, ip)
+	for _, name := range names {
+		name = strings.TrimSuffix(strings.ToLower(name), ".")
+		if !inDomains(name, domains) {
+			continue
+		}
+		addrs, _ := v.resolver.LookupHost(ctx, name)
+		for _, addr := range addrs {
+			verified = verified || net.ParseIP(addr).Equal(net.ParseIP(ip))
+		}
+		if verified {
+			break
+		}
+	}
+	if ctx.Err() != nil && !verified {
+		// not cached, the DNS may answer next time
+		return false
+	}
+
+	v.mu.Lock()
+	if len(v.cache) >= maxBotVerifications {
+		v.cache = make(map[string]botVerification)
+	}
+	v.cache[ip] = botVerification{domains: key, verified: verified, expires: now.Add(time.Hour)}
+	v.mu.Unlock()
+	return verified
+}
+
+func inDomains(name string, domains []string) bool {
+	for _, domain := range domains {
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResolver struct {
+	names   map[string][]string
+	addrs   map[string][]string
+	lookups int
+}
+
+func (r *fakeResolver) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	r.lookups++
+	if names, ok := r.names[addr]; ok {
+		return names, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addrs, ok := r.addrs[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestUserAgentFilterClassification(t *testing.T) {
+	resolver := &fakeResolver{
+		names: map[string][]string{
+			"66.249.66.1": {"crawl-66-249-66-1.googlebot.com."},
+			"192.0.2.9":   {"evil.googlebot.com.example."},
+		},
+		addrs: map[string][]string{"crawl-66-249-66-1.googlebot.com": {"66.249.66.1"}},
+	}
+	router := New()
+	router.Use(UserAgentFilter(UserAgentFilterConfig{Resolver: resolver}))
+	router.GET("/", func(c *Context) {
+		c.String(http.StatusOK, string(c.UserAgentInfo().Class))
+	})
+
+	const googlebot = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	for _, tt := range []struct {
+		userAgent, ip string
+		class         UserAgentClass
+	}{
+		{"Mozilla/5.0 (X11; Linux x86_64) Gecko/20100101 Firefox/89.0", "192.0.2.1", UserAgentBrowser},
+		{"curl/7.68.0", "192.0.2.1", UserAgentTool},
+		{"python-requests/2.25.1", "192.0.2.1", UserAgentTool},
+		{"Mozilla/5.0 (compatible; YandexBot/3.0)", "192.0.2.1", UserAgentBot},
+		{"", "192.0.2.1", UserAgentUnknown},
+		{googlebot, "66.249.66.1", UserAgentVerifiedBot},
+		{googlebot, "192.0.2.9", UserAgentSpoofedBot},
+		{googlebot, "192.0.2.1", UserAgentSpoofedBot},
+	} {
+		w := performRequest(router, "GET", "/", header{"User-Agent", tt.userAgent}, header{"X-Forwarded-For", tt.ip})
+		assert.Equal(t, string(tt.class), w.Body.String(), tt.userAgent)
+	}
+
+	// the verifications are cached
+	lookups := resolver.lookups
+	performRequest(router, "GET", "/", header{"User-Agent", googlebot}, header{"X-Forwarded-For", "66.249.66.1"})
+	assert.Equal(t, lookups, resolver.lookups)
+}
+
+func TestUserAgentFilterActions(t *testing.T) {
+	router := New()
+	router.Use(UserAgentFilter(UserAgentFilterConfig{
+		Rules: append([]UserAgentRule{
+			{Name: "scrapers", Pattern: regexp.MustCompile(`(?i)scrapy`), Class: UserAgentBot, Action: UserAgentBlock},
+			{Name: "crawlers", Pattern: regexp.MustCompile(`(?i)crawler`), Class: UserAgentBot, Action: UserAgentDeprioritize},
+		}, DefaultUserAgentRules...),
+		Unmatched: UserAgentBlock,
+		Spoofed:   UserAgentBlock,
+		Resolver:  &fakeResolver{},
+	}))
+	router.Use(RateLimit(RateLimitConfig{Limit: 1, Period: time.Minute, Key: RateLimitDeprioritized()}))
+	router.GET("/", func(c *Context) {
+		c.String(http.StatusOK, c.UserAgentInfo().Rule)
+	})
+
+	w := performRequest(router, "GET", "/", header{"User-Agent", "Scrapy/2.5"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "GET", "/")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequest(router, "GET", "/", header{"User-Agent", "Googlebot/2.1"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// only the deprioritized requests are limited
+	for i := 0; i < 2; i++ {
+		w = performRequest(router, "GET", "/", header{"User-Agent", "Mozilla/5.0 Firefox/89.0"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "browser", w.Body.String())
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+	w = performRequest(router, "GET", "/", header{"User-Agent", "SomeCrawler/1.0"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "crawlers", w.Body.String())
+	w = performRequest(router, "GET", "/", header{"User-Agent", "SomeCrawler/1.0"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestLogFieldUserAgentClass(t *testing.T) {
+	params := &LogFormatterParams{Keys: map[string]interface{}{UserAgentKey: &UserAgentInfo{Class: UserAgentTool}}}
+	assert.Equal(t, "tool", LogFieldUserAgentClass.Value(params))
+	assert.Nil(t, LogFieldUserAgentClass.Value(&LogFormatterParams{}))
+}
@@ -11,6 +11,8 @@ import (
 	"path"
 	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -113,6 +115,51 @@ func parseAccept(acceptHeader string) []string {
 	return out
 }
 
+// parseAcceptLanguage returns the normalized language tags of an Accept-Language
+// header by decreasing weight, leaving out the ones with a zero weight.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params := part, ""
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			tag, params = part[:i], part[i+1:]
+		}
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if v, err := strconv.ParseFloat(params[2:], 64); err == nil {
+				q = v
+			}
+		}
+		if tag = normalizeLanguageTag(tag); tag != "" && q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.tag
+	}
+	return out
+}
+
+// normalizeLanguageTag lowercases a language tag and uses dashes, "en_US" becomes "en-us".
+func normalizeLanguageTag(tag string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(tag)), "_", "-", -1)
+}
+
+// primaryLanguage returns the language subtag of a normalized tag, "de" for "de-ch".
+func primaryLanguage(tag string) string {
+	if i := strings.IndexByte(tag, '-'); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
 func lastChar(str string) uint8 {
 	if str == "" {
 		panic("The length of the string can't be 0")
@@ -121,6 +168,13 @@ func lastChar(str string) uint8 {
 }
 
 func nameOfFunction(f interface{}) string {
+	if h, ok := f.(HandlerFunc); ok {
+		return HandlerFuncName(h)
+	}
+	return funcName(f)
+}
+
+func funcName(f interface{}) string {
 	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
 }
 
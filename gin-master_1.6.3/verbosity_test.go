@@ -0,0 +1,140 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineSetMode(t *testing.T) {
+	SetMode(ReleaseMode)
+	defer SetMode(TestMode)
+
+	router := New()
+	assert.Equal(t, ReleaseMode, router.Mode())
+	assert.False(t, router.IsDebugging())
+
+	router.SetMode(DebugMode)
+	assert.Equal(t, DebugMode, router.Mode())
+	assert.True(t, router.IsDebugging())
+	assert.Equal(t, ReleaseMode, Mode())
+
+	router.SetMode("")
+	assert.Equal(t, ReleaseMode, router.Mode())
+
+	assert.Panics(t, func() { router.SetMode("verbose") })
+}
+
+func TestEngineModePrintsRoutes(t *testing.T) {
+	re := captureOutput(t, func() {
+		router := New()
+		router.SetMode(DebugMode)
+		router.GET("/ping", func(c *Context) {})
+	})
+	assert.Contains(t, re, "[GIN-debug] GET    /ping")
+
+	re = captureOutput(t, func() {
+		SetMode(DebugMode)
+		defer SetMode(TestMode)
+		router := New()
+		router.SetMode(ReleaseMode)
+		router.GET("/ping", func(c *Context) {})
+	})
+	assert.NotContains(t, re, "/ping")
+}
+
+func TestParseTraceFlags(t *testing.T) {
+	flags, err := ParseTraceFlags("render", "router")
+	assert.NoError(t, err)
+	assert.Equal(t, TraceRouter|TraceRender, flags)
+	assert.Equal(t, []string{"router", "render"}, flags.Names())
+	assert.Equal(t, []string{"router", "binding", "render"}, TraceAll.Names())
+	assert.Equal(t, []string{}, TraceNone.Names())
+
+	_, err = ParseTraceFlags("router", "sql")
+	assert.EqualError(t, err, "gin trace unknown: sql (available traces: router binding render)")
+}
+
+func TestEngineTrace(t *testing.T) {
+	type user struct {
+		Name string `json:"name" binding:"required"`
+	}
+	router := New()
+	router.POST("/users/:id", func(c *Context) {
+		var u user
+		if err := c.ShouldBindJSON(&u); err != nil {
+			c.String(http.StatusBadRequest, "invalid")
+			return
+		}
+		c.JSON(http.StatusCreated, u)
+	})
+	serve := func() {
+		w := performRequestWithBody(router, http.MethodPost, "/users/42", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		performRequest(router, http.MethodGet, "/missing")
+	}
+
+	out := new(bytes.Buffer)
+	defer func(w io.Writer) { DefaultWriter = w }(DefaultWriter)
+	DefaultWriter = out
+
+	serve()
+	assert.Empty(t, out.String())
+
+	router.SetTrace(TraceAll)
+	assert.Equal(t, TraceAll, router.Trace())
+	serve()
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[0], "[GIN-trace] binding: json into *gin.user: Key: 'user.Name'")
+	assert.Equal(t, "[GIN-trace] render: render.String 400", lines[1])
+	assert.Equal(t, "[GIN-trace] router: POST /users/42 --> /users/:id 400", lines[2])
+	assert.Equal(t, "[GIN-trace] router: GET /missing --> <no route> 404", lines[3])
+
+	router.SetTrace(TraceNone)
+	before := out.String()
+	serve()
+	assert.Equal(t, before, out.String())
+}
+
+func TestDebugVerbosity(t *testing.T) {
+	router := New()
+	router.DebugVerbosity("/debug/verbosity", func(c *Context) {
+		if c.GetHeader("X-Admin") != "yes" {
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+	})
+	admin := header{Key: "X-Admin", Value: "yes"}
+	put := func(body string) *bytes.Buffer {
+		w := performRequestWithBody(router, http.MethodPut, "/debug/verbosity", body, admin)
+		return w.Body
+	}
+
+	w := performRequest(router, http.MethodGet, "/debug/verbosity")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequest(router, http.MethodGet, "/debug/verbosity", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"mode":"test","trace":[]}`, w.Body.String())
+
+	assert.JSONEq(t, `{"mode":"test","trace":["router","binding"]}`, put(`{"trace":["binding","router"]}`).String())
+	assert.Equal(t, TraceRouter|TraceBinding, router.Trace())
+
+	assert.JSONEq(t, `{"mode":"release","trace":["router","binding"]}`, put(`{"mode":"release"}`).String())
+	assert.Equal(t, ReleaseMode, router.Mode())
+	assert.Equal(t, TestMode, Mode())
+
+	assert.JSONEq(t, `{"mode":"release","trace":[]}`, put(`{"trace":[]}`).String())
+
+	assert.JSONEq(t, `{"error":"gin mode unknown: verbose (available mode: debug release test)"}`, put(`{"mode":"verbose"}`).String())
+	assert.JSONEq(t, `{"error":"gin trace unknown: sql (available traces: router binding render)"}`, put(`{"trace":["sql"]}`).String())
+	assert.Equal(t, ReleaseMode, router.Mode())
+}
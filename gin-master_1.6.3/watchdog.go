@@ -0,0 +1,125 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+)
+
+const defaultWatchdogThreshold = 10 * time.Second
+
+// SlowRequest describes a request still in flight after the threshold of the watchdog.
+type SlowRequest struct {
+	Method    string
+	Path      string
+	Route     string
+	RequestID string
+	Elapsed   time.Duration
+	// Stack is the stack trace of the goroutine serving the request, i.e. where the
+	// handlers are stuck, empty if the request ended meanwhile.
+	Stack []byte
+}
+
+// WatchdogConfig defines the config for Watchdog middleware.
+type WatchdogConfig struct {
+	// Threshold is the duration after which a request in flight is reported.
+	// Optional. Default value is 10 seconds.
+	Threshold time.Duration
+
+	// OnSlow is called with each slow request, from another goroutine.
+	// Optional. The requests are written to Output by default.
+	OnSlow func(req *SlowRequest)
+
+	// Output is a writer where the slow requests are written when OnSlow is nil.
+	// Optional. Default value is gin.DefaultErrorWriter.
+	Output io.Writer
+}
+
+// Watchdog returns a middleware reporting the requests which are still in flight after
+// the threshold, with a snapshot of the stack of their goroutine, e.g. to find the
+// handlers stuck on a lock or on a downstream call:
+//     router.Use(gin.Watchdog(gin.WatchdogConfig{Threshold: 5 * time.Second}))
+// Each request is reported once, while the handlers keep running.
+func Watchdog(conf WatchdogConfig) HandlerFunc {
+	threshold := conf.Threshold
+	if threshold <= 0 {
+		threshold = defaultWatchdogThreshold
+	}
+	onSlow := conf.OnSlow
+	if onSlow == nil {
+		out := conf.Output
+		if out == nil {
+			out = DefaultErrorWriter
+		}
+		onSlow = func(req *SlowRequest) {
+			fmt.Fprintf(out, "[WATCHDOG] %s %s (%s) still in flight after %v, request id %q\n%s\n",
+				req.Method, req.Path, req.Route, req.Elapsed, req.RequestID, req.Stack)
+		}
+	}
+
+	return func(c *Context) {
+		// the Context is reused once the request ends, so the timer only uses copies
+		start := time.Now()
+		req := SlowRequest{
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Route:     c.FullPath(),
+			RequestID: c.RequestID(),
+		}
+		goroutine := currentGoroutine()
+		timer := time.AfterFunc(threshold, func() {
+			slow := req
+			slow.Elapsed = time.Since(start)
+			slow.Stack = goroutineStack(goroutine)
+			onSlow(&slow)
+		})
+		defer timer.Stop()
+
+		c.Next()
+	}
+}
+
+// currentGoroutine returns the header of the stack trace of the current goroutine,
+// e.g. "goroutine 18 [", which identifies it in the stack traces of all the goroutines.
+func currentGoroutine() []byte {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	if i := bytes.IndexByte(buf[:n], '['); i > 0 {
+		return append([]byte(nil), buf[:i+1]...)
+	}
+	return nil
+}
+
+// goroutineStack returns the stack trace of the goroutine, or nil if it's gone.
+func goroutineStack(goroutine []byte) []byte {
+	if goroutine == nil {
+		return nil
+	}
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	for len(buf) > 0 {
+		end := bytes.Index(buf, []byte("\n\n"))
+		if end < 0 {
+			end = len(buf)
+		}
+		if bytes.HasPrefix(buf, goroutine) {
+			return buf[:end]
+		}
+		buf = buf[end:]
+		buf = bytes.TrimLeft(buf, "\n")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Gin Core Team.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gin
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stuckHandler(release chan struct{}) HandlerFunc {
+	return func(c *Context) {
+		<-release
+		c.String(http.StatusOK, "done")
+	}
+}
+
+func TestWatchdogReportsStuckRequests(t *testing.T) {
+	var mu sync.Mutex
+	var reported []*SlowRequest
+	release := make(chan struct{})
+	router := New()
+	router.Use(Watchdog(WatchdogConfig{
+		Threshold: 10 * time.Millisecond,
+		OnSlow: func(req *SlowRequest) {
+			mu.Lock()
+			reported = append(reported, req)
+			mu.Unlock()
+			close(release)
+		},
+	}))
+	router.GET("/stuck/:id", stuckHandler(release))
+	router.GET("/fast", func(c *Context) {})
+
+	w := performRequest(router, "GET", "/fast")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(router, "GET", "/stuck/1")
+	assert.Equal(t, "done", w.Body.String())
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, reported, 1) {
+		req := reported[0]
+		assert.Equal(t, "GET", req.Method)
+		assert.Equal(t, "/stuck/1", req.Path)
+		assert.Equal(t, "/stuck/:id", req.Route)
+		assert.True(t, req.Elapsed >= 10*time.Millisecond)
+		assert.True(t, bytes.HasPrefix(req.Stack, []byte("goroutine ")))
+		assert.Contains(t, string(req.Stack), "stuckHandler")
+		assert.NotContains(t, string(req.Stack), "\n\n")
+	}
+}
+
+func TestWatchdogOutput(t *testing.T) {
+	var buffer syncBuffer
+	release := make(chan struct{})
+	router := New()
+	router.Use(Watchdog(WatchdogConfig{Threshold: time.Millisecond, Output: &buffer}))
+	router.GET("/stuck", stuckHandler(release))
+
+	go func() {
+		for !bytes.Contains(buffer.Bytes(), []byte("stuckHandler")) {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+	}()
+	performRequest(router, "GET", "/stuck")
+	assert.Contains(t, string(buffer.Bytes()), "[WATCHDOG] GET /stuck (/stuck) still in flight after")
+}
+
+func TestGoroutineStackOfEndedGoroutine(t *testing.T) {
+	done := make(chan []byte)
+	go func() { done <- currentGoroutine() }()
+	goroutine := <-done
+	waitFor(t, func() bool { return goroutineStack(goroutine) == nil })
+	assert.Nil(t, goroutineStack(nil))
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}